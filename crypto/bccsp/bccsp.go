@@ -77,7 +77,7 @@ type Signer interface {
 	//
 	// Note that when a signature of a hash of a larger message is needed,
 	// the caller is responsible for hashing the larger message and passing
-	// the hash (as digest).
+	// the hash (as digest), unless PrehashSignerOpts are used.
 	Sign(k Key, digest []byte, opts SignerOpts) (signature []byte, err error)
 }
 
@@ -104,6 +104,34 @@ type SignerOpts interface {
 	crypto.SignerOpts
 }
 
+// PrehashSignerOpts makes explicit whether the input of Sign and Verify
+// is the final digest or the message that has to be hashed first.
+// The zero value, as signer options of any other type, treats the input
+// as prehashed. RSA keys sign and verify PKCS #1 v1.5 signatures with it.
+type PrehashSignerOpts struct {
+	// Hash is the hash function applied to the message when HashMessage
+	// is set, or the one which produced the digest otherwise. If zero,
+	// the hash function configured in the CSP is used for hashing.
+	// It is required for RSA keys when the input is prehashed.
+	Hash crypto.Hash
+	// HashMessage is true when the input is the message which has to be
+	// hashed first, false when it is the final digest.
+	HashMessage bool
+	// AutoHashForCurve selects SHA-2 hash function matching the curve of
+	// ECDSA keys when HashMessage is set and Hash is zero: SHA-224 for
+	// curves of up to 224 bits, SHA-256 for P-256 and secp256k1, SHA-384
+	// for P-384 and SHA-512 for P-521. Hash function configured in the CSP
+	// is used for other keys.
+	AutoHashForCurve bool
+}
+
+// HashFunc returns an identifier for the hash function used to produce
+// the message passed to Signer.Sign, or else zero to indicate that no
+// hashing was done.
+func (opts *PrehashSignerOpts) HashFunc() crypto.Hash {
+	return opts.Hash
+}
+
 // EncrypterOpts contains options for encrypting with a CSP.
 type EncrypterOpts interface{}
 
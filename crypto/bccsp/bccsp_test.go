@@ -47,10 +47,10 @@ func TestAESOpts(t *testing.T) {
 func TestRSAOpts(t *testing.T) {
 	test := func(ephemeral bool) {
 		for _, opts := range []KeyGenOpts{
-			&RSA1024KeyGenOpts{ephemeral},
-			&RSA2048KeyGenOpts{ephemeral},
-			&RSA3072KeyGenOpts{ephemeral},
-			&RSA4096KeyGenOpts{ephemeral},
+			&RSA1024KeyGenOpts{Temporary: ephemeral},
+			&RSA2048KeyGenOpts{Temporary: ephemeral},
+			&RSA3072KeyGenOpts{Temporary: ephemeral},
+			&RSA4096KeyGenOpts{Temporary: ephemeral},
 		} {
 			expectedAlgorithm := reflect.TypeOf(opts).String()[7:14]
 			assert.Equal(t, expectedAlgorithm, opts.Algorithm())
@@ -124,9 +124,9 @@ func TestKeyGenOpts(t *testing.T) {
 	test := func(ephemeral bool) {
 		for _, opts := range []KeyGenOpts{
 			&HMACImportKeyOpts{ephemeral},
-			&RSAKeyGenOpts{ephemeral},
+			&RSAKeyGenOpts{Temporary: ephemeral},
 			&RSAGoPublicKeyImportOpts{ephemeral},
-			&X509PublicKeyImportOpts{ephemeral},
+			&X509PublicKeyImportOpts{Temporary: ephemeral},
 			&AES256ImportKeyOpts{ephemeral},
 		} {
 			expectedAlgorithm := expectedAlgorithms[reflect.TypeOf(opts)]
@@ -137,3 +137,33 @@ func TestKeyGenOpts(t *testing.T) {
 	test(true)
 	test(false)
 }
+
+func TestMemoryHardDeriveKeyOpts(t *testing.T) {
+	opts := &MemoryHardDeriveKeyOpts{KDF: Scrypt, Params: MemoryHardParams{N: 1 << 15, R: 8, P: 1}, Salt: []byte("salt"), Length: 32}
+	assert.Equal(t, "$scrypt$ln=15,r=8,p=1,len=32$c2FsdA", opts.String())
+	assert.Equal(t, Scrypt, opts.Algorithm())
+	parsed, err := ParseMemoryHardDeriveKeyOpts(opts.String())
+	assert.NoError(t, err)
+	assert.Equal(t, opts, parsed)
+
+	opts = &MemoryHardDeriveKeyOpts{KDF: Argon2id, Params: MemoryHardParams{Time: 3, Memory: 65536, Threads: 4}, Salt: []byte("salt")}
+	assert.Equal(t, "$argon2id$v=19$m=65536,t=3,p=4,len=0$c2FsdA", opts.String())
+	parsed, err = ParseMemoryHardDeriveKeyOpts(opts.String())
+	assert.NoError(t, err)
+	assert.Equal(t, opts, parsed)
+
+	for _, s := range []string{
+		"",
+		"$pbkdf2$len=32$c2FsdA",
+		"$argon2id$v=16$m=65536,t=3,p=4,len=32$c2FsdA",
+		"$scrypt$ln=15,r=8,p=1,len=32$!!",
+		"$scrypt$ln=15,r=8,p=x$c2FsdA",
+		"$scrypt$ln=15,r=8,p=-1$c2FsdA",
+		"$scrypt$ln=64,r=8,p=1$c2FsdA",
+		"$scrypt$ln=15,m=8$c2FsdA",
+		"$argon2id$v=19$m=65536,t=3,p=256$c2FsdA",
+	} {
+		_, err := ParseMemoryHardDeriveKeyOpts(s)
+		assert.Error(t, err, s)
+	}
+}
@@ -15,6 +15,8 @@
 
 package bccsp
 
+import "time"
+
 const (
 	// ECDSA Elliptic Curve Digital Signature Algorithm (key gen, import, sign, verify),
 	// at default security level.
@@ -31,6 +33,9 @@ const (
 	// ECDSA Elliptic Curve Digital Signature Algorithm over Curve25519
 	ED25519 = "ED25519"
 
+	// X25519 Elliptic Curve Diffie-Hellman key agreement over Curve25519
+	X25519 = "X25519"
+
 	// ECDSAReRand ECDSA key re-randomization
 	ECDSAReRand = "ECDSA_RERAND"
 
@@ -65,6 +70,14 @@ const (
 	HMAC = "HMAC"
 	// HMACTruncated256 HMAC truncated at 256 bits.
 	HMACTruncated256 = "HMAC_TRUNCATED_256"
+	// HKDF HMAC-based extract-and-expand key derivation function.
+	HKDF = "HKDF"
+	// Scrypt memory-hard password-based key derivation function.
+	Scrypt = "SCRYPT"
+	// Argon2id memory-hard password-based key derivation function.
+	Argon2id = "ARGON2ID"
+	// ECDH Elliptic Curve Diffie-Hellman key agreement.
+	ECDH = "ECDH"
 
 	// X509Certificate Label for X509 certificate related operation
 	X509Certificate = "X509Certificate"
@@ -218,6 +231,32 @@ func (opts *HMACDeriveKeyOpts) Argument() []byte {
 	return opts.Arg
 }
 
+// HKDFDeriveKeyOpts contains options for HKDF key derivation.
+// Derived keys are not exportable and can be derived from again,
+// which allows building chains of keys such as root, purpose and
+// context keys where only the leaf key is used.
+type HKDFDeriveKeyOpts struct {
+	Temporary bool
+	// Salt is the optional HKDF salt.
+	Salt []byte
+	// Info is the HKDF context and application specific information.
+	Info []byte
+	// Length is the length of derived key in bytes.
+	// Default of 32 bytes is used when zero.
+	Length int
+}
+
+// Algorithm returns the key derivation algorithm identifier (to be used).
+func (opts *HKDFDeriveKeyOpts) Algorithm() string {
+	return HKDF
+}
+
+// Ephemeral returns true if the key to generate has to be ephemeral,
+// false otherwise.
+func (opts *HKDFDeriveKeyOpts) Ephemeral() bool {
+	return opts.Temporary
+}
+
 // AES256ImportKeyOpts contains options for importing AES 256 keys.
 type AES256ImportKeyOpts struct {
 	Temporary bool
@@ -253,6 +292,10 @@ func (opts *HMACImportKeyOpts) Ephemeral() bool {
 // RSAKeyGenOpts contains options for RSA key generation.
 type RSAKeyGenOpts struct {
 	Temporary bool
+	// PublicExponent is the public exponent of the generated key. It must be
+	// odd and greater than 2^16 as recommended by FIPS 186-4. Zero selects
+	// the default exponent 65537.
+	PublicExponent int
 }
 
 // Algorithm returns the key generation algorithm identifier (to be used).
@@ -282,9 +325,30 @@ func (opts *RSAGoPublicKeyImportOpts) Ephemeral() bool {
 	return opts.Temporary
 }
 
+// RSAPrivateKeyImportOpts contains options for RSA secret key importation in DER format
+// or PKCS#8 format.
+type RSAPrivateKeyImportOpts struct {
+	Temporary bool
+}
+
+// Algorithm returns the key importation algorithm identifier (to be used).
+func (opts *RSAPrivateKeyImportOpts) Algorithm() string {
+	return RSA
+}
+
+// Ephemeral returns true if the key to generate has to be ephemeral,
+// false otherwise.
+func (opts *RSAPrivateKeyImportOpts) Ephemeral() bool {
+	return opts.Temporary
+}
+
 // X509PublicKeyImportOpts contains options for importing public keys from an x509 certificate
 type X509PublicKeyImportOpts struct {
 	Temporary bool
+	// CheckValidity rejects certificates which are expired or not yet valid.
+	CheckValidity bool
+	// Now returns the time at which validity is checked, time.Now when nil.
+	Now func() time.Time
 }
 
 // Algorithm returns the key importation algorithm identifier (to be used).
@@ -297,3 +361,11 @@ func (opts *X509PublicKeyImportOpts) Algorithm() string {
 func (opts *X509PublicKeyImportOpts) Ephemeral() bool {
 	return opts.Temporary
 }
+
+// ValidityTime returns the time at which certificate validity is checked.
+func (opts *X509PublicKeyImportOpts) ValidityTime() time.Time {
+	if opts.Now == nil {
+		return time.Now()
+	}
+	return opts.Now()
+}
@@ -15,7 +15,11 @@
 
 package bccsp
 
-import "io"
+import (
+	"io"
+
+	"github.com/ipfn/ipfn/pkg/digest"
+)
 
 // AES128KeyGenOpts contains options for AES key generation at 128 security level
 type AES128KeyGenOpts struct {
@@ -79,3 +83,36 @@ type AESCBCPKCS7ModeOpts struct {
 	// It is used only if different from nil.
 	PRNG io.Reader
 }
+
+// AESCBCHMACModeOpts contains options for AES encryption in CBC mode
+// with PKCS7 padding followed by an HMAC over the IV and ciphertext
+// (encrypt-then-MAC). The MAC is verified before any decryption.
+type AESCBCHMACModeOpts struct {
+	// MACKey is the key used to compute the HMAC tag.
+	// It should be different from the encryption key.
+	MACKey Key
+	// Hash is the hash function used to compute the HMAC tag.
+	Hash digest.Type
+}
+
+// AESGCMStreamModeOpts contains options for chunked authenticated
+// streaming encryption with AES in GCM mode.
+type AESGCMStreamModeOpts struct {
+	// ChunkSize is the size of plaintext chunks sealed separately.
+	// Default chunk size is used when zero.
+	ChunkSize int
+}
+
+// AESGCMSIVModeOpts contains options for nonce misuse-resistant
+// authenticated encryption with AES-GCM-SIV (RFC 8452).
+// Ciphertext is the nonce followed by sealed plaintext and tag.
+// Notice that reusing nonce only reveals whether the same plaintext
+// and additional data were encrypted.
+type AESGCMSIVModeOpts struct {
+	// Nonce is the 12 bytes nonce used on encryption.
+	// Random nonce is sampled when nil. On decryption nonce is read
+	// from ciphertext, Nonce must match it if different from nil.
+	Nonce []byte
+	// AdditionalData is authenticated but not encrypted.
+	AdditionalData []byte
+}
@@ -15,6 +15,12 @@
 
 package bccsp
 
+import (
+	"crypto"
+	"crypto/elliptic"
+	"math/big"
+)
+
 // ECDSAP256KeyGenOpts contains options for ECDSA key generation with curve P-256.
 type ECDSAP256KeyGenOpts struct {
 	Temporary bool
@@ -46,3 +52,93 @@ func (opts *ECDSAP384KeyGenOpts) Algorithm() string {
 func (opts *ECDSAP384KeyGenOpts) Ephemeral() bool {
 	return opts.Temporary
 }
+
+// ECDSAPointImportOpts contains options for importing ECDSA public keys
+// encoded as SEC1 compressed or uncompressed points.
+type ECDSAPointImportOpts struct {
+	Temporary bool
+	// Curve is the curve of the point.
+	Curve elliptic.Curve
+}
+
+// Algorithm returns the key importation algorithm identifier (to be used).
+func (opts *ECDSAPointImportOpts) Algorithm() string {
+	return ECDSA
+}
+
+// Ephemeral returns true if the key to generate has to be ephemeral,
+// false otherwise.
+func (opts *ECDSAPointImportOpts) Ephemeral() bool {
+	return opts.Temporary
+}
+
+// ECDHDeriveKeyOpts contains options for derivation of symmetric key from
+// ECDH shared secret of ECDSA private key and public key of the peer.
+type ECDHDeriveKeyOpts struct {
+	Temporary bool
+	// PeerPublicKey is ECDSA public key of the peer.
+	PeerPublicKey Key
+	// Length is length of derived key in bytes. Zero selects 32 bytes.
+	Length int
+}
+
+// Algorithm returns the key derivation algorithm identifier (to be used).
+func (opts *ECDHDeriveKeyOpts) Algorithm() string {
+	return ECDH
+}
+
+// Ephemeral returns true if the key to derive has to be ephemeral,
+// false otherwise.
+func (opts *ECDHDeriveKeyOpts) Ephemeral() bool {
+	return opts.Temporary
+}
+
+// ECDSAPartialSignOpts contains participant's share of a one-time presignature
+// used to produce partial ECDSA signature with a key share.
+// See utils.NewECDSAPresignatures for description of the threshold scheme.
+type ECDSAPartialSignOpts struct {
+	// Index is the participant index starting at 1.
+	Index int
+	// R is the R value of the signature.
+	R *big.Int
+	// NonceShare is participant's share of inverted nonce.
+	NonceShare *big.Int
+	// Correction is participant's correction term of nonce and key product.
+	Correction *big.Int
+	// H is the hash function to be used
+	H crypto.Hash
+}
+
+// HashFunc returns an identifier for the hash function used to produce
+// the message passed to Signer.Sign, or else zero to indicate that no
+// hashing was done.
+func (opts *ECDSAPartialSignOpts) HashFunc() crypto.Hash {
+	return opts.H
+}
+
+// ECDSASignerOpts contains options for ECDSA signing.
+type ECDSASignerOpts struct {
+	// Blind enables blinding of the secret scalar arithmetic.
+	// Nonce and private key are multiplied by random blinding factor
+	// before modular inversion and multiplication, which otherwise use
+	// variable time big integer arithmetic. Scalar base multiplication
+	// relies on constant time implementation of the standard library.
+	//
+	// Deterministic signatures are always computed with blinded arithmetic,
+	// blinding factor does not affect the signature.
+	Blind bool
+	// Deterministic selects RFC 6979 deterministic nonce when true and
+	// random nonce when false. When nil the default of the provider is used.
+	// Signing the same digest with the same key deterministically always
+	// produces the same signature.
+	Deterministic *bool
+	// H is the hash function to be used
+	H crypto.Hash
+}
+
+// HashFunc returns an identifier for the hash function used to produce
+// the message passed to Signer.Sign, or else zero to indicate that no
+// hashing was done.
+func (opts *ECDSASignerOpts) HashFunc() crypto.Hash {
+	return opts.H
+}
@@ -0,0 +1,158 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bccsp
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// Maximum cost parameters of memory-hard key derivation, which bound memory
+// and time that options, e.g. parsed from untrusted string, can request.
+// Scrypt uses 128 * N * R bytes of memory, up to 2 GiB.
+const (
+	// MaxScryptLogN - Maximum base 2 logarithm of scrypt cost N.
+	MaxScryptLogN = 20
+	// MaxScryptR - Maximum scrypt block size.
+	MaxScryptR = 16
+	// MaxScryptP - Maximum scrypt parallelization.
+	MaxScryptP = 16
+	// MaxArgon2Time - Maximum number of Argon2id passes.
+	MaxArgon2Time = 16
+	// MaxArgon2Memory - Maximum Argon2id memory size in KiB, 2 GiB.
+	MaxArgon2Memory = 2 << 20
+)
+
+// MemoryHardParams contains cost parameters of memory-hard key derivation.
+// N, R and P are used by Scrypt, Time, Memory and Threads by Argon2id.
+type MemoryHardParams struct {
+	// N is the scrypt CPU and memory cost, a power of two.
+	N int
+	// R is the scrypt block size.
+	R int
+	// P is the scrypt parallelization.
+	P int
+	// Time is the number of Argon2id passes over memory.
+	Time uint32
+	// Memory is the Argon2id memory size in KiB.
+	Memory uint32
+	// Threads is the Argon2id parallelism.
+	Threads uint8
+}
+
+// MemoryHardDeriveKeyOpts contains options for deriving symmetric key from
+// low-entropy secret, such as passphrase imported as HMAC key, using
+// memory-hard function. Options are the record required to derive
+// the same key again, see String and ParseMemoryHardDeriveKeyOpts.
+type MemoryHardDeriveKeyOpts struct {
+	Temporary bool
+	// KDF is the key derivation function, Scrypt or Argon2id.
+	KDF string
+	// Params are cost parameters of KDF.
+	Params MemoryHardParams
+	// Salt is the random salt, unique per secret.
+	Salt []byte
+	// Length is the length of derived key in bytes.
+	// Default of 32 bytes is used when zero.
+	Length int
+}
+
+// Algorithm returns the key derivation algorithm identifier (to be used).
+func (opts *MemoryHardDeriveKeyOpts) Algorithm() string {
+	return opts.KDF
+}
+
+// Ephemeral returns true if the key to generate has to be ephemeral,
+// false otherwise.
+func (opts *MemoryHardDeriveKeyOpts) Ephemeral() bool {
+	return opts.Temporary
+}
+
+// String encodes options in PHC string format without hash, e.g.
+// "$scrypt$ln=15,r=8,p=1,len=32$<salt>" or
+// "$argon2id$v=19$m=65536,t=3,p=4,len=32$<salt>",
+// with salt encoded as unpadded base64.
+func (opts *MemoryHardDeriveKeyOpts) String() string {
+	salt := base64.RawStdEncoding.EncodeToString(opts.Salt)
+	p := opts.Params
+	switch opts.KDF {
+	case Scrypt:
+		ln := 0
+		for n := p.N; n > 1; n >>= 1 {
+			ln++
+		}
+		return fmt.Sprintf("$scrypt$ln=%d,r=%d,p=%d,len=%d$%s", ln, p.R, p.P, opts.Length, salt)
+	case Argon2id:
+		return fmt.Sprintf("$argon2id$v=19$m=%d,t=%d,p=%d,len=%d$%s", p.Memory, p.Time, p.Threads, opts.Length, salt)
+	}
+	return fmt.Sprintf("$%s$len=%d$%s", strings.ToLower(opts.KDF), opts.Length, salt)
+}
+
+// ParseMemoryHardDeriveKeyOpts parses options encoded by String.
+// Parsed options are not ephemeral. Parameters exceeding maximums, such as
+// MaxScryptLogN, are rejected. They are not validated against minimums,
+// which is done on key derivation.
+func ParseMemoryHardDeriveKeyOpts(s string) (*MemoryHardDeriveKeyOpts, error) {
+	parts := strings.Split(s, "$")
+	opts := &MemoryHardDeriveKeyOpts{}
+	var params string
+	switch {
+	case len(parts) == 4 && parts[0] == "" && parts[1] == "scrypt":
+		opts.KDF, params = Scrypt, parts[2]
+	case len(parts) == 5 && parts[0] == "" && parts[1] == "argon2id" && parts[2] == "v=19":
+		opts.KDF, params = Argon2id, parts[3]
+	default:
+		return nil, fmt.Errorf("Invalid memory-hard KDF options [%s]", s)
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[len(parts)-1])
+	if err != nil {
+		return nil, fmt.Errorf("Invalid salt [%s]", err)
+	}
+	opts.Salt = salt
+
+	for _, kv := range strings.Split(params, ",") {
+		var (
+			name  string
+			value uint64
+		)
+		if i := strings.IndexByte(kv, '='); i > 0 {
+			name = kv[:i]
+			if _, err := fmt.Sscanf(kv[i+1:], "%d", &value); err != nil || fmt.Sprint(value) != kv[i+1:] {
+				return nil, fmt.Errorf("Invalid parameter [%s]", kv)
+			}
+		}
+		switch {
+		case name == "len" && value <= 1<<16:
+			opts.Length = int(value)
+		case opts.KDF == Scrypt && name == "ln" && value <= MaxScryptLogN:
+			opts.Params.N = 1 << value
+		case opts.KDF == Scrypt && name == "r" && value <= MaxScryptR:
+			opts.Params.R = int(value)
+		case opts.KDF == Scrypt && name == "p" && value <= MaxScryptP:
+			opts.Params.P = int(value)
+		case opts.KDF == Argon2id && name == "m" && value <= MaxArgon2Memory:
+			opts.Params.Memory = uint32(value)
+		case opts.KDF == Argon2id && name == "t" && value <= MaxArgon2Time:
+			opts.Params.Time = uint32(value)
+		case opts.KDF == Argon2id && name == "p" && value <= 255:
+			opts.Params.Threads = uint8(value)
+		default:
+			return nil, fmt.Errorf("Invalid parameter [%s]", kv)
+		}
+	}
+	return opts, nil
+}
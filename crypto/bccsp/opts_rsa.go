@@ -19,6 +19,10 @@ package bccsp
 // RSA1024KeyGenOpts contains options for RSA key generation at 1024 security.
 type RSA1024KeyGenOpts struct {
 	Temporary bool
+	// PublicExponent is the public exponent of the generated key. It must be
+	// odd and greater than 2^16 as recommended by FIPS 186-4. Zero selects
+	// the default exponent 65537.
+	PublicExponent int
 }
 
 // Algorithm returns the key generation algorithm identifier (to be used).
@@ -35,6 +39,10 @@ func (opts *RSA1024KeyGenOpts) Ephemeral() bool {
 // RSA2048KeyGenOpts contains options for RSA key generation at 2048 security.
 type RSA2048KeyGenOpts struct {
 	Temporary bool
+	// PublicExponent is the public exponent of the generated key. It must be
+	// odd and greater than 2^16 as recommended by FIPS 186-4. Zero selects
+	// the default exponent 65537.
+	PublicExponent int
 }
 
 // Algorithm returns the key generation algorithm identifier (to be used).
@@ -51,6 +59,10 @@ func (opts *RSA2048KeyGenOpts) Ephemeral() bool {
 // RSA3072KeyGenOpts contains options for RSA key generation at 3072 security.
 type RSA3072KeyGenOpts struct {
 	Temporary bool
+	// PublicExponent is the public exponent of the generated key. It must be
+	// odd and greater than 2^16 as recommended by FIPS 186-4. Zero selects
+	// the default exponent 65537.
+	PublicExponent int
 }
 
 // Algorithm returns the key generation algorithm identifier (to be used).
@@ -67,6 +79,10 @@ func (opts *RSA3072KeyGenOpts) Ephemeral() bool {
 // RSA4096KeyGenOpts contains options for RSA key generation at 4096 security.
 type RSA4096KeyGenOpts struct {
 	Temporary bool
+	// PublicExponent is the public exponent of the generated key. It must be
+	// odd and greater than 2^16 as recommended by FIPS 186-4. Zero selects
+	// the default exponent 65537.
+	PublicExponent int
 }
 
 // Algorithm returns the key generation algorithm identifier (to be used).
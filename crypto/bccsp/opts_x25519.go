@@ -0,0 +1,49 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bccsp
+
+// X25519KeyGenOpts contains options for X25519 key generation.
+type X25519KeyGenOpts struct {
+	Temporary bool
+}
+
+// Algorithm returns the key generation algorithm identifier (to be used).
+func (opts *X25519KeyGenOpts) Algorithm() string {
+	return X25519
+}
+
+// Ephemeral returns true if the key to generate has to be ephemeral,
+// false otherwise.
+func (opts *X25519KeyGenOpts) Ephemeral() bool {
+	return opts.Temporary
+}
+
+// X25519PublicKeyImportOpts contains options for importing raw 32 bytes
+// X25519 public keys.
+type X25519PublicKeyImportOpts struct {
+	Temporary bool
+}
+
+// Algorithm returns the key importation algorithm identifier (to be used).
+func (opts *X25519PublicKeyImportOpts) Algorithm() string {
+	return X25519
+}
+
+// Ephemeral returns true if the key to generate has to be ephemeral,
+// false otherwise.
+func (opts *X25519PublicKeyImportOpts) Ephemeral() bool {
+	return opts.Temporary
+}
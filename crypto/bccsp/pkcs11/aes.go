@@ -0,0 +1,145 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkcs11
+
+import (
+	"crypto/aes"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/miekg/pkcs11"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+)
+
+// encryptAES - Encrypts plaintext with AES key on the token in CBC mode with
+// PKCS#7 padding. Ciphertext is prefixed with IV, same as in software CSP.
+func (csp *impl) encryptAES(k *secretKey, plaintext []byte, opts bccsp.EncrypterOpts) ([]byte, error) {
+	o, err := aesCBCPKCS7Opts(opts)
+	if err != nil {
+		return nil, err
+	}
+	if len(o.IV) != 0 && o.PRNG != nil {
+		return nil, errors.New("Invalid options. Either IV or PRNG should be different from nil, or both nil.")
+	}
+	iv := o.IV
+	if len(iv) == 0 {
+		prng := o.PRNG
+		if prng == nil {
+			prng = rand.Reader
+		}
+		iv = make([]byte, aes.BlockSize)
+		if _, err := io.ReadFull(prng, iv); err != nil {
+			return nil, fmt.Errorf("Failed generating IV [%s]", err)
+		}
+	}
+	if len(iv) != aes.BlockSize {
+		return nil, fmt.Errorf("Invalid IV. It must have length the block size [%d].", aes.BlockSize)
+	}
+
+	p11lib := csp.ctx
+	session := csp.getSession()
+	defer csp.returnSession(session)
+
+	key, err := findSecretKeyFromSKI(p11lib, session, k.ski)
+	if err != nil {
+		return nil, fmt.Errorf("Secret key not found [%s]", err)
+	}
+	mech := []*pkcs11.Mechanism{pkcs11.NewMechanism(pkcs11.CKM_AES_CBC_PAD, iv)}
+	if err = p11lib.EncryptInit(session, mech, *key); err != nil {
+		return nil, fmt.Errorf("P11: encrypt-initialize failed [%s]", err)
+	}
+	ciphertext, err := p11lib.Encrypt(session, plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("P11: encrypt failed [%s]", err)
+	}
+	return append(append([]byte{}, iv...), ciphertext...), nil
+}
+
+// decryptAES - Decrypts ciphertext prefixed with IV with AES key on the token
+// in CBC mode with PKCS#7 padding.
+func (csp *impl) decryptAES(k *secretKey, ciphertext []byte, opts bccsp.DecrypterOpts) ([]byte, error) {
+	if _, err := aesCBCPKCS7Opts(opts); err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < 2*aes.BlockSize || len(ciphertext)%aes.BlockSize != 0 {
+		return nil, errors.New("Invalid ciphertext. It must be a multiple of the block size.")
+	}
+
+	p11lib := csp.ctx
+	session := csp.getSession()
+	defer csp.returnSession(session)
+
+	key, err := findSecretKeyFromSKI(p11lib, session, k.ski)
+	if err != nil {
+		return nil, fmt.Errorf("Secret key not found [%s]", err)
+	}
+	mech := []*pkcs11.Mechanism{pkcs11.NewMechanism(pkcs11.CKM_AES_CBC_PAD, ciphertext[:aes.BlockSize])}
+	if err = p11lib.DecryptInit(session, mech, *key); err != nil {
+		return nil, fmt.Errorf("P11: decrypt-initialize failed [%s]", err)
+	}
+	plaintext, err := p11lib.Decrypt(session, ciphertext[aes.BlockSize:])
+	if err != nil {
+		return nil, fmt.Errorf("P11: decrypt failed [%s]", err)
+	}
+	return plaintext, nil
+}
+
+// aesCBCPKCS7Opts - Returns CBC mode options, the only mode supported
+// with AES keys on the token.
+func aesCBCPKCS7Opts(opts interface{}) (*bccsp.AESCBCPKCS7ModeOpts, error) {
+	switch o := opts.(type) {
+	case *bccsp.AESCBCPKCS7ModeOpts:
+		return o, nil
+	case bccsp.AESCBCPKCS7ModeOpts:
+		return &o, nil
+	}
+	return nil, fmt.Errorf("Mode not supported with token key [%s]", opts)
+}
+
+// findSecretKeyFromSKI - Finds secret key object by SKI.
+func findSecretKeyFromSKI(mod *pkcs11.Ctx, session pkcs11.SessionHandle, ski []byte) (*pkcs11.ObjectHandle, error) {
+	template := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_SECRET_KEY),
+		pkcs11.NewAttribute(pkcs11.CKA_ID, ski),
+	}
+	if err := mod.FindObjectsInit(session, template); err != nil {
+		return nil, err
+	}
+	objs, _, err := mod.FindObjects(session, 1)
+	if err != nil {
+		return nil, err
+	}
+	if err = mod.FindObjectsFinal(session); err != nil {
+		return nil, err
+	}
+	if len(objs) == 0 {
+		return nil, fmt.Errorf("Key not found [%x]", ski)
+	}
+	return &objs[0], nil
+}
+
+// getSecretKey - Returns AES key on the token identified by SKI.
+func (csp *impl) getSecretKey(ski []byte) (bccsp.Key, error) {
+	session := csp.getSession()
+	defer csp.returnSession(session)
+	if _, err := findSecretKeyFromSKI(csp.ctx, session, ski); err != nil {
+		return nil, err
+	}
+	return &secretKey{ski}, nil
+}
@@ -92,6 +92,10 @@ type PKCS11Opts struct {
 	Pin        string `mapstructure:"pin" json:"pin"`
 	SoftVerify bool   `mapstructure:"softwareverify,omitempty" json:"softwareverify,omitempty"`
 	Immutable  bool   `mapstructure:"immutable,omitempty" json:"immutable,omitempty"`
+
+	// SlotID selects token slot directly by its ID.
+	// When set, Label is ignored and slot must contain a token.
+	SlotID *uint `mapstructure:"slotid,omitempty" json:"slotid,omitempty"`
 }
 
 // FileKeystoreOpts currently only ECDSA operations go to PKCS11, need a keystore still
@@ -0,0 +1,221 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkcs11
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/miekg/pkcs11"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp/utils"
+)
+
+// errECDHNotSupported - Error returned when token lacks ECDH mechanism
+// or its key derivation function.
+var errECDHNotSupported = errors.New("Token does not support ECDH key derivation (CKM_ECDH1_DERIVE with CKD_SHA256_KDF).")
+
+// secretKey - AES key which never leaves the token. It can be used to
+// encrypt and decrypt in CBC mode with PKCS#7 padding and loaded by SKI.
+type secretKey struct {
+	ski []byte
+}
+
+// Bytes converts this key to its byte representation,
+// if this operation is allowed.
+func (k *secretKey) Bytes() ([]byte, error) {
+	return nil, errors.New("Not supported.")
+}
+
+// SKI returns the subject key identifier of this key.
+func (k *secretKey) SKI() []byte {
+	return k.ski
+}
+
+// Symmetric returns true if this key is a symmetric key,
+// false if this key is asymmetric
+func (k *secretKey) Symmetric() bool {
+	return true
+}
+
+// Private returns true if this key is a private key,
+// false otherwise.
+func (k *secretKey) Private() bool {
+	return true
+}
+
+// PublicKey returns the corresponding public key part of an asymmetric public/private key pair.
+// This method returns an error in symmetric key schemes.
+func (k *secretKey) PublicKey() (bccsp.Key, error) {
+	return nil, errors.New("Cannot call this method on a symmetric key.")
+}
+
+// deriveECDH - Derives AES key on the token from ECDH shared secret of
+// private key k and public key of the peer using CKM_ECDH1_DERIVE, so the
+// shared secret never leaves the token. Shared secret is passed through
+// ANSI X9.63 KDF with SHA-256 (CKD_SHA256_KDF) and shared data binding the
+// key to public keys of both parties, see utils.ECDHKeyInfo. PKCS#11 does
+// not define HKDF, so keys do not match keys derived by software CSP.
+//
+// SKI of derived key is computed from the key, see secretKeySKI, so both
+// parties get the same SKI and deriving again returns the existing key.
+func (csp *impl) deriveECDH(k *ecdsaPrivateKey, opts *bccsp.ECDHDeriveKeyOpts) (bccsp.Key, error) {
+	length := opts.Length
+	if length == 0 {
+		length = 32
+	}
+	if length != 16 && length != 24 && length != 32 {
+		return nil, fmt.Errorf("Invalid key length [%d]. It must be 16, 24 or 32 bytes.", length)
+	}
+	peer, err := ecdhPeerPublicKey(opts.PeerPublicKey)
+	if err != nil {
+		return nil, err
+	}
+	if peer.Curve.Params().Name != k.pub.pub.Curve.Params().Name {
+		return nil, errors.New("Invalid peer key. Curves do not match.")
+	}
+	if err := csp.checkMechanism(pkcs11.CKM_ECDH1_DERIVE); err != nil {
+		return nil, err
+	}
+
+	p11lib := csp.ctx
+	session := csp.getSession()
+	defer csp.returnSession(session)
+
+	privateKey, err := findKeyPairFromSKI(p11lib, session, k.ski, privateKeyFlag)
+	if err != nil {
+		return nil, fmt.Errorf("Private key not found [%s]", err)
+	}
+
+	// Derive session key first, its SKI is known only after derivation
+	template := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_SECRET_KEY),
+		pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, pkcs11.CKK_AES),
+		pkcs11.NewAttribute(pkcs11.CKA_VALUE_LEN, length),
+		pkcs11.NewAttribute(pkcs11.CKA_TOKEN, false),
+		pkcs11.NewAttribute(pkcs11.CKA_PRIVATE, true),
+		pkcs11.NewAttribute(pkcs11.CKA_SENSITIVE, true),
+		pkcs11.NewAttribute(pkcs11.CKA_EXTRACTABLE, false),
+		pkcs11.NewAttribute(pkcs11.CKA_ENCRYPT, true),
+		pkcs11.NewAttribute(pkcs11.CKA_DECRYPT, true),
+	}
+	point := elliptic.Marshal(peer.Curve, peer.X, peer.Y)
+	info := utils.ECDHKeyInfo(k.pub.pub, peer)
+	mech := pkcs11.NewMechanism(pkcs11.CKM_ECDH1_DERIVE, pkcs11.NewECDH1DeriveParams(pkcs11.CKD_SHA256_KDF, info, point))
+	derived, err := p11lib.DeriveKey(session, []*pkcs11.Mechanism{mech}, *privateKey, template)
+	if err == pkcs11.Error(pkcs11.CKR_MECHANISM_INVALID) || err == pkcs11.Error(pkcs11.CKR_MECHANISM_PARAM_INVALID) {
+		return nil, errECDHNotSupported
+	}
+	if err != nil {
+		return nil, fmt.Errorf("P11: ECDH derive failed [%s]", err)
+	}
+
+	ski, err := secretKeySKI(p11lib, session, derived)
+	if err != nil {
+		p11lib.DestroyObject(session, derived)
+		return nil, fmt.Errorf("P11: ECDH key SKI failed [%s]", err)
+	}
+	if _, err := findSecretKeyFromSKI(p11lib, session, ski); err == nil {
+		p11lib.DestroyObject(session, derived)
+		return &secretKey{ski}, nil
+	}
+
+	attrs := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_ID, ski),
+		pkcs11.NewAttribute(pkcs11.CKA_LABEL, hex.EncodeToString(ski)),
+	}
+	if opts.Ephemeral() {
+		err = p11lib.SetAttributeValue(session, derived, attrs)
+	} else {
+		attrs = append(attrs, pkcs11.NewAttribute(pkcs11.CKA_TOKEN, true))
+		_, err = p11lib.CopyObject(session, derived, attrs)
+		p11lib.DestroyObject(session, derived)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("P11: ECDH key store failed [%s]", err)
+	}
+	return &secretKey{ski}, nil
+}
+
+// secretKeySKI - Returns SKI of AES key on the token, SHA-256 of zero block
+// encrypted with the key. Key value is not extractable, so key check value
+// identifies the key instead.
+func secretKeySKI(mod *pkcs11.Ctx, session pkcs11.SessionHandle, key pkcs11.ObjectHandle) ([]byte, error) {
+	mech := []*pkcs11.Mechanism{pkcs11.NewMechanism(pkcs11.CKM_AES_ECB, nil)}
+	if err := mod.EncryptInit(session, mech, key); err != nil {
+		return nil, err
+	}
+	kcv, err := mod.Encrypt(session, make([]byte, 16))
+	if err != nil {
+		return nil, err
+	}
+	ski := sha256.Sum256(kcv)
+	return ski[:], nil
+}
+
+// checkMechanism - Returns error if token does not support mechanism.
+func (csp *impl) checkMechanism(mechanism uint) error {
+	mechs, err := csp.ctx.GetMechanismList(csp.slot)
+	if err != nil {
+		return fmt.Errorf("P11: get mechanism list failed [%s]", err)
+	}
+	for _, m := range mechs {
+		if m.Mechanism == mechanism {
+			return nil
+		}
+	}
+	if mechanism == pkcs11.CKM_ECDH1_DERIVE {
+		return errECDHNotSupported
+	}
+	return fmt.Errorf("Token does not support mechanism [0x%x].", mechanism)
+}
+
+// ecdhPeerPublicKey - Returns ECDSA public key of the peer.
+func ecdhPeerPublicKey(k bccsp.Key) (*ecdsa.PublicKey, error) {
+	switch k := k.(type) {
+	case nil:
+		return nil, errors.New("Invalid peer key. It must not be nil.")
+	case *ecdsaPublicKey:
+		return k.pub, nil
+	case *ecdsaPrivateKey:
+		return k.pub.pub, nil
+	}
+	if k.Private() {
+		pub, err := k.PublicKey()
+		if err != nil {
+			return nil, fmt.Errorf("Failed getting peer public key [%s]", err)
+		}
+		k = pub
+	}
+	raw, err := k.Bytes()
+	if err != nil {
+		return nil, fmt.Errorf("Failed marshalling peer public key [%s]", err)
+	}
+	pub, err := utils.DERToPublicKey(raw)
+	if err != nil {
+		return nil, fmt.Errorf("Failed parsing peer public key [%s]", err)
+	}
+	ecPub, ok := pub.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, errors.New("Invalid peer key. Expected ECDSA public key.")
+	}
+	return ecPub, nil
+}
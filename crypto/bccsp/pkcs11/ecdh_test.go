@@ -0,0 +1,134 @@
+// +build pkcs11
+
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkcs11
+
+import (
+	"crypto/sha256"
+	"strings"
+	"testing"
+
+	"github.com/miekg/pkcs11"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+)
+
+// encryptZeroBlock - Encrypts zero block with AES key on the token.
+func encryptZeroBlock(t *testing.T, csp *impl, k bccsp.Key) []byte {
+	session := csp.getSession()
+	defer csp.returnSession(session)
+
+	template := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_SECRET_KEY),
+		pkcs11.NewAttribute(pkcs11.CKA_ID, k.SKI()),
+	}
+	assert.NoError(t, csp.ctx.FindObjectsInit(session, template))
+	objs, _, err := csp.ctx.FindObjects(session, 1)
+	assert.NoError(t, err)
+	assert.NoError(t, csp.ctx.FindObjectsFinal(session))
+	assert.Len(t, objs, 1)
+
+	mech := []*pkcs11.Mechanism{pkcs11.NewMechanism(pkcs11.CKM_AES_ECB, nil)}
+	assert.NoError(t, csp.ctx.EncryptInit(session, mech, objs[0]))
+	ct, err := csp.ctx.Encrypt(session, make([]byte, 16))
+	assert.NoError(t, err)
+	return ct
+}
+
+func TestECDHDeriveKey(t *testing.T) {
+	lib, _, _ := FindPKCS11Lib()
+	if !strings.Contains(lib, "softhsm") {
+		t.Skip("Skipping TestECDHDeriveKey, requires SoftHSM")
+	}
+	csp := currentBCCSP.(*impl)
+
+	alice, err := csp.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	bob, err := csp.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	alicePub, err := alice.PublicKey()
+	assert.NoError(t, err)
+	bobPub, err := bob.PublicKey()
+	assert.NoError(t, err)
+
+	aliceShared, err := csp.KeyDeriv(alice, &bccsp.ECDHDeriveKeyOpts{Temporary: true, PeerPublicKey: bobPub})
+	if err == errECDHNotSupported {
+		t.Skip("Skipping TestECDHDeriveKey, token lacks CKD_SHA256_KDF")
+	}
+	assert.NoError(t, err)
+	bobShared, err := csp.KeyDeriv(bob, &bccsp.ECDHDeriveKeyOpts{Temporary: true, PeerPublicKey: alicePub})
+	assert.NoError(t, err)
+	assert.True(t, aliceShared.Symmetric())
+	assert.True(t, aliceShared.Private())
+	_, err = aliceShared.Bytes()
+	assert.Error(t, err)
+
+	// Both parties derive the same key on the token
+	assert.Equal(t, encryptZeroBlock(t, csp, aliceShared), encryptZeroBlock(t, csp, bobShared))
+	assert.Equal(t, aliceShared.SKI(), bobShared.SKI())
+	kcv := sha256.Sum256(encryptZeroBlock(t, csp, aliceShared))
+	assert.Equal(t, kcv[:], aliceShared.SKI())
+
+	// Deriving again does not create another object
+	again, err := csp.KeyDeriv(bob, &bccsp.ECDHDeriveKeyOpts{Temporary: true, PeerPublicKey: alicePub})
+	assert.NoError(t, err)
+	assert.Equal(t, bobShared.SKI(), again.SKI())
+	session := csp.getSession()
+	assert.NoError(t, csp.ctx.FindObjectsInit(session, []*pkcs11.Attribute{pkcs11.NewAttribute(pkcs11.CKA_ID, again.SKI())}))
+	objs, _, err := csp.ctx.FindObjects(session, 10)
+	assert.NoError(t, err)
+	assert.NoError(t, csp.ctx.FindObjectsFinal(session))
+	csp.returnSession(session)
+	assert.Len(t, objs, 1)
+
+	// Derived key is usable through CSP
+	msg := []byte("Hello Bob")
+	ct, err := csp.Encrypt(aliceShared, msg, &bccsp.AESCBCPKCS7ModeOpts{})
+	assert.NoError(t, err)
+	pt, err := csp.Decrypt(bobShared, ct, bccsp.AESCBCPKCS7ModeOpts{})
+	assert.NoError(t, err)
+	assert.Equal(t, msg, pt)
+	loaded, err := csp.Key(aliceShared.SKI())
+	assert.NoError(t, err)
+	assert.Equal(t, aliceShared, loaded)
+	_, err = csp.Encrypt(aliceShared, msg, &bccsp.AESGCMSIVModeOpts{})
+	assert.Error(t, err)
+
+	// Peer key of software CSP
+	soft, err := csp.BCCSP.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	softPub, err := soft.PublicKey()
+	assert.NoError(t, err)
+	other, err := csp.KeyDeriv(alice, &bccsp.ECDHDeriveKeyOpts{Temporary: true, PeerPublicKey: softPub, Length: 16})
+	assert.NoError(t, err)
+	assert.NotEqual(t, aliceShared.SKI(), other.SKI())
+	assert.Len(t, encryptZeroBlock(t, csp, other), 16)
+
+	// Invalid arguments
+	_, err = csp.KeyDeriv(alice, &bccsp.ECDHDeriveKeyOpts{Temporary: true, PeerPublicKey: bobPub, Length: 20})
+	assert.Error(t, err)
+	_, err = csp.KeyDeriv(alice, &bccsp.ECDHDeriveKeyOpts{Temporary: true})
+	assert.Error(t, err)
+	p384, err := csp.KeyGen(&bccsp.ECDSAP384KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	p384Pub, err := p384.PublicKey()
+	assert.NoError(t, err)
+	_, err = csp.KeyDeriv(alice, &bccsp.ECDHDeriveKeyOpts{Temporary: true, PeerPublicKey: p384Pub})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Curves do not match")
+}
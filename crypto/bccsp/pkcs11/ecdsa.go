@@ -43,13 +43,15 @@ func (csp *impl) verifyECDSA(k ecdsaPublicKey, signature, digest []byte, opts bc
 		return false, fmt.Errorf("Failed unmashalling signature [%s]", err)
 	}
 
-	lowS, err := utils.IsLowS(k.pub, s)
-	if err != nil {
-		return false, err
-	}
-
-	if !lowS {
-		return false, fmt.Errorf("Invalid S. Must be smaller than half the order [%s][%s]", s, utils.GetCurveHalfOrdersAt(k.pub.Curve))
+	if utils.LowSEnforced(k.pub.Curve) {
+		lowS, err := utils.IsLowS(k.pub, s)
+		if err != nil {
+			return false, err
+		}
+
+		if !lowS {
+			return false, fmt.Errorf("Invalid S. Must be smaller than half the order [%s][%s]", s, utils.GetCurveHalfOrdersAt(k.pub.Curve))
+		}
 	}
 
 	if csp.softVerify {
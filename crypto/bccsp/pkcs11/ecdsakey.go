@@ -63,6 +63,11 @@ type ecdsaPublicKey struct {
 	pub *ecdsa.PublicKey
 }
 
+// GoPublicKey returns the Go public key of this key.
+func (k *ecdsaPublicKey) GoPublicKey() interface{} {
+	return k.pub
+}
+
 // Bytes converts this key to its byte representation,
 // if this operation is allowed.
 func (k *ecdsaPublicKey) Bytes() (raw []byte, err error) {
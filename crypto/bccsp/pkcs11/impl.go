@@ -20,9 +20,11 @@ import (
 	"crypto/rsa"
 	"crypto/x509"
 	"os"
+	"sync"
 
 	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
 	"github.com/ipfn/ipfn/pkg/crypto/bccsp/swcp"
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp/utils"
 	"github.com/ipfn/ipfn/pkg/utils/flog"
 	"github.com/miekg/pkcs11"
 	"github.com/pkg/errors"
@@ -56,14 +58,29 @@ func New(opts PKCS11Opts, keyStore bccsp.KeyStore) (bccsp.BCCSP, error) {
 	lib := opts.Library
 	pin := opts.Pin
 	label := opts.Label
-	ctx, slot, session, err := loadLib(lib, pin, label)
+	ctx, slot, session, err := loadLib(lib, pin, label, opts.SlotID)
 	if err != nil {
 		return nil, errors.Wrapf(err, "Failed initializing PKCS11 library %s %s",
 			lib, label)
 	}
 
 	sessions := make(chan pkcs11.SessionHandle, sessionCacheSize)
-	csp := &impl{swCSP, conf, keyStore, ctx, sessions, slot, lib, opts.SoftVerify, opts.Immutable}
+	csp := &impl{
+		BCCSP:      swCSP,
+		conf:       conf,
+		ks:         keyStore,
+		ctx:        ctx,
+		sessions:   sessions,
+		slot:       slot,
+		pin:        pin,
+		lib:        lib,
+		softVerify: opts.SoftVerify,
+		immutable:  opts.Immutable,
+	}
+	if err = csp.login(*session); err != nil {
+		return nil, errors.Wrapf(err, "Failed initializing PKCS11 library %s %s",
+			lib, label)
+	}
 	csp.returnSession(*session)
 	return csp, nil
 }
@@ -78,6 +95,11 @@ type impl struct {
 	sessions chan pkcs11.SessionHandle
 	slot     uint
 
+	// pin is used to log in, loginOnce guards login on first use
+	pin       string
+	loginOnce sync.Once
+	loginErr  error
+
 	lib        string
 	softVerify bool
 	//Immutable flag makes object immutable
@@ -123,6 +145,26 @@ func (csp *impl) KeyGen(opts bccsp.KeyGenOpts) (k bccsp.Key, err error) {
 	return k, nil
 }
 
+// KeyDeriv derives a key from k using opts.
+// The opts argument should be appropriate for the primitive used.
+func (csp *impl) KeyDeriv(k bccsp.Key, opts bccsp.KeyDerivOpts) (bccsp.Key, error) {
+	// Validate arguments
+	if k == nil {
+		return nil, errors.New("Invalid Key. It must not be nil")
+	}
+	if opts == nil {
+		return nil, errors.New("Invalid Opts parameter. It must not be nil")
+	}
+
+	switch k := k.(type) {
+	case *ecdsaPrivateKey:
+		if ecdhOpts, ok := opts.(*bccsp.ECDHDeriveKeyOpts); ok {
+			return csp.deriveECDH(k, ecdhOpts)
+		}
+	}
+	return csp.BCCSP.KeyDeriv(k, opts)
+}
+
 // KeyImport imports a key from its raw representation using opts.
 // The opts argument should be appropriate for the primitive used.
 func (csp *impl) KeyImport(raw interface{}, opts bccsp.KeyImportOpts) (k bccsp.Key, err error) {
@@ -142,6 +184,11 @@ func (csp *impl) KeyImport(raw interface{}, opts bccsp.KeyImportOpts) (k bccsp.K
 		if !ok {
 			return nil, errors.New("[X509PublicKeyImportOpts] Invalid raw material. Expected *x509.Certificate")
 		}
+		if x509Opts := opts.(*bccsp.X509PublicKeyImportOpts); x509Opts.CheckValidity {
+			if err := utils.CheckCertificateValidity(x509Cert, x509Opts.ValidityTime()); err != nil {
+				return nil, err
+			}
+		}
 
 		pk := x509Cert.PublicKey
 
@@ -170,6 +217,9 @@ func (csp *impl) Key(ski []byte) (bccsp.Key, error) {
 		}
 		return &ecdsaPublicKey{ski, pubKey}, nil
 	}
+	if k, err := csp.getSecretKey(ski); err == nil {
+		return k, nil
+	}
 	return csp.BCCSP.Key(ski)
 }
 
@@ -224,16 +274,27 @@ func (csp *impl) Verify(k bccsp.Key, signature, digest []byte, opts bccsp.Signer
 // Encrypt encrypts plaintext using key k.
 // The opts argument should be appropriate for the primitive used.
 func (csp *impl) Encrypt(k bccsp.Key, plaintext []byte, opts bccsp.EncrypterOpts) ([]byte, error) {
-	// TODO: Add PKCS11 support for encryption, when fabric starts requiring it
+	if k, ok := k.(*secretKey); ok {
+		return csp.encryptAES(k, plaintext, opts)
+	}
 	return csp.BCCSP.Encrypt(k, plaintext, opts)
 }
 
 // Decrypt decrypts ciphertext using key k.
 // The opts argument should be appropriate for the primitive used.
 func (csp *impl) Decrypt(k bccsp.Key, ciphertext []byte, opts bccsp.DecrypterOpts) ([]byte, error) {
+	if k, ok := k.(*secretKey); ok {
+		return csp.decryptAES(k, ciphertext, opts)
+	}
 	return csp.BCCSP.Decrypt(k, ciphertext, opts)
 }
 
+// MaxConcurrency returns maximum number of concurrent batch operations,
+// which is bounded by size of session pool.
+func (csp *impl) MaxConcurrency() int {
+	return cap(csp.sessions)
+}
+
 // FindPKCS11Lib IS ONLY USED FOR TESTING
 // This is a convenience function. Useful to self-configure, for tests where usual configuration is not
 // available
@@ -34,6 +34,7 @@ import (
 	"net"
 	"os"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -577,6 +578,47 @@ func TestECDSAPublicKeySKI(t *testing.T) {
 	}
 }
 
+func TestECDSAConcurrentSignAfterNew(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping TestECDSAConcurrentSignAfterNew")
+	}
+	lib, pin, label := FindPKCS11Lib()
+	csp, err := New(PKCS11Opts{
+		HashFamily: currentTestConfig.hashFamily,
+		SecLevel:   currentTestConfig.securityLevel,
+		SoftVerify: currentTestConfig.softVerify,
+		Library:    lib,
+		Label:      label,
+		Pin:        pin,
+	}, currentKS)
+	assert.NoError(t, err)
+
+	k, err := currentBCCSP.KeyGen(&bccsp.ECDSAKeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	digest, err := currentBCCSP.Hash([]byte("Hello World"), digest.Sha2_256)
+	assert.NoError(t, err)
+
+	// Start more goroutines than cached sessions so new sessions are created
+	var wg sync.WaitGroup
+	errs := make(chan error, 4*sessionCacheSize)
+	for i := 0; i < cap(errs); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			signature, err := csp.Sign(k, digest, nil)
+			if err == nil && len(signature) == 0 {
+				err = fmt.Errorf("empty signature")
+			}
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+}
+
 func TestECDSASign(t *testing.T) {
 	if testing.Short() {
 		t.Skip("Skipping TestECDSASign")
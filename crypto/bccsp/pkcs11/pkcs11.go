@@ -30,7 +30,7 @@ import (
 	"go.uber.org/zap/zapcore"
 )
 
-func loadLib(lib, pin, label string) (*pkcs11.Ctx, uint, *pkcs11.SessionHandle, error) {
+func loadLib(lib, pin, label string, slotID *uint) (*pkcs11.Ctx, uint, *pkcs11.SessionHandle, error) {
 	var slot uint
 	logger.Debugf("Loading pkcs11 library [%s]\n", lib)
 	if lib == "" {
@@ -47,21 +47,13 @@ func loadLib(lib, pin, label string) (*pkcs11.Ctx, uint, *pkcs11.SessionHandle,
 	if err != nil {
 		return nil, slot, nil, fmt.Errorf("Could not get Slot List [%s]", err)
 	}
-	found := false
-	for _, s := range slots {
-		info, errToken := ctx.GetTokenInfo(s)
-		if errToken != nil {
-			continue
-		}
-		logger.Debugf("Looking for %s, found label %s\n", label, info.Label)
-		if label == info.Label {
-			found = true
-			slot = s
-			break
-		}
+	if slotID != nil {
+		slot, err = findSlotByID(slots, *slotID)
+	} else {
+		slot, err = findSlotByLabel(ctx, slots, label)
 	}
-	if !found {
-		return nil, slot, nil, fmt.Errorf("Could not find token with label %s", label)
+	if err != nil {
+		return nil, slot, nil, err
 	}
 
 	var session pkcs11.SessionHandle
@@ -81,14 +73,48 @@ func loadLib(lib, pin, label string) (*pkcs11.Ctx, uint, *pkcs11.SessionHandle,
 	if pin == "" {
 		return nil, slot, nil, fmt.Errorf("No PIN set")
 	}
-	err = ctx.Login(session, pkcs11.CKU_USER, pin)
-	if err != nil {
-		if err != pkcs11.Error(pkcs11.CKR_USER_ALREADY_LOGGED_IN) {
-			return nil, slot, nil, fmt.Errorf("Login failed [%s]", err)
+
+	return ctx, slot, &session, nil
+}
+
+// login logs user in exactly once, even when sessions are concurrently
+// created on first use. Login state is shared by all sessions of the
+// application. Login error is returned on every call.
+func (csp *impl) login(session pkcs11.SessionHandle) error {
+	csp.loginOnce.Do(func() {
+		err := csp.ctx.Login(session, pkcs11.CKU_USER, csp.pin)
+		if err != nil && err != pkcs11.Error(pkcs11.CKR_USER_ALREADY_LOGGED_IN) {
+			csp.loginErr = fmt.Errorf("Login failed [%s]", err)
+		}
+	})
+	return csp.loginErr
+}
+
+// findSlotByID checks that the slot with given ID exists and has a token present.
+// Slots are listed with tokenPresent set, so a slot without token is not found.
+func findSlotByID(slots []uint, id uint) (uint, error) {
+	for _, s := range slots {
+		if s == id {
+			logger.Debugf("Using slot %d selected by ID\n", id)
+			return s, nil
 		}
 	}
+	return 0, fmt.Errorf("Could not find token in slot %d", id)
+}
 
-	return ctx, slot, &session, nil
+// findSlotByLabel looks for a slot containing token with given label.
+func findSlotByLabel(ctx *pkcs11.Ctx, slots []uint, label string) (uint, error) {
+	for _, s := range slots {
+		info, errToken := ctx.GetTokenInfo(s)
+		if errToken != nil {
+			continue
+		}
+		logger.Debugf("Looking for %s, found label %s\n", label, info.Label)
+		if label == info.Label {
+			return s, nil
+		}
+	}
+	return 0, fmt.Errorf("Could not find token with label %s", label)
 }
 
 func (csp *impl) getSession() (session pkcs11.SessionHandle) {
@@ -112,6 +138,9 @@ func (csp *impl) getSession() (session pkcs11.SessionHandle) {
 			panic(fmt.Errorf("OpenSession failed [%s]", err))
 		}
 		logger.Debugf("Created new pkcs11 session %+v on slot %d\n", s, csp.slot)
+		if err = csp.login(s); err != nil {
+			panic(err)
+		}
 		session = s
 	}
 	return session
@@ -48,26 +48,49 @@ func TestLoadLib(t *testing.T) {
 	lib, pin, label := FindPKCS11Lib()
 
 	// Test for no specified PKCS11 library
-	_, _, _, err := loadLib("", pin, label)
+	_, _, _, err := loadLib("", pin, label, nil)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "No PKCS11 library default")
 
 	// Test for invalid PKCS11 library
-	_, _, _, err = loadLib("badLib", pin, label)
+	_, _, _, err = loadLib("badLib", pin, label, nil)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "Instantiate failed")
 
 	// Test for invalid label
-	_, _, _, err = loadLib(lib, pin, "badLabel")
+	_, _, _, err = loadLib(lib, pin, "badLabel", nil)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "Could not find token with label")
 
 	// Test for no pin
-	_, _, _, err = loadLib(lib, "", label)
+	_, _, _, err = loadLib(lib, "", label, nil)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "No PIN set")
 }
 
+func TestLoadLibSlotID(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping TestLoadLibSlotID")
+	}
+	lib, pin, label := FindPKCS11Lib()
+
+	// Find slot of the token with default label
+	_, slot, _, err := loadLib(lib, pin, label, nil)
+	assert.NoError(t, err)
+
+	// Label is ignored when slot ID is set
+	_, slotByID, session, err := loadLib(lib, pin, "badLabel", &slot)
+	assert.NoError(t, err)
+	assert.NotNil(t, session)
+	assert.Equal(t, slot, slotByID)
+
+	// Test for slot without token
+	badSlot := ^uint(0)
+	_, _, _, err = loadLib(lib, pin, label, &badSlot)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Could not find token in slot")
+}
+
 func TestOIDFromNamedCurve(t *testing.T) {
 	if testing.Short() {
 		t.Skip("Skipping TestOIDFromNamedCurve")
@@ -0,0 +1,184 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkcs11
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/miekg/pkcs11"
+)
+
+// attributeKind - Kind of value expected by PKCS11 attribute.
+type attributeKind int
+
+const (
+	attributeBool attributeKind = iota
+	attributeUlong
+	attributeBytes
+	attributeDate
+)
+
+// attributeKinds - Kinds of values of known PKCS11 attributes.
+var attributeKinds = map[uint]attributeKind{
+	pkcs11.CKA_TOKEN:             attributeBool,
+	pkcs11.CKA_PRIVATE:           attributeBool,
+	pkcs11.CKA_MODIFIABLE:        attributeBool,
+	pkcs11.CKA_COPYABLE:          attributeBool,
+	pkcs11.CKA_DESTROYABLE:       attributeBool,
+	pkcs11.CKA_SENSITIVE:         attributeBool,
+	pkcs11.CKA_EXTRACTABLE:       attributeBool,
+	pkcs11.CKA_ALWAYS_SENSITIVE:  attributeBool,
+	pkcs11.CKA_NEVER_EXTRACTABLE: attributeBool,
+	pkcs11.CKA_LOCAL:             attributeBool,
+	pkcs11.CKA_TRUSTED:           attributeBool,
+	pkcs11.CKA_ENCRYPT:           attributeBool,
+	pkcs11.CKA_DECRYPT:           attributeBool,
+	pkcs11.CKA_WRAP:              attributeBool,
+	pkcs11.CKA_UNWRAP:            attributeBool,
+	pkcs11.CKA_SIGN:              attributeBool,
+	pkcs11.CKA_SIGN_RECOVER:      attributeBool,
+	pkcs11.CKA_VERIFY:            attributeBool,
+	pkcs11.CKA_VERIFY_RECOVER:    attributeBool,
+	pkcs11.CKA_DERIVE:            attributeBool,
+
+	pkcs11.CKA_CLASS:            attributeUlong,
+	pkcs11.CKA_KEY_TYPE:         attributeUlong,
+	pkcs11.CKA_CERTIFICATE_TYPE: attributeUlong,
+	pkcs11.CKA_VALUE_LEN:        attributeUlong,
+	pkcs11.CKA_MODULUS_BITS:     attributeUlong,
+
+	pkcs11.CKA_ID:              attributeBytes,
+	pkcs11.CKA_LABEL:           attributeBytes,
+	pkcs11.CKA_APPLICATION:     attributeBytes,
+	pkcs11.CKA_OBJECT_ID:       attributeBytes,
+	pkcs11.CKA_SUBJECT:         attributeBytes,
+	pkcs11.CKA_VALUE:           attributeBytes,
+	pkcs11.CKA_EC_PARAMS:       attributeBytes,
+	pkcs11.CKA_EC_POINT:        attributeBytes,
+	pkcs11.CKA_MODULUS:         attributeBytes,
+	pkcs11.CKA_PUBLIC_EXPONENT: attributeBytes,
+
+	pkcs11.CKA_START_DATE: attributeDate,
+	pkcs11.CKA_END_DATE:   attributeDate,
+}
+
+// BuildAttributeTemplate - Builds PKCS11 attribute template from map of
+// attribute types to values, sorted by attribute type.
+//
+// Values of known attributes are validated against their kind: booleans
+// must be bool, numeric attributes must be non-negative integers, byte
+// array attributes must be []byte or string and dates must be time.Time
+// with year between 1 and 9999. Vendor defined attributes accept booleans,
+// non-negative integers, []byte and string. Unknown attributes and nil
+// values are rejected. It returns an error instead of panicking on any
+// malformed input.
+func BuildAttributeTemplate(attrs map[uint]interface{}) ([]*pkcs11.Attribute, error) {
+	types := make([]uint, 0, len(attrs))
+	for typ := range attrs {
+		types = append(types, typ)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+
+	template := make([]*pkcs11.Attribute, 0, len(types))
+	for _, typ := range types {
+		value := attrs[typ]
+		if err := checkAttribute(typ, value); err != nil {
+			return nil, err
+		}
+		template = append(template, pkcs11.NewAttribute(typ, value))
+	}
+	return template, nil
+}
+
+// checkAttribute - Validates attribute value so that pkcs11.NewAttribute
+// accepts it without panicking.
+func checkAttribute(typ uint, value interface{}) error {
+	if value == nil {
+		return fmt.Errorf("Invalid attribute 0x%x. Value must not be nil.", typ)
+	}
+	kind, ok := attributeKinds[typ]
+	if !ok {
+		if typ < pkcs11.CKA_VENDOR_DEFINED {
+			return fmt.Errorf("Unsupported attribute 0x%x", typ)
+		}
+		switch value.(type) {
+		case bool, []byte, string:
+			return nil
+		}
+		if _, err := attributeInteger(value); err != nil {
+			return fmt.Errorf("Invalid vendor attribute 0x%x [%s]", typ, err)
+		}
+		return nil
+	}
+
+	switch kind {
+	case attributeBool:
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("Invalid attribute 0x%x. Expected bool, got %T.", typ, value)
+		}
+	case attributeUlong:
+		if _, err := attributeInteger(value); err != nil {
+			return fmt.Errorf("Invalid attribute 0x%x [%s]", typ, err)
+		}
+	case attributeBytes:
+		switch value.(type) {
+		case []byte, string:
+		default:
+			return fmt.Errorf("Invalid attribute 0x%x. Expected []byte or string, got %T.", typ, value)
+		}
+	case attributeDate:
+		date, ok := value.(time.Time)
+		if !ok {
+			return fmt.Errorf("Invalid attribute 0x%x. Expected time.Time, got %T.", typ, value)
+		}
+		if date.Year() < 1 || date.Year() > 9999 {
+			return fmt.Errorf("Invalid attribute 0x%x. Year %d is out of range.", typ, date.Year())
+		}
+	}
+	return nil
+}
+
+// attributeInteger - Returns value of integer attribute.
+// Negative values and non-integer types are rejected.
+func attributeInteger(value interface{}) (uint64, error) {
+	var v int64
+	switch n := value.(type) {
+	case uint:
+		return uint64(n), nil
+	case uint16:
+		return uint64(n), nil
+	case uint32:
+		return uint64(n), nil
+	case uint64:
+		return n, nil
+	case int:
+		v = int64(n)
+	case int16:
+		v = int64(n)
+	case int32:
+		v = int64(n)
+	case int64:
+		v = n
+	default:
+		return 0, fmt.Errorf("expected integer, got %T", value)
+	}
+	if v < 0 {
+		return 0, fmt.Errorf("negative value %d", v)
+	}
+	return uint64(v), nil
+}
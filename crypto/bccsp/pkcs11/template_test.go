@@ -0,0 +1,110 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkcs11
+
+import (
+	"testing"
+	"time"
+
+	"github.com/miekg/pkcs11"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildAttributeTemplate(t *testing.T) {
+	template, err := BuildAttributeTemplate(map[uint]interface{}{
+		pkcs11.CKA_LABEL:              "label",
+		pkcs11.CKA_CLASS:              pkcs11.CKO_PRIVATE_KEY,
+		pkcs11.CKA_TOKEN:              true,
+		pkcs11.CKA_ID:                 []byte{1, 2, 3},
+		pkcs11.CKA_VALUE_LEN:          32,
+		pkcs11.CKA_START_DATE:         time.Date(2018, 1, 2, 0, 0, 0, 0, time.UTC),
+		pkcs11.CKA_VENDOR_DEFINED + 1: uint64(7),
+	})
+	assert.NoError(t, err)
+	assert.Len(t, template, 7)
+	assert.Equal(t, pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_PRIVATE_KEY), template[0])
+	assert.Equal(t, pkcs11.NewAttribute(pkcs11.CKA_TOKEN, true), template[1])
+	assert.Equal(t, []byte("label"), template[2].Value)
+	assert.Equal(t, []byte("20180102"), template[4].Value)
+	for i := 1; i < len(template); i++ {
+		assert.True(t, template[i-1].Type < template[i].Type)
+	}
+
+	template, err = BuildAttributeTemplate(nil)
+	assert.NoError(t, err)
+	assert.Empty(t, template)
+}
+
+func TestBuildAttributeTemplateMalformed(t *testing.T) {
+	for _, attrs := range []map[uint]interface{}{
+		{pkcs11.CKA_TOKEN: nil},
+		{pkcs11.CKA_TOKEN: 1},
+		{pkcs11.CKA_SIGN: "true"},
+		{pkcs11.CKA_CLASS: -1},
+		{pkcs11.CKA_KEY_TYPE: 1.5},
+		{pkcs11.CKA_KEY_TYPE: true},
+		{pkcs11.CKA_LABEL: 42},
+		{pkcs11.CKA_ID: []int{1}},
+		{pkcs11.CKA_END_DATE: "2018-01-02"},
+		{pkcs11.CKA_END_DATE: time.Date(10000, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{pkcs11.CKA_VENDOR_DEFINED: struct{}{}},
+		{pkcs11.CKA_VENDOR_DEFINED: int16(-1)},
+		{0x7fff: []byte{}},
+		{pkcs11.CKA_TOKEN: true, pkcs11.CKA_PRIVATE: map[string]int{}},
+	} {
+		template, err := BuildAttributeTemplate(attrs)
+		assert.Error(t, err, "%v", attrs)
+		assert.Nil(t, template)
+	}
+}
+
+func FuzzBuildAttributeTemplate(f *testing.F) {
+	f.Add(uint64(pkcs11.CKA_TOKEN), byte(0), []byte{1})
+	f.Add(uint64(pkcs11.CKA_CLASS), byte(1), []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff})
+	f.Add(uint64(pkcs11.CKA_LABEL), byte(3), []byte("label"))
+	f.Add(uint64(pkcs11.CKA_START_DATE), byte(4), []byte{0x7f, 0xff, 0xff, 0xff})
+	f.Add(uint64(pkcs11.CKA_VENDOR_DEFINED), byte(5), []byte{})
+	f.Fuzz(func(t *testing.T, typ uint64, kind byte, data []byte) {
+		var n int64
+		for _, b := range data {
+			n = n<<8 | int64(b)
+		}
+		var value interface{}
+		switch kind % 7 {
+		case 0:
+			value = len(data) > 0 && data[0]&1 == 1
+		case 1:
+			value = int(n)
+		case 2:
+			value = uint64(n)
+		case 3:
+			value = string(data)
+		case 4:
+			value = time.Unix(n, 0)
+		case 5:
+			value = data
+		case 6:
+			value = float64(n)
+		}
+		template, err := BuildAttributeTemplate(map[uint]interface{}{uint(typ): value})
+		if err != nil {
+			return
+		}
+		if len(template) != 1 || template[0].Type != uint(typ) {
+			t.Fatalf("unexpected template %v", template)
+		}
+	})
+}
@@ -0,0 +1,162 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package signer
+
+import (
+	"bytes"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp/utils"
+	"github.com/ipfn/ipfn/pkg/digest"
+	"github.com/pkg/errors"
+)
+
+// Labels of CBOR signed envelope fields.
+const (
+	// CBORLabelAlg - Multihash code of payload hash function (unsigned integer).
+	CBORLabelAlg = 1
+	// CBORLabelSKI - Subject key identifier of signing key (byte string).
+	CBORLabelSKI = 2
+	// CBORLabelSig - Signature of payload hash (byte string).
+	CBORLabelSig = 3
+	// CBORLabelHash - Payload hash (byte string).
+	CBORLabelHash = 4
+)
+
+// SignCBOR - Creates compact CBOR signed envelope of payload.
+//
+// Envelope is a canonical CBOR map with integer labels:
+//
+//	{
+//	  1: uint, ; alg - multihash code of payload hash function, see digest.Type
+//	  2: bstr, ; ski - subject key identifier of signing key
+//	  3: bstr, ; sig - signature of payload hash
+//	  4: bstr, ; hash - payload hash
+//	}
+//
+// Payload is not included, it has to be delivered to the verifier separately.
+// Only payload hash is signed, alg and ski are bound to the signature by
+// VerifyCBOR recomputing the hash and comparing the key identifier.
+// Opts are passed to the CSP, e.g. RSA keys require them.
+func SignCBOR(csp bccsp.BCCSP, key bccsp.Key, payload []byte, hashType digest.Type, opts bccsp.SignerOpts) ([]byte, error) {
+	if csp == nil {
+		return nil, errors.New("bccsp instance must be different from nil.")
+	}
+	if key == nil {
+		return nil, errors.New("key must be different from nil.")
+	}
+	hash, err := csp.Hash(payload, hashType)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed hashing payload")
+	}
+	sig, err := csp.Sign(key, hash, opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed signing payload hash")
+	}
+	return utils.EncodeCBORMap(map[int64]interface{}{
+		CBORLabelAlg:  int64(hashType),
+		CBORLabelSKI:  key.SKI(),
+		CBORLabelSig:  sig,
+		CBORLabelHash: hash,
+	})
+}
+
+// VerifyCBOR - Verifies CBOR signed envelope of payload created with SignCBOR.
+//
+// Envelope which does not match the payload or key results in false and no
+// error. Malformed envelopes, including ones with unknown integer or text
+// labels, result in error. Envelope does not have to be canonically encoded.
+// Opts have to match the ones passed to SignCBOR.
+func VerifyCBOR(csp bccsp.BCCSP, key bccsp.Key, payload, envelope []byte, opts bccsp.SignerOpts) (bool, error) {
+	if csp == nil {
+		return false, errors.New("bccsp instance must be different from nil.")
+	}
+	if key == nil {
+		return false, errors.New("key must be different from nil.")
+	}
+	fields, err := utils.DecodeCBORMapStrict(envelope)
+	if err != nil {
+		return false, errors.Wrap(err, "failed decoding envelope")
+	}
+	if len(fields) != 4 {
+		return false, errors.Errorf("invalid envelope, expected 4 fields, got %d", len(fields))
+	}
+	alg, ok := fields[CBORLabelAlg].(int64)
+	if !ok || alg <= 0 {
+		return false, errors.New("invalid envelope alg field")
+	}
+	var values [3][]byte
+	for i, label := range []int64{CBORLabelSKI, CBORLabelSig, CBORLabelHash} {
+		if values[i], ok = fields[label].([]byte); !ok {
+			return false, errors.Errorf("invalid envelope field %d", label)
+		}
+	}
+	ski, sig, envHash := values[0], values[1], values[2]
+	if !bytes.Equal(ski, key.SKI()) {
+		return false, nil
+	}
+	hash, err := csp.Hash(payload, digest.Type(alg))
+	if err != nil {
+		return false, errors.Wrap(err, "failed hashing payload")
+	}
+	if !bytes.Equal(hash, envHash) {
+		return false, nil
+	}
+	return csp.Verify(key, sig, hash, opts)
+}
+
+// SignCanonicalCBOR - Signs hash of CBOR payload in canonical encoding.
+//
+// Payload is canonicalized with utils.CanonicalCBOR before hashing, so that
+// semantically equal payloads with different encodings, e.g. with differently
+// ordered map keys or integers not in the shortest form, produce the same hash.
+// Opts are passed to the CSP, e.g. RSA keys require them.
+func SignCanonicalCBOR(csp bccsp.BCCSP, key bccsp.Key, payload []byte, hashType digest.Type, opts bccsp.SignerOpts) ([]byte, error) {
+	if csp == nil {
+		return nil, errors.New("bccsp instance must be different from nil.")
+	}
+	hash, err := hashCanonicalCBOR(csp, payload, hashType)
+	if err != nil {
+		return nil, err
+	}
+	return csp.Sign(key, hash, opts)
+}
+
+// VerifyCanonicalCBOR - Verifies signature of CBOR payload created with
+// SignCanonicalCBOR. Payload does not have to be canonically encoded.
+// Opts have to match the ones passed to SignCanonicalCBOR.
+func VerifyCanonicalCBOR(csp bccsp.BCCSP, key bccsp.Key, payload, sig []byte, hashType digest.Type, opts bccsp.SignerOpts) (bool, error) {
+	if csp == nil {
+		return false, errors.New("bccsp instance must be different from nil.")
+	}
+	hash, err := hashCanonicalCBOR(csp, payload, hashType)
+	if err != nil {
+		return false, err
+	}
+	return csp.Verify(key, sig, hash, opts)
+}
+
+func hashCanonicalCBOR(csp bccsp.BCCSP, payload []byte, hashType digest.Type) ([]byte, error) {
+	canonical, err := utils.CanonicalCBOR(payload)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed canonicalizing payload")
+	}
+	hash, err := csp.Hash(canonical, hashType)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed hashing payload")
+	}
+	return hash, nil
+}
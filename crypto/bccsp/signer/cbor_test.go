@@ -0,0 +1,208 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package signer
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"encoding/hex"
+	"testing"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp/swcp"
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp/utils"
+	"github.com/ipfn/ipfn/pkg/digest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignCBOR(t *testing.T) {
+	csp, err := swcp.NewDefaultSecurityLevelWithKeystore(swcp.NewDummyKeyStore())
+	assert.NoError(t, err)
+	k, err := csp.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	pk, err := k.PublicKey()
+	assert.NoError(t, err)
+	payload := []byte("Hello World")
+
+	envelope, err := SignCBOR(csp, k, payload, digest.Sha2_256, nil)
+	assert.NoError(t, err)
+
+	// map(4), 1: 0x12, 2: bstr(32)
+	assert.Equal(t, []byte{0xa4, 0x01, 0x12, 0x02, 0x58, 0x20}, envelope[:6])
+	assert.Equal(t, k.SKI(), envelope[6:38])
+
+	for _, key := range []bccsp.Key{k, pk} {
+		valid, err := VerifyCBOR(csp, key, payload, envelope, nil)
+		assert.NoError(t, err)
+		assert.True(t, valid)
+	}
+
+	// Other payload or key
+	valid, err := VerifyCBOR(csp, k, []byte("Hello World!"), envelope, nil)
+	assert.NoError(t, err)
+	assert.False(t, valid)
+	other, err := csp.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	valid, err = VerifyCBOR(csp, other, payload, envelope, nil)
+	assert.NoError(t, err)
+	assert.False(t, valid)
+
+	// Tampered fields
+	fields, err := utils.DecodeCBORMap(envelope)
+	assert.NoError(t, err)
+	tamper := func(label int64, value interface{}) []byte {
+		tampered := make(map[int64]interface{}, len(fields))
+		for l, v := range fields {
+			tampered[l] = v
+		}
+		tampered[label] = value
+		raw, err := utils.EncodeCBORMap(tampered)
+		assert.NoError(t, err)
+		return raw
+	}
+	flip := func(b []byte) []byte {
+		b = append([]byte{}, b...)
+		b[len(b)-1] ^= 1
+		return b
+	}
+	for _, tampered := range [][]byte{
+		tamper(CBORLabelAlg, int64(digest.Sha3_256)),
+		tamper(CBORLabelSKI, flip(k.SKI())),
+		tamper(CBORLabelSig, flip(fields[CBORLabelSig].([]byte))),
+		tamper(CBORLabelHash, flip(fields[CBORLabelHash].([]byte))),
+	} {
+		valid, _ := VerifyCBOR(csp, k, payload, tampered, nil)
+		assert.False(t, valid)
+	}
+
+	// Malformed envelopes
+	for _, malformed := range [][]byte{
+		nil,
+		envelope[:len(envelope)-1],
+		tamper(CBORLabelAlg, fields[CBORLabelHash]),
+		tamper(CBORLabelSig, "signature"),
+		tamper(5, []byte{}),
+		// Unknown text label
+		append([]byte{0xa5}, append(append([]byte{}, envelope[1:]...), 0x61, 'x', 0x00)...),
+	} {
+		_, err := VerifyCBOR(csp, k, payload, malformed, nil)
+		assert.Error(t, err)
+	}
+
+	// Re-encoding produces the same envelope
+	reencoded, err := utils.EncodeCBORMap(fields)
+	assert.NoError(t, err)
+	assert.Equal(t, envelope, reencoded)
+
+	// Non-canonical encoding verifies, labels in reverse order and
+	// integers in long form
+	var noncanonical []byte
+	noncanonical = append(noncanonical, 0xa4)
+	for _, label := range []int64{CBORLabelHash, CBORLabelSig, CBORLabelSKI} {
+		b := fields[label].([]byte)
+		noncanonical = append(noncanonical, 0x18, byte(label), 0x59, 0, byte(len(b)))
+		noncanonical = append(noncanonical, b...)
+	}
+	noncanonical = append(noncanonical, 0x18, CBORLabelAlg, 0x19, 0, byte(digest.Sha2_256))
+	valid, err = VerifyCBOR(csp, pk, payload, noncanonical, nil)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+
+	// SHA3 hashes
+	envelope, err = SignCBOR(csp, k, payload, digest.Sha3_256, nil)
+	assert.NoError(t, err)
+	valid, err = VerifyCBOR(csp, pk, payload, envelope, nil)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+
+	_, err = SignCBOR(nil, k, payload, digest.Sha2_256, nil)
+	assert.Error(t, err)
+	_, err = SignCBOR(csp, nil, payload, digest.Sha2_256, nil)
+	assert.Error(t, err)
+	_, err = VerifyCBOR(nil, k, payload, envelope, nil)
+	assert.Error(t, err)
+
+	// RSA keys with opts
+	rsaKey, err := csp.KeyGen(&bccsp.RSA2048KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	opts := &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: crypto.SHA256}
+	envelope, err = SignCBOR(csp, rsaKey, payload, digest.Sha2_256, opts)
+	assert.NoError(t, err)
+	valid, err = VerifyCBOR(csp, rsaKey, payload, envelope, opts)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+}
+
+func TestSignCanonicalCBOR(t *testing.T) {
+	csp, err := swcp.NewDefaultSecurityLevelWithKeystore(swcp.NewDummyKeyStore())
+	assert.NoError(t, err)
+	k, err := csp.KeyGen(&bccsp.ED25519KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	pk, err := k.PublicKey()
+	assert.NoError(t, err)
+
+	// {"fmt": "none", "n": 1, -1: [1, 2]} in different encodings
+	var payloads [][]byte
+	for _, s := range []string{
+		"a363666d74646e6f6e65616e0120820102",
+		"a320820102616e0163666d74646e6f6e65",
+		"bf616e180163666d747f626e6f626e65ff209f0102ffff",
+	} {
+		payload, err := hex.DecodeString(s)
+		assert.NoError(t, err)
+		payloads = append(payloads, payload)
+	}
+
+	var signatures [][]byte
+	for _, payload := range payloads {
+		sig, err := SignCanonicalCBOR(csp, k, payload, digest.Sha2_256, nil)
+		assert.NoError(t, err)
+		signatures = append(signatures, sig)
+	}
+	// Ed25519 signatures are deterministic
+	assert.Equal(t, signatures[0], signatures[1])
+	assert.Equal(t, signatures[0], signatures[2])
+
+	for _, payload := range payloads {
+		valid, err := VerifyCanonicalCBOR(csp, pk, payload, signatures[0], digest.Sha2_256, nil)
+		assert.NoError(t, err)
+		assert.True(t, valid)
+	}
+
+	// Different payload
+	other, err := hex.DecodeString("a1616e02")
+	assert.NoError(t, err)
+	valid, err := VerifyCanonicalCBOR(csp, pk, other, signatures[0], digest.Sha2_256, nil)
+	assert.NoError(t, err)
+	assert.False(t, valid)
+
+	_, err = SignCanonicalCBOR(csp, k, []byte{0xff}, digest.Sha2_256, nil)
+	assert.Error(t, err)
+	_, err = VerifyCanonicalCBOR(csp, pk, []byte{0x01, 0x02}, signatures[0], digest.Sha2_256, nil)
+	assert.Error(t, err)
+	_, err = SignCanonicalCBOR(nil, k, payloads[0], digest.Sha2_256, nil)
+	assert.Error(t, err)
+
+	// RSA keys with opts
+	rsaKey, err := csp.KeyGen(&bccsp.RSA2048KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	opts := &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: crypto.SHA256}
+	sig, err := SignCanonicalCBOR(csp, rsaKey, payloads[0], digest.Sha2_256, opts)
+	assert.NoError(t, err)
+	valid, err = VerifyCanonicalCBOR(csp, rsaKey, payloads[2], sig, digest.Sha2_256, opts)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+}
@@ -0,0 +1,192 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package signer
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+	"github.com/pkg/errors"
+)
+
+// SelfSignedCert - Creates DER encoded self-signed certificate of key.
+//
+// Certificate is valid from now for the
+// validity duration and may be used for signing and as a root CA.
+// Signature algorithm is picked from the key type and hash of opts, which
+// defaults to SHA-256 when opts are nil. For RSA keys *rsa.PSSOptions
+// select RSA-PSS, any other opts select PKCS #1 v1.5.
+func SelfSignedCert(csp bccsp.BCCSP, key bccsp.Key, subject pkix.Name, validity time.Duration, opts crypto.SignerOpts) ([]byte, error) {
+	if validity <= 0 {
+		return nil, errors.New("validity must be positive.")
+	}
+	s, err := New(csp, key)
+	if err != nil {
+		return nil, err
+	}
+	template := &x509.Certificate{
+		Subject:               subject,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	return selfSign(s, template, validity, opts)
+}
+
+// CertUsage - Intended usage of certificate created by CertForUsage.
+type CertUsage int
+
+const (
+	// TLSServer - Certificate of TLS server.
+	TLSServer CertUsage = iota + 1
+	// TLSClient - Certificate of TLS client.
+	TLSClient
+	// CodeSigning - Certificate of code signer.
+	CodeSigning
+	// CA - Certificate of certificate authority.
+	CA
+)
+
+// String - Returns name of certificate usage.
+func (usage CertUsage) String() string {
+	switch usage {
+	case TLSServer:
+		return "tls-server"
+	case TLSClient:
+		return "tls-client"
+	case CodeSigning:
+		return "code-signing"
+	case CA:
+		return "ca"
+	}
+	return fmt.Sprintf("CertUsage(%d)", int(usage))
+}
+
+// CertForUsage - Creates DER encoded self-signed certificate of key with key
+// usage and extended key usage extensions set for the intended usage.
+//
+// RSA keys of TLS servers may also be used for key encipherment, as required
+// by RSA key exchange. Only CA certificates may sign other certificates.
+// Certificate is valid from now for the
+// validity duration and signed with SHA-256.
+func CertForUsage(csp bccsp.BCCSP, key bccsp.Key, subject pkix.Name, usage CertUsage, validity time.Duration) ([]byte, error) {
+	if validity <= 0 {
+		return nil, errors.New("validity must be positive.")
+	}
+	s, err := New(csp, key)
+	if err != nil {
+		return nil, err
+	}
+	template := &x509.Certificate{
+		Subject:               subject,
+		BasicConstraintsValid: true,
+	}
+	switch usage {
+	case TLSServer:
+		template.KeyUsage = x509.KeyUsageDigitalSignature
+		if _, ok := s.Public().(*rsa.PublicKey); ok {
+			template.KeyUsage |= x509.KeyUsageKeyEncipherment
+		}
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+	case TLSClient:
+		template.KeyUsage = x509.KeyUsageDigitalSignature
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+	case CodeSigning:
+		template.KeyUsage = x509.KeyUsageDigitalSignature
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning}
+	case CA:
+		template.KeyUsage = x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign | x509.KeyUsageCRLSign
+		template.IsCA = true
+	default:
+		return nil, errors.Errorf("unsupported certificate usage %s", usage)
+	}
+	return selfSign(s, template, validity, nil)
+}
+
+// selfSign - Signs certificate template with signer. Serial number,
+// validity and signature algorithm are set on template.
+func selfSign(s crypto.Signer, template *x509.Certificate, validity time.Duration, opts crypto.SignerOpts) ([]byte, error) {
+	algo, err := signatureAlgorithm(s.Public(), opts)
+	if err != nil {
+		return nil, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed generating serial number")
+	}
+	now := time.Now()
+	template.SerialNumber = serial
+	template.NotBefore = now
+	template.NotAfter = now.Add(validity)
+	template.SignatureAlgorithm = algo
+	der, err := x509.CreateCertificate(rand.Reader, template, template, s.Public(), s)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed creating certificate")
+	}
+	return der, nil
+}
+
+// signatureAlgorithm - Picks x509 signature algorithm for public key and opts.
+func signatureAlgorithm(pub crypto.PublicKey, opts crypto.SignerOpts) (x509.SignatureAlgorithm, error) {
+	hash := crypto.SHA256
+	if opts != nil {
+		hash = opts.HashFunc()
+	}
+	_, pss := opts.(*rsa.PSSOptions)
+	switch pub.(type) {
+	case *ecdsa.PublicKey:
+		if pss {
+			return x509.UnknownSignatureAlgorithm, errors.New("PSS options are not valid for ECDSA keys.")
+		}
+		switch hash {
+		case crypto.SHA256:
+			return x509.ECDSAWithSHA256, nil
+		case crypto.SHA384:
+			return x509.ECDSAWithSHA384, nil
+		case crypto.SHA512:
+			return x509.ECDSAWithSHA512, nil
+		}
+	case *rsa.PublicKey:
+		switch hash {
+		case crypto.SHA256:
+			if pss {
+				return x509.SHA256WithRSAPSS, nil
+			}
+			return x509.SHA256WithRSA, nil
+		case crypto.SHA384:
+			if pss {
+				return x509.SHA384WithRSAPSS, nil
+			}
+			return x509.SHA384WithRSA, nil
+		case crypto.SHA512:
+			if pss {
+				return x509.SHA512WithRSAPSS, nil
+			}
+			return x509.SHA512WithRSA, nil
+		}
+	default:
+		return x509.UnknownSignatureAlgorithm, errors.Errorf("unsupported key type %T", pub)
+	}
+	return x509.UnknownSignatureAlgorithm, errors.Errorf("unsupported hash function %v", hash)
+}
@@ -0,0 +1,124 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package signer
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"testing"
+	"time"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp/swcp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelfSignedCert(t *testing.T) {
+	csp, err := swcp.NewDefaultSecurityLevelWithKeystore(swcp.NewDummyKeyStore())
+	assert.NoError(t, err)
+
+	ecKey, err := csp.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	rsaKey, err := csp.KeyGen(&bccsp.RSA2048KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+
+	for _, tc := range []struct {
+		key  bccsp.Key
+		opts crypto.SignerOpts
+		algo x509.SignatureAlgorithm
+	}{
+		{ecKey, nil, x509.ECDSAWithSHA256},
+		{ecKey, crypto.SHA384, x509.ECDSAWithSHA384},
+		{rsaKey, nil, x509.SHA256WithRSA},
+		{rsaKey, crypto.SHA512, x509.SHA512WithRSA},
+		{rsaKey, &rsa.PSSOptions{Hash: crypto.SHA256}, x509.SHA256WithRSAPSS},
+	} {
+		subject := pkix.Name{CommonName: "Self Signed", Organization: []string{"IPFN"}}
+		// Certificate time is encoded with second precision.
+		before := time.Now().Truncate(time.Second)
+		der, err := SelfSignedCert(csp, tc.key, subject, time.Hour, tc.opts)
+		after := time.Now()
+		assert.NoError(t, err)
+		cert, err := x509.ParseCertificate(der)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.algo, cert.SignatureAlgorithm)
+		assert.Equal(t, "Self Signed", cert.Subject.CommonName)
+		assert.Equal(t, cert.Subject.String(), cert.Issuer.String())
+		assert.False(t, cert.NotBefore.Before(before))
+		assert.False(t, cert.NotBefore.After(after))
+		assert.Equal(t, time.Hour, cert.NotAfter.Sub(cert.NotBefore))
+		assert.NoError(t, cert.CheckSignatureFrom(cert))
+
+		pk, err := csp.KeyImport(cert, &bccsp.X509PublicKeyImportOpts{Temporary: true})
+		assert.NoError(t, err)
+		pub, err := tc.key.PublicKey()
+		assert.NoError(t, err)
+		assert.Equal(t, pub.SKI(), pk.SKI())
+	}
+
+	_, err = SelfSignedCert(csp, ecKey, pkix.Name{}, time.Hour, &rsa.PSSOptions{Hash: crypto.SHA256})
+	assert.Error(t, err)
+	_, err = SelfSignedCert(csp, ecKey, pkix.Name{}, time.Hour, crypto.SHA1)
+	assert.Error(t, err)
+	_, err = SelfSignedCert(csp, ecKey, pkix.Name{}, 0, nil)
+	assert.Error(t, err)
+	_, err = SelfSignedCert(nil, ecKey, pkix.Name{}, time.Hour, nil)
+	assert.Error(t, err)
+}
+
+func TestCertForUsage(t *testing.T) {
+	csp, err := swcp.NewDefaultSecurityLevelWithKeystore(swcp.NewDummyKeyStore())
+	assert.NoError(t, err)
+
+	ecKey, err := csp.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	rsaKey, err := csp.KeyGen(&bccsp.RSA2048KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+
+	for _, tc := range []struct {
+		key      bccsp.Key
+		usage    CertUsage
+		keyUsage x509.KeyUsage
+		extUsage []x509.ExtKeyUsage
+		isCA     bool
+	}{
+		{ecKey, TLSServer, x509.KeyUsageDigitalSignature, []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}, false},
+		{rsaKey, TLSServer, x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment, []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}, false},
+		{ecKey, TLSClient, x509.KeyUsageDigitalSignature, []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}, false},
+		{rsaKey, CodeSigning, x509.KeyUsageDigitalSignature, []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning}, false},
+		{ecKey, CA, x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign | x509.KeyUsageCRLSign, nil, true},
+	} {
+		der, err := CertForUsage(csp, tc.key, pkix.Name{CommonName: "example.com"}, tc.usage, time.Hour)
+		assert.NoError(t, err, tc.usage.String())
+		cert, err := x509.ParseCertificate(der)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.keyUsage, cert.KeyUsage, tc.usage.String())
+		assert.Equal(t, tc.extUsage, cert.ExtKeyUsage, tc.usage.String())
+		assert.True(t, cert.BasicConstraintsValid)
+		assert.Equal(t, tc.isCA, cert.IsCA)
+		assert.Equal(t, x509.SHA256WithRSA == cert.SignatureAlgorithm, tc.key == rsaKey)
+	}
+
+	_, err = CertForUsage(csp, ecKey, pkix.Name{}, CertUsage(0), time.Hour)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "CertUsage(0)")
+	_, err = CertForUsage(csp, ecKey, pkix.Name{}, TLSServer, 0)
+	assert.Error(t, err)
+	_, err = CertForUsage(nil, ecKey, pkix.Name{}, TLSServer, time.Hour)
+	assert.Error(t, err)
+}
@@ -0,0 +1,66 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package signer
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"io"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+	"github.com/pkg/errors"
+)
+
+// bccspCryptoDecrypter is the BCCSP-based implementation of a crypto.Decrypter
+type bccspCryptoDecrypter struct {
+	csp bccsp.BCCSP
+	key bccsp.Key
+	pk  *rsa.PublicKey
+}
+
+// NewDecrypter returns a new BCCSP-based crypto.Decrypter
+// for the given BCCSP instance and RSA private key.
+func NewDecrypter(csp bccsp.BCCSP, key bccsp.Key) (crypto.Decrypter, error) {
+	if key != nil && !key.Symmetric() && !key.Private() {
+		return nil, errors.New("key must be private.")
+	}
+	s, err := New(csp, key)
+	if err != nil {
+		return nil, err
+	}
+	pk, ok := s.Public().(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.Errorf("key must be an RSA key, got [%T].", s.Public())
+	}
+	return &bccspCryptoDecrypter{csp, key, pk}, nil
+}
+
+// Public returns the public key corresponding to the opaque,
+// private key.
+func (d *bccspCryptoDecrypter) Public() crypto.PublicKey {
+	return d.pk
+}
+
+// Decrypt decrypts ciphertext with the private key. Opts are either
+// *rsa.OAEPOptions for RSA-OAEP or *rsa.PKCS1v15DecryptOptions for
+// PKCS#1 v1.5 decryption, nil opts select the latter as in the rsa package.
+// Randomness is provided by the BCCSP, rand is ignored.
+func (d *bccspCryptoDecrypter) Decrypt(rand io.Reader, ciphertext []byte, opts crypto.DecrypterOpts) ([]byte, error) {
+	if opts == nil {
+		opts = &rsa.PKCS1v15DecryptOptions{}
+	}
+	return d.csp.Decrypt(d.key, ciphertext, opts)
+}
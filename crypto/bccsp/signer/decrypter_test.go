@@ -0,0 +1,76 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package signer
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp/swcp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecrypter(t *testing.T) {
+	csp, err := swcp.NewDefaultSecurityLevelWithKeystore(swcp.NewDummyKeyStore())
+	assert.NoError(t, err)
+	k, err := csp.KeyGen(&bccsp.RSA2048KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+
+	decrypter, err := NewDecrypter(csp, k)
+	assert.NoError(t, err)
+	pub := decrypter.Public().(*rsa.PublicKey)
+
+	msg := []byte("Hello World")
+	ct, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, pub, msg, nil)
+	assert.NoError(t, err)
+	pt, err := decrypter.Decrypt(rand.Reader, ct, &rsa.OAEPOptions{Hash: crypto.SHA256})
+	assert.NoError(t, err)
+	assert.Equal(t, msg, pt)
+
+	ct, err = rsa.EncryptPKCS1v15(rand.Reader, pub, msg)
+	assert.NoError(t, err)
+	pt, err = decrypter.Decrypt(rand.Reader, ct, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, msg, pt)
+
+	_, err = decrypter.Decrypt(rand.Reader, ct, &rsa.OAEPOptions{Hash: crypto.SHA256})
+	assert.Error(t, err)
+}
+
+func TestDecrypterInitFailures(t *testing.T) {
+	csp, err := swcp.NewDefaultSecurityLevelWithKeystore(swcp.NewDummyKeyStore())
+	assert.NoError(t, err)
+
+	_, err = NewDecrypter(nil, nil)
+	assert.Error(t, err)
+
+	ecKey, err := csp.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	_, err = NewDecrypter(csp, ecKey)
+	assert.Error(t, err)
+
+	rsaKey, err := csp.KeyGen(&bccsp.RSA2048KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	pk, err := rsaKey.PublicKey()
+	assert.NoError(t, err)
+	_, err = NewDecrypter(csp, pk)
+	assert.Error(t, err)
+	assert.Equal(t, "key must be private.", err.Error())
+}
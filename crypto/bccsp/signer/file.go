@@ -0,0 +1,70 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package signer
+
+import (
+	"io"
+	"os"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+	"github.com/ipfn/ipfn/pkg/digest"
+	"github.com/pkg/errors"
+)
+
+// SignFile - Creates detached signature of file contents.
+// File is streamed through hasher of given type and the digest is signed.
+func SignFile(csp bccsp.BCCSP, key bccsp.Key, path string, hashType digest.Type) ([]byte, error) {
+	if csp == nil {
+		return nil, errors.New("bccsp instance must be different from nil.")
+	}
+	hash, err := hashFile(csp, path, hashType)
+	if err != nil {
+		return nil, err
+	}
+	return csp.Sign(key, hash, nil)
+}
+
+// VerifyFile - Verifies detached signature of file contents created with SignFile.
+//
+// Invalid signature results in false and no error. Errors opening or reading
+// the file are returned wrapped, errors.Cause returns the underlying error,
+// which for a missing file satisfies os.IsNotExist.
+func VerifyFile(csp bccsp.BCCSP, key bccsp.Key, path string, sig []byte, hashType digest.Type) (bool, error) {
+	if csp == nil {
+		return false, errors.New("bccsp instance must be different from nil.")
+	}
+	hash, err := hashFile(csp, path, hashType)
+	if err != nil {
+		return false, err
+	}
+	return csp.Verify(key, sig, hash, nil)
+}
+
+func hashFile(csp bccsp.BCCSP, path string, hashType digest.Type) ([]byte, error) {
+	h, err := csp.Hasher(hashType)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed getting hasher")
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed opening file %s", path)
+	}
+	defer f.Close()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, errors.Wrapf(err, "failed reading file %s", path)
+	}
+	return h.Sum(nil), nil
+}
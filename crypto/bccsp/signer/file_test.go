@@ -0,0 +1,88 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package signer
+
+import (
+	"crypto/sha256"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp/swcp"
+	"github.com/ipfn/ipfn/pkg/digest"
+)
+
+func TestSignVerifyFile(t *testing.T) {
+	csp, err := swcp.NewDefaultSecurityLevelWithKeystore(swcp.NewDummyKeyStore())
+	assert.NoError(t, err)
+	k, err := csp.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	pk, err := k.PublicKey()
+	assert.NoError(t, err)
+
+	dir, err := ioutil.TempDir("", "signfile")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// Larger than a single read buffer
+	content := make([]byte, 100000)
+	for i := range content {
+		content[i] = byte(i)
+	}
+	path := filepath.Join(dir, "data.bin")
+	assert.NoError(t, ioutil.WriteFile(path, content, 0600))
+
+	sig, err := SignFile(csp, k, path, digest.Sha2_256)
+	assert.NoError(t, err)
+
+	valid, err := VerifyFile(csp, pk, path, sig, digest.Sha2_256)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+
+	// Equals to signature over digest of contents
+	hash := sha256.Sum256(content)
+	valid, err = csp.Verify(pk, sig, hash[:], nil)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+
+	// Tampered file
+	content[500] ^= 0xff
+	assert.NoError(t, ioutil.WriteFile(path, content, 0600))
+	valid, err = VerifyFile(csp, pk, path, sig, digest.Sha2_256)
+	assert.NoError(t, err)
+	assert.False(t, valid)
+
+	// Missing file
+	valid, err = VerifyFile(csp, pk, filepath.Join(dir, "missing.bin"), sig, digest.Sha2_256)
+	assert.False(t, valid)
+	assert.True(t, os.IsNotExist(errors.Cause(err)))
+	_, err = SignFile(csp, k, filepath.Join(dir, "missing.bin"), digest.Sha2_256)
+	assert.True(t, os.IsNotExist(errors.Cause(err)))
+
+	// Read error
+	valid, err = VerifyFile(csp, pk, dir, sig, digest.Sha2_256)
+	assert.False(t, valid)
+	assert.Error(t, err)
+
+	// Unsupported hash
+	_, err = SignFile(csp, k, path, digest.Keccak512)
+	assert.Error(t, err)
+}
@@ -0,0 +1,126 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package signer
+
+import (
+	"encoding/binary"
+	"time"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+	"github.com/ipfn/ipfn/pkg/digest"
+	"github.com/pkg/errors"
+)
+
+// TimestampSize - Size of timestamp prefix of payload.
+const TimestampSize = 8
+
+var (
+	// ErrStaleTimestamp - Error returned when payload timestamp is older than maximum age.
+	ErrStaleTimestamp = errors.New("payload timestamp is stale")
+
+	// ErrFutureTimestamp - Error returned when payload timestamp is in the future
+	// by more than tolerated clock skew.
+	ErrFutureTimestamp = errors.New("payload timestamp is in the future")
+)
+
+// DefaultClockSkew - Tolerated clock skew of timestamps in the future.
+const DefaultClockSkew = 30 * time.Second
+
+// Clock - Source of current time.
+type Clock interface {
+	// Now - Returns current time.
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// FreshOption - Option of VerifyFresh.
+type FreshOption func(*freshOptions)
+
+type freshOptions struct {
+	clock Clock
+	skew  time.Duration
+}
+
+// WithClock - Sets clock reporting current time to VerifyFresh,
+// system clock is used by default.
+func WithClock(clock Clock) FreshOption {
+	return func(opts *freshOptions) {
+		opts.clock = clock
+	}
+}
+
+// WithClockSkew - Sets how far in the future timestamps are accepted
+// by VerifyFresh, DefaultClockSkew by default.
+func WithClockSkew(skew time.Duration) FreshOption {
+	return func(opts *freshOptions) {
+		opts.skew = skew
+	}
+}
+
+// TimestampPayload - Frames message with timestamp for VerifyFresh.
+// Payload is timestamp in Unix nanoseconds encoded as 8 bytes
+// big endian integer followed by the message.
+func TimestampPayload(ts time.Time, msg []byte) []byte {
+	payload := make([]byte, TimestampSize+len(msg))
+	binary.BigEndian.PutUint64(payload, uint64(ts.UnixNano()))
+	copy(payload[TimestampSize:], msg)
+	return payload
+}
+
+// VerifyFresh - Verifies signature of payload and checks that timestamp embedded
+// in payload is not older than maxAge according to the clock, see WithClock.
+//
+// Payload has to be framed using TimestampPayload and signature has to be
+// created over SHA-256 digest of the whole payload. Timestamp is checked only
+// when signature is valid, ErrStaleTimestamp or ErrFutureTimestamp is returned
+// if it is not fresh. Timestamps ahead of the clock by at most the clock skew,
+// see WithClockSkew, are accepted.
+func VerifyFresh(csp bccsp.BCCSP, key bccsp.Key, payload, sig []byte, maxAge time.Duration, opts ...FreshOption) (bool, error) {
+	if csp == nil {
+		return false, errors.New("bccsp instance must be different from nil.")
+	}
+	if len(payload) < TimestampSize {
+		return false, errors.New("payload is too short to contain timestamp.")
+	}
+	options := freshOptions{clock: systemClock{}, skew: DefaultClockSkew}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	hash, err := csp.Hash(payload, digest.Sha2_256)
+	if err != nil {
+		return false, errors.Wrap(err, "failed hashing payload")
+	}
+	valid, err := csp.Verify(key, sig, hash, nil)
+	if err != nil || !valid {
+		return false, err
+	}
+
+	ts := time.Unix(0, int64(binary.BigEndian.Uint64(payload)))
+	now := options.clock.Now()
+	if ts.Sub(now) > options.skew {
+		return false, ErrFutureTimestamp
+	}
+	if now.Sub(ts) > maxAge {
+		return false, ErrStaleTimestamp
+	}
+	return true, nil
+}
@@ -0,0 +1,99 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package signer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp/swcp"
+	"github.com/ipfn/ipfn/pkg/digest"
+	"github.com/stretchr/testify/assert"
+)
+
+type fixedClock time.Time
+
+func (c fixedClock) Now() time.Time {
+	return time.Time(c)
+}
+
+func TestVerifyFresh(t *testing.T) {
+	csp, err := swcp.NewDefaultSecurityLevelWithKeystore(swcp.NewDummyKeyStore())
+	assert.NoError(t, err)
+	k, err := csp.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+
+	now := time.Unix(1500000000, 0)
+	clock := WithClock(fixedClock(now))
+
+	sign := func(ts time.Time) ([]byte, []byte) {
+		payload := TimestampPayload(ts, []byte("Hello World"))
+		hash, err := csp.Hash(payload, digest.Sha2_256)
+		assert.NoError(t, err)
+		sig, err := csp.Sign(k, hash, nil)
+		assert.NoError(t, err)
+		return payload, sig
+	}
+
+	// Fresh timestamps
+	for _, ts := range []time.Time{now, now.Add(-time.Minute), now.Add(-time.Hour)} {
+		payload, sig := sign(ts)
+		valid, err := VerifyFresh(csp, k, payload, sig, time.Hour, clock)
+		assert.NoError(t, err)
+		assert.True(t, valid)
+	}
+
+	// Stale timestamp
+	payload, sig := sign(now.Add(-time.Hour - time.Nanosecond))
+	valid, err := VerifyFresh(csp, k, payload, sig, time.Hour, clock)
+	assert.Equal(t, ErrStaleTimestamp, err)
+	assert.False(t, valid)
+
+	// Future timestamp within clock skew
+	payload, sig = sign(now.Add(DefaultClockSkew))
+	valid, err = VerifyFresh(csp, k, payload, sig, time.Hour, clock)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+
+	// Future timestamp
+	payload, sig = sign(now.Add(DefaultClockSkew + time.Nanosecond))
+	valid, err = VerifyFresh(csp, k, payload, sig, time.Hour, clock)
+	assert.Equal(t, ErrFutureTimestamp, err)
+	assert.False(t, valid)
+	payload, sig = sign(now.Add(time.Second))
+	valid, err = VerifyFresh(csp, k, payload, sig, time.Hour, clock, WithClockSkew(0))
+	assert.Equal(t, ErrFutureTimestamp, err)
+	assert.False(t, valid)
+
+	// System clock by default
+	payload, sig = sign(time.Now())
+	valid, err = VerifyFresh(csp, k, payload, sig, time.Hour)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+
+	// Tampered timestamp
+	payload, sig = sign(now.Add(-2 * time.Hour))
+	copy(payload, TimestampPayload(now, nil))
+	valid, err = VerifyFresh(csp, k, payload, sig, time.Hour, clock)
+	assert.NoError(t, err)
+	assert.False(t, valid)
+
+	_, err = VerifyFresh(csp, k, payload[:TimestampSize-1], sig, time.Hour, clock)
+	assert.Error(t, err)
+	_, err = VerifyFresh(nil, k, payload, sig, time.Hour)
+	assert.Error(t, err)
+}
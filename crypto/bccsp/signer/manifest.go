@@ -0,0 +1,68 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package signer
+
+import (
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+	"github.com/pkg/errors"
+)
+
+// ManifestEntry - Signed entry of a manifest, e.g. of software update.
+type ManifestEntry struct {
+	// SKI is the subject key identifier of the signing key.
+	SKI []byte
+	// Digest is the signed digest of the entry.
+	Digest []byte
+	// Signature is the signature of the digest.
+	Signature []byte
+}
+
+// VerifyManifest - Verifies signatures of all manifest entries with keys
+// resolved by their SKI from the key store and returns result of every entry.
+//
+// Entries signed by a key unknown to the key store, with malformed signature
+// or one failing verification result in false, they do not abort verification
+// of other entries. Error is returned only for invalid arguments.
+func VerifyManifest(csp bccsp.BCCSP, keys bccsp.KeyStore, manifest []ManifestEntry) ([]bool, error) {
+	if csp == nil {
+		return nil, errors.New("bccsp instance must be different from nil.")
+	}
+	if keys == nil {
+		return nil, errors.New("key store must be different from nil.")
+	}
+	results := make([]bool, len(manifest))
+	resolved := make(map[string]bccsp.Key)
+	for index, entry := range manifest {
+		if len(entry.SKI) == 0 {
+			continue
+		}
+		key, ok := resolved[string(entry.SKI)]
+		if !ok {
+			var err error
+			if key, err = keys.Key(entry.SKI); err != nil {
+				// Unknown keys are cached as nil
+				key = nil
+			}
+			resolved[string(entry.SKI)] = key
+		}
+		if key == nil {
+			continue
+		}
+		valid, err := csp.Verify(key, entry.Signature, entry.Digest, nil)
+		results[index] = err == nil && valid
+	}
+	return results, nil
+}
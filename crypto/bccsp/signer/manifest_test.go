@@ -0,0 +1,96 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package signer
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp/swcp"
+)
+
+func TestVerifyManifest(t *testing.T) {
+	csp, err := swcp.NewDefaultSecurityLevelWithKeystore(swcp.NewDummyKeyStore())
+	assert.NoError(t, err)
+
+	dir, err := ioutil.TempDir("", "manifest")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	keys, err := swcp.NewFileBasedKeyStore(nil, dir, false)
+	assert.NoError(t, err)
+
+	var signers []bccsp.Key
+	for _, opts := range []bccsp.KeyGenOpts{
+		&bccsp.ECDSAP256KeyGenOpts{Temporary: true},
+		&bccsp.ECDSAP384KeyGenOpts{Temporary: true},
+	} {
+		k, err := csp.KeyGen(opts)
+		assert.NoError(t, err)
+		pk, err := k.PublicKey()
+		assert.NoError(t, err)
+		assert.NoError(t, keys.StoreKey(pk))
+		signers = append(signers, k)
+	}
+	unknown, err := csp.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+
+	entry := func(k bccsp.Key, name string) ManifestEntry {
+		digest := sha256.Sum256([]byte(name))
+		sig, err := csp.Sign(k, digest[:], nil)
+		assert.NoError(t, err)
+		return ManifestEntry{SKI: k.SKI(), Digest: digest[:], Signature: sig}
+	}
+
+	var (
+		manifest []ManifestEntry
+		expected []bool
+	)
+	for i := 0; i < 6; i++ {
+		manifest = append(manifest, entry(signers[i%2], fmt.Sprintf("file-%d", i)))
+		expected = append(expected, true)
+	}
+	// Signature of a different digest
+	manifest[2].Digest = manifest[3].Digest
+	expected[2] = false
+	// Malformed signature
+	manifest[3].Signature = []byte("garbage")
+	expected[3] = false
+	// Unknown key
+	manifest = append(manifest, entry(unknown, "file-unknown"))
+	expected = append(expected, false)
+	// Missing SKI
+	manifest = append(manifest, ManifestEntry{Digest: manifest[0].Digest, Signature: manifest[0].Signature})
+	expected = append(expected, false)
+
+	results, err := VerifyManifest(csp, keys, manifest)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, results)
+
+	results, err = VerifyManifest(csp, keys, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, results)
+
+	_, err = VerifyManifest(nil, keys, manifest)
+	assert.Error(t, err)
+	_, err = VerifyManifest(csp, nil, manifest)
+	assert.Error(t, err)
+}
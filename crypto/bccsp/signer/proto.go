@@ -0,0 +1,82 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package signer
+
+import (
+	"github.com/golang/protobuf/proto"
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+	"github.com/ipfn/ipfn/pkg/digest"
+	"github.com/pkg/errors"
+)
+
+// SignProto - Signs hash of deterministically marshalled protobuf message.
+//
+// Message is marshalled with deterministic option of proto.Buffer, which is
+// proto.MarshalOptions{Deterministic: true} in the newer protobuf API. Map
+// entries are sorted by key, so the encoding of the same message does not
+// depend on map iteration order. Deterministic marshalling is stable for a
+// given build of the protobuf library, it is not canonical across languages
+// or library versions, verifiers should use the same message definition.
+// Opts are passed to the CSP, e.g. RSA keys require them.
+func SignProto(csp bccsp.BCCSP, key bccsp.Key, msg proto.Message, hashType digest.Type, opts bccsp.SignerOpts) ([]byte, error) {
+	if csp == nil {
+		return nil, errors.New("bccsp instance must be different from nil.")
+	}
+	hash, err := hashProto(csp, msg, hashType)
+	if err != nil {
+		return nil, err
+	}
+	return csp.Sign(key, hash, opts)
+}
+
+// VerifyProto - Verifies signature of protobuf message created with SignProto.
+// Opts have to match the ones passed to SignProto.
+func VerifyProto(csp bccsp.BCCSP, key bccsp.Key, msg proto.Message, sig []byte, hashType digest.Type, opts bccsp.SignerOpts) (bool, error) {
+	if csp == nil {
+		return false, errors.New("bccsp instance must be different from nil.")
+	}
+	hash, err := hashProto(csp, msg, hashType)
+	if err != nil {
+		return false, err
+	}
+	return csp.Verify(key, sig, hash, opts)
+}
+
+// MarshalProtoDeterministic - Marshals protobuf message deterministically,
+// as signed by SignProto.
+func MarshalProtoDeterministic(msg proto.Message) ([]byte, error) {
+	if msg == nil {
+		return nil, errors.New("message must be different from nil.")
+	}
+	buf := proto.NewBuffer(nil)
+	buf.SetDeterministic(true)
+	if err := buf.Marshal(msg); err != nil {
+		return nil, errors.Wrap(err, "failed marshalling message")
+	}
+	return buf.Bytes(), nil
+}
+
+func hashProto(csp bccsp.BCCSP, msg proto.Message, hashType digest.Type) ([]byte, error) {
+	body, err := MarshalProtoDeterministic(msg)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := csp.Hash(body, hashType)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed hashing message")
+	}
+	return hash, nil
+}
@@ -0,0 +1,99 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package signer
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"fmt"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	structpb "github.com/golang/protobuf/ptypes/struct"
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp/swcp"
+	"github.com/ipfn/ipfn/pkg/digest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignProto(t *testing.T) {
+	csp, err := swcp.NewDefaultSecurityLevelWithKeystore(swcp.NewDummyKeyStore())
+	assert.NoError(t, err)
+	csp.(*swcp.CSP).SetDeterministicECDSA(true)
+	k, err := csp.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+
+	newMessage := func() *structpb.Struct {
+		msg := &structpb.Struct{Fields: make(map[string]*structpb.Value)}
+		for i := 0; i < 32; i++ {
+			msg.Fields[fmt.Sprintf("field-%d", i)] = &structpb.Value{
+				Kind: &structpb.Value_NumberValue{NumberValue: float64(i)},
+			}
+		}
+		return msg
+	}
+
+	msg := newMessage()
+	expected, err := MarshalProtoDeterministic(msg)
+	assert.NoError(t, err)
+	sig, err := SignProto(csp, k, msg, digest.Sha2_256, nil)
+	assert.NoError(t, err)
+
+	// Encoding and signature do not depend on map iteration order
+	for i := 0; i < 16; i++ {
+		msg := newMessage()
+		body, err := MarshalProtoDeterministic(msg)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, body)
+		again, err := SignProto(csp, k, msg, digest.Sha2_256, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, sig, again)
+	}
+
+	valid, err := VerifyProto(csp, k, msg, sig, digest.Sha2_256, nil)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+
+	// Decoded message verifies
+	decoded := &structpb.Struct{}
+	assert.NoError(t, proto.Unmarshal(expected, decoded))
+	valid, err = VerifyProto(csp, k, decoded, sig, digest.Sha2_256, nil)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+
+	// Modified message
+	msg.Fields["field-0"] = &structpb.Value{Kind: &structpb.Value_StringValue{StringValue: "0"}}
+	valid, err = VerifyProto(csp, k, msg, sig, digest.Sha2_256, nil)
+	assert.NoError(t, err)
+	assert.False(t, valid)
+
+	_, err = SignProto(csp, k, nil, digest.Sha2_256, nil)
+	assert.Error(t, err)
+	_, err = SignProto(nil, k, msg, digest.Sha2_256, nil)
+	assert.Error(t, err)
+	_, err = VerifyProto(nil, k, msg, sig, digest.Sha2_256, nil)
+	assert.Error(t, err)
+
+	// RSA keys with opts
+	rsaKey, err := csp.KeyGen(&bccsp.RSA2048KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	opts := &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: crypto.SHA256}
+	sig, err = SignProto(csp, rsaKey, msg, digest.Sha2_256, opts)
+	assert.NoError(t, err)
+	valid, err = VerifyProto(csp, rsaKey, msg, sig, digest.Sha2_256, opts)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+}
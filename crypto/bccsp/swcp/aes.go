@@ -19,9 +19,11 @@ import (
 	"bytes"
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/hmac"
 	"crypto/rand"
 	"errors"
 	"fmt"
+	"hash"
 	"io"
 
 	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
@@ -179,6 +181,51 @@ func AESCBCPKCS7Decrypt(key, src []byte) ([]byte, error) {
 	return nil, err
 }
 
+// AESCBCHMACEncrypt combines CBC encryption and PKCS7 padding and appends
+// an HMAC tag computed over the IV and the ciphertext (encrypt-then-MAC).
+func AESCBCHMACEncrypt(key, macKey []byte, h func() hash.Hash, src []byte) ([]byte, error) {
+	ct, err := AESCBCPKCS7Encrypt(key, src)
+	if err != nil {
+		return nil, err
+	}
+
+	mac := hmac.New(h, macKey)
+	mac.Write(ct)
+	return mac.Sum(ct), nil
+}
+
+// AESCBCHMACDecrypt verifies the HMAC tag appended to the ciphertext in
+// constant time and only then performs CBC decryption and PKCS7 unpadding.
+func AESCBCHMACDecrypt(key, macKey []byte, h func() hash.Hash, src []byte) ([]byte, error) {
+	mac := hmac.New(h, macKey)
+	if len(src) < aes.BlockSize+mac.Size() {
+		return nil, errors.New("Invalid ciphertext. It must contain the IV and the MAC tag")
+	}
+
+	ct, tag := src[:len(src)-mac.Size()], src[len(src)-mac.Size():]
+	mac.Write(ct)
+	if !hmac.Equal(tag, mac.Sum(nil)) {
+		return nil, errors.New("Invalid MAC tag")
+	}
+
+	return AESCBCPKCS7Decrypt(key, ct)
+}
+
+// hmacParams returns the raw MAC key and hash function for the passed opts.
+func hmacParams(o *bccsp.AESCBCHMACModeOpts) ([]byte, func() hash.Hash, error) {
+	macKey, ok := o.MACKey.(*aesPrivateKey)
+	if !ok {
+		return nil, nil, errors.New("Invalid options. MACKey must be a symmetric key.")
+	}
+
+	h, err := hashFunction(o.Hash)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return macKey.privKey, h, nil
+}
+
 type aescbcpkcs7Encryptor struct{}
 
 func (e *aescbcpkcs7Encryptor) Encrypt(k bccsp.Key, plaintext []byte, opts bccsp.EncrypterOpts) ([]byte, error) {
@@ -201,6 +248,20 @@ func (e *aescbcpkcs7Encryptor) Encrypt(k bccsp.Key, plaintext []byte, opts bccsp
 		return AESCBCPKCS7Encrypt(k.(*aesPrivateKey).privKey, plaintext)
 	case bccsp.AESCBCPKCS7ModeOpts:
 		return e.Encrypt(k, plaintext, &o)
+	case *bccsp.AESCBCHMACModeOpts:
+		// AES in CBC mode with PKCS7 padding and HMAC (encrypt-then-MAC)
+		macKey, h, err := hmacParams(o)
+		if err != nil {
+			return nil, err
+		}
+		return AESCBCHMACEncrypt(k.(*aesPrivateKey).privKey, macKey, h, plaintext)
+	case bccsp.AESCBCHMACModeOpts:
+		return e.Encrypt(k, plaintext, &o)
+	case *bccsp.AESGCMSIVModeOpts:
+		// AES-GCM-SIV nonce misuse-resistant AEAD
+		return gcmsivEncrypt(k.(*aesPrivateKey).privKey, plaintext, o)
+	case bccsp.AESGCMSIVModeOpts:
+		return e.Encrypt(k, plaintext, &o)
 	default:
 		return nil, fmt.Errorf("Mode not recognized [%s]", opts)
 	}
@@ -208,12 +269,26 @@ func (e *aescbcpkcs7Encryptor) Encrypt(k bccsp.Key, plaintext []byte, opts bccsp
 
 type aescbcpkcs7Decryptor struct{}
 
-func (*aescbcpkcs7Decryptor) Decrypt(k bccsp.Key, ciphertext []byte, opts bccsp.DecrypterOpts) ([]byte, error) {
+func (d *aescbcpkcs7Decryptor) Decrypt(k bccsp.Key, ciphertext []byte, opts bccsp.DecrypterOpts) ([]byte, error) {
 	// check for mode
-	switch opts.(type) {
+	switch o := opts.(type) {
 	case *bccsp.AESCBCPKCS7ModeOpts, bccsp.AESCBCPKCS7ModeOpts:
 		// AES in CBC mode with PKCS7 padding
 		return AESCBCPKCS7Decrypt(k.(*aesPrivateKey).privKey, ciphertext)
+	case *bccsp.AESCBCHMACModeOpts:
+		// AES in CBC mode with PKCS7 padding and HMAC (encrypt-then-MAC)
+		macKey, h, err := hmacParams(o)
+		if err != nil {
+			return nil, err
+		}
+		return AESCBCHMACDecrypt(k.(*aesPrivateKey).privKey, macKey, h, ciphertext)
+	case bccsp.AESCBCHMACModeOpts:
+		return d.Decrypt(k, ciphertext, &o)
+	case *bccsp.AESGCMSIVModeOpts:
+		// AES-GCM-SIV nonce misuse-resistant AEAD
+		return gcmsivDecrypt(k.(*aesPrivateKey).privKey, ciphertext, o)
+	case bccsp.AESGCMSIVModeOpts:
+		return d.Decrypt(k, ciphertext, &o)
 	default:
 		return nil, fmt.Errorf("Mode not recognized [%s]", opts)
 	}
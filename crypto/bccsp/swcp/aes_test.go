@@ -19,6 +19,7 @@ import (
 	"bytes"
 	"crypto/aes"
 	"crypto/rand"
+	"crypto/sha256"
 	"math/big"
 	mrand "math/rand"
 	"testing"
@@ -28,6 +29,7 @@ import (
 	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
 	"github.com/ipfn/ipfn/pkg/crypto/bccsp/mocks"
 	"github.com/ipfn/ipfn/pkg/crypto/bccsp/utils"
+	"github.com/ipfn/ipfn/pkg/digest"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -678,3 +680,89 @@ func TestAESCBCPKCS7EncryptorWithRandSameCiphertext(t *testing.T) {
 
 	assert.Equal(t, ct, ct2)
 }
+
+func TestAESCBCHMACEncryptorDecrypt(t *testing.T) {
+	t.Parallel()
+
+	raw, err := GetRandomBytes(32)
+	assert.NoError(t, err)
+	macRaw, err := GetRandomBytes(32)
+	assert.NoError(t, err)
+
+	k := &aesPrivateKey{privKey: raw, exportable: false}
+	opts := &bccsp.AESCBCHMACModeOpts{
+		MACKey: &aesPrivateKey{privKey: macRaw, exportable: false},
+		Hash:   digest.Sha2_256,
+	}
+
+	msg := []byte("Hello World")
+	encryptor := &aescbcpkcs7Encryptor{}
+	decryptor := &aescbcpkcs7Decryptor{}
+
+	_, err = encryptor.Encrypt(k, msg, &bccsp.AESCBCHMACModeOpts{Hash: digest.Sha2_256})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "MACKey must be a symmetric key")
+
+	_, err = encryptor.Encrypt(k, msg, &bccsp.AESCBCHMACModeOpts{MACKey: opts.MACKey})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Unsupported hash type")
+
+	ct, err := encryptor.Encrypt(k, msg, opts)
+	assert.NoError(t, err)
+	assert.Len(t, ct, aes.BlockSize+aes.BlockSize+sha256.Size)
+
+	pt, err := decryptor.Decrypt(k, utils.Clone(ct), opts)
+	assert.NoError(t, err)
+	assert.Equal(t, msg, pt)
+
+	pt, err = decryptor.Decrypt(k, utils.Clone(ct), *opts)
+	assert.NoError(t, err)
+	assert.Equal(t, msg, pt)
+
+	_, err = decryptor.Decrypt(k, ct[:aes.BlockSize], opts)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Invalid ciphertext")
+
+	wrongMAC := &bccsp.AESCBCHMACModeOpts{MACKey: k, Hash: digest.Sha2_256}
+	_, err = decryptor.Decrypt(k, utils.Clone(ct), wrongMAC)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Invalid MAC tag")
+}
+
+func TestAESCBCHMACTamperDetection(t *testing.T) {
+	t.Parallel()
+
+	key, err := GetRandomBytes(32)
+	assert.NoError(t, err)
+	macKey, err := GetRandomBytes(32)
+	assert.NoError(t, err)
+
+	msg := []byte("a message that spans more than a single block")
+	ct, err := AESCBCHMACEncrypt(key, macKey, sha256.New, msg)
+	assert.NoError(t, err)
+
+	// Tamper with the IV
+	tampered := utils.Clone(ct)
+	tampered[0] ^= 0x01
+	_, err = AESCBCHMACDecrypt(key, macKey, sha256.New, tampered)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Invalid MAC tag")
+
+	// Tamper with the last ciphertext block, where the padding lives
+	tampered = utils.Clone(ct)
+	tampered[len(ct)-sha256.Size-1] ^= 0x01
+	_, err = AESCBCHMACDecrypt(key, macKey, sha256.New, tampered)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Invalid MAC tag")
+
+	// Tamper with the tag
+	tampered = utils.Clone(ct)
+	tampered[len(ct)-1] ^= 0x01
+	_, err = AESCBCHMACDecrypt(key, macKey, sha256.New, tampered)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Invalid MAC tag")
+
+	pt, err := AESCBCHMACDecrypt(key, macKey, sha256.New, ct)
+	assert.NoError(t, err)
+	assert.Equal(t, msg, pt)
+}
@@ -0,0 +1,148 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"sync"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+	"github.com/ipfn/ipfn/pkg/digest"
+)
+
+// AuditChain - Hash chain over signing operations.
+//
+// Every signing operation advances head of the chain to AuditLink of
+// previous head, SKI of the signing key, signed digest and signature.
+// Log of operations can be verified by recomputing the chain from
+// genesis, missing or altered operation results in different head.
+//
+// Operations are appended in the order in which they acquire lock of the
+// chain. The CSP appends an operation after its signature is created, so
+// concurrent operations may be recorded in other order than they were
+// started. The chain is the only record of that order, so it has to be
+// verified against the log returned by Entries, which keeps every entry
+// in memory for the lifetime of the chain.
+type AuditChain struct {
+	mu      sync.Mutex
+	genesis digest.Digest
+	head    digest.Digest
+	entries []AuditEntry
+}
+
+// AuditEntry - Signing operation recorded in audit chain.
+type AuditEntry struct {
+	// SKI - Subject key identifier of the signing key.
+	SKI []byte
+	// Digest - Signed digest.
+	Digest []byte
+	// Signature - Created signature.
+	Signature []byte
+	// Head - Head of the chain after the operation.
+	Head digest.Digest
+}
+
+// NewAuditChain - Creates new audit chain starting at genesis digest.
+func NewAuditChain(genesis digest.Digest) *AuditChain {
+	return &AuditChain{genesis: genesis, head: genesis}
+}
+
+// Genesis - Returns genesis digest of the chain.
+func (chain *AuditChain) Genesis() digest.Digest {
+	return chain.genesis
+}
+
+// Head - Returns current head of the chain.
+func (chain *AuditChain) Head() digest.Digest {
+	chain.mu.Lock()
+	defer chain.mu.Unlock()
+	return chain.head
+}
+
+// Entries - Returns copy of the log of operations in the order
+// of the chain, replaying them from genesis results in Head.
+func (chain *AuditChain) Entries() []AuditEntry {
+	chain.mu.Lock()
+	defer chain.mu.Unlock()
+	entries := make([]AuditEntry, len(chain.entries))
+	copy(entries, chain.entries)
+	return entries
+}
+
+// Append - Appends signing operation to the chain and returns new head.
+// Arguments are copied to the log of the chain.
+func (chain *AuditChain) Append(ski, hash, signature []byte) digest.Digest {
+	chain.mu.Lock()
+	defer chain.mu.Unlock()
+	chain.head = AuditLink(chain.head, ski, hash, signature)
+	chain.entries = append(chain.entries, AuditEntry{
+		SKI:       append([]byte(nil), ski...),
+		Digest:    append([]byte(nil), hash...),
+		Signature: append([]byte(nil), signature...),
+		Head:      chain.head,
+	})
+	return chain.head
+}
+
+// AuditLink - Computes next head of audit chain. It is SHA-256 of
+// length-prefixed previous head, key SKI, signed digest and signature.
+func AuditLink(prev digest.Digest, ski, hash, signature []byte) digest.Digest {
+	// SHA-2 family is always supported
+	link, _ := digest.SumFramed(digest.FamilySha2, prev[:], ski, hash, signature)
+	return link
+}
+
+// auditState - Audit chain of the CSP, nil if disabled.
+type auditState struct {
+	sync.RWMutex
+
+	chain *AuditChain
+}
+
+// append - Appends signing operation to the audit chain if it is enabled.
+func (audit *auditState) append(k bccsp.Key, hash, signature []byte) {
+	audit.RLock()
+	defer audit.RUnlock()
+	if audit.chain != nil {
+		audit.chain.Append(k.SKI(), hash, signature)
+	}
+}
+
+// SetAuditChain enables audit chain of signing operations.
+// Passing nil disables it. It is disabled by default.
+func (csp *CSP) SetAuditChain(chain *AuditChain) {
+	csp.audit.Lock()
+	csp.audit.chain = chain
+	csp.audit.Unlock()
+}
+
+// AuditChain returns audit chain of signing operations, which holds the log
+// of operations, see AuditChain.Entries. It returns nil if audit is disabled.
+func (csp *CSP) AuditChain() *AuditChain {
+	csp.audit.RLock()
+	defer csp.audit.RUnlock()
+	return csp.audit.chain
+}
+
+// AuditHead returns head of the audit chain,
+// or empty digest if audit chain is disabled.
+func (csp *CSP) AuditHead() digest.Digest {
+	csp.audit.RLock()
+	defer csp.audit.RUnlock()
+	if csp.audit.chain == nil {
+		return digest.Empty()
+	}
+	return csp.audit.chain.Head()
+}
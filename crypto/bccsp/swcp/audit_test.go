@@ -0,0 +1,126 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"crypto/sha256"
+	"sync"
+	"testing"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+	"github.com/ipfn/ipfn/pkg/digest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditChain(t *testing.T) {
+	provider, _, cleanup := currentTestConfig.Provider(t)
+	defer cleanup()
+	csp := provider.(*CSP)
+
+	k, err := provider.KeyGen(&bccsp.ECDSAKeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	hash := sha256.Sum256([]byte("Hello World"))
+
+	// Disabled by default
+	_, err = provider.Sign(k, hash[:], nil)
+	assert.NoError(t, err)
+	assert.Equal(t, digest.Empty(), csp.AuditHead())
+
+	genesis := digest.SumSha256([]byte("genesis"))
+	csp.SetAuditChain(NewAuditChain(genesis))
+	assert.Equal(t, genesis, csp.AuditHead())
+
+	type operation struct{ digest, signature []byte }
+	var log []operation
+	for _, msg := range []string{"first", "second", "third"} {
+		hash := sha256.Sum256([]byte(msg))
+		signature, err := provider.Sign(k, hash[:], nil)
+		assert.NoError(t, err)
+		log = append(log, operation{hash[:], signature})
+	}
+	head := csp.AuditHead()
+	assert.NotEqual(t, genesis, head)
+
+	// Failed operations do not advance the chain
+	_, err = provider.Sign(k, nil, nil)
+	assert.Error(t, err)
+	assert.Equal(t, head, csp.AuditHead())
+
+	replay := func(ops []operation) digest.Digest {
+		chain := NewAuditChain(genesis)
+		for _, op := range ops {
+			chain.Append(k.SKI(), op.digest, op.signature)
+		}
+		return chain.Head()
+	}
+
+	// Chain advances deterministically
+	assert.Equal(t, head, replay(log))
+	assert.Equal(t, replay(log), replay(log))
+
+	// Log can be read back from the chain
+	chain := csp.AuditChain()
+	assert.Equal(t, genesis, chain.Genesis())
+	entries := chain.Entries()
+	assert.Len(t, entries, len(log))
+	for i, entry := range entries {
+		assert.Equal(t, k.SKI(), entry.SKI)
+		assert.Equal(t, log[i].digest, entry.Digest)
+		assert.Equal(t, log[i].signature, entry.Signature)
+		assert.Equal(t, replay(log[:i+1]), entry.Head)
+	}
+	assert.Equal(t, head, entries[len(entries)-1].Head)
+
+	// Missing or reordered operation is detected
+	assert.NotEqual(t, head, replay([]operation{log[0], log[2]}))
+	assert.NotEqual(t, head, replay([]operation{log[1], log[0], log[2]}))
+
+	csp.SetAuditChain(nil)
+	assert.Equal(t, digest.Empty(), csp.AuditHead())
+	assert.Nil(t, csp.AuditChain())
+}
+
+func TestAuditChainConcurrent(t *testing.T) {
+	provider, _, cleanup := currentTestConfig.Provider(t)
+	defer cleanup()
+	csp := provider.(*CSP)
+
+	k, err := provider.KeyGen(&bccsp.ECDSAKeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	genesis := digest.SumSha256([]byte("genesis"))
+	csp.SetAuditChain(NewAuditChain(genesis))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			hash := sha256.Sum256([]byte{byte(i)})
+			_, err := provider.Sign(k, hash[:], nil)
+			assert.NoError(t, err)
+		}(i)
+	}
+	wg.Wait()
+
+	// Log in order of the chain replays to its head
+	entries := csp.AuditChain().Entries()
+	assert.Len(t, entries, 8)
+	chain := NewAuditChain(genesis)
+	for _, entry := range entries {
+		assert.Equal(t, entry.Head, chain.Append(entry.SKI, entry.Digest, entry.Signature))
+	}
+	assert.Equal(t, csp.AuditHead(), chain.Head())
+}
@@ -0,0 +1,87 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+
+	"github.com/pkg/errors"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp/utils"
+)
+
+// KeyImportAuto imports key from material of format detected by
+// utils.DetectKeyType, which simplifies ingestion of key material from
+// heterogeneous sources. Supported are X.509 certificates, PKIX public
+// keys and PKCS#1, PKCS#8 or SEC 1 private keys, encoded as DER or PEM,
+// and JWKs of public keys. Only ECDSA and RSA keys can be imported.
+// Key is stored in the keystore unless temporary is true, as in KeyImport.
+func (csp *CSP) KeyImportAuto(data []byte, temporary bool) (bccsp.Key, error) {
+	kind, der, err := utils.DetectKeyType(data)
+	if err != nil {
+		return nil, err
+	}
+	switch kind {
+	case utils.KeyTypeCertificate:
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed parsing certificate")
+		}
+		return csp.KeyImport(cert, &bccsp.X509PublicKeyImportOpts{Temporary: temporary})
+	case utils.KeyTypePublicKey:
+		pub, err := utils.DERToPublicKey(der)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed parsing public key")
+		}
+		if _, ok := pub.(*ecdsa.PublicKey); ok {
+			return csp.KeyImport(der, &bccsp.ECDSAPKIXPublicKeyImportOpts{Temporary: temporary})
+		}
+		return csp.importGoPublicKey(pub, temporary)
+	case utils.KeyTypePrivateKey:
+		priv, err := utils.DERToPrivateKey(der)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed parsing private key")
+		}
+		switch priv.(type) {
+		case *ecdsa.PrivateKey:
+			return csp.KeyImport(der, &bccsp.ECDSAPrivateKeyImportOpts{Temporary: temporary})
+		case *rsa.PrivateKey:
+			return csp.KeyImport(der, &bccsp.RSAPrivateKeyImportOpts{Temporary: temporary})
+		}
+		return nil, errors.Errorf("Unsupported private key type [%T]", priv)
+	case utils.KeyTypeJWK:
+		pub, err := utils.JWKToPublicKey(der)
+		if err != nil {
+			return nil, err
+		}
+		return csp.importGoPublicKey(pub, temporary)
+	}
+	return nil, errors.Errorf("Unsupported key material [%s]", kind)
+}
+
+// importGoPublicKey - Imports ECDSA or RSA public key of Go type.
+func (csp *CSP) importGoPublicKey(pub interface{}, temporary bool) (bccsp.Key, error) {
+	switch pub.(type) {
+	case *ecdsa.PublicKey:
+		return csp.KeyImport(pub, &bccsp.ECDSAGoPublicKeyImportOpts{Temporary: temporary})
+	case *rsa.PublicKey:
+		return csp.KeyImport(pub, &bccsp.RSAGoPublicKeyImportOpts{Temporary: temporary})
+	}
+	return nil, errors.Errorf("Unsupported public key type [%T]", pub)
+}
@@ -0,0 +1,97 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp/utils"
+)
+
+func TestKeyImportAuto(t *testing.T) {
+	provider, ks, cleanup := currentTestConfig.Provider(t)
+	defer cleanup()
+	csp := provider.(*CSP)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	// Certificate
+	certPEM := newTestCACert(t, ecKey, "auto", time.Now().Add(time.Hour))
+	k, err := csp.KeyImportAuto(certPEM, true)
+	assert.NoError(t, err)
+	assert.IsType(t, &ecdsaPublicKey{}, k)
+	certDER, _ := pem.Decode(certPEM)
+	_, err = csp.KeyImportAuto(certDER.Bytes, true)
+	assert.NoError(t, err)
+
+	// PKIX public keys
+	ecPub, err := x509.MarshalPKIXPublicKey(&ecKey.PublicKey)
+	assert.NoError(t, err)
+	ecPubKey, err := csp.KeyImportAuto(ecPub, true)
+	assert.NoError(t, err)
+	assert.False(t, ecPubKey.Private())
+	assert.True(t, utils.SamePublicKey(k, ecPubKey))
+	rsaPub, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	assert.NoError(t, err)
+	k, err = csp.KeyImportAuto(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: rsaPub}), true)
+	assert.NoError(t, err)
+	assert.IsType(t, &rsaPublicKey{}, k)
+
+	// PEM private keys, stored unless temporary
+	ecPEM, err := utils.PrivateKeyToPEM(ecKey, nil)
+	assert.NoError(t, err)
+	k, err = csp.KeyImportAuto(ecPEM, false)
+	assert.NoError(t, err)
+	assert.True(t, k.Private())
+	assert.Equal(t, ecPubKey.SKI(), k.SKI())
+	stored, err := ks.Key(k.SKI())
+	assert.NoError(t, err)
+	assert.True(t, stored.Private())
+	rsaPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)})
+	k, err = csp.KeyImportAuto(rsaPEM, true)
+	assert.NoError(t, err)
+	assert.IsType(t, &rsaPrivateKey{}, k)
+
+	// JWK
+	jwk, err := utils.PublicKeyToJWK(ecPubKey)
+	assert.NoError(t, err)
+	jwkJSON, err := json.Marshal(jwk)
+	assert.NoError(t, err)
+	k, err = csp.KeyImportAuto(jwkJSON, true)
+	assert.NoError(t, err)
+	assert.Equal(t, ecPubKey.SKI(), k.SKI())
+
+	// Unrecognized material
+	_, err = csp.KeyImportAuto([]byte("not a key"), true)
+	assert.EqualError(t, err, "Unrecognized key material. Expected certificate, public or private key as DER or PEM, or JWK.")
+	_, err = csp.KeyImportAuto(nil, true)
+	assert.Error(t, err)
+	_, err = csp.KeyImportAuto([]byte(`{"kty":"OKP","crv":"Ed25519","x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}`), true)
+	assert.EqualError(t, err, "Unsupported public key type [ed25519.PublicKey]")
+}
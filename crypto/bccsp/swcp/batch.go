@@ -0,0 +1,250 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"runtime"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+)
+
+// VerifyRequest - Signature verification request of VerifyTuples.
+type VerifyRequest struct {
+	Key       bccsp.Key
+	Signature []byte
+	Digest    []byte
+	Opts      bccsp.SignerOpts
+}
+
+// VerifyTuples - Verifies independent signatures in parallel with default
+// concurrency of VerifyBatchTuples. Results are in order of tuples. Tuple
+// which verification fails with an error is reported as invalid and the
+// error of the first such tuple is returned alongside of all results.
+func (csp *CSP) VerifyTuples(tuples []VerifyRequest) ([]bool, error) {
+	return VerifyBatchTuples(csp, VerifyBatch{Tuples: tuples})
+}
+
+// VerifyBatch - Batch of signature verifications of VerifyBatchTuples.
+type VerifyBatch struct {
+	Tuples []VerifyRequest
+	// Concurrency - Maximum number of concurrent verifications,
+	// see DefaultConcurrency.
+	Concurrency int
+}
+
+// SignRequest - Signing request of SignBatchTuples.
+type SignRequest struct {
+	Key    bccsp.Key
+	Digest []byte
+	Opts   bccsp.SignerOpts
+}
+
+// SignBatch - Batch of signing operations of SignBatchTuples.
+type SignBatch struct {
+	Tuples []SignRequest
+	// Concurrency - Maximum number of concurrent signing operations,
+	// see DefaultConcurrency.
+	Concurrency int
+}
+
+// KeyGenBatch - Batch of key generations of GenerateKeys.
+type KeyGenBatch struct {
+	Opts []bccsp.KeyGenOpts
+	// Concurrency - Maximum number of concurrent key generations,
+	// see DefaultConcurrency.
+	Concurrency int
+}
+
+// DefaultConcurrency - Returns number of concurrent operations of batches
+// with zero Concurrency, which is GOMAXPROCS. Concurrency of every batch is
+// capped by MaxConcurrency of the CSP, if implemented.
+func DefaultConcurrency() int {
+	return runtime.GOMAXPROCS(0)
+}
+
+// ConcurrencyLimiter - Implemented by CSPs which bound number of concurrent
+// operations, such as PKCS#11 CSP by its session pool size.
+type ConcurrencyLimiter interface {
+	// MaxConcurrency - Returns maximum number of concurrent operations.
+	MaxConcurrency() int
+}
+
+// VerifyBatchTuples - Verifies independent signatures of batch in parallel
+// using any CSP with at most batch.Concurrency workers. Results and errors
+// are reported as in VerifyTuples.
+func VerifyBatchTuples(csp bccsp.BCCSP, batch VerifyBatch) ([]bool, error) {
+	if csp == nil {
+		return nil, errors.New("Invalid CSP. It must not be nil.")
+	}
+	workers, err := batchConcurrency(csp, batch.Concurrency)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]bool, len(batch.Tuples))
+	err = runBatch(workers, len(batch.Tuples), func(i int) (err error) {
+		t := batch.Tuples[i]
+		results[i], err = csp.Verify(t.Key, t.Signature, t.Digest, t.Opts)
+		return errors.Wrapf(err, "Failed verifying tuple %d", i)
+	})
+	return results, err
+}
+
+// SignBatchTuples - Signs independent digests of batch in parallel using
+// any CSP with at most batch.Concurrency workers. Signatures are in order
+// of tuples. Tuple which signing fails has nil signature and the error of
+// the first such tuple is returned alongside of all signatures.
+func SignBatchTuples(csp bccsp.BCCSP, batch SignBatch) ([][]byte, error) {
+	if csp == nil {
+		return nil, errors.New("Invalid CSP. It must not be nil.")
+	}
+	workers, err := batchConcurrency(csp, batch.Concurrency)
+	if err != nil {
+		return nil, err
+	}
+	signatures := make([][]byte, len(batch.Tuples))
+	err = runBatch(workers, len(batch.Tuples), func(i int) (err error) {
+		t := batch.Tuples[i]
+		signatures[i], err = csp.Sign(t.Key, t.Digest, t.Opts)
+		return errors.Wrapf(err, "Failed signing tuple %d", i)
+	})
+	return signatures, err
+}
+
+// GenerateKeys - Generates keys of batch in parallel using any CSP with at
+// most batch.Concurrency workers. Keys are in order of opts. Key which
+// generation fails is nil and the error of the first such key is returned
+// alongside of all keys.
+func GenerateKeys(csp bccsp.BCCSP, batch KeyGenBatch) ([]bccsp.Key, error) {
+	if csp == nil {
+		return nil, errors.New("Invalid CSP. It must not be nil.")
+	}
+	workers, err := batchConcurrency(csp, batch.Concurrency)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]bccsp.Key, len(batch.Opts))
+	err = runBatch(workers, len(batch.Opts), func(i int) (err error) {
+		keys[i], err = csp.KeyGen(batch.Opts[i])
+		return errors.Wrapf(err, "Failed generating key %d", i)
+	})
+	return keys, err
+}
+
+// runBatch - Runs n operations of batch from at most workers goroutines and
+// returns error of the first failed operation after all of them finish.
+func runBatch(workers, n int, fn func(i int) error) error {
+	errs := make([]error, n)
+	runWorkers(workers, n, func(i int) {
+		errs[i] = fn(i)
+	})
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// batchConcurrency - Returns number of workers for batch of CSP.
+func batchConcurrency(csp bccsp.BCCSP, concurrency int) (int, error) {
+	if concurrency < 0 {
+		return 0, errors.Errorf("Invalid concurrency [%d]. It must be positive.", concurrency)
+	}
+	if concurrency == 0 {
+		concurrency = DefaultConcurrency()
+	}
+	if limiter, ok := csp.(ConcurrencyLimiter); ok {
+		if max := limiter.MaxConcurrency(); max > 0 && concurrency > max {
+			concurrency = max
+		}
+	}
+	return concurrency, nil
+}
+
+// runWorkers - Calls fn for each index below n from at most workers
+// goroutines and waits for all of them to finish.
+func runWorkers(workers, n int, fn func(i int)) {
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > n {
+		workers = n
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				fn(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// ImportSpec - Key import request of ImportKeys.
+type ImportSpec struct {
+	Raw  interface{}
+	Opts bccsp.KeyImportOpts
+}
+
+// ImportKeys - Imports batch of keys in order of specs. Keys which SKI is
+// already present in the keystore, or repeats earlier in the batch, are not
+// imported again and their SKIs are reported as skipped, so provisioning
+// the same batch twice is idempotent. Import stops on the first error,
+// keys imported before it are returned alongside of the error.
+func ImportKeys(csp *CSP, specs []ImportSpec) (imported []bccsp.Key, skipped [][]byte, err error) {
+	if csp == nil {
+		return nil, nil, errors.New("Invalid CSP. It must not be nil.")
+	}
+	seen := make(map[string]bool, len(specs))
+	for i, spec := range specs {
+		k, err := csp.importKey(spec.Raw, spec.Opts)
+		if err != nil {
+			return imported, skipped, errors.Wrapf(err, "Failed importing key %d", i)
+		}
+		ski := k.SKI()
+		if seen[string(ski)] {
+			skipped = append(skipped, ski)
+			continue
+		}
+		seen[string(ski)] = true
+		if _, err := csp.ks.Key(ski); err == nil {
+			skipped = append(skipped, ski)
+			continue
+		}
+		if !spec.Opts.Ephemeral() {
+			if err := csp.ks.StoreKey(k); err != nil {
+				return imported, skipped, errors.Wrapf(err, "Failed storing key %d", i)
+			}
+			csp.indexStoredKey(k)
+		} else {
+			csp.markEphemeral(k)
+		}
+		imported = append(imported, k)
+	}
+	return imported, skipped, nil
+}
@@ -0,0 +1,285 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp/mocks"
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp/utils"
+)
+
+func TestVerifyTuples(t *testing.T) {
+	provider, _, cleanup := currentTestConfig.Provider(t)
+	defer cleanup()
+	csp := provider.(*CSP)
+
+	var (
+		tuples   []VerifyRequest
+		expected []bool
+	)
+	for i := 0; i < 20; i++ {
+		k, err := provider.KeyGen(&bccsp.ECDSAKeyGenOpts{Temporary: true})
+		assert.NoError(t, err)
+		pk, err := k.PublicKey()
+		assert.NoError(t, err)
+		digest := sha256.Sum256([]byte(fmt.Sprintf("tx %d", i)))
+		sig, err := provider.Sign(k, digest[:], nil)
+		assert.NoError(t, err)
+
+		valid := i%3 != 0
+		if !valid {
+			// signature of a different message
+			digest[0] ^= 0xff
+		}
+		tuples = append(tuples, VerifyRequest{Key: pk, Signature: sig, Digest: digest[:]})
+		expected = append(expected, valid)
+	}
+
+	results, err := csp.VerifyTuples(tuples)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, results)
+
+	// Single worker gives same results
+	results, err = VerifyBatchTuples(csp, VerifyBatch{Tuples: tuples, Concurrency: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, expected, results)
+
+	// Errors are reported without aborting other tuples
+	tuples[4].Signature = []byte("garbage")
+	expected[4] = false
+	results, err = csp.VerifyTuples(tuples)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Failed verifying tuple 4")
+	assert.Equal(t, expected, results)
+
+	results, err = csp.VerifyTuples(nil)
+	assert.NoError(t, err)
+	assert.Empty(t, results)
+}
+
+func TestImportKeys(t *testing.T) {
+	provider, _, cleanup := currentTestConfig.Provider(t)
+	defer cleanup()
+	csp := provider.(*CSP)
+
+	var specs []ImportSpec
+	for i := 0; i < 2; i++ {
+		priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		assert.NoError(t, err)
+		der, err := utils.PrivateKeyToDER(priv)
+		assert.NoError(t, err)
+		specs = append(specs, ImportSpec{Raw: der, Opts: &bccsp.ECDSAPrivateKeyImportOpts{Temporary: false}})
+	}
+	aesKey, err := GetRandomBytes(32)
+	assert.NoError(t, err)
+	specs = append(specs, ImportSpec{Raw: aesKey, Opts: &bccsp.AES256ImportKeyOpts{Temporary: false}})
+	// Intentional duplicate
+	specs = append(specs, specs[0])
+
+	imported, skipped, err := ImportKeys(csp, specs)
+	assert.NoError(t, err)
+	assert.Len(t, imported, 3)
+	assert.Len(t, skipped, 1)
+	assert.Equal(t, imported[0].SKI(), skipped[0])
+	for _, k := range imported {
+		stored, err := csp.Key(k.SKI())
+		assert.NoError(t, err)
+		assert.Equal(t, k.SKI(), stored.SKI())
+	}
+
+	// Importing the batch again is idempotent
+	imported, skipped, err = ImportKeys(csp, specs)
+	assert.NoError(t, err)
+	assert.Empty(t, imported)
+	assert.Len(t, skipped, 4)
+
+	// Import stops on the first error
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	der, err := utils.PrivateKeyToDER(priv)
+	assert.NoError(t, err)
+	imported, skipped, err = ImportKeys(csp, []ImportSpec{
+		{Raw: der, Opts: &bccsp.ECDSAPrivateKeyImportOpts{Temporary: true}},
+		{Raw: []byte("invalid"), Opts: &bccsp.ECDSAPrivateKeyImportOpts{Temporary: true}},
+	})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Failed importing key 1")
+	assert.Len(t, imported, 1)
+	assert.Empty(t, skipped)
+}
+
+type countingBCCSP struct {
+	mocks.MockBCCSP
+	max     int
+	active  int32
+	highest int32
+}
+
+// enter - Records operation in progress until returned func is called.
+func (m *countingBCCSP) enter() func() {
+	n := atomic.AddInt32(&m.active, 1)
+	for {
+		h := atomic.LoadInt32(&m.highest)
+		if n <= h || atomic.CompareAndSwapInt32(&m.highest, h, n) {
+			break
+		}
+	}
+	time.Sleep(time.Millisecond)
+	return func() { atomic.AddInt32(&m.active, -1) }
+}
+
+func (m *countingBCCSP) Verify(k bccsp.Key, signature, digest []byte, opts bccsp.SignerOpts) (bool, error) {
+	defer m.enter()()
+	return true, nil
+}
+
+func (m *countingBCCSP) Sign(k bccsp.Key, digest []byte, opts bccsp.SignerOpts) ([]byte, error) {
+	defer m.enter()()
+	return []byte("signature"), nil
+}
+
+func (m *countingBCCSP) KeyGen(opts bccsp.KeyGenOpts) (bccsp.Key, error) {
+	defer m.enter()()
+	return &mocks.MockKey{}, nil
+}
+
+type limitedBCCSP struct {
+	countingBCCSP
+}
+
+func (m *limitedBCCSP) MaxConcurrency() int {
+	return m.max
+}
+
+func TestVerifyBatchTuples(t *testing.T) {
+	tuples := make([]VerifyRequest, 50)
+
+	for _, concurrency := range []int{1, 3, 8} {
+		csp := &countingBCCSP{}
+		results, err := VerifyBatchTuples(csp, VerifyBatch{Tuples: tuples, Concurrency: concurrency})
+		assert.NoError(t, err)
+		assert.Len(t, results, len(tuples))
+		assert.True(t, csp.highest >= 1)
+		assert.True(t, int(csp.highest) <= concurrency, "%d workers exceed limit %d", csp.highest, concurrency)
+	}
+
+	// Default concurrency
+	csp := &countingBCCSP{}
+	_, err := VerifyBatchTuples(csp, VerifyBatch{Tuples: tuples})
+	assert.NoError(t, err)
+	assert.True(t, int(csp.highest) <= DefaultConcurrency())
+
+	// Capped by CSP limit
+	limited := &limitedBCCSP{countingBCCSP{max: 2}}
+	_, err = VerifyBatchTuples(limited, VerifyBatch{Tuples: tuples, Concurrency: 16})
+	assert.NoError(t, err)
+	assert.True(t, limited.highest <= 2)
+
+	_, err = VerifyBatchTuples(csp, VerifyBatch{Tuples: tuples, Concurrency: -1})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Invalid concurrency")
+	_, err = VerifyBatchTuples(nil, VerifyBatch{Tuples: tuples})
+	assert.Error(t, err)
+}
+
+func TestSignBatchTuples(t *testing.T) {
+	tuples := make([]SignRequest, 50)
+
+	for _, concurrency := range []int{1, 3, 8} {
+		csp := &countingBCCSP{}
+		signatures, err := SignBatchTuples(csp, SignBatch{Tuples: tuples, Concurrency: concurrency})
+		assert.NoError(t, err)
+		assert.Len(t, signatures, len(tuples))
+		assert.True(t, int(csp.highest) <= concurrency, "%d workers exceed limit %d", csp.highest, concurrency)
+	}
+
+	limited := &limitedBCCSP{countingBCCSP{max: 2}}
+	_, err := SignBatchTuples(limited, SignBatch{Tuples: tuples, Concurrency: 16})
+	assert.NoError(t, err)
+	assert.True(t, limited.highest <= 2)
+
+	_, err = SignBatchTuples(limited, SignBatch{Tuples: tuples, Concurrency: -1})
+	assert.Error(t, err)
+	_, err = SignBatchTuples(nil, SignBatch{Tuples: tuples})
+	assert.Error(t, err)
+
+	// Signatures and errors are in order of tuples
+	provider, _, cleanup := currentTestConfig.Provider(t)
+	defer cleanup()
+	k, err := provider.KeyGen(&bccsp.ECDSAKeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	digest := sha256.Sum256([]byte("tx"))
+	signatures, err := SignBatchTuples(provider, SignBatch{Tuples: []SignRequest{
+		{Key: k, Digest: digest[:]},
+		{Key: k},
+		{Key: k, Digest: digest[:]},
+	}})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Failed signing tuple 1")
+	assert.Nil(t, signatures[1])
+	for _, i := range []int{0, 2} {
+		valid, err := provider.Verify(k, signatures[i], digest[:], nil)
+		assert.NoError(t, err)
+		assert.True(t, valid)
+	}
+}
+
+func TestGenerateKeys(t *testing.T) {
+	opts := make([]bccsp.KeyGenOpts, 50)
+
+	for _, concurrency := range []int{1, 3, 8} {
+		csp := &countingBCCSP{}
+		keys, err := GenerateKeys(csp, KeyGenBatch{Opts: opts, Concurrency: concurrency})
+		assert.NoError(t, err)
+		assert.Len(t, keys, len(opts))
+		assert.True(t, int(csp.highest) <= concurrency, "%d workers exceed limit %d", csp.highest, concurrency)
+	}
+
+	// Default concurrency
+	csp := &countingBCCSP{}
+	_, err := GenerateKeys(csp, KeyGenBatch{Opts: opts})
+	assert.NoError(t, err)
+	assert.True(t, int(csp.highest) <= DefaultConcurrency())
+
+	_, err = GenerateKeys(csp, KeyGenBatch{Opts: opts, Concurrency: -1})
+	assert.Error(t, err)
+	_, err = GenerateKeys(nil, KeyGenBatch{Opts: opts})
+	assert.Error(t, err)
+
+	provider, _, cleanup := currentTestConfig.Provider(t)
+	defer cleanup()
+	keys, err := GenerateKeys(provider, KeyGenBatch{Opts: []bccsp.KeyGenOpts{
+		&bccsp.ECDSAP256KeyGenOpts{Temporary: true},
+		nil,
+		&bccsp.AES256KeyGenOpts{Temporary: true},
+	}})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Failed generating key 1")
+	assert.Nil(t, keys[1])
+	assert.False(t, keys[0].Symmetric())
+	assert.True(t, keys[2].Symmetric())
+}
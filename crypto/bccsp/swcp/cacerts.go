@@ -0,0 +1,89 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp/utils"
+)
+
+// ImportCACerts imports public keys of certificates in PEM bundle into the
+// KeyStore as verify-only keys and returns number of imported keys.
+// Certificates which are expired or not yet valid now, see
+// utils.CheckCertificateValidity, and malformed certificates are skipped
+// and reported in returned error after all other certificates are imported.
+func ImportCACerts(ks bccsp.KeyStore, pemBundle []byte) (int, error) {
+	return importCACerts(ks, pemBundle, time.Now())
+}
+
+// importCACerts - Imports certificates in PEM bundle valid at now.
+func importCACerts(ks bccsp.KeyStore, pemBundle []byte, now time.Time) (int, error) {
+	if ks == nil {
+		return 0, errors.New("Invalid KeyStore. It must not be nil.")
+	}
+
+	var (
+		count   int
+		skipped []string
+	)
+	for index := 0; ; index++ {
+		var block *pem.Block
+		block, pemBundle = pem.Decode(pemBundle)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			skipped = append(skipped, fmt.Sprintf("block %d: unexpected type %q", index, block.Type))
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			skipped = append(skipped, fmt.Sprintf("block %d: %s", index, err))
+			continue
+		}
+		if err := utils.CheckCertificateValidity(cert, now); err != nil {
+			skipped = append(skipped, fmt.Sprintf("block %d: certificate %q [%s]", index, cert.Subject.CommonName, err))
+			continue
+		}
+		var k bccsp.Key
+		switch pk := cert.PublicKey.(type) {
+		case *ecdsa.PublicKey:
+			k = &ecdsaPublicKey{pubKey: pk}
+		case *rsa.PublicKey:
+			k = &rsaPublicKey{pubKey: pk}
+		default:
+			skipped = append(skipped, fmt.Sprintf("block %d: unsupported public key type %T", index, pk))
+			continue
+		}
+		if err := ks.StoreKey(k); err != nil {
+			return count, fmt.Errorf("Failed storing key of certificate %q [%s]", cert.Subject.CommonName, err)
+		}
+		count++
+	}
+	if len(skipped) > 0 {
+		return count, fmt.Errorf("Skipped %d certificates [%s]", len(skipped), strings.Join(skipped, "; "))
+	}
+	return count, nil
+}
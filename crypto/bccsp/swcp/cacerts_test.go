@@ -0,0 +1,119 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestCACert(t *testing.T, priv crypto.Signer, name string, notAfter time.Time) []byte {
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             notAfter.Add(-365 * 24 * time.Hour),
+		NotAfter:              notAfter,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, priv.Public(), priv)
+	assert.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func TestImportCACerts(t *testing.T) {
+	provider, ks, cleanup := currentTestConfig.Provider(t)
+	defer cleanup()
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	expiredKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	futureKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	validUntil := time.Now().Add(24 * time.Hour)
+	var bundle []byte
+	bundle = append(bundle, newTestCACert(t, ecKey, "ECDSA CA", validUntil)...)
+	bundle = append(bundle, newTestCACert(t, expiredKey, "Expired CA", time.Now().Add(-time.Hour))...)
+	bundle = append(bundle, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte{0, 1, 2, 3}})...)
+	bundle = append(bundle, newTestCACert(t, rsaKey, "RSA CA", validUntil)...)
+	// Not valid before a year from now
+	bundle = append(bundle, newTestCACert(t, futureKey, "Future CA", time.Now().Add(2*365*24*time.Hour))...)
+
+	n, err := ImportCACerts(ks, bundle)
+	assert.Equal(t, 2, n)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Skipped 3 certificates")
+	assert.Contains(t, err.Error(), "Expired CA")
+	assert.Contains(t, err.Error(), "Future CA")
+
+	digest := sha256.Sum256([]byte("Hello World"))
+
+	// ECDSA CA key is usable for verification
+	k, err := ks.Key((&ecdsaPublicKey{pubKey: &ecKey.PublicKey}).SKI())
+	assert.NoError(t, err)
+	assert.False(t, k.Private())
+	signature, err := signECDSA(ecKey, digest[:], nil)
+	assert.NoError(t, err)
+	valid, err := provider.Verify(k, signature, digest[:], nil)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+
+	// RSA CA key is usable for verification
+	k, err = ks.Key((&rsaPublicKey{pubKey: &rsaKey.PublicKey}).SKI())
+	assert.NoError(t, err)
+	assert.False(t, k.Private())
+	signature, err = rsa.SignPSS(rand.Reader, rsaKey, crypto.SHA256, digest[:], nil)
+	assert.NoError(t, err)
+	valid, err = provider.Verify(k, signature, digest[:], &rsa.PSSOptions{Hash: crypto.SHA256})
+	assert.NoError(t, err)
+	assert.True(t, valid)
+
+	// Expired and not yet valid CA keys were not imported
+	_, err = ks.Key((&ecdsaPublicKey{pubKey: &expiredKey.PublicKey}).SKI())
+	assert.Error(t, err)
+	_, err = ks.Key((&ecdsaPublicKey{pubKey: &futureKey.PublicKey}).SKI())
+	assert.Error(t, err)
+
+	// Bundle with valid certificates only
+	n, err = ImportCACerts(ks, newTestCACert(t, ecKey, "ECDSA CA", validUntil))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	// Validity is checked at the given time
+	n, err = importCACerts(ks, newTestCACert(t, ecKey, "ECDSA CA", validUntil), validUntil.Add(time.Hour))
+	assert.Error(t, err)
+	assert.Equal(t, 0, n)
+
+	_, err = ImportCACerts(nil, bundle)
+	assert.Error(t, err)
+}
@@ -17,6 +17,7 @@ package swcp
 
 import (
 	"crypto/elliptic"
+	"crypto/sha512"
 	"fmt"
 	"hash"
 
@@ -29,6 +30,7 @@ import (
 type config struct {
 	ellipticCurve elliptic.Curve
 	hashFunction  func() hash.Hash
+	hashType      digest.Type
 	aesBitLength  int
 	rsaBitLength  int
 }
@@ -50,6 +52,7 @@ func (conf *config) setSecurityLevelSHA2(level int) (err error) {
 	case 256:
 		conf.ellipticCurve = elliptic.P256()
 		conf.hashFunction = sha256.New
+		conf.hashType = digest.Sha2_256
 		conf.rsaBitLength = 2048
 		conf.aesBitLength = 32
 	default:
@@ -63,11 +66,13 @@ func (conf *config) setSecurityLevelSHA3(level int) (err error) {
 	case 256:
 		conf.ellipticCurve = elliptic.P256()
 		conf.hashFunction = sha3.New256
+		conf.hashType = digest.Sha3_256
 		conf.rsaBitLength = 2048
 		conf.aesBitLength = 32
 	case 384:
 		conf.ellipticCurve = elliptic.P384()
 		conf.hashFunction = sha3.New384
+		conf.hashType = digest.Sha3_384
 		conf.rsaBitLength = 3072
 		conf.aesBitLength = 32
 	default:
@@ -75,3 +80,50 @@ func (conf *config) setSecurityLevelSHA3(level int) (err error) {
 	}
 	return
 }
+
+// setSecurityStrength - Sets parameters of NIST SP 800-57 security strength.
+func (conf *config) setSecurityStrength(strength int, hashFamily digest.Family) (err error) {
+	switch strength {
+	case 112:
+		conf.ellipticCurve = elliptic.P256()
+		conf.rsaBitLength = 2048
+		conf.aesBitLength = 16
+	case 128:
+		conf.ellipticCurve = elliptic.P256()
+		conf.rsaBitLength = 3072
+		conf.aesBitLength = 16
+	case 192:
+		conf.ellipticCurve = elliptic.P384()
+		conf.rsaBitLength = 7680
+		conf.aesBitLength = 24
+	case 256:
+		// RSA 15360 key generation takes minutes, size must be explicit
+		conf.ellipticCurve = elliptic.P521()
+		conf.aesBitLength = 32
+	default:
+		return fmt.Errorf("Security strength not supported [%d]", strength)
+	}
+	switch {
+	case hashFamily == digest.FamilySha2 && strength <= 128:
+		conf.hashFunction = sha256.New
+		conf.hashType = digest.Sha2_256
+	case hashFamily == digest.FamilySha2 && strength == 192:
+		conf.hashFunction = sha512.New384
+		conf.hashType = digest.Sha2_384
+	case hashFamily == digest.FamilySha2:
+		conf.hashFunction = sha512.New
+		conf.hashType = digest.Sha2_512
+	case hashFamily == digest.FamilySha3 && strength <= 128:
+		conf.hashFunction = sha3.New256
+		conf.hashType = digest.Sha3_256
+	case hashFamily == digest.FamilySha3 && strength == 192:
+		conf.hashFunction = sha3.New384
+		conf.hashType = digest.Sha3_384
+	case hashFamily == digest.FamilySha3:
+		conf.hashFunction = sha3.New512
+		conf.hashType = digest.Sha3_512
+	default:
+		err = fmt.Errorf("Hash Family not supported [%s]", hashFamily)
+	}
+	return
+}
@@ -0,0 +1,156 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"crypto/elliptic"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+	"github.com/ipfn/ipfn/pkg/digest"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewWithSecurityStrength(t *testing.T) {
+	for _, test := range []struct {
+		strength int
+		family   digest.Family
+		curve    elliptic.Curve
+		rsaBits  int
+		aesBytes int
+		hashType digest.Type
+	}{
+		{112, digest.FamilySha2, elliptic.P256(), 2048, 16, digest.Sha2_256},
+		{128, digest.FamilySha2, elliptic.P256(), 3072, 16, digest.Sha2_256},
+		{192, digest.FamilySha2, elliptic.P384(), 7680, 24, digest.Sha2_384},
+		{256, digest.FamilySha2, elliptic.P521(), 0, 32, digest.Sha2_512},
+		{112, digest.FamilySha3, elliptic.P256(), 2048, 16, digest.Sha3_256},
+		{128, digest.FamilySha3, elliptic.P256(), 3072, 16, digest.Sha3_256},
+		{192, digest.FamilySha3, elliptic.P384(), 7680, 24, digest.Sha3_384},
+		{256, digest.FamilySha3, elliptic.P521(), 0, 32, digest.Sha3_512},
+	} {
+		provider, err := NewWithSecurityStrength(test.strength, test.family, NewDummyKeyStore())
+		assert.NoError(t, err)
+		csp := provider.(*CSP)
+		assert.Equal(t, test.hashType, csp.hashType)
+
+		k, err := csp.KeyGen(&bccsp.ECDSAKeyGenOpts{Temporary: true})
+		assert.NoError(t, err)
+		assert.Equal(t, test.curve, k.(*ecdsaPrivateKey).privKey.Curve)
+
+		k, err = csp.KeyGen(&bccsp.AESKeyGenOpts{Temporary: true})
+		assert.NoError(t, err)
+		assert.Len(t, k.(*aesPrivateKey).privKey, test.aesBytes)
+
+		kg := csp.keyGenerators[reflect.TypeOf(&bccsp.RSAKeyGenOpts{})]
+		assert.Equal(t, test.rsaBits, kg.(*rsaKeyGenerator).length)
+		if test.rsaBits == 0 {
+			_, err = csp.KeyGen(&bccsp.RSAKeyGenOpts{Temporary: true})
+			assert.Error(t, err)
+		}
+
+		// Default hash type is available
+		_, err = csp.Hash([]byte("Hello World"), test.hashType)
+		assert.NoError(t, err)
+	}
+
+	_, err := NewWithSecurityStrength(80, digest.FamilySha2, NewDummyKeyStore())
+	assert.Error(t, err)
+	_, err = NewWithSecurityStrength(128, digest.FamilyKeccak, NewDummyKeyStore())
+	assert.Error(t, err)
+}
+
+func TestNewFromConfig(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "bccspks")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	provider, err := NewFromConfig(Config{})
+	assert.NoError(t, err)
+	csp := provider.(*CSP)
+	assert.Equal(t, digest.Sha2_256, csp.hashType)
+	assert.False(t, csp.fips)
+	assert.IsType(t, &inMemoryKeyStore{}, csp.ks)
+
+	// Keys are kept in memory without key store path
+	k, err := csp.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: false})
+	assert.NoError(t, err)
+	loaded, err := csp.Key(k.SKI())
+	assert.NoError(t, err)
+	assert.Equal(t, k.SKI(), loaded.SKI())
+
+	provider, err = NewFromConfig(Config{
+		SecurityLevel:      384,
+		HashFamily:         digest.FamilySha3,
+		KeyStorePath:       tempDir,
+		Encrypted:          true,
+		Password:           []byte("secret"),
+		FIPS:               true,
+		DeterministicECDSA: true,
+	})
+	assert.NoError(t, err)
+	csp = provider.(*CSP)
+	assert.Equal(t, digest.Sha3_384, csp.hashType)
+	assert.True(t, csp.fips)
+	assert.Equal(t, int32(1), csp.deterministicECDSA)
+	assert.Equal(t, []byte("secret"), csp.ks.(*fileBasedKeyStore).pwd)
+
+	k, err = csp.KeyGen(&bccsp.ECDSAKeyGenOpts{Temporary: false})
+	assert.NoError(t, err)
+	assert.Equal(t, elliptic.P384(), k.(*ecdsaPrivateKey).privKey.Curve)
+	_, err = csp.KeyGen(&bccsp.ED25519KeyGenOpts{Temporary: true})
+	assert.Equal(t, ErrNotPermittedFIPS, errors.Cause(err))
+
+	provider, err = NewFromConfig(Config{SecurityStrength: 192, KeyStorePath: tempDir, ReadOnly: true})
+	assert.NoError(t, err)
+	assert.True(t, provider.(*CSP).ks.ReadOnly())
+	assert.Equal(t, digest.Sha2_384, provider.(*CSP).hashType)
+}
+
+func TestNewFromConfigInvalid(t *testing.T) {
+	path := filepath.Join(os.TempDir(), "bccspks-invalid-config")
+	for _, test := range []struct {
+		cfg Config
+		err string
+	}{
+		{Config{FIPS: true, HashFamily: digest.FamilyKeccak}, "not permitted in FIPS mode"},
+		{Config{FIPS: true, HashFamily: digest.FamilyBlake2b}, "not permitted in FIPS mode"},
+		{Config{HashFamily: digest.FamilyKeccak}, "Hash Family not supported"},
+		{Config{SecurityLevel: 384}, "Security level not supported"},
+		{Config{SecurityStrength: 80}, "Security strength not supported"},
+		{Config{SecurityLevel: 256, SecurityStrength: 128}, "mutually exclusive"},
+		{Config{SecurityLevel: -1}, "must not be negative"},
+		{Config{Encrypted: true, Password: []byte("secret")}, "requires key store path"},
+		{Config{ReadOnly: true}, "requires key store path"},
+		{Config{KeyStorePath: path, Encrypted: true}, "requires password"},
+		{Config{KeyStorePath: path, Password: []byte("secret")}, "not encrypted"},
+	} {
+		assert.Error(t, test.cfg.Validate())
+		_, err := NewFromConfig(test.cfg)
+		if assert.Error(t, err, "%+v", test.cfg) {
+			assert.Contains(t, err.Error(), test.err)
+		}
+	}
+
+	// Key store is not created for invalid configuration
+	_, err := os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,152 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"io"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/hkdf"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp/utils"
+)
+
+// ECDHDeriveAESKey - Derives AES key of given size in bits from ECDH shared
+// secret of private key myKey and public key of the peer. Shared secret is
+// the x coordinate of the agreed point expanded with HKDF using configured
+// hash function and info. Both peers derive the same key using the same info.
+// Returned key is temporary, it is not stored in the key store.
+func (csp *CSP) ECDHDeriveAESKey(myKey, peerPub bccsp.Key, info []byte, bits int) (bccsp.Key, error) {
+	if bits != 128 && bits != 192 && bits != 256 {
+		return nil, errors.Errorf("Invalid AES key size %d. It must be 128, 192 or 256 bits.", bits)
+	}
+	priv, ok := myKey.(*ecdsaPrivateKey)
+	if !ok {
+		return nil, errors.New("Invalid key. Expected ECDSA private key.")
+	}
+	var pub *ecdsa.PublicKey
+	switch k := peerPub.(type) {
+	case *ecdsaPublicKey:
+		pub = k.pubKey
+	case *ecdsaPrivateKey:
+		pub = &k.privKey.PublicKey
+	default:
+		return nil, errors.New("Invalid peer key. Expected ECDSA public key.")
+	}
+	if err := csp.checkFIPSKey(priv); err != nil {
+		return nil, err
+	}
+	hashFunc, err := hashFunction(csp.hashType)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed getting configured hash function")
+	}
+
+	secret, err := ecdhSharedSecret(priv.privKey, pub)
+	if err != nil {
+		return nil, err
+	}
+
+	key := make([]byte, bits/8)
+	if _, err := io.ReadFull(hkdf.New(hashFunc, secret, nil, info), key); err != nil {
+		return nil, errors.Wrap(err, "Failed expanding shared secret")
+	}
+	return &aesPrivateKey{key, false}, nil
+}
+
+// ecdhSharedSecret - Computes x coordinate of ECDH agreed point
+// padded to the size of the curve.
+func ecdhSharedSecret(priv *ecdsa.PrivateKey, pub *ecdsa.PublicKey) ([]byte, error) {
+	curve := priv.Curve
+	if pub == nil || pub.Curve.Params().Name != curve.Params().Name {
+		return nil, errors.New("Invalid peer key. Curves do not match.")
+	}
+	if !curve.IsOnCurve(pub.X, pub.Y) {
+		return nil, errors.New("Invalid peer key. Point is not on curve.")
+	}
+	x, _ := curve.ScalarMult(pub.X, pub.Y, priv.D.Bytes())
+	if x.Sign() == 0 {
+		return nil, errors.New("Invalid shared secret.")
+	}
+	secret := make([]byte, (curve.Params().BitSize+7)/8)
+	xb := x.Bytes()
+	copy(secret[len(secret)-len(xb):], xb)
+	return secret, nil
+}
+
+// ecdhDeriveKey - Derives AES key from ECDH shared secret of private key
+// and public key of the peer. Shared secret is expanded with HKDF-SHA256
+// and info binding the key to public keys of both parties, see
+// utils.ECDHKeyInfo, so both parties derive the same key.
+func ecdhDeriveKey(priv *ecdsa.PrivateKey, opts *bccsp.ECDHDeriveKeyOpts) (bccsp.Key, error) {
+	length := opts.Length
+	if length == 0 {
+		length = 32
+	}
+	if length != 16 && length != 24 && length != 32 {
+		return nil, errors.Errorf("Invalid key length [%d]. It must be 16, 24 or 32 bytes.", length)
+	}
+	pub, err := ecdhPeerPublicKey(opts.PeerPublicKey)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := ecdhSharedSecret(priv, pub)
+	if err != nil {
+		return nil, err
+	}
+	defer wipe(secret)
+
+	info := utils.ECDHKeyInfo(&priv.PublicKey, pub)
+	key := make([]byte, length)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, secret, nil, info), key); err != nil {
+		return nil, errors.Wrap(err, "Failed expanding shared secret")
+	}
+	return &aesPrivateKey{key, false}, nil
+}
+
+// ecdhPeerPublicKey - Returns ECDSA public key of the peer.
+func ecdhPeerPublicKey(k bccsp.Key) (*ecdsa.PublicKey, error) {
+	switch k := k.(type) {
+	case nil:
+		return nil, errors.New("Invalid peer key. It must not be nil.")
+	case *ecdsaPublicKey:
+		return k.pubKey, nil
+	case *ecdsaPrivateKey:
+		return &k.privKey.PublicKey, nil
+	}
+	if k.Private() {
+		pub, err := k.PublicKey()
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed getting peer public key")
+		}
+		k = pub
+	}
+	raw, err := k.Bytes()
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed marshalling peer public key")
+	}
+	pub, err := utils.DERToPublicKey(raw)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed parsing peer public key")
+	}
+	ecPub, ok := pub.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, errors.New("Invalid peer key. Expected ECDSA public key.")
+	}
+	return ecPub, nil
+}
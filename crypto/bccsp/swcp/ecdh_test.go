@@ -0,0 +1,144 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"crypto/sha256"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/hkdf"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp/utils"
+)
+
+func TestECDHDeriveAESKey(t *testing.T) {
+	provider, _, cleanup := currentTestConfig.Provider(t)
+	defer cleanup()
+	csp := provider.(*CSP)
+
+	alice, err := provider.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	bob, err := provider.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	alicePub, err := alice.PublicKey()
+	assert.NoError(t, err)
+	bobPub, err := bob.PublicKey()
+	assert.NoError(t, err)
+
+	info := []byte("session")
+	for _, bits := range []int{128, 192, 256} {
+		aliceKey, err := csp.ECDHDeriveAESKey(alice, bobPub, info, bits)
+		assert.NoError(t, err)
+		bobKey, err := csp.ECDHDeriveAESKey(bob, alicePub, info, bits)
+		assert.NoError(t, err)
+		assert.Equal(t, aliceKey.SKI(), bobKey.SKI())
+		assert.Len(t, aliceKey.(*aesPrivateKey).privKey, bits/8)
+
+		msg := []byte("Hello Bob")
+		ct, err := provider.Encrypt(aliceKey, msg, &bccsp.AESCBCPKCS7ModeOpts{})
+		assert.NoError(t, err)
+		pt, err := provider.Decrypt(bobKey, ct, &bccsp.AESCBCPKCS7ModeOpts{})
+		assert.NoError(t, err)
+		assert.Equal(t, msg, pt)
+
+		msg = []byte("Hello Alice")
+		ct, err = provider.Encrypt(bobKey, msg, &bccsp.AESCBCPKCS7ModeOpts{})
+		assert.NoError(t, err)
+		pt, err = provider.Decrypt(aliceKey, ct, &bccsp.AESCBCPKCS7ModeOpts{})
+		assert.NoError(t, err)
+		assert.Equal(t, msg, pt)
+	}
+
+	// Different info yields different key
+	k1, err := csp.ECDHDeriveAESKey(alice, bobPub, []byte("a"), 256)
+	assert.NoError(t, err)
+	k2, err := csp.ECDHDeriveAESKey(alice, bobPub, []byte("b"), 256)
+	assert.NoError(t, err)
+	assert.NotEqual(t, k1.SKI(), k2.SKI())
+
+	_, err = csp.ECDHDeriveAESKey(alice, bobPub, info, 512)
+	assert.Error(t, err)
+	_, err = csp.ECDHDeriveAESKey(alicePub, bobPub, info, 256)
+	assert.Error(t, err)
+
+	other, err := provider.KeyGen(&bccsp.ECDSAP384KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	_, err = csp.ECDHDeriveAESKey(alice, other, info, 256)
+	assert.EqualError(t, err, "Invalid peer key. Curves do not match.")
+}
+
+func TestECDHKeyDeriv(t *testing.T) {
+	provider, _, cleanup := currentTestConfig.Provider(t)
+	defer cleanup()
+
+	alice, err := provider.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	bob, err := provider.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	alicePub, err := alice.PublicKey()
+	assert.NoError(t, err)
+	bobPub, err := bob.PublicKey()
+	assert.NoError(t, err)
+
+	aliceKey, err := provider.KeyDeriv(alice, &bccsp.ECDHDeriveKeyOpts{Temporary: true, PeerPublicKey: bobPub})
+	assert.NoError(t, err)
+	bobKey, err := provider.KeyDeriv(bob, &bccsp.ECDHDeriveKeyOpts{Temporary: true, PeerPublicKey: alicePub})
+	assert.NoError(t, err)
+	assert.True(t, aliceKey.Symmetric())
+	assert.Equal(t, aliceKey.SKI(), bobKey.SKI())
+	assert.Len(t, aliceKey.(*aesPrivateKey).privKey, 32)
+
+	msg := []byte("Hello Bob")
+	ct, err := provider.Encrypt(aliceKey, msg, &bccsp.AESCBCPKCS7ModeOpts{})
+	assert.NoError(t, err)
+	pt, err := provider.Decrypt(bobKey, ct, &bccsp.AESCBCPKCS7ModeOpts{})
+	assert.NoError(t, err)
+	assert.Equal(t, msg, pt)
+
+	// Key is shared secret expanded with HKDF-SHA256
+	priv := alice.(*ecdsaPrivateKey).privKey
+	pub := bobPub.(*ecdsaPublicKey).pubKey
+	secret, err := ecdhSharedSecret(priv, pub)
+	assert.NoError(t, err)
+	expected := make([]byte, 16)
+	_, err = io.ReadFull(hkdf.New(sha256.New, secret, nil, utils.ECDHKeyInfo(pub, &priv.PublicKey)), expected)
+	assert.NoError(t, err)
+	short, err := provider.KeyDeriv(alice, &bccsp.ECDHDeriveKeyOpts{Temporary: true, PeerPublicKey: bobPub, Length: 16})
+	assert.NoError(t, err)
+	assert.Equal(t, expected, short.(*aesPrivateKey).privKey)
+
+	// Stored unless temporary
+	stored, err := provider.KeyDeriv(alice, &bccsp.ECDHDeriveKeyOpts{PeerPublicKey: bobPub})
+	assert.NoError(t, err)
+	loaded, err := provider.Key(stored.SKI())
+	assert.NoError(t, err)
+	assert.Equal(t, stored.SKI(), loaded.SKI())
+
+	// Invalid arguments
+	_, err = provider.KeyDeriv(alice, &bccsp.ECDHDeriveKeyOpts{Temporary: true, PeerPublicKey: bobPub, Length: 20})
+	assert.Error(t, err)
+	_, err = provider.KeyDeriv(alice, &bccsp.ECDHDeriveKeyOpts{Temporary: true})
+	assert.Error(t, err)
+	_, err = provider.KeyDeriv(alicePub, &bccsp.ECDHDeriveKeyOpts{Temporary: true, PeerPublicKey: bobPub})
+	assert.Error(t, err)
+	other, err := provider.KeyGen(&bccsp.ECDSAP384KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	_, err = provider.KeyDeriv(alice, &bccsp.ECDHDeriveKeyOpts{Temporary: true, PeerPublicKey: other})
+	assert.Error(t, err)
+}
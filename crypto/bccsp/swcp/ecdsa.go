@@ -18,14 +18,32 @@ package swcp
 import (
 	"crypto/ecdsa"
 	"crypto/rand"
+	"errors"
 	"fmt"
+	"io"
+	"math/big"
 
 	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
 	"github.com/ipfn/ipfn/pkg/crypto/bccsp/utils"
 )
 
+// blindingReader is the source of nonces and blinding factors of blinded signing.
+var blindingReader io.Reader = rand.Reader
+
 func signECDSA(k *ecdsa.PrivateKey, digest []byte, opts bccsp.SignerOpts) ([]byte, error) {
-	r, s, err := ecdsa.Sign(rand.Reader, k, digest)
+	var (
+		r, s *big.Int
+		err  error
+	)
+	o, _ := opts.(*bccsp.ECDSASignerOpts)
+	switch {
+	case o != nil && o.Deterministic != nil && *o.Deterministic:
+		r, s, err = signECDSABlinded(blindingReader, k, digest, rfc6979Nonces(k, digest, o.H))
+	case o != nil && o.Blind:
+		r, s, err = signECDSABlinded(blindingReader, k, digest, nil)
+	default:
+		r, s, err = ecdsa.Sign(rand.Reader, k, digest)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -38,24 +56,155 @@ func signECDSA(k *ecdsa.PrivateKey, digest []byte, opts bccsp.SignerOpts) ([]byt
 	return utils.MarshalECDSASignature(r, s)
 }
 
+// signECDSABlinded computes ECDSA signature s = k^-1 * (z + r * d) with
+// the secret scalar arithmetic blinded by random factor b as
+// s = (k * b)^-1 * (z * b + r * (d * b)), so variable time big integer
+// operations never process unblinded nonce or private key.
+// Nonce point is computed with constant time scalar base multiplication
+// of the standard library curves.
+// Nonces are read from nonces or, when it is nil, generated using prng.
+func signECDSABlinded(prng io.Reader, k *ecdsa.PrivateKey, digest []byte, nonces func() (*big.Int, error)) (r, s *big.Int, err error) {
+	n := k.Params().N
+	z := hashToInt(digest, n)
+	if nonces == nil {
+		nonces = func() (*big.Int, error) { return randScalar(prng, n) }
+	}
+	for {
+		nonce, err := nonces()
+		if err != nil {
+			return nil, nil, err
+		}
+		b, err := randScalar(prng, n)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		x, _ := k.ScalarBaseMult(nonce.Bytes())
+		r = new(big.Int).Mod(x, n)
+		if r.Sign() == 0 {
+			continue
+		}
+
+		kb := new(big.Int).Mul(nonce, b)
+		kb.Mod(kb, n)
+		kbInv := new(big.Int).ModInverse(kb, n)
+
+		db := new(big.Int).Mul(k.D, b)
+		db.Mod(db, n)
+		s = new(big.Int).Mul(r, db)
+		s.Add(s, new(big.Int).Mul(z, b))
+		s.Mul(s, kbInv)
+		s.Mod(s, n)
+		if s.Sign() != 0 {
+			return r, s, nil
+		}
+	}
+}
+
+// randScalar returns random integer in range [1, n-1].
+func randScalar(prng io.Reader, n *big.Int) (*big.Int, error) {
+	buf := make([]byte, (n.BitLen()+7)/8+8)
+	if _, err := io.ReadFull(prng, buf); err != nil {
+		return nil, fmt.Errorf("Failed reading randomness [%s]", err)
+	}
+	one := big.NewInt(1)
+	k := new(big.Int).SetBytes(buf)
+	k.Mod(k, new(big.Int).Sub(n, one))
+	return k.Add(k, one), nil
+}
+
 func verifyECDSA(k *ecdsa.PublicKey, signature, digest []byte, opts bccsp.SignerOpts) (bool, error) {
 	r, s, err := utils.UnmarshalECDSASignature(signature)
 	if err != nil {
 		return false, fmt.Errorf("Failed unmashalling signature [%s]", err)
 	}
 
-	lowS, err := utils.IsLowS(k, s)
-	if err != nil {
-		return false, err
-	}
+	if utils.LowSEnforced(k.Curve) {
+		lowS, err := utils.IsLowS(k, s)
+		if err != nil {
+			return false, err
+		}
 
-	if !lowS {
-		return false, fmt.Errorf("Invalid S. Must be smaller than half the order [%s][%s].", s, utils.GetCurveHalfOrdersAt(k.Curve))
+		if !lowS {
+			return false, fmt.Errorf("Invalid S. Must be smaller than half the order [%s][%s].", s, utils.GetCurveHalfOrdersAt(k.Curve))
+		}
 	}
 
 	return ecdsa.Verify(k, digest, r, s), nil
 }
 
+// partialSignECDSA computes partial signature s_i = a_i * z + r * (a_i * d_i + m_i).
+// See utils.NewECDSAPresignatures for description of the threshold scheme.
+func partialSignECDSA(k *ecdsa.PrivateKey, digest []byte, opts *bccsp.ECDSAPartialSignOpts) ([]byte, error) {
+	if opts.Index <= 0 {
+		return nil, fmt.Errorf("Invalid participant index %d. It must be positive.", opts.Index)
+	}
+	if opts.R == nil || opts.NonceShare == nil || opts.Correction == nil {
+		return nil, errors.New("Invalid presignature. R, NonceShare and Correction must not be nil.")
+	}
+	n := k.Params().N
+	if opts.R.Sign() <= 0 || opts.R.Cmp(n) >= 0 {
+		return nil, errors.New("Invalid presignature. R must be in range [1, N-1].")
+	}
+
+	z := hashToInt(digest, n)
+	s := new(big.Int).Mul(opts.NonceShare, k.D)
+	s.Add(s, opts.Correction)
+	s.Mul(s, opts.R)
+	s.Add(s, new(big.Int).Mul(opts.NonceShare, z))
+	s.Mod(s, n)
+
+	return utils.MarshalECDSAPartialSignature(opts.Index, opts.R, s)
+}
+
+// hashToInt converts digest to integer the same way as ECDSA does,
+// truncating it to the bit length of curve order.
+func hashToInt(digest []byte, n *big.Int) *big.Int {
+	orderBits := n.BitLen()
+	orderBytes := (orderBits + 7) / 8
+	if len(digest) > orderBytes {
+		digest = digest[:orderBytes]
+	}
+
+	ret := new(big.Int).SetBytes(digest)
+	excess := len(digest)*8 - orderBits
+	if excess > 0 {
+		ret.Rsh(ret, uint(excess))
+	}
+	return ret
+}
+
+// PartialSign produces partial signature of digest using ECDSA key share k
+// and presignature share in opts. Partial signatures of threshold number of
+// participants are combined using utils.CombinePartialSignatures.
+func (csp *CSP) PartialSign(k bccsp.Key, digest []byte, opts *bccsp.ECDSAPartialSignOpts) ([]byte, error) {
+	// Validate arguments
+	if k == nil {
+		return nil, errors.New("Invalid Key. It must not be nil.")
+	}
+	if len(digest) == 0 {
+		return nil, errors.New("Invalid digest. Cannot be empty.")
+	}
+	if opts == nil {
+		return nil, errors.New("Invalid opts. It must not be nil.")
+	}
+
+	sk, ok := k.(*ecdsaPrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("Unsupported 'SignKey' provided [%T]", k)
+	}
+
+	if csp.isRevoked(k) {
+		return nil, ErrKeyRevoked
+	}
+
+	if err := csp.checkFIPSKey(k); err != nil {
+		return nil, err
+	}
+
+	return partialSignECDSA(sk.privKey, digest, opts)
+}
+
 type ecdsaSigner struct{}
 
 func (s *ecdsaSigner) Sign(k bccsp.Key, digest []byte, opts bccsp.SignerOpts) ([]byte, error) {
@@ -21,9 +21,11 @@ import (
 	"crypto/rand"
 	"crypto/sha256"
 	"crypto/x509"
+	"io"
 	"math/big"
 	"testing"
 
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
 	"github.com/ipfn/ipfn/pkg/crypto/bccsp/utils"
 	"github.com/stretchr/testify/assert"
 )
@@ -77,6 +79,44 @@ func TestVerifyECDSA(t *testing.T) {
 	assert.Contains(t, err.Error(), "Invalid S. Must be smaller than half the order [")
 }
 
+func TestVerifyECDSALowSPolicy(t *testing.T) {
+	lowLevelKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	assert.NoError(t, err)
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	highS := func(k *ecdsa.PrivateKey, msg []byte) []byte {
+		sigma, err := signECDSA(k, msg, nil)
+		assert.NoError(t, err)
+		R, S, err := utils.UnmarshalECDSASignature(sigma)
+		assert.NoError(t, err)
+		S.Sub(k.Params().N, S)
+		sigma, err = utils.MarshalECDSASignature(R, S)
+		assert.NoError(t, err)
+		return sigma
+	}
+
+	msg := []byte("hello world")
+	sigma := highS(lowLevelKey, msg)
+	otherSigma := highS(otherKey, msg)
+
+	_, err = verifyECDSA(&lowLevelKey.PublicKey, sigma, msg, nil)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Invalid S. Must be smaller than half the order [")
+
+	utils.SetLowSPolicy(elliptic.P384(), false)
+	defer utils.SetLowSPolicy(elliptic.P384(), true)
+
+	valid, err := verifyECDSA(&lowLevelKey.PublicKey, sigma, msg, nil)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+
+	// Policy of other curves is not affected
+	_, err = verifyECDSA(&otherKey.PublicKey, otherSigma, msg, nil)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Invalid S. Must be smaller than half the order [")
+}
+
 func TestEcdsaSignerSign(t *testing.T) {
 	t.Parallel()
 
@@ -150,7 +190,7 @@ func TestEcdsaPublicKey(t *testing.T) {
 
 	lowLevelKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
 	assert.NoError(t, err)
-	k := &ecdsaPublicKey{&lowLevelKey.PublicKey}
+	k := &ecdsaPublicKey{pubKey: &lowLevelKey.PublicKey}
 
 	assert.False(t, k.Symmetric())
 	assert.False(t, k.Private())
@@ -183,3 +223,187 @@ func TestEcdsaPublicKey(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "Failed marshalling key [")
 }
+
+func TestEcdsaPublicKeyPointFormat(t *testing.T) {
+	t.Parallel()
+
+	lowLevelKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	priv := &ecdsaPrivateKey{lowLevelKey}
+	pub := &lowLevelKey.PublicKey
+
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	assert.NoError(t, err)
+	uncompressed := elliptic.Marshal(pub.Curve, pub.X, pub.Y)
+	compressed := append([]byte{0x02 | byte(pub.Y.Bit(0))}, uncompressed[1:33]...)
+
+	formats := map[PointFormat][]byte{
+		PointPKIX:         der,
+		PointCompressed:   compressed,
+		PointUncompressed: uncompressed,
+	}
+	for format, expected := range formats {
+		raw, err := MarshalECDSAPublicKey(priv, format)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, raw, "format %d", format)
+		raw, err = MarshalECDSAPublicKey(&ecdsaPublicKey{pubKey: pub}, format)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, raw, "format %d", format)
+	}
+	assert.Len(t, compressed, 33)
+
+	// Bytes is always PKIX DER
+	pk, err := priv.PublicKey()
+	assert.NoError(t, err)
+	raw, err := pk.Bytes()
+	assert.NoError(t, err)
+	assert.Equal(t, der, raw)
+
+	_, err = MarshalECDSAPublicKey(priv, PointUncompressed+1)
+	assert.Error(t, err)
+	_, err = MarshalECDSAPublicKey(&aesPrivateKey{}, PointCompressed)
+	assert.Error(t, err)
+}
+
+func TestECDSAPartialSign(t *testing.T) {
+	provider, _, cleanup := currentTestConfig.Provider(t)
+	defer cleanup()
+	csp := provider.(*CSP)
+
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	shares, err := utils.SplitECDSAKey(priv, 2, 3)
+	assert.NoError(t, err)
+	keys := make([]bccsp.Key, len(shares))
+	for i, share := range shares {
+		der, err := utils.PrivateKeyToDER(share)
+		assert.NoError(t, err)
+		keys[i], err = provider.KeyImport(der, &bccsp.ECDSAPrivateKeyImportOpts{Temporary: true})
+		assert.NoError(t, err)
+	}
+
+	digest := sha256.Sum256([]byte("Hello World"))
+	presigs, err := utils.NewECDSAPresignatures(priv, shares, 2)
+	assert.NoError(t, err)
+	partials := make([][]byte, len(keys))
+	for i, k := range keys {
+		partials[i], err = csp.PartialSign(k, digest[:], presigs[i])
+		assert.NoError(t, err)
+	}
+
+	// Any two participants can produce a valid signature
+	for _, set := range [][]int{{0, 1}, {0, 2}, {2, 1}} {
+		sig, err := utils.CombinePartialSignatures(&priv.PublicKey, digest[:], 2, [][]byte{partials[set[0]], partials[set[1]]})
+		assert.NoError(t, err)
+		valid, err := provider.Verify(&ecdsaPublicKey{pubKey: &priv.PublicKey}, sig, digest[:], nil)
+		assert.NoError(t, err)
+		assert.True(t, valid)
+	}
+
+	// Combining below threshold fails
+	_, err = utils.CombinePartialSignatures(&priv.PublicKey, digest[:], 2, partials[:1])
+	assert.Error(t, err)
+	_, err = utils.CombinePartialSignatures(&priv.PublicKey, digest[:], 1, partials[:1])
+	assert.Error(t, err)
+
+	// Partial signature of another digest is detected
+	other := sha256.Sum256([]byte("Hello World!"))
+	partial, err := csp.PartialSign(keys[1], other[:], presigs[1])
+	assert.NoError(t, err)
+	_, err = utils.CombinePartialSignatures(&priv.PublicKey, digest[:], 2, [][]byte{partials[0], partial})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "verification failed")
+}
+
+func TestECDSAPartialSignInvalidParameters(t *testing.T) {
+	provider, _, cleanup := currentTestConfig.Provider(t)
+	defer cleanup()
+	csp := provider.(*CSP)
+
+	k, err := provider.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	digest := sha256.Sum256([]byte("Hello World"))
+	opts := &bccsp.ECDSAPartialSignOpts{Index: 1, R: big.NewInt(1), NonceShare: big.NewInt(1), Correction: big.NewInt(1)}
+
+	_, err = csp.PartialSign(nil, digest[:], opts)
+	assert.Error(t, err)
+	_, err = csp.PartialSign(k, nil, opts)
+	assert.Error(t, err)
+	_, err = csp.PartialSign(k, digest[:], nil)
+	assert.Error(t, err)
+	pk, err := k.PublicKey()
+	assert.NoError(t, err)
+	_, err = csp.PartialSign(pk, digest[:], opts)
+	assert.Error(t, err)
+	_, err = csp.PartialSign(k, digest[:], &bccsp.ECDSAPartialSignOpts{Index: 0, R: big.NewInt(1), NonceShare: big.NewInt(1), Correction: big.NewInt(1)})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Invalid participant index")
+	_, err = csp.PartialSign(k, digest[:], &bccsp.ECDSAPartialSignOpts{Index: 1, R: big.NewInt(1)})
+	assert.Error(t, err)
+	_, err = csp.PartialSign(k, digest[:], opts)
+	assert.NoError(t, err)
+}
+
+type countingReader struct {
+	n int
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	r.n += len(p)
+	return rand.Read(p)
+}
+
+func TestSignECDSABlinded(t *testing.T) {
+	reader := &countingReader{}
+	defer func(r io.Reader) { blindingReader = r }(blindingReader)
+	blindingReader = reader
+
+	digest := sha256.Sum256([]byte("Hello World"))
+	for _, curve := range []elliptic.Curve{elliptic.P256(), elliptic.P384(), elliptic.P521()} {
+		key, err := ecdsa.GenerateKey(curve, rand.Reader)
+		assert.NoError(t, err)
+
+		// Unblinded signing does not use blinding path
+		reader.n = 0
+		sig, err := signECDSA(key, digest[:], &bccsp.ECDSASignerOpts{})
+		assert.NoError(t, err)
+		assert.Equal(t, 0, reader.n)
+		valid, err := verifyECDSA(&key.PublicKey, sig, digest[:], nil)
+		assert.NoError(t, err)
+		assert.True(t, valid)
+
+		// Blinded signing draws nonce and blinding factor
+		for i := 0; i < 10; i++ {
+			reader.n = 0
+			sig, err = signECDSA(key, digest[:], &bccsp.ECDSASignerOpts{Blind: true})
+			assert.NoError(t, err)
+			assert.True(t, reader.n > 0)
+			valid, err = verifyECDSA(&key.PublicKey, sig, digest[:], nil)
+			assert.NoError(t, err)
+			assert.True(t, valid, curve.Params().Name)
+
+			lowS, err := utils.IsLowSSignature(sig, curve)
+			assert.NoError(t, err)
+			assert.True(t, lowS)
+		}
+	}
+
+	// Through the provider
+	provider, _, cleanup := currentTestConfig.Provider(t)
+	defer cleanup()
+	k, err := provider.KeyGen(&bccsp.ECDSAKeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	reader.n = 0
+	sig, err := provider.Sign(k, digest[:], &bccsp.ECDSASignerOpts{Blind: true})
+	assert.NoError(t, err)
+	assert.True(t, reader.n > 0)
+	valid, err := provider.Verify(k, sig, digest[:], nil)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+
+	// Failing randomness source
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	_, _, err = signECDSABlinded(&io.LimitedReader{R: rand.Reader, N: 0}, key, digest[:], nil)
+	assert.Error(t, err)
+}
@@ -23,6 +23,7 @@ import (
 	"fmt"
 
 	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp/utils"
 	"github.com/ipfn/ipfn/pkg/digest"
 )
 
@@ -64,11 +65,60 @@ func (k *ecdsaPrivateKey) Private() bool {
 // PublicKey returns the corresponding public key part of an asymmetric public/private key pair.
 // This method returns an error in symmetric key schemes.
 func (k *ecdsaPrivateKey) PublicKey() (bccsp.Key, error) {
-	return &ecdsaPublicKey{&k.privKey.PublicKey}, nil
+	return &ecdsaPublicKey{pubKey: &k.privKey.PublicKey}, nil
+}
+
+// PointFormat - Encoding of ECDSA public key, see MarshalECDSAPublicKey.
+type PointFormat int
+
+const (
+	// PointPKIX - PKIX DER encoding, as returned by Bytes.
+	PointPKIX PointFormat = iota
+	// PointCompressed - SEC1 compressed point encoding.
+	PointCompressed
+	// PointUncompressed - SEC1 uncompressed point encoding.
+	PointUncompressed
+)
+
+// MarshalECDSAPublicKey - Returns public key of ECDSA key encoded in format,
+// e.g. as compressed point for compact protocols. Private keys are encoded
+// by their public key. Bytes of ECDSA public keys is always PKIX DER, which
+// other packages rely on to detect key type, and SKI is always computed
+// over the uncompressed point.
+func MarshalECDSAPublicKey(k bccsp.Key, format PointFormat) ([]byte, error) {
+	var pub *ecdsa.PublicKey
+	switch kk := k.(type) {
+	case *ecdsaPrivateKey:
+		pub = &kk.privKey.PublicKey
+	case *ecdsaPublicKey:
+		pub = kk.pubKey
+	default:
+		return nil, fmt.Errorf("Invalid key type. Expected ECDSA key, got [%T]", k)
+	}
+	switch format {
+	case PointPKIX:
+		return (&ecdsaPublicKey{pubKey: pub}).Bytes()
+	case PointCompressed:
+		return utils.CompressECDSAPublicKey(pub), nil
+	case PointUncompressed:
+		return elliptic.Marshal(pub.Curve, pub.X, pub.Y), nil
+	}
+	return nil, fmt.Errorf("Invalid point format [%d]", format)
 }
 
 type ecdsaPublicKey struct {
 	pubKey *ecdsa.PublicKey
+	origin utils.Origin
+}
+
+// KeyOrigin returns how this key was imported.
+func (k *ecdsaPublicKey) KeyOrigin() utils.Origin {
+	return k.origin
+}
+
+// GoPublicKey returns the Go public key of this key.
+func (k *ecdsaPublicKey) GoPublicKey() interface{} {
+	return k.pubKey
 }
 
 // Bytes converts this key to its byte representation,
@@ -0,0 +1,253 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/hkdf"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+)
+
+// eciesMultiVersion - Version of multi-recipient ECIES envelope.
+const eciesMultiVersion = 1
+
+// eciesMultiInfo - HKDF info prefix of key encryption keys.
+var eciesMultiInfo = []byte("ipfn-ecies-multi")
+
+// EncryptMulti - Encrypts plaintext once so that it can be decrypted with
+// private key of any of the ECDSA recipients using DecryptMulti.
+// Opts are reserved for future use and may be nil.
+//
+// Plaintext is encrypted with random 256 bit content key using AES-GCM.
+// Content key is wrapped for every recipient with AES-GCM using key derived
+// with HKDF-SHA256 from ECDH secret of the recipient and fresh ephemeral key
+// on the recipient's curve. The envelope is
+//
+//	version    1 byte, currently 1
+//	count      uvarint number of recipients
+//	count times:
+//	  ski      uvarint length prefixed SKI of the recipient
+//	  ephem    uvarint length prefixed uncompressed ephemeral public key
+//	  wrapped  uvarint length prefixed 12 bytes nonce and wrapped content key
+//	nonce      12 bytes
+//	body       AES-GCM sealed plaintext
+//
+// SHA-256 of the header, that is everything preceding the body nonce, is
+// authenticated as additional data of the body, so recipients cannot be
+// removed, added or reordered without detection.
+//
+// Notice that SKIs of the recipients are visible in the envelope.
+func (csp *CSP) EncryptMulti(recipients []bccsp.Key, plaintext []byte, opts bccsp.EncrypterOpts) ([]byte, error) {
+	if len(recipients) == 0 {
+		return nil, errors.New("Invalid recipients. At least one is required.")
+	}
+	if err := csp.checkInputSize(len(plaintext)); err != nil {
+		return nil, err
+	}
+	pubs := make([]*ecdsa.PublicKey, len(recipients))
+	for i, k := range recipients {
+		switch kk := k.(type) {
+		case *ecdsaPublicKey:
+			pubs[i] = kk.pubKey
+		case *ecdsaPrivateKey:
+			pubs[i] = &kk.privKey.PublicKey
+		default:
+			return nil, errors.Errorf("Invalid recipient %d. Expected ECDSA public key.", i)
+		}
+		if err := csp.checkFIPSKey(k); err != nil {
+			return nil, err
+		}
+	}
+
+	contentKey := make([]byte, 32)
+	if _, err := rand.Read(contentKey); err != nil {
+		return nil, errors.Wrap(err, "Failed generating content key")
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte(eciesMultiVersion)
+	writeUvarint(&buf, uint64(len(pubs)))
+	for i, pub := range pubs {
+		ephemeral, err := ecdsa.GenerateKey(pub.Curve, rand.Reader)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed generating ephemeral key")
+		}
+		ephemeralPub := elliptic.Marshal(pub.Curve, ephemeral.X, ephemeral.Y)
+		kek, err := eciesKEK(ephemeral, pub, ephemeralPub)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed deriving key of recipient %d", i)
+		}
+		wrapped, err := gcmSeal(kek, contentKey, nil)
+		if err != nil {
+			return nil, err
+		}
+		writeBytes(&buf, recipients[i].SKI())
+		writeBytes(&buf, ephemeralPub)
+		writeBytes(&buf, wrapped)
+	}
+
+	header := sha256.Sum256(buf.Bytes())
+	body, err := gcmSeal(contentKey, plaintext, header[:])
+	if err != nil {
+		return nil, err
+	}
+	buf.Write(body)
+	return buf.Bytes(), nil
+}
+
+// DecryptMulti - Decrypts envelope created by EncryptMulti using
+// private key of one of the recipients.
+// Opts are reserved for future use and may be nil.
+func (csp *CSP) DecryptMulti(k bccsp.Key, ciphertext []byte, opts bccsp.DecrypterOpts) ([]byte, error) {
+	priv, ok := k.(*ecdsaPrivateKey)
+	if !ok {
+		return nil, errors.New("Invalid key. Expected ECDSA private key.")
+	}
+	if err := csp.checkFIPSKey(k); err != nil {
+		return nil, err
+	}
+
+	r := bytes.NewReader(ciphertext)
+	version, err := r.ReadByte()
+	if err != nil || version != eciesMultiVersion {
+		return nil, errors.New("Invalid ciphertext. Unsupported envelope version.")
+	}
+	count, err := binary.ReadUvarint(r)
+	if err != nil || count == 0 || count > uint64(r.Len()) {
+		return nil, errors.New("Invalid ciphertext. Malformed recipients.")
+	}
+
+	ski := k.SKI()
+	var contentKey []byte
+	for i := uint64(0); i < count; i++ {
+		recipient, err1 := readBytes(r)
+		ephemeralPub, err2 := readBytes(r)
+		wrapped, err3 := readBytes(r)
+		if err1 != nil || err2 != nil || err3 != nil {
+			return nil, errors.New("Invalid ciphertext. Malformed recipients.")
+		}
+		if contentKey != nil || !bytes.Equal(recipient, ski) {
+			continue
+		}
+		curve := priv.privKey.Curve
+		x, y := elliptic.Unmarshal(curve, ephemeralPub)
+		if x == nil {
+			return nil, errors.New("Invalid ciphertext. Malformed ephemeral key.")
+		}
+		kek, err := eciesKEK(priv.privKey, &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, ephemeralPub)
+		if err != nil {
+			return nil, err
+		}
+		contentKey, err = gcmOpen(kek, wrapped, nil)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed unwrapping content key")
+		}
+	}
+	if contentKey == nil {
+		return nil, errors.New("Key is not a recipient of the ciphertext.")
+	}
+
+	body := ciphertext[len(ciphertext)-r.Len():]
+	header := sha256.Sum256(ciphertext[:len(ciphertext)-r.Len()])
+	plaintext, err := gcmOpen(contentKey, body, header[:])
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed decrypting ciphertext")
+	}
+	return plaintext, nil
+}
+
+// eciesKEK - Derives key encryption key from ECDH secret.
+func eciesKEK(priv *ecdsa.PrivateKey, pub *ecdsa.PublicKey, ephemeralPub []byte) ([]byte, error) {
+	secret, err := ecdhSharedSecret(priv, pub)
+	if err != nil {
+		return nil, err
+	}
+	info := append(append([]byte{}, eciesMultiInfo...), ephemeralPub...)
+	kek := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, secret, nil, info), kek); err != nil {
+		return nil, errors.Wrap(err, "Failed expanding shared secret")
+	}
+	return kek, nil
+}
+
+// gcmSeal - Encrypts plaintext with AES-GCM authenticating additionalData,
+// random nonce is prepended.
+func gcmSeal(key, plaintext, additionalData []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, errors.Wrap(err, "Failed generating nonce")
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// gcmOpen - Decrypts ciphertext created by gcmSeal.
+func gcmOpen(key, ciphertext, additionalData []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, errors.New("Invalid ciphertext. It is too short.")
+	}
+	n := aead.NonceSize()
+	return aead.Open(nil, ciphertext[:n], ciphertext[n:], additionalData)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed creating AES cipher")
+	}
+	return cipher.NewGCM(block)
+}
+
+func writeUvarint(buf *bytes.Buffer, x uint64) {
+	var tmp [binary.MaxVarintLen64]byte
+	buf.Write(tmp[:binary.PutUvarint(tmp[:], x)])
+}
+
+func writeBytes(buf *bytes.Buffer, b []byte) {
+	writeUvarint(buf, uint64(len(b)))
+	buf.Write(b)
+}
+
+func readBytes(r *bytes.Reader) ([]byte, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if n > uint64(r.Len()) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	b := make([]byte, n)
+	_, err = io.ReadFull(r, b)
+	return b, err
+}
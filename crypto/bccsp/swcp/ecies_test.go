@@ -0,0 +1,102 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+)
+
+func TestEncryptMulti(t *testing.T) {
+	provider, _, cleanup := currentTestConfig.Provider(t)
+	defer cleanup()
+	csp := provider.(*CSP)
+
+	var privs, pubs []bccsp.Key
+	for _, opts := range []bccsp.KeyGenOpts{
+		&bccsp.ECDSAP256KeyGenOpts{Temporary: true},
+		&bccsp.ECDSAP384KeyGenOpts{Temporary: true},
+		&bccsp.ECDSAP256KeyGenOpts{Temporary: true},
+	} {
+		k, err := provider.KeyGen(opts)
+		assert.NoError(t, err)
+		pk, err := k.PublicKey()
+		assert.NoError(t, err)
+		privs = append(privs, k)
+		pubs = append(pubs, pk)
+	}
+
+	msg := []byte("Hello Group")
+	ct, err := csp.EncryptMulti(pubs, msg, nil)
+	assert.NoError(t, err)
+
+	for i, k := range privs {
+		pt, err := csp.DecryptMulti(k, ct, nil)
+		assert.NoError(t, err, "recipient %d", i)
+		assert.Equal(t, msg, pt, "recipient %d", i)
+	}
+
+	// Not a recipient
+	outsider, err := provider.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	_, err = csp.DecryptMulti(outsider, ct, nil)
+	assert.EqualError(t, err, "Key is not a recipient of the ciphertext.")
+
+	// Tampered body
+	tampered := append([]byte{}, ct...)
+	tampered[len(tampered)-1] ^= 0xff
+	_, err = csp.DecryptMulti(privs[0], tampered, nil)
+	assert.Error(t, err)
+
+	// Removed recipient
+	r := bytes.NewReader(ct[1:])
+	count, err := binary.ReadUvarint(r)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(3), count)
+	var stripped bytes.Buffer
+	stripped.WriteByte(ct[0])
+	writeUvarint(&stripped, 2)
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			field, err := readBytes(r)
+			assert.NoError(t, err)
+			if i < 2 {
+				writeBytes(&stripped, field)
+			}
+		}
+	}
+	stripped.Write(ct[len(ct)-r.Len():])
+	_, err = csp.DecryptMulti(privs[0], stripped.Bytes(), nil)
+	assert.Error(t, err)
+
+	// Malformed envelopes
+	_, err = csp.DecryptMulti(privs[0], nil, nil)
+	assert.Error(t, err)
+	_, err = csp.DecryptMulti(privs[0], ct[:20], nil)
+	assert.Error(t, err)
+
+	_, err = csp.EncryptMulti(nil, msg, nil)
+	assert.Error(t, err)
+	aesKey, err := provider.KeyGen(&bccsp.AES256KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	_, err = csp.EncryptMulti([]bccsp.Key{pubs[0], aesKey}, msg, nil)
+	assert.EqualError(t, err, "Invalid recipient 1. Expected ECDSA public key.")
+}
@@ -82,6 +82,11 @@ func (k *ed25519PublicKey) Bytes() (raw []byte, err error) {
 	return k.pubKey, nil
 }
 
+// GoPublicKey returns the Go public key of this key.
+func (k *ed25519PublicKey) GoPublicKey() interface{} {
+	return k.pubKey
+}
+
 // SKI returns the subject key identifier of this key.
 func (k *ed25519PublicKey) SKI() []byte {
 	return digest.SumSha256Bytes(k.pubKey)
@@ -0,0 +1,105 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"container/list"
+	"errors"
+	"sync"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+)
+
+// ErrEphemeralKey - Error returned by StoreKey in strict ephemeral mode when
+// key was generated, derived or imported as ephemeral.
+var ErrEphemeralKey = errors.New("Key is ephemeral and must not be stored.")
+
+// maxEphemeralKeys - Maximum number of SKIs tracked in strict mode.
+const maxEphemeralKeys = 1 << 16
+
+// ephemeralKeys - Bounded set of SKIs of ephemeral keys, tracked in strict
+// mode. When full, the least recently created key is forgotten.
+type ephemeralKeys struct {
+	sync.RWMutex
+
+	strict bool
+	limit  int
+	skis   map[string]*list.Element
+	order  *list.List
+}
+
+// SetStrictEphemeral configures whether StoreKey refuses keys which were
+// generated, derived or imported with ephemeral opts, which catches keys
+// accidentally persisted. It is disabled by default. Only keys created while
+// enabled are tracked, at most 65536 most recently created, so long-running
+// process does not accumulate SKIs of discarded keys. Disabling forgets all.
+func (csp *CSP) SetStrictEphemeral(enabled bool) {
+	csp.ephemeral.Lock()
+	defer csp.ephemeral.Unlock()
+	csp.ephemeral.strict = enabled
+	if enabled {
+		if csp.ephemeral.skis == nil {
+			csp.ephemeral.limit = maxEphemeralKeys
+			csp.ephemeral.skis = make(map[string]*list.Element)
+			csp.ephemeral.order = list.New()
+		}
+	} else {
+		csp.ephemeral.skis, csp.ephemeral.order = nil, nil
+	}
+}
+
+// markEphemeral - Records key as ephemeral in strict mode.
+func (csp *CSP) markEphemeral(k bccsp.Key) {
+	e := &csp.ephemeral
+	e.Lock()
+	defer e.Unlock()
+	if !e.strict {
+		return
+	}
+	ski := string(k.SKI())
+	if elem, found := e.skis[ski]; found {
+		e.order.MoveToFront(elem)
+		return
+	}
+	e.skis[ski] = e.order.PushFront(ski)
+	for e.order.Len() > e.limit {
+		elem := e.order.Back()
+		e.order.Remove(elem)
+		delete(e.skis, elem.Value.(string))
+	}
+}
+
+// isEphemeral - Returns true if key was recorded as ephemeral in strict mode.
+func (csp *CSP) isEphemeral(k bccsp.Key) bool {
+	csp.ephemeral.RLock()
+	defer csp.ephemeral.RUnlock()
+	if !csp.ephemeral.strict {
+		return false
+	}
+	_, found := csp.ephemeral.skis[string(k.SKI())]
+	return found
+}
+
+// forgetEphemeral - Removes key recorded as ephemeral in strict mode.
+func (csp *CSP) forgetEphemeral(k bccsp.Key) {
+	e := &csp.ephemeral
+	e.Lock()
+	defer e.Unlock()
+	if elem, found := e.skis[string(k.SKI())]; found {
+		e.order.Remove(elem)
+		delete(e.skis, string(k.SKI()))
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+)
+
+func TestStrictEphemeral(t *testing.T) {
+	provider, ks, cleanup := currentTestConfig.Provider(t)
+	defer cleanup()
+	csp := provider.(*CSP)
+
+	// Allowed by default
+	k, err := provider.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	assert.NoError(t, provider.StoreKey(k))
+	_, err = ks.Key(k.SKI())
+	assert.NoError(t, err)
+
+	csp.SetStrictEphemeral(true)
+
+	// Key created before strict mode is not tracked
+	assert.NoError(t, provider.StoreKey(k))
+
+	k, err = provider.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	assert.Equal(t, ErrEphemeralKey, provider.StoreKey(k))
+	_, err = ks.Key(k.SKI())
+	assert.Error(t, err)
+
+	aesKey, err := provider.KeyGen(&bccsp.AES256KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	derived, err := provider.KeyDeriv(aesKey, &bccsp.HMACTruncated256AESDeriveKeyOpts{Temporary: true, Arg: []byte{1}})
+	assert.NoError(t, err)
+	assert.Equal(t, ErrEphemeralKey, provider.StoreKey(derived))
+
+	imported, err := provider.KeyImport(make([]byte, 32), &bccsp.AES256ImportKeyOpts{Temporary: true})
+	assert.NoError(t, err)
+	assert.Equal(t, ErrEphemeralKey, provider.StoreKey(imported))
+
+	batch, _, err := ImportKeys(csp, []ImportSpec{{Raw: []byte("0123456789abcdef0123456789abcdef"), Opts: &bccsp.AES256ImportKeyOpts{Temporary: true}}})
+	assert.NoError(t, err)
+	assert.Equal(t, ErrEphemeralKey, provider.StoreKey(batch[0]))
+
+	// Least recently created keys are forgotten
+	csp.ephemeral.limit = 2
+	for i := 0; i < 2; i++ {
+		_, err = provider.KeyGen(&bccsp.AES256KeyGenOpts{Temporary: true})
+		assert.NoError(t, err)
+	}
+	assert.Len(t, csp.ephemeral.skis, 2)
+	assert.False(t, csp.isEphemeral(k))
+
+	// Persistent keys are stored
+	persistent, err := provider.KeyGen(&bccsp.ECDSAP256KeyGenOpts{})
+	assert.NoError(t, err)
+	assert.NoError(t, provider.StoreKey(persistent))
+
+	// Disabled again
+	csp.SetStrictEphemeral(false)
+	assert.NoError(t, provider.StoreKey(k))
+	_, err = ks.Key(k.SKI())
+	assert.NoError(t, err)
+}
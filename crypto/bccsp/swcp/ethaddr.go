@@ -0,0 +1,48 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"crypto/ecdsa"
+
+	"github.com/pkg/errors"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp/utils"
+)
+
+// EthAddressBySKI returns EIP-55 checksum encoded Ethereum address of the
+// stored secp256k1 key with SKI ski, private or public. Keys of other types
+// and curves are rejected.
+//
+// Notice that file-based KeyStore cannot persist secp256k1 keys, as they
+// have no standard PEM encoding, so keys have to be kept in a KeyStore which
+// holds key objects, such as one created by NewInMemoryKeyStore.
+func (csp *CSP) EthAddressBySKI(ski []byte) (string, error) {
+	k, err := csp.Key(ski)
+	if err != nil {
+		return "", err
+	}
+	var pub *ecdsa.PublicKey
+	switch k := k.(type) {
+	case *ecdsaPrivateKey:
+		pub = &k.privKey.PublicKey
+	case *ecdsaPublicKey:
+		pub = k.pubKey
+	default:
+		return "", errors.Errorf("Invalid key with SKI [%x]. Expected secp256k1 key, got [%T].", ski, k)
+	}
+	return utils.EthAddress(pub)
+}
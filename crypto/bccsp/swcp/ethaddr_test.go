@@ -0,0 +1,61 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+)
+
+func TestEthAddressBySKI(t *testing.T) {
+	provider, err := NewDefaultSecurityLevelWithKeystore(NewInMemoryKeyStore())
+	assert.NoError(t, err)
+	csp := provider.(*CSP)
+
+	raw, _ := hex.DecodeString("4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318")
+	priv, pub := btcec.PrivKeyFromBytes(btcec.S256(), raw)
+	privKey := &ecdsaPrivateKey{privKey: priv.ToECDSA()}
+	assert.NoError(t, provider.StoreKey(privKey))
+	pk, err := provider.KeyImport(pub.ToECDSA(), &bccsp.ECDSAGoPublicKeyImportOpts{})
+	assert.NoError(t, err)
+
+	for _, ski := range [][]byte{privKey.SKI(), pk.SKI()} {
+		addr, err := csp.EthAddressBySKI(ski)
+		assert.NoError(t, err)
+		assert.Equal(t, "0x2c7536E3605D9C16a7a3D7b1898e529396a65c23", addr)
+	}
+
+	// Keys on other curves
+	p256, err := provider.KeyGen(&bccsp.ECDSAP256KeyGenOpts{})
+	assert.NoError(t, err)
+	_, err = csp.EthAddressBySKI(p256.SKI())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Expected secp256k1")
+	aes, err := provider.KeyGen(&bccsp.AES256KeyGenOpts{})
+	assert.NoError(t, err)
+	_, err = csp.EthAddressBySKI(aes.SKI())
+	assert.Error(t, err)
+
+	// Unknown SKI
+	_, err = csp.EthAddressBySKI([]byte{1, 2, 3})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Failed getting key")
+}
@@ -19,12 +19,16 @@ import (
 	"bytes"
 	"crypto/ecdsa"
 	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/hex"
+	"encoding/pem"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 
@@ -32,6 +36,29 @@ import (
 	"github.com/ipfn/ipfn/pkg/crypto/bccsp/utils"
 )
 
+// KeyStoreFormatVersion is the latest version of the on-disk layout of the
+// file-based KeyStore, stores of versions 1 to KeyStoreFormatVersion can
+// be opened. It is recorded in the KeyStoreVersionFile so that layout
+// changes can be detected. Newly created stores are of the latest version,
+// stores of version 1, which may hold entries without checksum, are upgraded
+// to version 2 by ReEncryptStore.
+const KeyStoreFormatVersion = 2
+
+// pemKeyStoreFormatVersion - Version of store with PEM entries only,
+// entries may have no checksum. Stores without version marker are of it.
+const pemKeyStoreFormatVersion = 1
+
+// KeyStoreVersionFile is the name of the marker file holding the decimal
+// format version of a file-based KeyStore.
+const KeyStoreVersionFile = "VERSION"
+
+// ErrKeyCorrupted - Error returned when contents of a key file
+// do not match the checksum stored with the key.
+var ErrKeyCorrupted = errors.New("Key file is corrupted.")
+
+// checksumHeader - PEM header holding checksum of the key.
+const checksumHeader = "Checksum"
+
 // NewFileBasedKeyStore instantiated a file-based key store at a given position.
 // The key store can be encrypted if a non-empty password is specifiec.
 // It can be also be set as read only. In this case, any store operation
@@ -48,6 +75,26 @@ func NewFileBasedKeyStore(pwd []byte, path string, readOnly bool) (bccsp.KeyStor
 // The KeyStore can be initialized with a password, this password
 // is used to encrypt and decrypt the files storing the keys.
 // A KeyStore can be read only to avoid the overwriting of keys.
+//
+// The on-disk layout (format version 1) is a flat directory where each
+// file is named after the hex-encoded SKI of the key followed by an
+// underscore and a type suffix:
+//
+//	<hex ski>_sk   PEM-encoded private key (ECDSA or RSA)
+//	<hex ski>_pk   PEM-encoded public key (ECDSA or RSA)
+//	<hex ski>_key  PEM-encoded AES key
+//
+// PEM blocks carry DER contents and are encrypted when a password is set.
+// Each block has a Checksum header with hex encoded first 8 bytes of SHA-256
+// of the block contents, files written before its introduction have none
+// and are loaded without verification in stores of format version 1.
+// The KeyStoreVersionFile holds the format version. Stores created before
+// the marker was introduced have no such file and are read as version 1.
+//
+// In format version 2, blocks encrypted with AEAD instead of legacy PEM
+// encryption carry AEAD, Salt and Nonce headers, see ReEncryptStore.
+// Blocks without Checksum header are rejected as corrupted.
+// The KeyStoreAEADFile holds the AEAD of newly stored keys.
 type fileBasedKeyStore struct {
 	path string
 
@@ -56,6 +103,11 @@ type fileBasedKeyStore struct {
 
 	pwd []byte
 
+	// aead encrypts new entries when set, see ReEncryptStore.
+	aead AEADSpec
+	// version of the on-disk format.
+	version int
+
 	// Sync
 	m sync.Mutex
 }
@@ -87,6 +139,7 @@ func (ks *fileBasedKeyStore) Init(pwd []byte, path string, readOnly bool) error
 
 	ks.path = path
 	ks.pwd = utils.Clone(pwd)
+	ks.readOnly = readOnly
 
 	err := ks.createKeyStoreIfNotExists()
 	if err != nil {
@@ -98,8 +151,6 @@ func (ks *fileBasedKeyStore) Init(pwd []byte, path string, readOnly bool) error
 		return err
 	}
 
-	ks.readOnly = readOnly
-
 	return nil
 }
 
@@ -123,6 +174,9 @@ func (ks *fileBasedKeyStore) Key(ski []byte) (bccsp.Key, error) {
 		// Load the key
 		key, err := ks.loadKey(hex.EncodeToString(ski))
 		if err != nil {
+			if err == ErrKeyCorrupted {
+				return nil, err
+			}
 			return nil, fmt.Errorf("Failed loading key [%x] [%s]", ski, err)
 		}
 
@@ -131,6 +185,9 @@ func (ks *fileBasedKeyStore) Key(ski []byte) (bccsp.Key, error) {
 		// Load the private key
 		key, err := ks.loadPrivateKey(hex.EncodeToString(ski))
 		if err != nil {
+			if err == ErrKeyCorrupted {
+				return nil, err
+			}
 			return nil, fmt.Errorf("Failed loading secret key [%x] [%s]", ski, err)
 		}
 
@@ -146,14 +203,17 @@ func (ks *fileBasedKeyStore) Key(ski []byte) (bccsp.Key, error) {
 		// Load the public key
 		key, err := ks.loadPublicKey(hex.EncodeToString(ski))
 		if err != nil {
+			if err == ErrKeyCorrupted {
+				return nil, err
+			}
 			return nil, fmt.Errorf("Failed loading public key [%x] [%s]", ski, err)
 		}
 
 		switch key.(type) {
 		case *ecdsa.PublicKey:
-			return &ecdsaPublicKey{key.(*ecdsa.PublicKey)}, nil
+			return &ecdsaPublicKey{pubKey: key.(*ecdsa.PublicKey)}, nil
 		case *rsa.PublicKey:
-			return &rsaPublicKey{key.(*rsa.PublicKey)}, nil
+			return &rsaPublicKey{pubKey: key.(*rsa.PublicKey)}, nil
 		default:
 			return nil, errors.New("Public key type not recognized")
 		}
@@ -220,6 +280,29 @@ func (ks *fileBasedKeyStore) StoreKey(k bccsp.Key) (err error) {
 	return
 }
 
+// SKIs returns subject key identifiers of all keys in this KeyStore.
+// Private and public key files of the same key are reported once.
+func (ks *fileBasedKeyStore) SKIs() ([][]byte, error) {
+	files, err := ioutil.ReadDir(ks.path)
+	if err != nil {
+		return nil, fmt.Errorf("Failed listing keystore [%s]", err)
+	}
+	var skis [][]byte
+	seen := make(map[string]bool)
+	for _, f := range files {
+		if f.IsDir() || !isKeyFile(f.Name()) {
+			continue
+		}
+		ski, err := hex.DecodeString(f.Name()[:strings.LastIndexByte(f.Name(), '_')])
+		if err != nil || seen[string(ski)] {
+			continue
+		}
+		seen[string(ski)] = true
+		skis = append(skis, ski)
+	}
+	return skis, nil
+}
+
 func (ks *fileBasedKeyStore) searchKeystoreForSKI(ski []byte) (k bccsp.Key, err error) {
 
 	files, _ := ioutil.ReadDir(ks.path)
@@ -236,6 +319,9 @@ func (ks *fileBasedKeyStore) searchKeystoreForSKI(ski []byte) (k bccsp.Key, err
 		if err != nil {
 			continue
 		}
+		if raw, err = ks.decodeEntry(raw); err != nil {
+			continue
+		}
 
 		key, err := utils.PEMtoPrivateKey(raw, ks.pwd)
 		if err != nil {
@@ -280,13 +366,21 @@ func (ks *fileBasedKeyStore) getSuffix(alias string) string {
 }
 
 func (ks *fileBasedKeyStore) storePrivateKey(alias string, privateKey interface{}) error {
-	rawKey, err := utils.PrivateKeyToPEM(privateKey, ks.pwd)
+	rawKey, err := ks.encodeEntry(func(pwd []byte) ([]byte, error) {
+		return utils.PrivateKeyToPEM(privateKey, pwd)
+	})
 	if err != nil {
 		logger.Errorf("Failed converting private key to PEM [%s]: [%s]", alias, err)
 		return err
 	}
 
-	err = ioutil.WriteFile(ks.getPathForAlias(alias, "sk"), rawKey, 0600)
+	rawKey, err = addChecksum(rawKey)
+	if err != nil {
+		logger.Errorf("Failed adding checksum to private key [%s]: [%s]", alias, err)
+		return err
+	}
+
+	err = writeFileAtomic(ks.getPathForAlias(alias, "sk"), rawKey, 0600)
 	if err != nil {
 		logger.Errorf("Failed storing private key [%s]: [%s]", alias, err)
 		return err
@@ -296,13 +390,21 @@ func (ks *fileBasedKeyStore) storePrivateKey(alias string, privateKey interface{
 }
 
 func (ks *fileBasedKeyStore) storePublicKey(alias string, publicKey interface{}) error {
-	rawKey, err := utils.PublicKeyToPEM(publicKey, ks.pwd)
+	rawKey, err := ks.encodeEntry(func(pwd []byte) ([]byte, error) {
+		return utils.PublicKeyToPEM(publicKey, pwd)
+	})
 	if err != nil {
 		logger.Errorf("Failed converting public key to PEM [%s]: [%s]", alias, err)
 		return err
 	}
 
-	err = ioutil.WriteFile(ks.getPathForAlias(alias, "pk"), rawKey, 0600)
+	rawKey, err = addChecksum(rawKey)
+	if err != nil {
+		logger.Errorf("Failed adding checksum to public key [%s]: [%s]", alias, err)
+		return err
+	}
+
+	err = writeFileAtomic(ks.getPathForAlias(alias, "pk"), rawKey, 0600)
 	if err != nil {
 		logger.Errorf("Failed storing private key [%s]: [%s]", alias, err)
 		return err
@@ -312,13 +414,21 @@ func (ks *fileBasedKeyStore) storePublicKey(alias string, publicKey interface{})
 }
 
 func (ks *fileBasedKeyStore) storeKey(alias string, key []byte) error {
-	pem, err := utils.AEStoEncryptedPEM(key, ks.pwd)
+	pem, err := ks.encodeEntry(func(pwd []byte) ([]byte, error) {
+		return utils.AEStoEncryptedPEM(key, pwd)
+	})
 	if err != nil {
 		logger.Errorf("Failed converting key to PEM [%s]: [%s]", alias, err)
 		return err
 	}
 
-	err = ioutil.WriteFile(ks.getPathForAlias(alias, "key"), pem, 0600)
+	pem, err = addChecksum(pem)
+	if err != nil {
+		logger.Errorf("Failed adding checksum to key [%s]: [%s]", alias, err)
+		return err
+	}
+
+	err = writeFileAtomic(ks.getPathForAlias(alias, "key"), pem, 0600)
 	if err != nil {
 		logger.Errorf("Failed storing key [%s]: [%s]", alias, err)
 		return err
@@ -338,6 +448,18 @@ func (ks *fileBasedKeyStore) loadPrivateKey(alias string) (interface{}, error) {
 		return nil, err
 	}
 
+	if err = verifyChecksum(raw, ks.requireChecksum()); err != nil {
+		logger.Errorf("Failed verifying private key [%s]: [%s].", alias, err)
+
+		return nil, err
+	}
+
+	if raw, err = ks.decodeEntry(raw); err != nil {
+		logger.Errorf("Failed decrypting private key [%s]: [%s].", alias, err)
+
+		return nil, err
+	}
+
 	privateKey, err := utils.PEMtoPrivateKey(raw, ks.pwd)
 	if err != nil {
 		logger.Errorf("Failed parsing private key [%s]: [%s].", alias, err.Error())
@@ -359,6 +481,18 @@ func (ks *fileBasedKeyStore) loadPublicKey(alias string) (interface{}, error) {
 		return nil, err
 	}
 
+	if err = verifyChecksum(raw, ks.requireChecksum()); err != nil {
+		logger.Errorf("Failed verifying public key [%s]: [%s].", alias, err)
+
+		return nil, err
+	}
+
+	if raw, err = ks.decodeEntry(raw); err != nil {
+		logger.Errorf("Failed decrypting public key [%s]: [%s].", alias, err)
+
+		return nil, err
+	}
+
 	privateKey, err := utils.PEMtoPublicKey(raw, ks.pwd)
 	if err != nil {
 		logger.Errorf("Failed parsing private key [%s]: [%s].", alias, err.Error())
@@ -380,6 +514,18 @@ func (ks *fileBasedKeyStore) loadKey(alias string) ([]byte, error) {
 		return nil, err
 	}
 
+	if err = verifyChecksum(pem, ks.requireChecksum()); err != nil {
+		logger.Errorf("Failed verifying key [%s]: [%s].", alias, err)
+
+		return nil, err
+	}
+
+	if pem, err = ks.decodeEntry(pem); err != nil {
+		logger.Errorf("Failed decrypting key [%s]: [%s].", alias, err)
+
+		return nil, err
+	}
+
 	key, err := utils.PEMtoAES(pem, ks.pwd)
 	if err != nil {
 		logger.Errorf("Failed parsing key [%s]: [%s]", alias, err)
@@ -415,6 +561,13 @@ func (ks *fileBasedKeyStore) createKeyStore() error {
 
 	os.MkdirAll(ksPath, 0755)
 
+	if !ks.readOnly {
+		err := ks.writeVersion(KeyStoreFormatVersion)
+		if err != nil {
+			return err
+		}
+	}
+
 	logger.Debugf("KeyStore created at [%s].", ksPath)
 	return nil
 }
@@ -423,12 +576,131 @@ func (ks *fileBasedKeyStore) openKeyStore() error {
 	if ks.isOpen {
 		return nil
 	}
+	version, err := ks.readVersion()
+	if err != nil {
+		return err
+	}
+	if version > KeyStoreFormatVersion {
+		return fmt.Errorf("Unsupported KeyStore format version %d at [%s], at most %d is supported", version, ks.path, KeyStoreFormatVersion)
+	}
+	ks.aead, err = ks.readAEAD()
+	if err != nil {
+		return err
+	}
+	ks.version = version
 	ks.isOpen = true
 	logger.Debugf("KeyStore opened at [%s]...done", ks.path)
 
 	return nil
 }
 
+// readVersion returns the format version recorded in the store directory.
+// A missing marker denotes a store created before versioning was introduced.
+func (ks *fileBasedKeyStore) readVersion() (int, error) {
+	raw, err := ioutil.ReadFile(filepath.Join(ks.path, KeyStoreVersionFile))
+	if os.IsNotExist(err) {
+		return pemKeyStoreFormatVersion, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("Failed reading KeyStore version [%s]", err)
+	}
+	version, err := strconv.Atoi(strings.TrimSpace(string(raw)))
+	if err != nil || version < 1 {
+		return 0, fmt.Errorf("Invalid KeyStore version marker %q at [%s]", strings.TrimSpace(string(raw)), ks.path)
+	}
+	return version, nil
+}
+
+func (ks *fileBasedKeyStore) writeVersion(version int) error {
+	raw := []byte(strconv.Itoa(version) + "\n")
+	err := writeFileAtomic(filepath.Join(ks.path, KeyStoreVersionFile), raw, 0600)
+	if err != nil {
+		logger.Errorf("Failed writing KeyStore version at [%s]: [%s]", ks.path, err)
+		return err
+	}
+	return nil
+}
+
+// keyChecksum - Returns checksum of PEM block contents.
+func keyChecksum(block *pem.Block) string {
+	sum := sha256.Sum256(block.Bytes)
+	return hex.EncodeToString(sum[:8])
+}
+
+// addChecksum - Adds checksum header to PEM encoded key.
+func addChecksum(raw []byte) ([]byte, error) {
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, errors.New("Invalid PEM. It must not be empty.")
+	}
+	if block.Headers == nil {
+		block.Headers = make(map[string]string)
+	}
+	block.Headers[checksumHeader] = keyChecksum(block)
+	return pem.EncodeToMemory(block), nil
+}
+
+// requireChecksum - Returns true if entries must carry checksum header,
+// which is the case since format version 2.
+func (ks *fileBasedKeyStore) requireChecksum() bool {
+	ks.m.Lock()
+	defer ks.m.Unlock()
+	return ks.version >= KeyStoreFormatVersion
+}
+
+// verifyChecksum - Verifies checksum header of PEM encoded key.
+// Keys without header are accepted unless required is set.
+func verifyChecksum(raw []byte, required bool) error {
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return ErrKeyCorrupted
+	}
+	sum, ok := block.Headers[checksumHeader]
+	if !ok {
+		if required {
+			return ErrKeyCorrupted
+		}
+		return nil
+	}
+	if subtle.ConstantTimeCompare([]byte(sum), []byte(keyChecksum(block))) != 1 {
+		return ErrKeyCorrupted
+	}
+	return nil
+}
+
+// renameFile - Moves file in place, replaced in tests to inject failures.
+var renameFile = os.Rename
+
+// writeFileAtomic writes data to a temporary file in the same directory
+// and renames it to path on success. On any failure the temporary file
+// is removed so that no partially written key remains in the store.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) (err error) {
+	dir, name := filepath.Split(path)
+	f, err := ioutil.TempFile(dir, "."+name+".tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+	if err = f.Chmod(perm); err != nil {
+		return err
+	}
+	if _, err = f.Write(data); err != nil {
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	return renameFile(f.Name(), path)
+}
+
 func (ks *fileBasedKeyStore) getPathForAlias(alias, suffix string) string {
 	return filepath.Join(ks.path, alias+"_"+suffix)
 }
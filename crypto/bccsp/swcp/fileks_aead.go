@@ -0,0 +1,322 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// AEADSpec - AEAD algorithm encrypting entries of password protected
+// file-based KeyStore, see ReEncryptStore.
+type AEADSpec string
+
+const (
+	// AEADAESGCM - AES-256-GCM.
+	AEADAESGCM AEADSpec = "AES-256-GCM"
+	// AEADAESGCMSIV - AES-256-GCM-SIV, resistant to nonce misuse.
+	AEADAESGCMSIV AEADSpec = "AES-256-GCM-SIV"
+)
+
+// KeyStoreAEADFile is the name of the marker file holding AEADSpec used
+// to encrypt new entries of a file-based KeyStore. Stores without marker
+// encrypt entries with legacy PEM encryption.
+const KeyStoreAEADFile = "AEAD"
+
+// PEM headers of AEAD encrypted entries.
+const (
+	// aeadHeader - AEAD algorithm of the entry.
+	aeadHeader = "AEAD"
+	// aeadSaltHeader - Hex encoded salt of scrypt entry key derivation.
+	aeadSaltHeader = "Salt"
+	// aeadNonceHeader - Hex encoded AEAD nonce.
+	aeadNonceHeader = "Nonce"
+	// aeadNonceSize - Size of AEAD nonce of both algorithms.
+	aeadNonceSize = 12
+)
+
+// StoreReEncrypter is implemented by keystores able to re-encrypt their
+// entries. File-based KeyStore implements it.
+type StoreReEncrypter interface {
+	// ReEncryptStore rewrites all entries encrypted with newAEAD.
+	ReEncryptStore(newAEAD AEADSpec) error
+}
+
+// valid - Returns true if spec is a supported AEAD.
+func (spec AEADSpec) valid() bool {
+	return spec == AEADAESGCM || spec == AEADAESGCMSIV
+}
+
+// ReEncryptStore rewrites every entry of the password protected KeyStore
+// encrypted with newAEAD, recording the algorithm in the entry header, and
+// encrypts entries stored afterwards with newAEAD. Each entry is replaced
+// atomically, entries encrypted with legacy PEM encryption or other AEAD
+// remain readable, so that the store is usable during migration and after
+// a failed migration, which can be resumed by calling it again.
+//
+// Entry keys are derived from the password with scrypt and per-entry salt.
+// Store is upgraded to format version 2 before any entry is re-encrypted,
+// entries without checksum, written by stores of version 1, can be loaded
+// again only after they are re-encrypted, so failed migration must be resumed.
+func (ks *fileBasedKeyStore) ReEncryptStore(newAEAD AEADSpec) error {
+	if ks.readOnly {
+		return errors.New("Read only KeyStore.")
+	}
+	if len(ks.pwd) == 0 {
+		return errors.New("KeyStore is not encrypted. Password must be set.")
+	}
+	if !newAEAD.valid() {
+		return fmt.Errorf("Unsupported AEAD [%s]", newAEAD)
+	}
+
+	ks.m.Lock()
+	defer ks.m.Unlock()
+
+	version, err := ks.readVersion()
+	if err != nil {
+		return err
+	}
+	if version < KeyStoreFormatVersion {
+		if err := ks.writeVersion(KeyStoreFormatVersion); err != nil {
+			return fmt.Errorf("Failed writing KeyStore version [%s]", err)
+		}
+		ks.version = KeyStoreFormatVersion
+	}
+	err = writeFileAtomic(filepath.Join(ks.path, KeyStoreAEADFile), []byte(newAEAD+"\n"), 0600)
+	if err != nil {
+		return fmt.Errorf("Failed writing KeyStore AEAD [%s]", err)
+	}
+	ks.aead = newAEAD
+
+	files, err := ioutil.ReadDir(ks.path)
+	if err != nil {
+		return fmt.Errorf("Failed listing keystore [%s]", err)
+	}
+	for _, f := range files {
+		if f.IsDir() || !isKeyFile(f.Name()) {
+			continue
+		}
+		if err := ks.reEncryptFile(filepath.Join(ks.path, f.Name()), newAEAD); err != nil {
+			return fmt.Errorf("Failed re-encrypting key file [%s] [%s]", f.Name(), err)
+		}
+	}
+	return nil
+}
+
+// reEncryptFile - Rewrites key file encrypted with spec unless it already is.
+// Key files written by stores of version 1 may have no checksum.
+func (ks *fileBasedKeyStore) reEncryptFile(path string, spec AEADSpec) error {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err = verifyChecksum(raw, false); err != nil {
+		return err
+	}
+	block, _ := pem.Decode(raw)
+	if AEADSpec(block.Headers[aeadHeader]) == spec {
+		return nil
+	}
+	plain, err := ks.decryptBlock(block)
+	if err != nil {
+		return err
+	}
+	sealed, err := sealBlock(plain, ks.pwd, spec)
+	if err != nil {
+		return err
+	}
+	sealed.Headers[checksumHeader] = keyChecksum(sealed)
+	return writeFileAtomic(path, pem.EncodeToMemory(sealed), 0600)
+}
+
+// readAEAD - Reads AEAD marker of the store, empty if there is none.
+func (ks *fileBasedKeyStore) readAEAD() (AEADSpec, error) {
+	raw, err := ioutil.ReadFile(filepath.Join(ks.path, KeyStoreAEADFile))
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("Failed reading KeyStore AEAD [%s]", err)
+	}
+	spec := AEADSpec(strings.TrimSpace(string(raw)))
+	if !spec.valid() {
+		return "", fmt.Errorf("Unsupported KeyStore AEAD %q at [%s]", spec, ks.path)
+	}
+	return spec, nil
+}
+
+// encodeEntry - Encodes key to PEM with encode and encrypts it with AEAD
+// of the store, if set, or with legacy PEM encryption otherwise.
+func (ks *fileBasedKeyStore) encodeEntry(encode func(pwd []byte) ([]byte, error)) ([]byte, error) {
+	ks.m.Lock()
+	spec := ks.aead
+	ks.m.Unlock()
+	if spec == "" || len(ks.pwd) == 0 {
+		return encode(ks.pwd)
+	}
+	raw, err := encode(nil)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, errors.New("Invalid PEM. It must not be empty.")
+	}
+	sealed, err := sealBlock(block, ks.pwd, spec)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(sealed), nil
+}
+
+// decodeEntry - Decrypts AEAD encrypted entry to plain PEM. Entries
+// without AEAD header are returned unchanged.
+func (ks *fileBasedKeyStore) decodeEntry(raw []byte) ([]byte, error) {
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, ErrKeyCorrupted
+	}
+	if _, ok := block.Headers[aeadHeader]; !ok {
+		return raw, nil
+	}
+	plain, err := openBlock(block, ks.pwd)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(plain), nil
+}
+
+// decryptBlock - Decrypts AEAD, legacy encrypted or plain PEM block.
+func (ks *fileBasedKeyStore) decryptBlock(block *pem.Block) (*pem.Block, error) {
+	if _, ok := block.Headers[aeadHeader]; ok {
+		return openBlock(block, ks.pwd)
+	}
+	if !x509.IsEncryptedPEMBlock(block) {
+		return &pem.Block{Type: block.Type, Bytes: block.Bytes}, nil
+	}
+	decrypted, err := x509.DecryptPEMBlock(block, ks.pwd)
+	if err != nil {
+		return nil, fmt.Errorf("Failed PEM decryption [%s]", err)
+	}
+	return &pem.Block{Type: block.Type, Bytes: decrypted}, nil
+}
+
+// sealBlock - Encrypts plain PEM block with spec and key derived from pwd.
+// Block type is authenticated as additional data.
+func sealBlock(block *pem.Block, pwd []byte, spec AEADSpec) (*pem.Block, error) {
+	salt := make([]byte, minKDFSaltSize)
+	nonce := make([]byte, aeadNonceSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("Failed generating salt [%s]", err)
+	}
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("Failed generating nonce [%s]", err)
+	}
+	key, err := scrypt.Key(pwd, salt, minScryptN, minScryptR, 1, 32)
+	if err != nil {
+		return nil, fmt.Errorf("Failed deriving key [%s]", err)
+	}
+	defer wipe(key)
+
+	var sealed []byte
+	switch spec {
+	case AEADAESGCM:
+		aead, err := newGCM(key)
+		if err != nil {
+			return nil, err
+		}
+		sealed = aead.Seal(nil, nonce, block.Bytes, []byte(block.Type))
+	case AEADAESGCMSIV:
+		if sealed, err = gcmsivSeal(key, nonce, block.Bytes, []byte(block.Type)); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("Unsupported AEAD [%s]", spec)
+	}
+	return &pem.Block{
+		Type: block.Type,
+		Headers: map[string]string{
+			aeadHeader:      string(spec),
+			aeadSaltHeader:  hex.EncodeToString(salt),
+			aeadNonceHeader: hex.EncodeToString(nonce),
+		},
+		Bytes: sealed,
+	}, nil
+}
+
+// openBlock - Decrypts PEM block encrypted by sealBlock.
+func openBlock(block *pem.Block, pwd []byte) (*pem.Block, error) {
+	if len(pwd) == 0 {
+		return nil, errors.New("Encrypted Key. Need a password")
+	}
+	salt, err := hex.DecodeString(block.Headers[aeadSaltHeader])
+	if err != nil || len(salt) < minKDFSaltSize {
+		return nil, errors.New("Invalid AEAD salt header.")
+	}
+	nonce, err := hex.DecodeString(block.Headers[aeadNonceHeader])
+	if err != nil || len(nonce) != aeadNonceSize {
+		return nil, errors.New("Invalid AEAD nonce header.")
+	}
+	key, err := scrypt.Key(pwd, salt, minScryptN, minScryptR, 1, 32)
+	if err != nil {
+		return nil, fmt.Errorf("Failed deriving key [%s]", err)
+	}
+	defer wipe(key)
+
+	var plain []byte
+	switch spec := AEADSpec(block.Headers[aeadHeader]); spec {
+	case AEADAESGCM:
+		aead, err := newGCM(key)
+		if err != nil {
+			return nil, err
+		}
+		plain, err = aead.Open(nil, nonce, block.Bytes, []byte(block.Type))
+		if err != nil {
+			return nil, fmt.Errorf("Failed AEAD decryption [%s]", err)
+		}
+	case AEADAESGCMSIV:
+		plain, err = gcmsivOpen(key, nonce, block.Bytes, []byte(block.Type))
+		if err != nil {
+			return nil, fmt.Errorf("Failed AEAD decryption [%s]", err)
+		}
+	default:
+		return nil, fmt.Errorf("Unsupported AEAD [%s]", spec)
+	}
+	return &pem.Block{Type: block.Type, Bytes: plain}, nil
+}
+
+// isKeyFile - Returns true if file name is of a key file.
+func isKeyFile(name string) bool {
+	sep := strings.LastIndexByte(name, '_')
+	if sep <= 0 {
+		return false
+	}
+	switch name[sep+1:] {
+	case "sk", "pk", "key":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,176 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+)
+
+// entryAEADs - Returns AEAD header of every key file in the store.
+func entryAEADs(t *testing.T, path string) map[string]AEADSpec {
+	files, err := ioutil.ReadDir(path)
+	assert.NoError(t, err)
+	specs := make(map[string]AEADSpec)
+	for _, f := range files {
+		if !isKeyFile(f.Name()) {
+			continue
+		}
+		raw, err := ioutil.ReadFile(filepath.Join(path, f.Name()))
+		assert.NoError(t, err)
+		block, _ := pem.Decode(raw)
+		specs[f.Name()] = AEADSpec(block.Headers[aeadHeader])
+	}
+	return specs
+}
+
+func TestReEncryptStore(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "bccspks")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	ksPath := filepath.Join(tempDir, "bccspks")
+	pwd := []byte("password")
+
+	// Store of version 1, as created before AEAD support
+	assert.NoError(t, os.MkdirAll(ksPath, 0755))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(ksPath, KeyStoreVersionFile), []byte("1\n"), 0600))
+	ks, err := NewFileBasedKeyStore(pwd, ksPath, false)
+	assert.NoError(t, err)
+	csp, err := NewDefaultSecurityLevelWithKeystore(ks)
+	assert.NoError(t, err)
+
+	var keys []bccsp.Key
+	for _, opts := range []bccsp.KeyGenOpts{
+		&bccsp.ECDSAP256KeyGenOpts{Temporary: false},
+		&bccsp.ECDSAP384KeyGenOpts{Temporary: false},
+		&bccsp.AES256KeyGenOpts{Temporary: false},
+	} {
+		k, err := csp.KeyGen(opts)
+		assert.NoError(t, err)
+		keys = append(keys, k)
+	}
+	// Keys are stored with legacy PEM encryption
+	for name, spec := range entryAEADs(t, ksPath) {
+		assert.Empty(t, spec, name)
+	}
+
+	// checkKeys - Asserts all keys are loaded and usable.
+	checkKeys := func(ks bccsp.KeyStore) {
+		csp, err := NewDefaultSecurityLevelWithKeystore(ks)
+		assert.NoError(t, err)
+		digest := sha256.Sum256([]byte("message"))
+		for _, k := range keys {
+			loaded, err := ks.Key(k.SKI())
+			if !assert.NoError(t, err) {
+				continue
+			}
+			assert.Equal(t, k.SKI(), loaded.SKI())
+			if loaded.Symmetric() {
+				assert.Equal(t, k.(*aesPrivateKey).privKey, loaded.(*aesPrivateKey).privKey)
+				continue
+			}
+			var opts bccsp.SignerOpts
+			if _, ok := loaded.(*rsaPrivateKey); ok {
+				opts = &rsa.PSSOptions{SaltLength: 32, Hash: crypto.SHA256}
+			}
+			sig, err := csp.Sign(loaded, digest[:], opts)
+			assert.NoError(t, err)
+			valid, err := csp.Verify(k, sig, digest[:], opts)
+			assert.NoError(t, err)
+			assert.True(t, valid)
+		}
+	}
+
+	// Store is upgraded to version 2 on re-encryption
+	readVersion := func() string {
+		raw, err := ioutil.ReadFile(filepath.Join(ksPath, KeyStoreVersionFile))
+		assert.NoError(t, err)
+		return string(raw)
+	}
+	assert.Equal(t, "1\n", readVersion())
+	reEncrypter := ks.(StoreReEncrypter)
+	assert.NoError(t, reEncrypter.ReEncryptStore(AEADAESGCM))
+	assert.Equal(t, "2\n", readVersion())
+	for name, spec := range entryAEADs(t, ksPath) {
+		assert.Equal(t, AEADAESGCM, spec, name)
+	}
+	checkKeys(ks)
+
+	// New keys are stored with the new AEAD, including RSA keys
+	// unsupported by legacy PEM encryption
+	k, err := csp.KeyGen(&bccsp.RSA2048KeyGenOpts{Temporary: false})
+	assert.NoError(t, err)
+	keys = append(keys, k)
+	assert.Equal(t, AEADAESGCM, entryAEADs(t, ksPath)[hex.EncodeToString(k.SKI())+"_sk"])
+	checkKeys(ks)
+
+	// Interrupted migration leaves mixed store readable
+	defer func(rename func(string, string) error) { renameFile = rename }(renameFile)
+	renames := 0
+	renameFile = func(from, to string) error {
+		renames++
+		if renames > 3 {
+			return errors.New("disk full")
+		}
+		return os.Rename(from, to)
+	}
+	err = reEncrypter.ReEncryptStore(AEADAESGCMSIV)
+	assert.Error(t, err)
+	counts := make(map[AEADSpec]int)
+	for _, spec := range entryAEADs(t, ksPath) {
+		counts[spec]++
+	}
+	assert.Equal(t, map[AEADSpec]int{AEADAESGCM: 2, AEADAESGCMSIV: 2}, counts)
+	checkKeys(ks)
+
+	// Migration is resumed
+	renameFile = os.Rename
+	assert.NoError(t, reEncrypter.ReEncryptStore(AEADAESGCMSIV))
+	for name, spec := range entryAEADs(t, ksPath) {
+		assert.Equal(t, AEADAESGCMSIV, spec, name)
+	}
+	checkKeys(ks)
+
+	// AEAD is restored when store is reopened
+	reopened, err := NewFileBasedKeyStore(pwd, ksPath, false)
+	assert.NoError(t, err)
+	assert.Equal(t, AEADAESGCMSIV, reopened.(*fileBasedKeyStore).aead)
+	assert.Equal(t, "2\n", readVersion())
+	checkKeys(reopened)
+
+	wrong, err := NewFileBasedKeyStore([]byte("wrong"), ksPath, true)
+	assert.NoError(t, err)
+	_, err = wrong.Key(keys[0].SKI())
+	assert.Error(t, err)
+
+	assert.Error(t, reEncrypter.ReEncryptStore("AES-128-CBC"))
+	assert.Error(t, wrong.(StoreReEncrypter).ReEncryptStore(AEADAESGCM))
+	plain, err := NewFileBasedKeyStore(nil, filepath.Join(tempDir, "plain"), false)
+	assert.NoError(t, err)
+	assert.Error(t, plain.(StoreReEncrypter).ReEncryptStore(AEADAESGCM))
+}
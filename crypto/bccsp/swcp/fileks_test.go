@@ -20,15 +20,19 @@ import (
 	"crypto/elliptic"
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
 	"github.com/ipfn/ipfn/pkg/crypto/bccsp/utils"
+	"github.com/ipfn/ipfn/pkg/digest"
 )
 
 func TestInvalidStoreKey(t *testing.T) {
@@ -54,12 +58,12 @@ func TestInvalidStoreKey(t *testing.T) {
 		t.Fatal("Error should be different from nil in this case")
 	}
 
-	err = ks.StoreKey(&ecdsaPublicKey{nil})
+	err = ks.StoreKey(&ecdsaPublicKey{pubKey: nil})
 	if err == nil {
 		t.Fatal("Error should be different from nil in this case")
 	}
 
-	err = ks.StoreKey(&rsaPublicKey{nil})
+	err = ks.StoreKey(&rsaPublicKey{pubKey: nil})
 	if err == nil {
 		t.Fatal("Error should be different from nil in this case")
 	}
@@ -131,3 +135,193 @@ func TestReInitKeyStore(t *testing.T) {
 	err = fbKs.Init(nil, ksPath, false)
 	assert.EqualError(t, err, "KeyStore already initilized.")
 }
+
+func TestKeyStoreVersionMarker(t *testing.T) {
+	ksPath, err := ioutil.TempDir("", "bccspks")
+	assert.NoError(t, err)
+	defer os.RemoveAll(ksPath)
+
+	_, err = NewFileBasedKeyStore(nil, ksPath, false)
+	assert.NoError(t, err)
+
+	// New stores are created at the latest version
+	raw, err := ioutil.ReadFile(filepath.Join(ksPath, KeyStoreVersionFile))
+	assert.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("%d\n", KeyStoreFormatVersion), string(raw))
+}
+
+func TestKeyStoreLegacyFormat(t *testing.T) {
+	ksPath, err := ioutil.TempDir("", "bccspks")
+	assert.NoError(t, err)
+	defer os.RemoveAll(ksPath)
+
+	// Lay out a store by hand the way it was written before versioning
+	privKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	cspKey := &ecdsaPrivateKey{privKey}
+	rawKey, err := utils.PrivateKeyToPEM(privKey, nil)
+	assert.NoError(t, err)
+	name := hex.EncodeToString(cspKey.SKI()) + "_sk"
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(ksPath, name), rawKey, 0600))
+
+	ks, err := NewFileBasedKeyStore(nil, ksPath, true)
+	assert.NoError(t, err)
+
+	k, err := ks.Key(cspKey.SKI())
+	assert.NoError(t, err)
+	assert.Equal(t, cspKey.SKI(), k.SKI())
+
+	_, err = os.Stat(filepath.Join(ksPath, KeyStoreVersionFile))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestKeyStoreFutureFormat(t *testing.T) {
+	ksPath, err := ioutil.TempDir("", "bccspks")
+	assert.NoError(t, err)
+	defer os.RemoveAll(ksPath)
+
+	future := fmt.Sprintf("%d\n", KeyStoreFormatVersion+1)
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(ksPath, KeyStoreVersionFile), []byte(future), 0600))
+
+	_, err = NewFileBasedKeyStore(nil, ksPath, false)
+	expected := fmt.Sprintf("Unsupported KeyStore format version %d at [%s], at most %d is supported", KeyStoreFormatVersion+1, ksPath, KeyStoreFormatVersion)
+	assert.EqualError(t, err, expected)
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(ksPath, KeyStoreVersionFile), []byte("garbage"), 0600))
+	_, err = NewFileBasedKeyStore(nil, ksPath, false)
+	assert.Error(t, err)
+}
+
+func TestKeyGenStoreFailureLeavesNoFiles(t *testing.T) {
+	ksPath, err := ioutil.TempDir("", "bccspks")
+	assert.NoError(t, err)
+	defer os.RemoveAll(ksPath)
+
+	ks, err := NewFileBasedKeyStore(nil, ksPath, false)
+	assert.NoError(t, err)
+	csp, err := NewWithParams(256, digest.FamilySha2, ks)
+	assert.NoError(t, err)
+
+	renameFile = func(string, string) error { return errors.New("injected failure") }
+	defer func() { renameFile = os.Rename }()
+
+	_, err = csp.KeyGen(&bccsp.ECDSAKeyGenOpts{Temporary: false})
+	assert.Error(t, err)
+	_, err = csp.KeyGen(&bccsp.AESKeyGenOpts{Temporary: false})
+	assert.Error(t, err)
+
+	files, err := ioutil.ReadDir(ksPath)
+	assert.NoError(t, err)
+	for _, f := range files {
+		assert.Equal(t, KeyStoreVersionFile, f.Name())
+	}
+
+	renameFile = os.Rename
+	k, err := csp.KeyGen(&bccsp.ECDSAKeyGenOpts{Temporary: false})
+	assert.NoError(t, err)
+	files, err = ioutil.ReadDir(ksPath)
+	assert.NoError(t, err)
+	assert.Len(t, files, 2)
+	_, err = os.Stat(filepath.Join(ksPath, hex.EncodeToString(k.SKI())+"_sk"))
+	assert.NoError(t, err)
+}
+
+func TestKeyStoreChecksum(t *testing.T) {
+	ksPath, err := ioutil.TempDir("", "bccspks")
+	assert.NoError(t, err)
+	defer os.RemoveAll(ksPath)
+
+	ks, err := NewFileBasedKeyStore(nil, ksPath, false)
+	assert.NoError(t, err)
+
+	privKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	keys := []struct {
+		suffix string
+		key    bccsp.Key
+	}{
+		{"sk", &ecdsaPrivateKey{privKey}},
+		{"pk", &ecdsaPublicKey{pubKey: &privKey.PublicKey}},
+		{"key", &aesPrivateKey{[]byte("0123456789abcdef0123456789abcdef"), false}},
+	}
+	for _, entry := range keys {
+		suffix, k := entry.suffix, entry.key
+		assert.NoError(t, ks.StoreKey(k))
+		path := filepath.Join(ksPath, hex.EncodeToString(k.SKI())+"_"+suffix)
+
+		// Untouched file loads cleanly
+		loaded, err := ks.Key(k.SKI())
+		assert.NoError(t, err, suffix)
+		assert.Equal(t, k.SKI(), loaded.SKI())
+
+		raw, err := ioutil.ReadFile(path)
+		assert.NoError(t, err)
+		assert.Contains(t, string(raw), checksumHeader+": ")
+
+		// Flip a byte in the middle of encoded key
+		corrupted := append([]byte{}, raw...)
+		i := len(corrupted) - 40
+		if corrupted[i] == 'A' {
+			corrupted[i] = 'B'
+		} else {
+			corrupted[i] = 'A'
+		}
+		assert.NoError(t, ioutil.WriteFile(path, corrupted, 0600))
+		_, err = ks.Key(k.SKI())
+		assert.Equal(t, ErrKeyCorrupted, err, suffix)
+
+		// Damaged checksum
+		corrupted = []byte(strings.Replace(string(raw), checksumHeader+": ", checksumHeader+": ff", 1))
+		assert.NoError(t, ioutil.WriteFile(path, corrupted, 0600))
+		_, err = ks.Key(k.SKI())
+		assert.Equal(t, ErrKeyCorrupted, err, suffix)
+
+		// private and public key share SKI
+		assert.NoError(t, os.Remove(path))
+	}
+}
+
+func TestKeyStoreLegacyChecksum(t *testing.T) {
+	ksPath, err := ioutil.TempDir("", "bccspks")
+	assert.NoError(t, err)
+	defer os.RemoveAll(ksPath)
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(ksPath, KeyStoreVersionFile), []byte("1\n"), 0600))
+	ks, err := NewFileBasedKeyStore(nil, ksPath, false)
+	assert.NoError(t, err)
+
+	// Key written without checksum to store of version 1 still loads
+	k := &aesPrivateKey{[]byte("0123456789abcdef0123456789abcdef"), false}
+	raw := utils.AEStoPEM(k.privKey)
+	path := filepath.Join(ksPath, hex.EncodeToString(k.SKI())+"_key")
+	assert.NoError(t, ioutil.WriteFile(path, raw, 0600))
+	loaded, err := ks.Key(k.SKI())
+	assert.NoError(t, err)
+	assert.Equal(t, k.SKI(), loaded.SKI())
+}
+
+func TestKeyStoreChecksumRequired(t *testing.T) {
+	ksPath, err := ioutil.TempDir("", "bccspks")
+	assert.NoError(t, err)
+	defer os.RemoveAll(ksPath)
+
+	version := fmt.Sprintf("%d\n", KeyStoreFormatVersion)
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(ksPath, KeyStoreVersionFile), []byte(version), 0600))
+	ks, err := NewFileBasedKeyStore(nil, ksPath, false)
+	assert.NoError(t, err)
+
+	// Key written without checksum is rejected
+	k := &aesPrivateKey{[]byte("0123456789abcdef0123456789abcdef"), false}
+	raw := utils.AEStoPEM(k.privKey)
+	path := filepath.Join(ksPath, hex.EncodeToString(k.SKI())+"_key")
+	assert.NoError(t, ioutil.WriteFile(path, raw, 0600))
+	_, err = ks.Key(k.SKI())
+	assert.Equal(t, ErrKeyCorrupted, err)
+
+	// Key stored with checksum loads
+	assert.NoError(t, os.Remove(path))
+	assert.NoError(t, ks.StoreKey(k))
+	loaded, err := ks.Key(k.SKI())
+	assert.NoError(t, err)
+	assert.Equal(t, k.SKI(), loaded.SKI())
+}
@@ -0,0 +1,122 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp/utils"
+)
+
+// fingerprintIndex - Maps key fingerprints to subject key identifiers.
+type fingerprintIndex struct {
+	sync.RWMutex
+
+	skis map[string][]byte
+	// loaded is true once keys of the keystore were indexed.
+	loaded bool
+}
+
+// IndexFingerprint adds the key to the fingerprint index used by
+// VerifyByFingerprint and returns its utils.KeyFingerprint value.
+// Keys stored by KeyGen, KeyDeriv, KeyImport and StoreKey are indexed
+// automatically, keys already present in the keystore are indexed on first
+// use of VerifyByFingerprint if the keystore implements KeyLister, otherwise
+// they have to be indexed explicitly. Symmetric keys are not indexed.
+func (csp *CSP) IndexFingerprint(k bccsp.Key) (string, error) {
+	fp, err := utils.KeyFingerprint(k)
+	if err != nil {
+		return "", err
+	}
+	csp.fingerprints.Lock()
+	if csp.fingerprints.skis == nil {
+		csp.fingerprints.skis = make(map[string][]byte)
+	}
+	csp.fingerprints.skis[fp] = k.SKI()
+	csp.fingerprints.Unlock()
+	return fp, nil
+}
+
+// indexStoredKey - Indexes fingerprint of stored asymmetric key.
+func (csp *CSP) indexStoredKey(k bccsp.Key) {
+	if k.Symmetric() {
+		return
+	}
+	if _, err := csp.IndexFingerprint(k); err != nil {
+		logger.Warningf("Failed indexing fingerprint of key [%x]: [%s]", k.SKI(), err)
+	}
+}
+
+// loadFingerprints - Indexes fingerprints of asymmetric keys present in the
+// keystore, once, if the keystore implements KeyLister.
+func (csp *CSP) loadFingerprints() {
+	csp.fingerprints.Lock()
+	defer csp.fingerprints.Unlock()
+	if csp.fingerprints.loaded {
+		return
+	}
+	csp.fingerprints.loaded = true
+	lister, ok := csp.ks.(KeyLister)
+	if !ok {
+		return
+	}
+	skis, err := lister.SKIs()
+	if err != nil {
+		logger.Warningf("Failed listing keys to index fingerprints: [%s]", err)
+		return
+	}
+	if csp.fingerprints.skis == nil {
+		csp.fingerprints.skis = make(map[string][]byte, len(skis))
+	}
+	for _, ski := range skis {
+		k, err := csp.ks.Key(ski)
+		if err != nil {
+			logger.Warningf("Failed loading key [%x] to index fingerprint: [%s]", ski, err)
+			continue
+		}
+		if k.Symmetric() {
+			continue
+		}
+		fp, err := utils.KeyFingerprint(k)
+		if err != nil {
+			logger.Warningf("Failed indexing fingerprint of key [%x]: [%s]", ski, err)
+			continue
+		}
+		csp.fingerprints.skis[fp] = k.SKI()
+	}
+}
+
+// VerifyByFingerprint verifies signature against digest using the key
+// with the utils.KeyFingerprint value fp, see IndexFingerprint.
+// Fingerprint is matched case-insensitively.
+func (csp *CSP) VerifyByFingerprint(fp string, signature, digest []byte, opts bccsp.SignerOpts) (bool, error) {
+	csp.loadFingerprints()
+	csp.fingerprints.RLock()
+	ski, ok := csp.fingerprints.skis[strings.ToLower(fp)]
+	csp.fingerprints.RUnlock()
+	if !ok {
+		return false, errors.Errorf("No key with fingerprint [%s].", fp)
+	}
+	k, err := csp.Key(ski)
+	if err != nil {
+		return false, err
+	}
+	return csp.Verify(k, signature, digest, opts)
+}
@@ -0,0 +1,93 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp/utils"
+)
+
+func TestVerifyByFingerprint(t *testing.T) {
+	provider, ks, cleanup := currentTestConfig.Provider(t)
+	defer cleanup()
+	csp := provider.(*CSP)
+
+	k, err := provider.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: false})
+	assert.NoError(t, err)
+	fp, err := utils.KeyFingerprint(k)
+	assert.NoError(t, err)
+
+	msg := []byte("Hello Fingerprint")
+	sig, err := provider.Sign(k, msg, nil)
+	assert.NoError(t, err)
+
+	valid, err := csp.VerifyByFingerprint(fp, sig, msg, nil)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+	valid, err = csp.VerifyByFingerprint(strings.ToUpper(fp), sig, msg, nil)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+	valid, err = csp.VerifyByFingerprint(fp, sig, []byte("Hello Tampered"), nil)
+	assert.NoError(t, err)
+	assert.False(t, valid)
+
+	// Fingerprint of public key matches the private key
+	pk, err := k.PublicKey()
+	assert.NoError(t, err)
+	pfp, err := utils.KeyFingerprint(pk)
+	assert.NoError(t, err)
+	assert.Equal(t, fp, pfp)
+
+	// Temporary keys are not stored nor indexed
+	tmp, err := provider.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	tfp, err := utils.KeyFingerprint(tmp)
+	assert.NoError(t, err)
+	_, err = csp.VerifyByFingerprint(tfp, sig, msg, nil)
+	assert.EqualError(t, err, "No key with fingerprint ["+tfp+"].")
+
+	// Keys stored before the CSP was created are indexed on first use
+	fresh, err := NewDefaultSecurityLevelWithKeystore(ks)
+	assert.NoError(t, err)
+	if _, isLister := ks.(KeyLister); isLister {
+		valid, err = fresh.(*CSP).VerifyByFingerprint(fp, sig, msg, nil)
+		assert.NoError(t, err)
+		assert.True(t, valid)
+	}
+
+	// or explicitly if keystore cannot list keys
+	fresh, err = NewDefaultSecurityLevelWithKeystore(struct{ bccsp.KeyStore }{ks})
+	assert.NoError(t, err)
+	_, err = fresh.(*CSP).VerifyByFingerprint(fp, sig, msg, nil)
+	assert.Error(t, err)
+	ifp, err := fresh.(*CSP).IndexFingerprint(k)
+	assert.NoError(t, err)
+	assert.Equal(t, fp, ifp)
+	valid, err = fresh.(*CSP).VerifyByFingerprint(fp, sig, msg, nil)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+
+	// Symmetric keys are not indexed
+	_, err = provider.KeyGen(&bccsp.AES256KeyGenOpts{Temporary: false})
+	assert.NoError(t, err)
+	_, err = csp.IndexFingerprint(&aesPrivateKey{privKey: make([]byte, 32)})
+	assert.Error(t, err)
+}
@@ -0,0 +1,123 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"crypto/elliptic"
+	"errors"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+	"github.com/ipfn/ipfn/pkg/digest"
+)
+
+// ErrNotPermittedFIPS - Error returned when FIPS mode CSP is requested to use
+// algorithm which is not FIPS 140 approved.
+var ErrNotPermittedFIPS = errors.New("algorithm not permitted in FIPS mode")
+
+// NewFIPS - Creates software-based BCCSP in FIPS mode.
+//
+// In FIPS mode only FIPS 140 approved algorithms are permitted:
+//
+//	ECDSA   curves P-224, P-256, P-384 and P-521 (FIPS 186-4)
+//	RSA     keys of at least 2048 bits (FIPS 186-4)
+//	AES     128, 192 and 256 bit keys, including HMAC keys (FIPS 197, 198-1)
+//	SHA-2   SHA-224, SHA-256, SHA-384 and SHA-512 (FIPS 180-4)
+//	SHA-3   SHA3-224, SHA3-256, SHA3-384 and SHA3-512 (FIPS 202)
+//
+// Generating, importing, deriving or using any other key and hashing with any
+// other function, such as Ed25519, secp256k1 or Keccak, fails with
+// ErrNotPermittedFIPS. So does key derivation with memory-hard functions,
+// scrypt and Argon2. This also applies to algorithms added with Register
+// functions.
+//
+// Notice that this mode restricts usage of algorithms, it does not make
+// the implementation FIPS 140 validated.
+func NewFIPS(securityLevel int, hashFamily digest.Family, keyStore bccsp.KeyStore) (bccsp.BCCSP, error) {
+	csp, err := NewWithParams(securityLevel, hashFamily, keyStore)
+	if err != nil {
+		return nil, err
+	}
+	csp.(*CSP).fips = true
+	return csp, nil
+}
+
+// fipsCurves - Curves approved in FIPS mode.
+var fipsCurves = map[string]bool{
+	elliptic.P224().Params().Name: true,
+	elliptic.P256().Params().Name: true,
+	elliptic.P384().Params().Name: true,
+	elliptic.P521().Params().Name: true,
+}
+
+// fipsHashes - Hash functions approved in FIPS mode.
+var fipsHashes = map[digest.Type]bool{
+	digest.Sha2_224: true,
+	digest.Sha2_256: true,
+	digest.Sha2_384: true,
+	digest.Sha2_512: true,
+	digest.Sha3_224: true,
+	digest.Sha3_256: true,
+	digest.Sha3_384: true,
+	digest.Sha3_512: true,
+}
+
+// fipsMinRSABits - Minimum RSA modulus size in FIPS mode.
+const fipsMinRSABits = 2048
+
+// checkFIPSKey - Returns ErrNotPermittedFIPS in FIPS mode if key is not approved.
+func (csp *CSP) checkFIPSKey(k bccsp.Key) error {
+	if !csp.fips {
+		return nil
+	}
+	approved := false
+	switch kk := k.(type) {
+	case *ecdsaPrivateKey:
+		approved = kk.privKey != nil && fipsCurves[kk.privKey.Curve.Params().Name]
+	case *ecdsaPublicKey:
+		approved = kk.pubKey != nil && fipsCurves[kk.pubKey.Curve.Params().Name]
+	case *rsaPrivateKey:
+		approved = kk.privKey != nil && kk.privKey.N.BitLen() >= fipsMinRSABits
+	case *rsaPublicKey:
+		approved = kk.pubKey != nil && kk.pubKey.N.BitLen() >= fipsMinRSABits
+	case *aesPrivateKey:
+		n := len(kk.privKey)
+		approved = n == 16 || n == 24 || n == 32
+	}
+	if !approved {
+		return ErrNotPermittedFIPS
+	}
+	return nil
+}
+
+// checkFIPSDerive - Returns ErrNotPermittedFIPS in FIPS mode if key
+// derivation function is not approved.
+func (csp *CSP) checkFIPSDerive(opts bccsp.KeyDerivOpts) error {
+	if !csp.fips {
+		return nil
+	}
+	if _, memoryHard := opts.(*bccsp.MemoryHardDeriveKeyOpts); memoryHard {
+		return ErrNotPermittedFIPS
+	}
+	return nil
+}
+
+// checkFIPSHash - Returns ErrNotPermittedFIPS in FIPS mode if hash is not approved.
+func (csp *CSP) checkFIPSHash(hashType digest.Type) error {
+	if csp.fips && !fipsHashes[hashType] {
+		return ErrNotPermittedFIPS
+	}
+	return nil
+}
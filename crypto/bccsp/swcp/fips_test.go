@@ -0,0 +1,98 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+	"github.com/ipfn/ipfn/pkg/digest"
+)
+
+func TestFIPSMode(t *testing.T) {
+	csp, err := NewFIPS(256, digest.FamilySha2, NewDummyKeyStore())
+	assert.NoError(t, err)
+
+	// Approved algorithms
+	msg := []byte("Hello World")
+	hash, err := csp.Hash(msg, digest.Sha2_256)
+	assert.NoError(t, err)
+	_, err = csp.Hash(msg, digest.Sha3_256)
+	assert.NoError(t, err)
+
+	for _, opts := range []bccsp.KeyGenOpts{
+		&bccsp.ECDSAP256KeyGenOpts{Temporary: true},
+		&bccsp.ECDSAP384KeyGenOpts{Temporary: true},
+		&bccsp.RSA2048KeyGenOpts{Temporary: true},
+	} {
+		k, err := csp.KeyGen(opts)
+		assert.NoError(t, err, opts.Algorithm())
+		pk, err := k.PublicKey()
+		assert.NoError(t, err)
+		var signerOpts bccsp.SignerOpts
+		if _, isRSA := k.(*rsaPrivateKey); isRSA {
+			signerOpts = &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: crypto.SHA256}
+		}
+		sig, err := csp.Sign(k, hash, signerOpts)
+		assert.NoError(t, err, opts.Algorithm())
+		valid, err := csp.Verify(pk, sig, hash, signerOpts)
+		assert.NoError(t, err, opts.Algorithm())
+		assert.True(t, valid)
+	}
+
+	aesKey, err := csp.KeyGen(&bccsp.AES256KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	ct, err := csp.Encrypt(aesKey, msg, &bccsp.AESCBCPKCS7ModeOpts{})
+	assert.NoError(t, err)
+	pt, err := csp.Decrypt(aesKey, ct, &bccsp.AESCBCPKCS7ModeOpts{})
+	assert.NoError(t, err)
+	assert.Equal(t, msg, pt)
+
+	// Non-approved algorithms
+	_, err = csp.Hash(msg, digest.Keccak256)
+	assert.Equal(t, ErrNotPermittedFIPS, err)
+	_, err = csp.Hasher(digest.Keccak256)
+	assert.Equal(t, ErrNotPermittedFIPS, err)
+	_, err = csp.KeyGen(&bccsp.ED25519KeyGenOpts{Temporary: true})
+	assert.Equal(t, ErrNotPermittedFIPS, err)
+	_, err = csp.KeyGen(&bccsp.RSA1024KeyGenOpts{Temporary: true})
+	assert.Equal(t, ErrNotPermittedFIPS, err)
+
+	_, err = csp.KeyDeriv(aesKey, &bccsp.MemoryHardDeriveKeyOpts{Temporary: true})
+	assert.Equal(t, ErrNotPermittedFIPS, err)
+	for _, size := range []int{8, 20, 64} {
+		_, err = csp.Encrypt(&aesPrivateKey{make([]byte, size), false}, msg, &bccsp.AESCBCPKCS7ModeOpts{})
+		assert.Equal(t, ErrNotPermittedFIPS, err, "size %d", size)
+	}
+
+	secp256k1 := &elliptic.CurveParams{Name: "secp256k1", BitSize: 256}
+	k1 := &ecdsaPublicKey{pubKey: &ecdsa.PublicKey{Curve: secp256k1, X: big.NewInt(1), Y: big.NewInt(1)}}
+	_, err = csp.Verify(k1, []byte{1}, hash, nil)
+	assert.Equal(t, ErrNotPermittedFIPS, err)
+
+	// The same operations are allowed outside of FIPS mode
+	plain, err := NewWithParams(256, digest.FamilySha2, NewDummyKeyStore())
+	assert.NoError(t, err)
+	_, err = plain.KeyGen(&bccsp.ED25519KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+}
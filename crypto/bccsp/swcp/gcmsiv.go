@@ -0,0 +1,243 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+)
+
+const (
+	// gcmsivNonceSize - Size of AES-GCM-SIV nonce.
+	gcmsivNonceSize = 12
+
+	// gcmsivTagSize - Size of AES-GCM-SIV tag.
+	gcmsivTagSize = 16
+)
+
+// gcmsivEncrypt - Encrypts plaintext with AES-GCM-SIV using options.
+// Nonce is prepended to sealed plaintext.
+func gcmsivEncrypt(key, plaintext []byte, opts *bccsp.AESGCMSIVModeOpts) ([]byte, error) {
+	nonce := opts.Nonce
+	if nonce == nil {
+		nonce = make([]byte, gcmsivNonceSize)
+		if _, err := rand.Read(nonce); err != nil {
+			return nil, fmt.Errorf("Failed generating nonce [%s]", err)
+		}
+	}
+	sealed, err := gcmsivSeal(key, nonce, plaintext, opts.AdditionalData)
+	if err != nil {
+		return nil, err
+	}
+	return append(append(make([]byte, 0, len(nonce)+len(sealed)), nonce...), sealed...), nil
+}
+
+// gcmsivDecrypt - Decrypts ciphertext created by gcmsivEncrypt.
+func gcmsivDecrypt(key, ciphertext []byte, opts *bccsp.AESGCMSIVModeOpts) ([]byte, error) {
+	if len(ciphertext) < gcmsivNonceSize+gcmsivTagSize {
+		return nil, errors.New("Invalid ciphertext. It is too short.")
+	}
+	nonce := ciphertext[:gcmsivNonceSize]
+	if opts.Nonce != nil && !bytes.Equal(opts.Nonce, nonce) {
+		return nil, errors.New("Invalid ciphertext. Nonce does not match options.")
+	}
+	return gcmsivOpen(key, nonce, ciphertext[gcmsivNonceSize:], opts.AdditionalData)
+}
+
+// gcmsivSeal - Encrypts and authenticates plaintext, returns ciphertext
+// followed by tag as defined by RFC 8452.
+func gcmsivSeal(key, nonce, plaintext, additionalData []byte) ([]byte, error) {
+	authKey, block, err := gcmsivDeriveKeys(key, nonce)
+	if err != nil {
+		return nil, err
+	}
+	tag := gcmsivTag(block, authKey, nonce, plaintext, additionalData)
+	out := make([]byte, len(plaintext)+gcmsivTagSize)
+	gcmsivCTR(block, tag, out, plaintext)
+	copy(out[len(plaintext):], tag[:])
+	return out, nil
+}
+
+// gcmsivOpen - Decrypts and verifies ciphertext followed by tag.
+// Plaintext is not returned unless the tag is valid.
+func gcmsivOpen(key, nonce, sealed, additionalData []byte) ([]byte, error) {
+	if len(sealed) < gcmsivTagSize {
+		return nil, errors.New("Invalid ciphertext. It is too short.")
+	}
+	authKey, block, err := gcmsivDeriveKeys(key, nonce)
+	if err != nil {
+		return nil, err
+	}
+	var tag [gcmsivTagSize]byte
+	copy(tag[:], sealed[len(sealed)-gcmsivTagSize:])
+	plaintext := make([]byte, len(sealed)-gcmsivTagSize)
+	gcmsivCTR(block, tag, plaintext, sealed[:len(plaintext)])
+	expected := gcmsivTag(block, authKey, nonce, plaintext, additionalData)
+	if subtle.ConstantTimeCompare(expected[:], tag[:]) != 1 {
+		for i := range plaintext {
+			plaintext[i] = 0
+		}
+		return nil, errors.New("Failed authenticating ciphertext.")
+	}
+	return plaintext, nil
+}
+
+// gcmsivDeriveKeys - Derives message authentication key and message
+// encryption cipher from key generating key and nonce.
+func gcmsivDeriveKeys(key, nonce []byte) ([16]byte, cipher.Block, error) {
+	var authKey [16]byte
+	if len(key) != 16 && len(key) != 32 {
+		return authKey, nil, fmt.Errorf("Invalid key length [%d]. AES-GCM-SIV requires 128 or 256 bit key.", len(key))
+	}
+	if len(nonce) != gcmsivNonceSize {
+		return authKey, nil, fmt.Errorf("Invalid nonce length [%d]. It must be %d bytes.", len(nonce), gcmsivNonceSize)
+	}
+	kgk, err := aes.NewCipher(key)
+	if err != nil {
+		return authKey, nil, fmt.Errorf("Failed creating AES cipher [%s]", err)
+	}
+
+	// Every derived block contributes its first half
+	derived := make([]byte, 16+len(key))
+	var in, out [16]byte
+	copy(in[4:], nonce)
+	for i := 0; i < len(derived)/8; i++ {
+		binary.LittleEndian.PutUint32(in[:4], uint32(i))
+		kgk.Encrypt(out[:], in[:])
+		copy(derived[8*i:], out[:8])
+	}
+	copy(authKey[:], derived[:16])
+	block, err := aes.NewCipher(derived[16:])
+	if err != nil {
+		return authKey, nil, fmt.Errorf("Failed creating AES cipher [%s]", err)
+	}
+	return authKey, block, nil
+}
+
+// gcmsivTag - Computes tag of plaintext and additional data.
+func gcmsivTag(block cipher.Block, authKey [16]byte, nonce, plaintext, additionalData []byte) (tag [gcmsivTagSize]byte) {
+	var lengths [16]byte
+	binary.LittleEndian.PutUint64(lengths[:8], uint64(len(additionalData))*8)
+	binary.LittleEndian.PutUint64(lengths[8:], uint64(len(plaintext))*8)
+
+	p := newPolyval(authKey)
+	p.update(additionalData)
+	p.update(plaintext)
+	p.update(lengths[:])
+	s := p.sum()
+	for i := range nonce {
+		s[i] ^= nonce[i]
+	}
+	s[15] &= 0x7f
+	block.Encrypt(tag[:], s[:])
+	return
+}
+
+// gcmsivCTR - Encrypts src into dst in counter mode with initial counter
+// block derived from tag. Counter is 32 bit little-endian.
+func gcmsivCTR(block cipher.Block, tag [gcmsivTagSize]byte, dst, src []byte) {
+	counter := tag
+	counter[15] |= 0x80
+	var stream [16]byte
+	for i := 0; i < len(src); i += 16 {
+		block.Encrypt(stream[:], counter[:])
+		end := i + 16
+		if end > len(src) {
+			end = len(src)
+		}
+		for j := i; j < end; j++ {
+			dst[j] = src[j] ^ stream[j-i]
+		}
+		binary.LittleEndian.PutUint32(counter[:4], binary.LittleEndian.Uint32(counter[:4])+1)
+	}
+}
+
+// polyval - POLYVAL universal hash of RFC 8452.
+//
+// It is computed in GHASH representation, see RFC 8452 Appendix A:
+// POLYVAL(H, X) = ByteReverse(GHASH(mulX_GHASH(ByteReverse(H)), ByteReverse(X))).
+type polyval struct {
+	h, s gf128
+}
+
+// gf128 - GHASH field element, big-endian halves.
+type gf128 struct {
+	hi, lo uint64
+}
+
+func newPolyval(key [16]byte) *polyval {
+	h := gf128FromReversed(key[:])
+	return &polyval{h: h.mulX()}
+}
+
+// update - Absorbs data zero padded to block size.
+func (p *polyval) update(data []byte) {
+	var block [16]byte
+	for len(data) > 0 {
+		n := copy(block[:], data)
+		for i := n; i < 16; i++ {
+			block[i] = 0
+		}
+		data = data[n:]
+		x := gf128FromReversed(block[:])
+		p.s = gf128{p.s.hi ^ x.hi, p.s.lo ^ x.lo}.mul(p.h)
+	}
+}
+
+// sum - Returns POLYVAL of absorbed blocks.
+func (p *polyval) sum() (out [16]byte) {
+	binary.LittleEndian.PutUint64(out[:8], p.s.lo)
+	binary.LittleEndian.PutUint64(out[8:], p.s.hi)
+	return
+}
+
+// gf128FromReversed - Reads byte reversed 16 bytes block as field element.
+func gf128FromReversed(b []byte) gf128 {
+	return gf128{binary.LittleEndian.Uint64(b[8:]), binary.LittleEndian.Uint64(b[:8])}
+}
+
+// mulX - Multiplies element by x in GHASH representation.
+func (x gf128) mulX() gf128 {
+	mask := -(x.lo & 1)
+	return gf128{x.hi>>1 ^ 0xe100000000000000&mask, x.lo>>1 | x.hi<<63}
+}
+
+// mul - Multiplies elements in GHASH representation in constant time,
+// see NIST SP 800-38D Algorithm 1.
+func (x gf128) mul(y gf128) (z gf128) {
+	v := y
+	for i := 0; i < 128; i++ {
+		var bit uint64
+		if i < 64 {
+			bit = x.hi >> uint(63-i) & 1
+		} else {
+			bit = x.lo >> uint(127-i) & 1
+		}
+		mask := -bit
+		z.hi ^= v.hi & mask
+		z.lo ^= v.lo & mask
+		v = v.mulX()
+	}
+	return
+}
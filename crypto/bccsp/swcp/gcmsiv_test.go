@@ -0,0 +1,115 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+)
+
+func TestGCMSIVVectors(t *testing.T) {
+	// RFC 8452 Appendix C
+	for i, v := range []struct {
+		key, nonce, plaintext, aad, result string
+	}{
+		{"01000000000000000000000000000000", "030000000000000000000000", "", "",
+			"dc20e2d83f25705bb49e439eca56de25"},
+		{"01000000000000000000000000000000", "030000000000000000000000", "0100000000000000", "",
+			"b5d839330ac7b786578782fff6013b815b287c22493a364c"},
+		{"01000000000000000000000000000000", "030000000000000000000000", "010000000000000000000000", "",
+			"7323ea61d05932260047d942a4978db357391a0bc4fdec8b0d106639"},
+		{"01000000000000000000000000000000", "030000000000000000000000", "0200000000000000", "01",
+			"1e6daba35669f4273b0a1a2560969cdf790d99759abd1508"},
+		{"0100000000000000000000000000000000000000000000000000000000000000", "030000000000000000000000", "", "",
+			"07f5f4169bbf55a8400cd47ea6fd400f"},
+		{"0100000000000000000000000000000000000000000000000000000000000000", "030000000000000000000000", "0100000000000000", "",
+			"c2ef328e5c71c83b843122130f7364b761e0b97427e3df28"},
+	} {
+		key, _ := hex.DecodeString(v.key)
+		nonce, _ := hex.DecodeString(v.nonce)
+		plaintext, _ := hex.DecodeString(v.plaintext)
+		aad, _ := hex.DecodeString(v.aad)
+
+		sealed, err := gcmsivSeal(key, nonce, plaintext, aad)
+		assert.NoError(t, err, "vector %d", i)
+		assert.Equal(t, v.result, hex.EncodeToString(sealed), "vector %d", i)
+
+		opened, err := gcmsivOpen(key, nonce, sealed, aad)
+		assert.NoError(t, err, "vector %d", i)
+		assert.Equal(t, hex.EncodeToString(plaintext), hex.EncodeToString(opened), "vector %d", i)
+	}
+}
+
+func TestAESGCMSIVEncryptDecrypt(t *testing.T) {
+	provider, _, cleanup := currentTestConfig.Provider(t)
+	defer cleanup()
+
+	msg := []byte("Hello GCM-SIV, longer than a single block of AES")
+	for _, keyGen := range []bccsp.KeyGenOpts{
+		&bccsp.AES128KeyGenOpts{Temporary: true},
+		&bccsp.AES256KeyGenOpts{Temporary: true},
+	} {
+		k, err := provider.KeyGen(keyGen)
+		assert.NoError(t, err)
+
+		// Random nonce
+		opts := &bccsp.AESGCMSIVModeOpts{AdditionalData: []byte("header")}
+		ct1, err := provider.Encrypt(k, msg, opts)
+		assert.NoError(t, err)
+		ct2, err := provider.Encrypt(k, msg, opts)
+		assert.NoError(t, err)
+		assert.NotEqual(t, ct1, ct2)
+		pt, err := provider.Decrypt(k, ct1, opts)
+		assert.NoError(t, err)
+		assert.Equal(t, msg, pt)
+
+		// Fixed nonce is deterministic
+		opts = &bccsp.AESGCMSIVModeOpts{Nonce: make([]byte, 12)}
+		ct1, err = provider.Encrypt(k, msg, opts)
+		assert.NoError(t, err)
+		ct2, err = provider.Encrypt(k, msg, *opts)
+		assert.NoError(t, err)
+		assert.Equal(t, ct1, ct2)
+		pt, err = provider.Decrypt(k, ct1, &bccsp.AESGCMSIVModeOpts{})
+		assert.NoError(t, err)
+		assert.Equal(t, msg, pt)
+
+		// Tampering and mismatched additional data
+		tampered := append([]byte{}, ct1...)
+		tampered[20] ^= 1
+		_, err = provider.Decrypt(k, tampered, opts)
+		assert.Error(t, err)
+		_, err = provider.Decrypt(k, ct1, &bccsp.AESGCMSIVModeOpts{AdditionalData: []byte("header")})
+		assert.Error(t, err)
+		_, err = provider.Decrypt(k, ct1, &bccsp.AESGCMSIVModeOpts{Nonce: []byte("other nonce!")})
+		assert.Error(t, err)
+		_, err = provider.Decrypt(k, ct1[:20], opts)
+		assert.Error(t, err)
+
+		_, err = provider.Encrypt(k, msg, &bccsp.AESGCMSIVModeOpts{Nonce: []byte("short")})
+		assert.Error(t, err)
+	}
+
+	k, err := provider.KeyGen(&bccsp.AES192KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	_, err = provider.Encrypt(k, msg, &bccsp.AESGCMSIVModeOpts{})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "AES-GCM-SIV requires 128 or 256 bit key.")
+}
@@ -16,12 +16,39 @@
 package swcp
 
 import (
+	stdsha256 "crypto/sha256"
+	"crypto/sha512"
 	"fmt"
 	"hash"
 
+	"github.com/minio/sha256-simd"
+	"golang.org/x/crypto/sha3"
+
 	"github.com/ipfn/ipfn/pkg/digest"
 )
 
+// hashFunctions maps digest types to hash constructors
+// that can be used to instantiate HMACs.
+var hashFunctions = map[digest.Type]func() hash.Hash{
+	digest.Sha2_224: stdsha256.New224,
+	digest.Sha2_256: sha256.New,
+	digest.Sha2_384: sha512.New384,
+	digest.Sha2_512: sha512.New,
+	digest.Sha3_224: sha3.New224,
+	digest.Sha3_256: sha3.New256,
+	digest.Sha3_384: sha3.New384,
+	digest.Sha3_512: sha3.New512,
+}
+
+// hashFunction returns hash constructor for given digest type.
+func hashFunction(algo digest.Type) (func() hash.Hash, error) {
+	h, ok := hashFunctions[algo]
+	if !ok {
+		return nil, fmt.Errorf("Unsupported hash type [%s]", algo)
+	}
+	return h, nil
+}
+
 type hasher struct {
 	algo digest.Type
 	impl func() hash.Hash
@@ -24,6 +24,7 @@ import (
 	"github.com/ipfn/ipfn/pkg/crypto/bccsp/swcp/mocks"
 	"github.com/ipfn/ipfn/pkg/digest"
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/sha3"
 )
 
 func TestHash(t *testing.T) {
@@ -106,3 +107,37 @@ func TestHasherSha256(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, hf, sha256.New())
 }
+
+func TestAddHasherNonCryptographic(t *testing.T) {
+	t.Parallel()
+
+	csp := CSP{hashers: make(map[digest.Type]bccsp.Hasher)}
+	err := csp.AddHasher(digest.XXH3, &mocks.Hasher{})
+	assert.EqualError(t, err, "hash type xxh3-64 is not cryptographic")
+	_, err = csp.Hash([]byte("Hello World"), digest.XXH3)
+	assert.Error(t, err)
+}
+
+func TestHash224(t *testing.T) {
+	provider, _, cleanup := currentTestConfig.Provider(t)
+	defer cleanup()
+
+	msg := []byte("abc")
+	out, err := provider.Hash(msg, digest.Sha2_224)
+	assert.NoError(t, err)
+	expected := sha256.Sum224(msg)
+	assert.Equal(t, expected[:], out)
+	assert.Equal(t, digest.SumSha224(msg), out)
+
+	out, err = provider.Hash(msg, digest.Sha3_224)
+	assert.NoError(t, err)
+	expected3 := sha3.Sum224(msg)
+	assert.Equal(t, expected3[:], out)
+	assert.Equal(t, digest.SumSha3_224(msg), out)
+
+	for _, hashType := range []digest.Type{digest.Sha2_224, digest.Sha3_224} {
+		h, err := hashFunction(hashType)
+		assert.NoError(t, err)
+		assert.Equal(t, 28, h().Size())
+	}
+}
@@ -16,8 +16,12 @@
 package swcp
 
 import (
+	"crypto/rand"
 	"hash"
+	"io"
 	"reflect"
+	"sync/atomic"
+	"time"
 
 	"github.com/pkg/errors"
 
@@ -35,6 +39,8 @@ var (
 // following algorithm-based wrappers: KeyGenerator, KeyDeriver, KeyImporter,
 // Encryptor, Decryptor, Signer, Verifier, Hasher. Each wrapper is bound to a
 // goland type representing either an option or a key.
+// Wrappers for custom algorithms can be registered using RegisterKeyGenerator,
+// RegisterSigner, RegisterVerifier, RegisterEncryptor and RegisterDecryptor.
 type CSP struct {
 	ks bccsp.KeyStore
 
@@ -46,10 +52,60 @@ type CSP struct {
 	signers       map[reflect.Type]bccsp.Signer
 	verifiers     map[reflect.Type]bccsp.Verifier
 	hashers       map[digest.Type]bccsp.Hasher
+
+	// hashType is the hash applied to messages that are not prehashed.
+	hashType digest.Type
+
+	revoked    revocationList
+	ephemeral  ephemeralKeys
+	audit      auditState
+	signerOpts defaultSignerOpts
+
+	// fips restricts algorithms to FIPS approved ones, see NewFIPS.
+	fips bool
+
+	// maxInputBytes limits size of inputs, see SetMaxInputBytes.
+	maxInputBytes int64
+
+	// deterministicECDSA enables RFC 6979 nonces by default, see SetDeterministicECDSA.
+	deterministicECDSA int32
+
+	rsaImport rsaImportPolicy
+
+	fingerprints fingerprintIndex
+
+	// verifyCache memoizes verification results, see SetVerifyCacheSize.
+	verifyCache verifyCache
+
+	// observer holds Observer of operations, see SetObserver.
+	observer atomic.Value
+
+	// sigProcessors holds signature hooks, see SetSignatureProcessors.
+	sigProcessors atomic.Value
+
+	// opCosts caches calibrated operation costs, see EstimateOpCost.
+	opCosts opCostCache
+
+	// rng is the random source of the CSP checked by HealthCheckRNG.
+	rng io.Reader
 }
 
-// New - Creates new software implemented BCCSP.
+// New - Creates new software implemented BCCSP
+// with wrappers registered for custom algorithms.
 func New(keyStore bccsp.KeyStore) (*CSP, error) {
+	csp, err := newCSP(keyStore)
+	if err != nil {
+		return nil, err
+	}
+
+	// Bind wrappers registered for custom algorithms
+	bindRegistered(csp)
+
+	return csp, nil
+}
+
+// newCSP - Creates new software implemented BCCSP without wrappers.
+func newCSP(keyStore bccsp.KeyStore) (*CSP, error) {
 	if keyStore == nil {
 		return nil, errors.Errorf("Invalid bccsp.KeyStore instance. It must be different from nil")
 	}
@@ -63,10 +119,18 @@ func New(keyStore bccsp.KeyStore) (*CSP, error) {
 	keyImporters := make(map[reflect.Type]bccsp.KeyImporter)
 	hashers := make(map[digest.Type]bccsp.Hasher)
 
-	csp := &CSP{keyStore,
-		keyGenerators, keyDerivers, keyImporters, encryptors,
-		decryptors, signers, verifiers, hashers}
-
+	csp := &CSP{
+		ks:            keyStore,
+		keyGenerators: keyGenerators,
+		keyDerivers:   keyDerivers,
+		keyImporters:  keyImporters,
+		encryptors:    encryptors,
+		decryptors:    decryptors,
+		signers:       signers,
+		verifiers:     verifiers,
+		hashers:       hashers,
+		rng:           rand.Reader,
+	}
 	return csp, nil
 }
 
@@ -79,7 +143,14 @@ func (csp *CSP) ReadOnly() bool {
 // StoreKey stores the key k in this KeyStore.
 // If this KeyStore is read only then the method will fail.
 func (csp *CSP) StoreKey(k bccsp.Key) (err error) {
-	return csp.ks.StoreKey(k)
+	if csp.isEphemeral(k) {
+		return ErrEphemeralKey
+	}
+	if err = csp.ks.StoreKey(k); err != nil {
+		return err
+	}
+	csp.indexStoredKey(k)
+	return nil
 }
 
 // KeyGen generates a key using opts.
@@ -99,6 +170,10 @@ func (csp *CSP) KeyGen(opts bccsp.KeyGenOpts) (k bccsp.Key, err error) {
 		return nil, errors.Wrapf(err, "Failed generating key with opts [%v]", opts)
 	}
 
+	if err = csp.checkFIPSKey(k); err != nil {
+		return nil, err
+	}
+
 	// If the key is not Ephemeral, store it.
 	if !opts.Ephemeral() {
 		// Store the key
@@ -106,6 +181,9 @@ func (csp *CSP) KeyGen(opts bccsp.KeyGenOpts) (k bccsp.Key, err error) {
 		if err != nil {
 			return nil, errors.Wrapf(err, "Failed storing key [%s]", opts.Algorithm())
 		}
+		csp.indexStoredKey(k)
+	} else {
+		csp.markEphemeral(k)
 	}
 
 	return k, nil
@@ -127,11 +205,22 @@ func (csp *CSP) KeyDeriv(k bccsp.Key, opts bccsp.KeyDerivOpts) (dk bccsp.Key, er
 		return nil, errors.Errorf("Unsupported 'Key' provided [%v]", k)
 	}
 
+	if err = csp.checkFIPSKey(k); err != nil {
+		return nil, err
+	}
+	if err = csp.checkFIPSDerive(opts); err != nil {
+		return nil, err
+	}
+
 	k, err = keyDeriver.KeyDeriv(k, opts)
 	if err != nil {
 		return nil, errors.Wrapf(err, "Failed deriving key with opts [%v]", opts)
 	}
 
+	if err = csp.checkFIPSKey(k); err != nil {
+		return nil, err
+	}
+
 	// If the key is not Ephemeral, store it.
 	if !opts.Ephemeral() {
 		// Store the key
@@ -139,6 +228,9 @@ func (csp *CSP) KeyDeriv(k bccsp.Key, opts bccsp.KeyDerivOpts) (dk bccsp.Key, er
 		if err != nil {
 			return nil, errors.Wrapf(err, "Failed storing key [%s]", opts.Algorithm())
 		}
+		csp.indexStoredKey(k)
+	} else {
+		csp.markEphemeral(k)
 	}
 
 	return k, nil
@@ -147,6 +239,28 @@ func (csp *CSP) KeyDeriv(k bccsp.Key, opts bccsp.KeyDerivOpts) (dk bccsp.Key, er
 // KeyImport imports a key from its raw representation using opts.
 // The opts argument should be appropriate for the primitive used.
 func (csp *CSP) KeyImport(raw interface{}, opts bccsp.KeyImportOpts) (k bccsp.Key, err error) {
+	k, err = csp.importKey(raw, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// If the key is not Ephemeral, store it.
+	if !opts.Ephemeral() {
+		// Store the key
+		err = csp.ks.StoreKey(k)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed storing imported key with opts [%v]", opts)
+		}
+		csp.indexStoredKey(k)
+	} else {
+		csp.markEphemeral(k)
+	}
+
+	return
+}
+
+// importKey - Imports and validates key without storing it.
+func (csp *CSP) importKey(raw interface{}, opts bccsp.KeyImportOpts) (k bccsp.Key, err error) {
 	// Validate arguments
 	if raw == nil {
 		return nil, errors.New("Invalid raw. It must not be nil.")
@@ -165,13 +279,12 @@ func (csp *CSP) KeyImport(raw interface{}, opts bccsp.KeyImportOpts) (k bccsp.Ke
 		return nil, errors.Wrapf(err, "Failed importing key with opts [%v]", opts)
 	}
 
-	// If the key is not Ephemeral, store it.
-	if !opts.Ephemeral() {
-		// Store the key
-		err = csp.ks.StoreKey(k)
-		if err != nil {
-			return nil, errors.Wrapf(err, "Failed storing imported key with opts [%v]", opts)
-		}
+	if err = csp.checkFIPSKey(k); err != nil {
+		return nil, err
+	}
+
+	if err = csp.checkRSAImport(k); err != nil {
+		return nil, err
 	}
 
 	return
@@ -190,6 +303,14 @@ func (csp *CSP) Key(ski []byte) (k bccsp.Key, err error) {
 
 // Hash hashes messages msg using options opts.
 func (csp *CSP) Hash(msg []byte, hashType digest.Type) (digest []byte, err error) {
+	if err = csp.checkInputSize(len(msg)); err != nil {
+		return nil, err
+	}
+
+	if err = csp.checkFIPSHash(hashType); err != nil {
+		return nil, err
+	}
+
 	hasher, found := csp.hashers[hashType]
 	if !found {
 		return nil, errors.Errorf("Unsupported hash type [%v]", hashType)
@@ -206,6 +327,10 @@ func (csp *CSP) Hash(msg []byte, hashType digest.Type) (digest []byte, err error
 // Hasher returns and instance of hash.Hash using options opts.
 // If opts is nil then the default hash function is returned.
 func (csp *CSP) Hasher(hashType digest.Type) (h hash.Hash, err error) {
+	if err = csp.checkFIPSHash(hashType); err != nil {
+		return nil, err
+	}
+
 	hasher, found := csp.hashers[hashType]
 	if !found {
 		return nil, errors.Errorf("Unsupported hash type [%v]", hashType)
@@ -225,7 +350,17 @@ func (csp *CSP) Hasher(hashType digest.Type) (h hash.Hash, err error) {
 // Note that when a signature of a hash of a larger message is needed,
 // the caller is responsible for hashing the larger message and passing
 // the hash (as digest).
-func (csp *CSP) Sign(k bccsp.Key, digest []byte, opts bccsp.SignerOpts) (signature []byte, err error) {
+func (csp *CSP) Sign(k bccsp.Key, digest []byte, opts bccsp.SignerOpts) ([]byte, error) {
+	return csp.sign(k, digest, opts, true)
+}
+
+// sign - Signs digest, applying signature post-processor when process is
+// true. Helpers converting signature themselves sign without processing.
+func (csp *CSP) sign(k bccsp.Key, digest []byte, opts bccsp.SignerOpts, process bool) (signature []byte, err error) {
+	if observer := csp.loadObserver(); observer != nil {
+		defer observe(observer, OperationSign, k, time.Now(), &err, nil)
+	}
+
 	// Validate arguments
 	if k == nil {
 		return nil, errors.New("Invalid Key. It must not be nil.")
@@ -240,16 +375,53 @@ func (csp *CSP) Sign(k bccsp.Key, digest []byte, opts bccsp.SignerOpts) (signatu
 		return nil, errors.Errorf("Unsupported 'SignKey' provided [%s]", keyType)
 	}
 
+	if csp.isRevoked(k) {
+		return nil, ErrKeyRevoked
+	}
+
+	if err = csp.checkFIPSKey(k); err != nil {
+		return nil, err
+	}
+
+	opts = csp.resolveSignerOpts(k, opts)
+
+	digest, opts, err = csp.prehash(k, digest, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	opts = csp.resolveNonceOpts(k, opts)
+
 	signature, err = signer.Sign(k, digest, opts)
 	if err != nil {
 		return nil, errors.Wrapf(err, "Failed signing with opts [%v]", opts)
 	}
 
+	if process {
+		signature, err = csp.postProcessSignature(signature)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	csp.audit.append(k, digest, signature)
+
 	return
 }
 
 // Verify verifies signature against key k and digest
-func (csp *CSP) Verify(k bccsp.Key, signature, digest []byte, opts bccsp.SignerOpts) (valid bool, err error) {
+func (csp *CSP) Verify(k bccsp.Key, signature, digest []byte, opts bccsp.SignerOpts) (bool, error) {
+	return csp.verify(k, signature, digest, opts, true)
+}
+
+// verify - Verifies signature, applying signature pre-processor when process
+// is true. Helpers passing signature they built themselves, for example
+// converted to DER, verify without processing.
+func (csp *CSP) verify(k bccsp.Key, signature, digest []byte, opts bccsp.SignerOpts, process bool) (valid bool, err error) {
+	if observer := csp.loadObserver(); observer != nil {
+		defer observe(observer, OperationVerify, k, time.Now(), &err, &valid)
+	}
+
 	// Validate arguments
 	if k == nil {
 		return false, errors.New("Invalid Key. It must not be nil.")
@@ -266,7 +438,33 @@ func (csp *CSP) Verify(k bccsp.Key, signature, digest []byte, opts bccsp.SignerO
 		return false, errors.Errorf("Unsupported 'VerifyKey' provided [%v]", k)
 	}
 
-	valid, err = verifier.Verify(k, signature, digest, opts)
+	if csp.isRevokedOnVerify(k) {
+		return false, ErrKeyRevoked
+	}
+
+	if err = csp.checkFIPSKey(k); err != nil {
+		return false, err
+	}
+
+	opts = csp.resolveSignerOpts(k, opts)
+
+	digest, opts, err = csp.prehash(k, digest, opts)
+	if err != nil {
+		return false, err
+	}
+
+	if err = checkDigestHash(digest, opts); err != nil {
+		return false, err
+	}
+
+	if process {
+		signature, err = csp.preProcessSignature(signature)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	valid, err = csp.cachedVerify(verifier, k, signature, digest, opts)
 	if err != nil {
 		return false, errors.Wrapf(err, "Failed verifing with opts [%v]", opts)
 	}
@@ -276,7 +474,11 @@ func (csp *CSP) Verify(k bccsp.Key, signature, digest []byte, opts bccsp.SignerO
 
 // Encrypt encrypts plaintext using key k.
 // The opts argument should be appropriate for the primitive used.
-func (csp *CSP) Encrypt(k bccsp.Key, plaintext []byte, opts bccsp.EncrypterOpts) ([]byte, error) {
+func (csp *CSP) Encrypt(k bccsp.Key, plaintext []byte, opts bccsp.EncrypterOpts) (ciphertext []byte, err error) {
+	if observer := csp.loadObserver(); observer != nil {
+		defer observe(observer, OperationEncrypt, k, time.Now(), &err, nil)
+	}
+
 	// Validate arguments
 	if k == nil {
 		return nil, errors.New("Invalid Key. It must not be nil.")
@@ -287,12 +489,24 @@ func (csp *CSP) Encrypt(k bccsp.Key, plaintext []byte, opts bccsp.EncrypterOpts)
 		return nil, errors.Errorf("Unsupported 'EncryptKey' provided [%v]", k)
 	}
 
+	if err = csp.checkFIPSKey(k); err != nil {
+		return nil, err
+	}
+
+	if err = csp.checkInputSize(len(plaintext)); err != nil {
+		return nil, err
+	}
+
 	return encryptor.Encrypt(k, plaintext, opts)
 }
 
 // Decrypt decrypts ciphertext using key k.
 // The opts argument should be appropriate for the primitive used.
 func (csp *CSP) Decrypt(k bccsp.Key, ciphertext []byte, opts bccsp.DecrypterOpts) (plaintext []byte, err error) {
+	if observer := csp.loadObserver(); observer != nil {
+		defer observe(observer, OperationDecrypt, k, time.Now(), &err, nil)
+	}
+
 	// Validate arguments
 	if k == nil {
 		return nil, errors.New("Invalid Key. It must not be nil.")
@@ -303,6 +517,10 @@ func (csp *CSP) Decrypt(k bccsp.Key, ciphertext []byte, opts bccsp.DecrypterOpts
 		return nil, errors.Errorf("Unsupported 'DecryptKey' provided [%v]", k)
 	}
 
+	if err = csp.checkFIPSKey(k); err != nil {
+		return nil, err
+	}
+
 	plaintext, err = decryptor.Decrypt(k, ciphertext, opts)
 	if err != nil {
 		return nil, errors.Wrapf(err, "Failed decrypting with opts [%v]", opts)
@@ -343,7 +561,11 @@ func (csp *CSP) AddWrapper(t reflect.Type, w interface{}) error {
 }
 
 // AddHasher binds the passed type to the passed wrapper.
+// Non-cryptographic hash types are refused.
 func (csp *CSP) AddHasher(t digest.Type, hasher bccsp.Hasher) error {
+	if !t.Cryptographic() {
+		return errors.Errorf("hash type %s is not cryptographic", t)
+	}
 	if csp.hashers[t] != nil {
 		return errors.Errorf("hasher for type %s already implemented", t)
 	}
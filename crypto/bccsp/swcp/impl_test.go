@@ -1504,7 +1504,7 @@ func TestRSAKeyGenEphemeral(t *testing.T) {
 func TestRSAPublicKeyInvalidBytes(t *testing.T) {
 	t.Parallel()
 
-	rsaKey := &rsaPublicKey{nil}
+	rsaKey := &rsaPublicKey{pubKey: nil}
 	b, err := rsaKey.Bytes()
 	if err == nil {
 		t.Fatal("It must fail in this case")
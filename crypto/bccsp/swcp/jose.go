@@ -0,0 +1,116 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"crypto"
+	"crypto/elliptic"
+	"crypto/rsa"
+	_ "crypto/sha256" // hash functions of algorithms
+	_ "crypto/sha512"
+	"math/big"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/pkg/errors"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp/utils"
+)
+
+// joseAlg - Signature scheme of JOSE algorithm.
+type joseAlg struct {
+	hash  crypto.Hash
+	curve elliptic.Curve
+	pss   bool
+	rsa   bool
+}
+
+// joseAlgs - Supported JOSE signature algorithms (RFC 7518, RFC 8037 and
+// RFC 8812) by name.
+var joseAlgs = map[string]joseAlg{
+	"ES256":  {hash: crypto.SHA256, curve: elliptic.P256()},
+	"ES384":  {hash: crypto.SHA384, curve: elliptic.P384()},
+	"ES512":  {hash: crypto.SHA512, curve: elliptic.P521()},
+	"ES256K": {hash: crypto.SHA256, curve: btcec.S256()},
+	"PS256":  {hash: crypto.SHA256, rsa: true, pss: true},
+	"PS384":  {hash: crypto.SHA384, rsa: true, pss: true},
+	"PS512":  {hash: crypto.SHA512, rsa: true, pss: true},
+	"RS256":  {hash: crypto.SHA256, rsa: true},
+	"RS384":  {hash: crypto.SHA384, rsa: true},
+	"RS512":  {hash: crypto.SHA512, rsa: true},
+	"EdDSA":  {},
+}
+
+// VerifyWithAlg verifies detached signature of payload using key k and JOSE
+// algorithm alg, such as "ES256", "PS256", "RS256" or "EdDSA". Payload is
+// hashed with hash function of the algorithm, except for EdDSA. ECDSA
+// signatures are fixed size R || S as in JWS. Key must match the algorithm,
+// ECDSA keys must be on the curve of the algorithm. Signature processors
+// are not applied, JWS signatures are not produced by Sign.
+func (csp *CSP) VerifyWithAlg(k bccsp.Key, alg string, payload, sig []byte) (bool, error) {
+	if k == nil {
+		return false, errors.New("Invalid Key. It must not be nil.")
+	}
+	scheme, found := joseAlgs[alg]
+	if !found {
+		return false, errors.Errorf("Unsupported algorithm [%s]", alg)
+	}
+	if err := csp.checkInputSize(len(payload)); err != nil {
+		return false, err
+	}
+
+	var opts bccsp.SignerOpts
+	switch key := k.(type) {
+	case *ed25519PrivateKey, *ed25519PublicKey:
+		if alg != "EdDSA" {
+			return false, errors.Errorf("Invalid key for algorithm [%s]. Got Ed25519 key.", alg)
+		}
+		return csp.verify(k, sig, payload, nil, false)
+	case *rsaPrivateKey, *rsaPublicKey:
+		if !scheme.rsa {
+			return false, errors.Errorf("Invalid key for algorithm [%s]. Got RSA key.", alg)
+		}
+		opts = scheme.hash
+		if scheme.pss {
+			opts = &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: scheme.hash}
+		}
+	case *ecdsaPrivateKey, *ecdsaPublicKey:
+		var curve elliptic.Curve
+		if priv, ok := key.(*ecdsaPrivateKey); ok {
+			curve = priv.privKey.Curve
+		} else {
+			curve = key.(*ecdsaPublicKey).pubKey.Curve
+		}
+		if scheme.curve == nil || curve.Params().Name != scheme.curve.Params().Name {
+			return false, errors.Errorf("Invalid key for algorithm [%s]. Got ECDSA key on curve [%s].", alg, curve.Params().Name)
+		}
+		size := (scheme.curve.Params().BitSize + 7) / 8
+		if len(sig) != 2*size {
+			return false, errors.Errorf("Invalid signature length %d, expected %d", len(sig), 2*size)
+		}
+		der, err := utils.MarshalECDSASignature(new(big.Int).SetBytes(sig[:size]), new(big.Int).SetBytes(sig[size:]))
+		if err != nil {
+			return false, err
+		}
+		sig, opts = der, scheme.hash
+	default:
+		return false, errors.Errorf("Unsupported key type [%T]", k)
+	}
+
+	h := scheme.hash.New()
+	h.Write(payload)
+	return csp.verify(k, sig, h.Sum(nil), opts, false)
+}
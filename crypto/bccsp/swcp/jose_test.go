@@ -0,0 +1,105 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp/utils"
+)
+
+func TestVerifyWithAlg(t *testing.T) {
+	provider, _, cleanup := currentTestConfig.Provider(t)
+	defer cleanup()
+	csp := provider.(*CSP)
+
+	payload := []byte("eyJhbGciOiJFUzI1NiJ9.eyJpc3MiOiJqb2UifQ")
+	digest := sha256.Sum256(payload)
+
+	// ES256
+	ecKey, err := provider.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	ecPub, err := ecKey.PublicKey()
+	assert.NoError(t, err)
+	der, err := provider.Sign(ecKey, digest[:], nil)
+	assert.NoError(t, err)
+	r, s, err := utils.UnmarshalECDSASignature(der)
+	assert.NoError(t, err)
+	es256 := make([]byte, 64)
+	r.FillBytes(es256[:32])
+	s.FillBytes(es256[32:])
+	valid, err := csp.VerifyWithAlg(ecPub, "ES256", payload, es256)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+	valid, err = csp.VerifyWithAlg(ecKey, "ES256", []byte("other"), es256)
+	assert.NoError(t, err)
+	assert.False(t, valid)
+	_, err = csp.VerifyWithAlg(ecPub, "ES256", payload, der)
+	assert.Error(t, err)
+	_, err = csp.VerifyWithAlg(ecPub, "ES384", payload, es256)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "curve [P-256]")
+
+	// PS256 and RS256
+	rsaKey, err := provider.KeyGen(&bccsp.RSA2048KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	rsaPub, err := rsaKey.PublicKey()
+	assert.NoError(t, err)
+	ps256, err := provider.Sign(rsaKey, digest[:], &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: crypto.SHA256})
+	assert.NoError(t, err)
+	valid, err = csp.VerifyWithAlg(rsaPub, "PS256", payload, ps256)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+	rs256, err := provider.Sign(rsaKey, digest[:], crypto.SHA256)
+	assert.NoError(t, err)
+	valid, err = csp.VerifyWithAlg(rsaPub, "RS256", payload, rs256)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+	valid, _ = csp.VerifyWithAlg(rsaPub, "PS256", payload, rs256)
+	assert.False(t, valid)
+	_, err = csp.VerifyWithAlg(rsaPub, "ES256", payload, ps256)
+	assert.Error(t, err)
+
+	// EdDSA
+	edKey, err := provider.KeyGen(&bccsp.ED25519KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	edPub, err := edKey.PublicKey()
+	assert.NoError(t, err)
+	eddsa, err := provider.Sign(edKey, payload, nil)
+	assert.NoError(t, err)
+	valid, err = csp.VerifyWithAlg(edPub, "EdDSA", payload, eddsa)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+	_, err = csp.VerifyWithAlg(edPub, "ES256", payload, eddsa)
+	assert.Error(t, err)
+	_, err = csp.VerifyWithAlg(ecPub, "EdDSA", payload, es256)
+	assert.Error(t, err)
+
+	// Unknown algorithms
+	for _, alg := range []string{"none", "HS256", "es256", ""} {
+		_, err = csp.VerifyWithAlg(ecPub, alg, payload, es256)
+		assert.Error(t, err, alg)
+		assert.Contains(t, err.Error(), "Unsupported algorithm", alg)
+	}
+	_, err = csp.VerifyWithAlg(nil, "ES256", payload, es256)
+	assert.Error(t, err)
+}
@@ -0,0 +1,78 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp/utils"
+)
+
+// KeyLister is implemented by keystores able to enumerate their keys.
+// File-based KeyStore implements it.
+type KeyLister interface {
+	// SKIs returns subject key identifiers of all keys in the store.
+	SKIs() ([][]byte, error)
+}
+
+// ExportJWKS - Serializes public keys of all keys in the keystore as JWK Set
+// (RFC 7517) suitable for publishing at /.well-known/jwks.json.
+// Every key carries 'kid' set to utils.JWTKeyID of the key.
+//
+// Keystore must implement KeyLister. Symmetric keys are skipped, so are
+// keys without JWK representation. Private and public key of the same key
+// pair are exported once.
+func ExportJWKS(ks bccsp.KeyStore) ([]byte, error) {
+	if ks == nil {
+		return nil, errors.New("Invalid KeyStore. It must not be nil.")
+	}
+	lister, ok := ks.(KeyLister)
+	if !ok {
+		return nil, errors.Errorf("KeyStore [%T] cannot list its keys", ks)
+	}
+	skis, err := lister.SKIs()
+	if err != nil {
+		return nil, err
+	}
+
+	jwks := struct {
+		Keys []map[string]string `json:"keys"`
+	}{Keys: []map[string]string{}}
+	exported := make(map[string]bool)
+	for _, ski := range skis {
+		k, err := ks.Key(ski)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed getting key for SKI [%x]", ski)
+		}
+		if k.Symmetric() {
+			continue
+		}
+		jwk, err := utils.PublicKeyToJWK(k)
+		if err != nil {
+			logger.Warningf("Skipping key [%x] without JWK representation [%s]", ski, err)
+			continue
+		}
+		if exported[jwk["kid"]] {
+			continue
+		}
+		exported[jwk["kid"]] = true
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+	return json.Marshal(jwks)
+}
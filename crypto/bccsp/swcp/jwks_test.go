@@ -0,0 +1,84 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp/utils"
+)
+
+func TestExportJWKS(t *testing.T) {
+	provider, ks, cleanup := currentTestConfig.Provider(t)
+	defer cleanup()
+
+	raw, err := ExportJWKS(ks)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"keys":[]}`, string(raw))
+
+	kids := make(map[string]string)
+	for _, opts := range []bccsp.KeyGenOpts{
+		&bccsp.ECDSAP256KeyGenOpts{Temporary: false},
+		&bccsp.ECDSAP384KeyGenOpts{Temporary: false},
+		&bccsp.RSA2048KeyGenOpts{Temporary: false},
+	} {
+		k, err := provider.KeyGen(opts)
+		assert.NoError(t, err)
+		kids[utils.JWTKeyID(k)] = opts.Algorithm()
+	}
+	// Public key of stored pair is exported once
+	k, err := provider.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: false})
+	assert.NoError(t, err)
+	pk, err := k.PublicKey()
+	assert.NoError(t, err)
+	assert.NoError(t, ks.StoreKey(pk))
+	kids[utils.JWTKeyID(k)] = "ECDSAP256"
+	// Symmetric keys are skipped
+	_, err = provider.KeyGen(&bccsp.AES256KeyGenOpts{Temporary: false})
+	assert.NoError(t, err)
+
+	raw, err = ExportJWKS(ks)
+	assert.NoError(t, err)
+	var jwks struct {
+		Keys []map[string]string `json:"keys"`
+	}
+	assert.NoError(t, json.Unmarshal(raw, &jwks))
+	assert.Len(t, jwks.Keys, len(kids))
+	for _, jwk := range jwks.Keys {
+		assert.Contains(t, kids, jwk["kid"])
+		assert.NotContains(t, jwk, "d")
+		switch jwk["kty"] {
+		case "EC":
+			assert.Contains(t, []string{"P-256", "P-384"}, jwk["crv"])
+			assert.NotEmpty(t, jwk["x"])
+			assert.NotEmpty(t, jwk["y"])
+		case "RSA":
+			assert.Equal(t, "AQAB", jwk["e"])
+			assert.NotEmpty(t, jwk["n"])
+		default:
+			t.Fatalf("unexpected key type %q", jwk["kty"])
+		}
+	}
+
+	_, err = ExportJWKS(NewDummyKeyStore())
+	assert.Error(t, err)
+	_, err = ExportJWKS(nil)
+	assert.EqualError(t, err, "Invalid KeyStore. It must not be nil.")
+}
@@ -0,0 +1,94 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"fmt"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/scrypt"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+)
+
+// Minimum parameters accepted by memory-hard key derivation.
+const (
+	// minKDFSaltSize - Minimum salt size in bytes.
+	minKDFSaltSize = 16
+	// minKDFKeySize - Minimum derived key size in bytes.
+	minKDFKeySize = 16
+	// maxKDFKeySize - Maximum derived key size in bytes.
+	maxKDFKeySize = 1024
+
+	// minScryptN - Minimum scrypt CPU and memory cost.
+	minScryptN = 1 << 14
+	// minScryptR - Minimum scrypt block size.
+	minScryptR = 8
+
+	// minArgon2Time - Minimum number of Argon2id passes.
+	minArgon2Time = 2
+	// minArgon2Memory - Minimum Argon2id memory size in KiB.
+	minArgon2Memory = 19 * 1024
+)
+
+// memoryHardDeriveKey - Derives key from secret with scrypt or Argon2id.
+// Parameters are validated against minimums, so that weak options
+// cannot be used by accident, and against maximums, such as
+// bccsp.MaxScryptLogN, so that options cannot exhaust memory.
+func memoryHardDeriveKey(secret []byte, opts *bccsp.MemoryHardDeriveKeyOpts) ([]byte, error) {
+	length := opts.Length
+	if length == 0 {
+		length = 32
+	}
+	if length < minKDFKeySize || length > maxKDFKeySize {
+		return nil, fmt.Errorf("Invalid key length %d. It must be between %d and %d.", length, minKDFKeySize, maxKDFKeySize)
+	}
+	if len(opts.Salt) < minKDFSaltSize {
+		return nil, fmt.Errorf("Invalid salt. It must be at least %d bytes.", minKDFSaltSize)
+	}
+
+	p := opts.Params
+	switch opts.KDF {
+	case bccsp.Scrypt:
+		if p.N < minScryptN || p.N&(p.N-1) != 0 {
+			return nil, fmt.Errorf("Invalid scrypt parameters. N must be a power of two of at least %d.", minScryptN)
+		}
+		if p.R < minScryptR || p.P < 1 {
+			return nil, fmt.Errorf("Invalid scrypt parameters. R must be at least %d and P at least 1.", minScryptR)
+		}
+		if p.N > 1<<bccsp.MaxScryptLogN || p.R > bccsp.MaxScryptR || p.P > bccsp.MaxScryptP {
+			return nil, fmt.Errorf("Invalid scrypt parameters. N must be at most %d, R at most %d and P at most %d.",
+				1<<bccsp.MaxScryptLogN, bccsp.MaxScryptR, bccsp.MaxScryptP)
+		}
+		key, err := scrypt.Key(secret, opts.Salt, p.N, p.R, p.P, length)
+		if err != nil {
+			return nil, fmt.Errorf("Failed deriving key [%s]", err)
+		}
+		return key, nil
+	case bccsp.Argon2id:
+		if p.Time < minArgon2Time || p.Memory < minArgon2Memory || p.Threads < 1 {
+			return nil, fmt.Errorf("Invalid Argon2id parameters. Time must be at least %d, memory at least %d KiB and threads at least 1.",
+				minArgon2Time, minArgon2Memory)
+		}
+		if p.Time > bccsp.MaxArgon2Time || p.Memory > bccsp.MaxArgon2Memory {
+			return nil, fmt.Errorf("Invalid Argon2id parameters. Time must be at most %d and memory at most %d KiB.",
+				bccsp.MaxArgon2Time, bccsp.MaxArgon2Memory)
+		}
+		return argon2.IDKey(secret, opts.Salt, p.Time, p.Memory, p.Threads, uint32(length)), nil
+	default:
+		return nil, fmt.Errorf("Unsupported memory-hard KDF [%s]", opts.KDF)
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+)
+
+func TestMemoryHardKeyDeriv(t *testing.T) {
+	provider, _, cleanup := currentTestConfig.Provider(t)
+	defer cleanup()
+
+	passphrase, err := provider.KeyImport([]byte("correct horse battery staple"), &bccsp.HMACImportKeyOpts{Temporary: true})
+	assert.NoError(t, err)
+	salt := []byte("0123456789abcdef")
+	otherSalt := []byte("fedcba9876543210")
+
+	for _, opts := range []*bccsp.MemoryHardDeriveKeyOpts{
+		{Temporary: true, KDF: bccsp.Scrypt, Params: bccsp.MemoryHardParams{N: 1 << 14, R: 8, P: 1}, Salt: salt},
+		{Temporary: true, KDF: bccsp.Argon2id, Params: bccsp.MemoryHardParams{Time: 2, Memory: 19 * 1024, Threads: 1}, Salt: salt, Length: 16},
+	} {
+		k1, err := provider.KeyDeriv(passphrase, opts)
+		assert.NoError(t, err, opts.KDF)
+		assert.True(t, k1.Symmetric())
+		assert.True(t, k1.Private())
+
+		// Re-derived from recorded options
+		parsed, err := bccsp.ParseMemoryHardDeriveKeyOpts(opts.String())
+		assert.NoError(t, err, opts.KDF)
+		parsed.Temporary = true
+		k2, err := provider.KeyDeriv(passphrase, parsed)
+		assert.NoError(t, err, opts.KDF)
+		assert.Equal(t, k1.SKI(), k2.SKI(), opts.KDF)
+
+		// Different salt
+		salted := *opts
+		salted.Salt = otherSalt
+		k3, err := provider.KeyDeriv(passphrase, &salted)
+		assert.NoError(t, err, opts.KDF)
+		assert.NotEqual(t, k1.SKI(), k3.SKI(), opts.KDF)
+	}
+
+	for _, opts := range []*bccsp.MemoryHardDeriveKeyOpts{
+		{KDF: bccsp.Scrypt, Params: bccsp.MemoryHardParams{N: 1 << 10, R: 8, P: 1}, Salt: salt},
+		{KDF: bccsp.Scrypt, Params: bccsp.MemoryHardParams{N: 3 << 14, R: 8, P: 1}, Salt: salt},
+		{KDF: bccsp.Scrypt, Params: bccsp.MemoryHardParams{N: 1 << 14, R: 1, P: 1}, Salt: salt},
+		{KDF: bccsp.Scrypt, Params: bccsp.MemoryHardParams{N: 1 << 14, R: 8, P: 1}, Salt: salt[:8]},
+		{KDF: bccsp.Scrypt, Params: bccsp.MemoryHardParams{N: 1 << 14, R: 8, P: 1}, Salt: salt, Length: 8},
+		{KDF: bccsp.Argon2id, Params: bccsp.MemoryHardParams{Time: 1, Memory: 64 * 1024, Threads: 1}, Salt: salt},
+		{KDF: bccsp.Argon2id, Params: bccsp.MemoryHardParams{Time: 3, Memory: 1024, Threads: 1}, Salt: salt},
+		{KDF: bccsp.Argon2id, Params: bccsp.MemoryHardParams{Time: 3, Memory: 64 * 1024}, Salt: salt},
+		{KDF: bccsp.Scrypt, Params: bccsp.MemoryHardParams{N: 1 << 30, R: 8, P: 1}, Salt: salt},
+		{KDF: bccsp.Scrypt, Params: bccsp.MemoryHardParams{N: 1 << 14, R: 1 << 20, P: 1}, Salt: salt},
+		{KDF: bccsp.Scrypt, Params: bccsp.MemoryHardParams{N: 1 << 14, R: 8, P: 1 << 20}, Salt: salt},
+		{KDF: bccsp.Argon2id, Params: bccsp.MemoryHardParams{Time: 1 << 20, Memory: 64 * 1024, Threads: 1}, Salt: salt},
+		{KDF: bccsp.Argon2id, Params: bccsp.MemoryHardParams{Time: 3, Memory: 1 << 30, Threads: 1}, Salt: salt},
+		{KDF: "PBKDF2", Salt: salt},
+	} {
+		_, err := provider.KeyDeriv(passphrase, opts)
+		assert.Error(t, err, opts.String())
+	}
+
+	// Excessive costs are rejected by the parser
+	for _, s := range []string{
+		"$scrypt$ln=30,r=8,p=1,len=32$MDEyMzQ1Njc4OWFiY2RlZg",
+		"$scrypt$ln=14,r=1073741824,p=1,len=32$MDEyMzQ1Njc4OWFiY2RlZg",
+		"$scrypt$ln=14,r=8,p=1073741824,len=32$MDEyMzQ1Njc4OWFiY2RlZg",
+		"$argon2id$v=19$m=4294967295,t=3,p=1,len=32$MDEyMzQ1Njc4OWFiY2RlZg",
+		"$argon2id$v=19$m=65536,t=4294967295,p=1,len=32$MDEyMzQ1Njc4OWFiY2RlZg",
+	} {
+		_, err := bccsp.ParseMemoryHardDeriveKeyOpts(s)
+		assert.Error(t, err, s)
+	}
+	_, err = bccsp.ParseMemoryHardDeriveKeyOpts("$scrypt$ln=20,r=16,p=16,len=32$MDEyMzQ1Njc4OWFiY2RlZg")
+	assert.NoError(t, err)
+}
@@ -20,8 +20,11 @@ import (
 	"crypto/hmac"
 	"errors"
 	"fmt"
+	"io"
 	"math/big"
 
+	"golang.org/x/crypto/hkdf"
+
 	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
 )
 
@@ -64,7 +67,7 @@ func (kd *ecdsaPublicKeyKeyDeriver) KeyDeriv(k bccsp.Key, opts bccsp.KeyDerivOpt
 			return nil, errors.New("Failed temporary public key IsOnCurve check.")
 		}
 
-		return &ecdsaPublicKey{tempSK}, nil
+		return &ecdsaPublicKey{pubKey: tempSK}, nil
 	default:
 		return nil, fmt.Errorf("Unsupported 'KeyDerivOpts' provided [%v]", opts)
 	}
@@ -117,6 +120,8 @@ func (kd *ecdsaPrivateKeyKeyDeriver) KeyDeriv(k bccsp.Key, opts bccsp.KeyDerivOp
 		}
 
 		return &ecdsaPrivateKey{tempSK}, nil
+	case *bccsp.ECDHDeriveKeyOpts:
+		return ecdhDeriveKey(ecdsaK.privKey, opts.(*bccsp.ECDHDeriveKeyOpts))
 	default:
 		return nil, fmt.Errorf("Unsupported 'KeyDerivOpts' provided [%v]", opts)
 	}
@@ -148,6 +153,30 @@ func (kd *aesPrivateKeyKeyDeriver) KeyDeriv(k bccsp.Key, opts bccsp.KeyDerivOpts
 		mac := hmac.New(kd.conf.hashFunction, aesK.privKey)
 		mac.Write(hmacOpts.Argument())
 		return &aesPrivateKey{mac.Sum(nil), true}, nil
+
+	case *bccsp.HKDFDeriveKeyOpts:
+		hkdfOpts := opts.(*bccsp.HKDFDeriveKeyOpts)
+
+		length := hkdfOpts.Length
+		if length == 0 {
+			length = 32
+		}
+		if length < 0 || length > 255*kd.conf.hashFunction().Size() {
+			return nil, fmt.Errorf("Invalid key length %d", length)
+		}
+		key := make([]byte, length)
+		r := hkdf.New(kd.conf.hashFunction, aesK.privKey, hkdfOpts.Salt, hkdfOpts.Info)
+		if _, err := io.ReadFull(r, key); err != nil {
+			return nil, fmt.Errorf("Failed expanding key [%s]", err)
+		}
+		return &aesPrivateKey{key, false}, nil
+
+	case *bccsp.MemoryHardDeriveKeyOpts:
+		key, err := memoryHardDeriveKey(aesK.privKey, opts.(*bccsp.MemoryHardDeriveKeyOpts))
+		if err != nil {
+			return nil, err
+		}
+		return &aesPrivateKey{key, false}, nil
 	default:
 		return nil, fmt.Errorf("Unsupported 'KeyDerivOpts' provided [%v]", opts)
 	}
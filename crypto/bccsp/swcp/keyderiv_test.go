@@ -100,3 +100,63 @@ func TestAESPrivateKeyKeyDeriver(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "Unsupported 'KeyDerivOpts' provided [")
 }
+
+func TestKeyDerivChain(t *testing.T) {
+	provider, ks, cleanup := currentTestConfig.Provider(t)
+	defer cleanup()
+
+	raw, err := GetRandomBytes(32)
+	assert.NoError(t, err)
+	root, err := provider.KeyImport(raw, &bccsp.AES256ImportKeyOpts{Temporary: true})
+	assert.NoError(t, err)
+
+	derive := func(root bccsp.Key) (bccsp.Key, bccsp.Key, bccsp.Key) {
+		purpose, err := provider.KeyDeriv(root, &bccsp.HKDFDeriveKeyOpts{Info: []byte("purpose")})
+		assert.NoError(t, err)
+		context, err := provider.KeyDeriv(purpose, &bccsp.HKDFDeriveKeyOpts{Temporary: true, Salt: []byte("salt"), Info: []byte("context")})
+		assert.NoError(t, err)
+		leaf, err := provider.KeyDeriv(context, &bccsp.HMACDeriveKeyOpts{Temporary: true, Arg: []byte("leaf")})
+		assert.NoError(t, err)
+		return purpose, context, leaf
+	}
+
+	purpose, context, leaf := derive(root)
+
+	// Intermediate keys are not exportable
+	_, err = purpose.Bytes()
+	assert.Error(t, err)
+	_, err = context.Bytes()
+	assert.Error(t, err)
+	assert.True(t, purpose.Symmetric())
+	assert.NotEqual(t, purpose.SKI(), context.SKI())
+
+	// Chain is reproducible from the root
+	purpose2, context2, leaf2 := derive(root)
+	assert.Equal(t, purpose.SKI(), purpose2.SKI())
+	assert.Equal(t, context.SKI(), context2.SKI())
+	assert.Equal(t, leaf.SKI(), leaf2.SKI())
+	leafRaw, err := leaf.Bytes()
+	assert.NoError(t, err)
+	leafRaw2, err := leaf2.Bytes()
+	assert.NoError(t, err)
+	assert.Equal(t, leafRaw, leafRaw2)
+
+	// Stored intermediate key can be derived from
+	stored, err := ks.Key(purpose.SKI())
+	assert.NoError(t, err)
+	context3, err := provider.KeyDeriv(stored, &bccsp.HKDFDeriveKeyOpts{Temporary: true, Salt: []byte("salt"), Info: []byte("context")})
+	assert.NoError(t, err)
+	assert.Equal(t, context.SKI(), context3.SKI())
+
+	// Different info gives different key
+	other, err := provider.KeyDeriv(root, &bccsp.HKDFDeriveKeyOpts{Temporary: true, Info: []byte("other")})
+	assert.NoError(t, err)
+	assert.NotEqual(t, purpose.SKI(), other.SKI())
+
+	// Custom length
+	short, err := provider.KeyDeriv(root, &bccsp.HKDFDeriveKeyOpts{Temporary: true, Length: 16})
+	assert.NoError(t, err)
+	assert.Len(t, short.(*aesPrivateKey).privKey, 16)
+	_, err = provider.KeyDeriv(root, &bccsp.HKDFDeriveKeyOpts{Temporary: true, Length: -1})
+	assert.Error(t, err)
+}
@@ -21,6 +21,8 @@ import (
 	"crypto/rand"
 	"crypto/rsa"
 	"fmt"
+	"io"
+	"math/big"
 
 	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
 )
@@ -56,6 +58,21 @@ type rsaKeyGenerator struct {
 }
 
 func (kg *rsaKeyGenerator) KeyGen(opts bccsp.KeyGenOpts) (bccsp.Key, error) {
+	if kg.length == 0 {
+		return nil, fmt.Errorf("RSA key size is not configured. Use opts of explicit key size.")
+	}
+	exponent := rsaPublicExponent(opts)
+	if exponent != 0 && exponent != defaultRSAExponent {
+		if exponent <= 1<<16 || exponent%2 == 0 {
+			return nil, fmt.Errorf("Invalid RSA public exponent %d. It must be odd and greater than 65536.", exponent)
+		}
+		lowLevelKey, err := generateRSAKey(rand.Reader, kg.length, exponent)
+		if err != nil {
+			return nil, fmt.Errorf("Failed generating RSA %d key [%s]", kg.length, err)
+		}
+		return &rsaPrivateKey{lowLevelKey}, nil
+	}
+
 	lowLevelKey, err := rsa.GenerateKey(rand.Reader, int(kg.length))
 
 	if err != nil {
@@ -64,3 +81,78 @@ func (kg *rsaKeyGenerator) KeyGen(opts bccsp.KeyGenOpts) (bccsp.Key, error) {
 
 	return &rsaPrivateKey{lowLevelKey}, nil
 }
+
+// defaultRSAExponent - Public exponent used by rsa.GenerateKey.
+const defaultRSAExponent = 65537
+
+// rsaPublicExponent - Returns public exponent requested in RSA key generation opts.
+func rsaPublicExponent(opts bccsp.KeyGenOpts) int {
+	switch o := opts.(type) {
+	case *bccsp.RSAKeyGenOpts:
+		return o.PublicExponent
+	case *bccsp.RSA1024KeyGenOpts:
+		return o.PublicExponent
+	case *bccsp.RSA2048KeyGenOpts:
+		return o.PublicExponent
+	case *bccsp.RSA3072KeyGenOpts:
+		return o.PublicExponent
+	case *bccsp.RSA4096KeyGenOpts:
+		return o.PublicExponent
+	}
+	return 0
+}
+
+// generateRSAKey - Generates two prime RSA key with public exponent e.
+// Primes are chosen so that e is coprime to p-1 and q-1, private exponent
+// is the inverse of e modulo lcm(p-1, q-1) as in FIPS 186-4.
+func generateRSAKey(random io.Reader, bits, e int) (*rsa.PrivateKey, error) {
+	one := big.NewInt(1)
+	bigE := big.NewInt(int64(e))
+	prime := func() (p *big.Int, err error) {
+		for {
+			if p, err = rand.Prime(random, bits-bits/2); err != nil {
+				return
+			}
+			pm := new(big.Int).Sub(p, one)
+			if new(big.Int).GCD(nil, nil, bigE, pm).Cmp(one) == 0 {
+				return
+			}
+		}
+	}
+	for {
+		p, err := prime()
+		if err != nil {
+			return nil, err
+		}
+		q, err := prime()
+		if err != nil {
+			return nil, err
+		}
+		if p.Cmp(q) == 0 {
+			continue
+		}
+		n := new(big.Int).Mul(p, q)
+		if n.BitLen() != bits {
+			continue
+		}
+		pm := new(big.Int).Sub(p, one)
+		qm := new(big.Int).Sub(q, one)
+		gcd := new(big.Int).GCD(nil, nil, pm, qm)
+		lambda := new(big.Int).Mul(pm, qm)
+		lambda.Div(lambda, gcd)
+		d := new(big.Int).ModInverse(bigE, lambda)
+		if d == nil {
+			continue
+		}
+		key := &rsa.PrivateKey{
+			PublicKey: rsa.PublicKey{N: n, E: e},
+			D:         d,
+			Primes:    []*big.Int{p, q},
+		}
+		if err := key.Validate(); err != nil {
+			return nil, err
+		}
+		key.Precompute()
+		return key, nil
+	}
+}
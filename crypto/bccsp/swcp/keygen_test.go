@@ -16,7 +16,10 @@
 package swcp
 
 import (
+	"crypto"
 	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
 	"errors"
 	"reflect"
 	"testing"
@@ -85,6 +88,46 @@ func TestRSAKeyGenerator(t *testing.T) {
 	assert.Equal(t, rsaK.privKey.N.BitLen(), 512)
 }
 
+func TestRSAKeyGeneratorPublicExponent(t *testing.T) {
+	t.Parallel()
+	provider, _, cleanup := currentTestConfig.Provider(t)
+	defer cleanup()
+
+	k, err := provider.KeyGen(&bccsp.RSA2048KeyGenOpts{Temporary: true, PublicExponent: 65539})
+	assert.NoError(t, err)
+	rsaK := k.(*rsaPrivateKey)
+	assert.Equal(t, 65539, rsaK.privKey.E)
+	assert.Equal(t, 2048, rsaK.privKey.N.BitLen())
+	assert.NoError(t, rsaK.privKey.Validate())
+
+	pk, err := k.PublicKey()
+	assert.NoError(t, err)
+	digest := sha256.Sum256([]byte("Hello World"))
+	opts := &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: crypto.SHA256}
+	signature, err := provider.Sign(k, digest[:], opts)
+	assert.NoError(t, err)
+	valid, err := provider.Verify(pk, signature, digest[:], opts)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+
+	// Default exponent
+	for _, opts := range []bccsp.KeyGenOpts{
+		&bccsp.RSA2048KeyGenOpts{Temporary: true},
+		&bccsp.RSAKeyGenOpts{Temporary: true, PublicExponent: 65537},
+	} {
+		k, err = provider.KeyGen(opts)
+		assert.NoError(t, err)
+		assert.Equal(t, 65537, k.(*rsaPrivateKey).privKey.E)
+	}
+
+	// Even and small exponents
+	for _, exponent := range []int{3, 17, 65535, 65538, -65537} {
+		_, err = provider.KeyGen(&bccsp.RSA2048KeyGenOpts{Temporary: true, PublicExponent: exponent})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "Invalid RSA public exponent")
+	}
+}
+
 func TestAESKeyGenerator(t *testing.T) {
 	t.Parallel()
 
@@ -83,7 +83,35 @@ func (*ecdsaPKIXPublicKeyImportOptsKeyImporter) KeyImport(raw interface{}, opts
 		return nil, errors.New("Failed casting to ECDSA public key. Invalid raw material.")
 	}
 
-	return &ecdsaPublicKey{ecdsaPK}, nil
+	origin := utils.Origin{Kind: utils.OriginDER, Raw: utils.Clone(der)}
+	return &ecdsaPublicKey{pubKey: ecdsaPK, origin: origin}, nil
+}
+
+type ecdsaPointImportOptsKeyImporter struct{}
+
+func (*ecdsaPointImportOptsKeyImporter) KeyImport(raw interface{}, opts bccsp.KeyImportOpts) (bccsp.Key, error) {
+	point, ok := raw.([]byte)
+	if !ok {
+		return nil, errors.New("Invalid raw material. Expected byte array.")
+	}
+
+	if len(point) == 0 {
+		return nil, errors.New("Invalid raw. It must not be nil.")
+	}
+
+	pointOpts, ok := opts.(*bccsp.ECDSAPointImportOpts)
+	if !ok || pointOpts.Curve == nil {
+		return nil, errors.New("Invalid opts. Curve must be set.")
+	}
+
+	// Point is decoded, so that SKI is computed over the uncompressed point
+	// regardless of the encoding of the imported point.
+	pub, err := utils.UnmarshalECDSAPoint(pointOpts.Curve, point)
+	if err != nil {
+		return nil, fmt.Errorf("Failed converting point to ECDSA public key [%s]", err)
+	}
+
+	return &ecdsaPublicKey{pubKey: pub}, nil
 }
 
 type ecdsaPrivateKeyImportOptsKeyImporter struct{}
@@ -111,6 +139,31 @@ func (*ecdsaPrivateKeyImportOptsKeyImporter) KeyImport(raw interface{}, opts bcc
 	return &ecdsaPrivateKey{ecdsaSK}, nil
 }
 
+type rsaPrivateKeyImportOptsKeyImporter struct{}
+
+func (*rsaPrivateKeyImportOptsKeyImporter) KeyImport(raw interface{}, opts bccsp.KeyImportOpts) (bccsp.Key, error) {
+	der, ok := raw.([]byte)
+	if !ok {
+		return nil, errors.New("[RSAPrivateKeyImportOpts] Invalid raw material. Expected byte array.")
+	}
+
+	if len(der) == 0 {
+		return nil, errors.New("[RSAPrivateKeyImportOpts] Invalid raw. It must not be nil.")
+	}
+
+	lowLevelKey, err := utils.DERToPrivateKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("Failed converting DER to RSA private key [%s]", err)
+	}
+
+	rsaSK, ok := lowLevelKey.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("Failed casting to RSA private key. Invalid raw material.")
+	}
+
+	return &rsaPrivateKey{rsaSK}, nil
+}
+
 type ecdsaGoPublicKeyImportOptsKeyImporter struct{}
 
 func (*ecdsaGoPublicKeyImportOptsKeyImporter) KeyImport(raw interface{}, opts bccsp.KeyImportOpts) (bccsp.Key, error) {
@@ -119,7 +172,7 @@ func (*ecdsaGoPublicKeyImportOptsKeyImporter) KeyImport(raw interface{}, opts bc
 		return nil, errors.New("Invalid raw material. Expected *ecdsa.PublicKey.")
 	}
 
-	return &ecdsaPublicKey{lowLevelKey}, nil
+	return &ecdsaPublicKey{pubKey: lowLevelKey, origin: utils.Origin{Kind: utils.OriginGoKey}}, nil
 }
 
 type rsaGoPublicKeyImportOptsKeyImporter struct{}
@@ -130,7 +183,7 @@ func (*rsaGoPublicKeyImportOptsKeyImporter) KeyImport(raw interface{}, opts bccs
 		return nil, errors.New("Invalid raw material. Expected *rsa.PublicKey.")
 	}
 
-	return &rsaPublicKey{lowLevelKey}, nil
+	return &rsaPublicKey{pubKey: lowLevelKey, origin: utils.Origin{Kind: utils.OriginGoKey}}, nil
 }
 
 type x509PublicKeyImportOptsKeyImporter struct {
@@ -142,19 +195,40 @@ func (ki *x509PublicKeyImportOptsKeyImporter) KeyImport(raw interface{}, opts bc
 	if !ok {
 		return nil, errors.New("Invalid raw material. Expected *x509.Certificate.")
 	}
+	if x509Opts, ok := opts.(*bccsp.X509PublicKeyImportOpts); ok && x509Opts.CheckValidity {
+		if err := utils.CheckCertificateValidity(x509Cert, x509Opts.ValidityTime()); err != nil {
+			return nil, err
+		}
+	}
 
 	pk := x509Cert.PublicKey
 
+	var (
+		k   bccsp.Key
+		err error
+	)
 	switch pk.(type) {
 	case *ecdsa.PublicKey:
-		return ki.bccsp.keyImporters[reflect.TypeOf(&bccsp.ECDSAGoPublicKeyImportOpts{})].KeyImport(
+		k, err = ki.bccsp.keyImporters[reflect.TypeOf(&bccsp.ECDSAGoPublicKeyImportOpts{})].KeyImport(
 			pk,
 			&bccsp.ECDSAGoPublicKeyImportOpts{Temporary: opts.Ephemeral()})
 	case *rsa.PublicKey:
-		return ki.bccsp.keyImporters[reflect.TypeOf(&bccsp.RSAGoPublicKeyImportOpts{})].KeyImport(
+		k, err = ki.bccsp.keyImporters[reflect.TypeOf(&bccsp.RSAGoPublicKeyImportOpts{})].KeyImport(
 			pk,
 			&bccsp.RSAGoPublicKeyImportOpts{Temporary: opts.Ephemeral()})
 	default:
 		return nil, errors.New("Certificate's public key type not recognized. Supported keys: [ECDSA, RSA]")
 	}
+	if err != nil {
+		return nil, err
+	}
+
+	origin := utils.Origin{Kind: utils.OriginCertificate, Raw: utils.Clone(x509Cert.Raw)}
+	switch kk := k.(type) {
+	case *ecdsaPublicKey:
+		kk.origin = origin
+	case *rsaPublicKey:
+		kk.origin = origin
+	}
+	return k, nil
 }
@@ -16,12 +16,19 @@
 package swcp
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
+	"encoding/pem"
 	"errors"
+	"math/big"
 	"reflect"
 	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec"
 
 	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
 	mocks2 "github.com/ipfn/ipfn/pkg/crypto/bccsp/mocks"
@@ -209,3 +216,187 @@ func TestX509PublicKeyImportOptsKeyImporter(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "Certificate's public key type not recognized. Supported keys: [ECDSA, RSA]")
 }
+
+func TestX509PublicKeyImportValidity(t *testing.T) {
+	provider, _, cleanup := currentTestConfig.Provider(t)
+	defer cleanup()
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	expiredAt := time.Now().Add(-time.Hour)
+	block, _ := pem.Decode(newTestCACert(t, ecKey, "Expired CA", expiredAt))
+	cert, err := x509.ParseCertificate(block.Bytes)
+	assert.NoError(t, err)
+
+	// Validity is not checked by default
+	_, err = provider.KeyImport(cert, &bccsp.X509PublicKeyImportOpts{Temporary: true})
+	assert.NoError(t, err)
+
+	_, err = provider.KeyImport(cert, &bccsp.X509PublicKeyImportOpts{Temporary: true, CheckValidity: true})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "certificate expired at")
+	var invalid x509.CertificateInvalidError
+	assert.True(t, errors.As(err, &invalid))
+	assert.Equal(t, x509.Expired, invalid.Reason)
+
+	// Injected clock
+	at := func(t time.Time) func() time.Time {
+		return func() time.Time { return t }
+	}
+	_, err = provider.KeyImport(cert, &bccsp.X509PublicKeyImportOpts{
+		Temporary:     true,
+		CheckValidity: true,
+		Now:           at(expiredAt.Add(-time.Minute)),
+	})
+	assert.NoError(t, err)
+	_, err = provider.KeyImport(cert, &bccsp.X509PublicKeyImportOpts{
+		Temporary:     true,
+		CheckValidity: true,
+		Now:           at(cert.NotBefore.Add(-time.Minute)),
+	})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "certificate is not valid before")
+}
+
+func TestKeyOrigin(t *testing.T) {
+	provider, _, cleanup := currentTestConfig.Provider(t)
+	defer cleanup()
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	block, _ := pem.Decode(newTestCACert(t, ecKey, "Origin CA", time.Now().Add(time.Hour)))
+	cert, err := x509.ParseCertificate(block.Bytes)
+	assert.NoError(t, err)
+
+	k, err := provider.KeyImport(cert, &bccsp.X509PublicKeyImportOpts{Temporary: true})
+	assert.NoError(t, err)
+	origin, err := utils.KeyOrigin(k)
+	assert.NoError(t, err)
+	assert.Equal(t, utils.OriginCertificate, origin.Kind)
+	assert.Equal(t, cert.Raw, origin.Raw)
+
+	der, err := x509.MarshalPKIXPublicKey(&ecKey.PublicKey)
+	assert.NoError(t, err)
+	k, err = provider.KeyImport(der, &bccsp.ECDSAPKIXPublicKeyImportOpts{Temporary: true})
+	assert.NoError(t, err)
+	origin, err = utils.KeyOrigin(k)
+	assert.NoError(t, err)
+	assert.Equal(t, utils.OriginDER, origin.Kind)
+	assert.Equal(t, der, origin.Raw)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	k, err = provider.KeyImport(&rsaKey.PublicKey, &bccsp.RSAGoPublicKeyImportOpts{Temporary: true})
+	assert.NoError(t, err)
+	origin, err = utils.KeyOrigin(k)
+	assert.NoError(t, err)
+	assert.Equal(t, utils.OriginGoKey, origin.Kind)
+	assert.Nil(t, origin.Raw)
+
+	// Generated keys are of unknown origin
+	k, err = provider.KeyGen(&bccsp.ECDSAKeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	pk, err := k.PublicKey()
+	assert.NoError(t, err)
+	origin, err = utils.KeyOrigin(pk)
+	assert.NoError(t, err)
+	assert.Equal(t, utils.OriginUnknown, origin.Kind)
+
+	_, err = utils.KeyOrigin(k)
+	assert.Error(t, err)
+	_, err = utils.KeyOrigin(nil)
+	assert.Error(t, err)
+}
+
+func TestRSAImportPolicy(t *testing.T) {
+	provider, _, cleanup := currentTestConfig.Provider(t)
+	defer cleanup()
+	csp := provider.(*CSP)
+
+	weak, err := rsa.GenerateKey(rand.Reader, 1024)
+	assert.NoError(t, err)
+	strong, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	// 1024 bit keys are rejected by default
+	_, err = provider.KeyImport(&weak.PublicKey, &bccsp.RSAGoPublicKeyImportOpts{Temporary: true})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "smaller than minimum of 2048 bits")
+	_, err = provider.KeyImport(x509.MarshalPKCS1PrivateKey(weak), &bccsp.RSAPrivateKeyImportOpts{Temporary: true})
+	assert.Error(t, err)
+
+	// 2048 bit keys are accepted
+	_, err = provider.KeyImport(&strong.PublicKey, &bccsp.RSAGoPublicKeyImportOpts{Temporary: true})
+	assert.NoError(t, err)
+	_, err = provider.KeyImport(x509.MarshalPKCS1PrivateKey(strong), &bccsp.RSAPrivateKeyImportOpts{Temporary: true})
+	assert.NoError(t, err)
+
+	// Small exponent
+	smallE := &rsa.PublicKey{N: strong.N, E: 3}
+	_, err = provider.KeyImport(smallE, &bccsp.RSAGoPublicKeyImportOpts{Temporary: true})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Public exponent 3")
+
+	csp.SetRSAImportPolicy(RSAImportPolicy{MinRSABits: 1024, AllowSmallExponent: true})
+	_, err = provider.KeyImport(&weak.PublicKey, &bccsp.RSAGoPublicKeyImportOpts{Temporary: true})
+	assert.NoError(t, err)
+	_, err = provider.KeyImport(smallE, &bccsp.RSAGoPublicKeyImportOpts{Temporary: true})
+	assert.NoError(t, err)
+
+	// Modulus with ROCA fingerprint, residue of 65537^a modulo primorial
+	m := big.NewInt(1)
+	for _, p := range rocaPrimes {
+		m.Mul(m, big.NewInt(p))
+	}
+	roca := new(big.Int).Exp(big.NewInt(65537), big.NewInt(12345), m)
+	roca.Add(roca, new(big.Int).Mul(m, new(big.Int).Lsh(big.NewInt(1), uint(2048-m.BitLen()))))
+	rocaKey := &rsa.PublicKey{N: roca, E: 65537}
+	assert.True(t, IsROCAVulnerable(rocaKey))
+	assert.False(t, IsROCAVulnerable(&strong.PublicKey))
+
+	_, err = provider.KeyImport(rocaKey, &bccsp.RSAGoPublicKeyImportOpts{Temporary: true})
+	assert.NoError(t, err)
+	csp.SetRSAImportPolicy(RSAImportPolicy{CheckROCA: true})
+	_, err = provider.KeyImport(rocaKey, &bccsp.RSAGoPublicKeyImportOpts{Temporary: true})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "ROCA")
+	_, err = provider.KeyImport(&strong.PublicKey, &bccsp.RSAGoPublicKeyImportOpts{Temporary: true})
+	assert.NoError(t, err)
+}
+
+func TestECDSAPointImportSKI(t *testing.T) {
+	provider, _, cleanup := currentTestConfig.Provider(t)
+	defer cleanup()
+
+	for _, curve := range []elliptic.Curve{elliptic.P256(), elliptic.P384(), elliptic.P521(), btcec.S256()} {
+		priv, err := ecdsa.GenerateKey(curve, rand.Reader)
+		assert.NoError(t, err)
+		expected := (&ecdsaPrivateKey{priv}).SKI()
+
+		compressed := utils.CompressECDSAPublicKey(&priv.PublicKey)
+		uncompressed := elliptic.Marshal(curve, priv.X, priv.Y)
+		for _, point := range [][]byte{compressed, uncompressed} {
+			k, err := provider.KeyImport(point, &bccsp.ECDSAPointImportOpts{Temporary: true, Curve: curve})
+			assert.NoError(t, err, curve.Params().Name)
+			assert.Equal(t, expected, k.SKI(), "%s point %x", curve.Params().Name, point[0])
+			pub := k.(*ecdsaPublicKey).pubKey
+			assert.Equal(t, 0, priv.X.Cmp(pub.X))
+			assert.Equal(t, 0, priv.Y.Cmp(pub.Y))
+		}
+		if curve == btcec.S256() {
+			continue
+		}
+		der, err := x509.MarshalPKIXPublicKey(&priv.PublicKey)
+		assert.NoError(t, err)
+		k, err := provider.KeyImport(der, &bccsp.ECDSAPKIXPublicKeyImportOpts{Temporary: true})
+		assert.NoError(t, err)
+		assert.Equal(t, expected, k.SKI())
+	}
+
+	_, err := provider.KeyImport([]byte{0x02, 1, 2}, &bccsp.ECDSAPointImportOpts{Temporary: true, Curve: elliptic.P256()})
+	assert.Error(t, err)
+	_, err = provider.KeyImport(make([]byte, 33), &bccsp.ECDSAPointImportOpts{Temporary: true, Curve: elliptic.P256()})
+	assert.Error(t, err)
+	_, err = provider.KeyImport([]byte{0x02, 1}, &bccsp.ECDSAPointImportOpts{Temporary: true})
+	assert.Error(t, err)
+}
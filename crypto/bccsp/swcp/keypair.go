@@ -0,0 +1,74 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"crypto/x509"
+
+	"github.com/pkg/errors"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+)
+
+// GenerateKeyPairDER - Generates asymmetric key with csp and returns PKCS #8
+// DER of its private key and PKIX DER of its public key alongside of key.
+//
+// Private key DER is returned only for keys generated by software CSP. Keys
+// of other CSPs, such as PKCS #11 keys which never leave the HSM, cannot be
+// exported and are returned with empty privDER and nil error. Their public
+// key DER is the byte representation of public key, see bccsp.Key.Bytes.
+func GenerateKeyPairDER(csp bccsp.BCCSP, opts bccsp.KeyGenOpts) (privDER, pubDER []byte, key bccsp.Key, err error) {
+	if csp == nil {
+		return nil, nil, nil, errors.New("Invalid CSP. It must not be nil.")
+	}
+	key, err = csp.KeyGen(opts)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if key.Symmetric() {
+		return nil, nil, key, errors.New("Invalid opts. Generated key must be asymmetric.")
+	}
+
+	var priv, pub interface{}
+	switch k := key.(type) {
+	case *ecdsaPrivateKey:
+		priv, pub = k.privKey, &k.privKey.PublicKey
+	case *rsaPrivateKey:
+		priv, pub = k.privKey, &k.privKey.PublicKey
+	case *ed25519PrivateKey:
+		priv, pub = k.privKey, k.pubKey.pubKey
+	default:
+		pk, err := key.PublicKey()
+		if err != nil {
+			return nil, nil, key, errors.Wrap(err, "Failed getting public key")
+		}
+		pubDER, err = pk.Bytes()
+		if err != nil {
+			return nil, nil, key, errors.Wrap(err, "Failed marshalling public key")
+		}
+		return nil, pubDER, key, nil
+	}
+
+	privDER, err = x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return nil, nil, key, errors.Wrap(err, "Failed marshalling private key")
+	}
+	pubDER, err = x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil, nil, key, errors.Wrap(err, "Failed marshalling public key")
+	}
+	return privDER, pubDER, key, nil
+}
@@ -0,0 +1,86 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp/mocks"
+	"golang.org/x/crypto/ed25519"
+)
+
+type keyGenBCCSP struct {
+	mocks.MockBCCSP
+	key bccsp.Key
+}
+
+func (m *keyGenBCCSP) KeyGen(opts bccsp.KeyGenOpts) (bccsp.Key, error) {
+	return m.key, nil
+}
+
+func TestGenerateKeyPairDER(t *testing.T) {
+	provider, _, cleanup := currentTestConfig.Provider(t)
+	defer cleanup()
+
+	for _, opts := range []bccsp.KeyGenOpts{
+		&bccsp.ECDSAP256KeyGenOpts{Temporary: true},
+		&bccsp.ECDSAP384KeyGenOpts{Temporary: true},
+		&bccsp.RSA2048KeyGenOpts{Temporary: true},
+		&bccsp.ED25519KeyGenOpts{Temporary: true},
+	} {
+		privDER, pubDER, k, err := GenerateKeyPairDER(provider, opts)
+		assert.NoError(t, err)
+		assert.True(t, k.Private())
+
+		priv, err := x509.ParsePKCS8PrivateKey(privDER)
+		assert.NoError(t, err)
+		pub, err := x509.ParsePKIXPublicKey(pubDER)
+		assert.NoError(t, err)
+
+		switch priv := priv.(type) {
+		case *ecdsa.PrivateKey:
+			assert.Equal(t, k.(*ecdsaPrivateKey).privKey.D, priv.D)
+			assert.Equal(t, priv.X, pub.(*ecdsa.PublicKey).X)
+		case *rsa.PrivateKey:
+			assert.Equal(t, k.(*rsaPrivateKey).privKey.D, priv.D)
+			assert.Equal(t, priv.N, pub.(*rsa.PublicKey).N)
+		case ed25519.PrivateKey:
+			assert.Equal(t, k.(*ed25519PrivateKey).privKey, priv)
+			assert.Equal(t, priv.Public(), pub)
+		default:
+			t.Fatalf("unexpected private key type %T", priv)
+		}
+	}
+
+	_, _, _, err := GenerateKeyPairDER(provider, &bccsp.AES256KeyGenOpts{Temporary: true})
+	assert.Error(t, err)
+	_, _, _, err = GenerateKeyPairDER(nil, &bccsp.ECDSAP256KeyGenOpts{Temporary: true})
+	assert.Error(t, err)
+
+	// Keys of other CSPs are not exported
+	pubRaw := []byte{1, 2, 3}
+	hsm := &keyGenBCCSP{key: &mocks.MockKey{Pvt: true, PK: &mocks.MockKey{BytesValue: pubRaw}}}
+	privDER, pubDER, _, err := GenerateKeyPairDER(hsm, &bccsp.ECDSAP256KeyGenOpts{})
+	assert.NoError(t, err)
+	assert.Empty(t, privDER)
+	assert.Equal(t, pubRaw, pubDER)
+}
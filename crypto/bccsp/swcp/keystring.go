@@ -0,0 +1,104 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+)
+
+// Encoding - Text encoding of key material.
+type Encoding int
+
+const (
+	// EncodingHex - Hexadecimal encoding, upper or lower case.
+	EncodingHex Encoding = iota
+
+	// EncodingBase64 - Base64 encoding, standard or URL safe alphabet,
+	// with or without padding.
+	EncodingBase64
+)
+
+// String - Returns encoding name.
+func (enc Encoding) String() string {
+	switch enc {
+	case EncodingHex:
+		return "hex"
+	case EncodingBase64:
+		return "base64"
+	default:
+		return fmt.Sprintf("Encoding(%d)", int(enc))
+	}
+}
+
+// ImportSymmetricKeyString - Decodes symmetric key from string and imports
+// it with opts. Opts must be bccsp.AES256ImportKeyOpts, which requires key
+// of 32 bytes, or bccsp.HMACImportKeyOpts. Surrounding whitespace is ignored.
+// Decoded key material is zeroed after import.
+func ImportSymmetricKeyString(csp *CSP, s string, encoding Encoding, opts bccsp.KeyImportOpts) (bccsp.Key, error) {
+	if csp == nil {
+		return nil, errors.New("Invalid CSP. It must not be nil.")
+	}
+	raw, err := decodeKeyString(strings.TrimSpace(s), encoding)
+	if err != nil {
+		return nil, err
+	}
+	defer wipe(raw)
+
+	switch opts.(type) {
+	case *bccsp.AES256ImportKeyOpts:
+		if len(raw) != 32 {
+			return nil, errors.Errorf("Invalid key length [%d]. Must be 32 bytes.", len(raw))
+		}
+	case *bccsp.HMACImportKeyOpts:
+		if len(raw) == 0 {
+			return nil, errors.New("Invalid key. It must not be empty.")
+		}
+	default:
+		return nil, errors.Errorf("Invalid opts [%T]. Must be symmetric key import opts.", opts)
+	}
+	return csp.KeyImport(raw, opts)
+}
+
+// decodeKeyString - Decodes key material from string.
+func decodeKeyString(s string, encoding Encoding) ([]byte, error) {
+	switch encoding {
+	case EncodingHex:
+		raw, err := hex.DecodeString(s)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed decoding hex key")
+		}
+		return raw, nil
+	case EncodingBase64:
+		for _, enc := range []*base64.Encoding{
+			base64.StdEncoding, base64.RawStdEncoding,
+			base64.URLEncoding, base64.RawURLEncoding,
+		} {
+			if raw, err := enc.DecodeString(s); err == nil {
+				return raw, nil
+			}
+		}
+		return nil, errors.New("Failed decoding base64 key.")
+	default:
+		return nil, errors.Errorf("Unsupported encoding [%s]", encoding)
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+)
+
+func TestImportSymmetricKeyString(t *testing.T) {
+	provider, _, cleanup := currentTestConfig.Provider(t)
+	defer cleanup()
+	csp := provider.(*CSP)
+
+	raw := bytes.Repeat([]byte{0xa5, 0x01}, 16)
+	opts := &bccsp.AES256ImportKeyOpts{Temporary: true}
+	for _, tc := range []struct {
+		s        string
+		encoding Encoding
+	}{
+		{hex.EncodeToString(raw), EncodingHex},
+		{" " + hex.EncodeToString(raw) + "\n", EncodingHex},
+		{base64.StdEncoding.EncodeToString(raw), EncodingBase64},
+		{base64.RawURLEncoding.EncodeToString(raw), EncodingBase64},
+	} {
+		k, err := ImportSymmetricKeyString(csp, tc.s, tc.encoding, opts)
+		assert.NoError(t, err, tc.s)
+		assert.True(t, k.Symmetric())
+		assert.Equal(t, raw, k.(*aesPrivateKey).privKey)
+	}
+
+	// HMAC keys may be of any length
+	k, err := ImportSymmetricKeyString(csp, "00112233", EncodingHex, &bccsp.HMACImportKeyOpts{Temporary: true})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x00, 0x11, 0x22, 0x33}, k.(*aesPrivateKey).privKey)
+
+	_, err = ImportSymmetricKeyString(csp, hex.EncodeToString(raw[:16]), EncodingHex, opts)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Invalid key length [16]")
+	_, err = ImportSymmetricKeyString(csp, base64.StdEncoding.EncodeToString(append(raw, 0)), EncodingBase64, opts)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Invalid key length [33]")
+	_, err = ImportSymmetricKeyString(csp, "zz", EncodingHex, opts)
+	assert.Error(t, err)
+	_, err = ImportSymmetricKeyString(csp, "!!", EncodingBase64, opts)
+	assert.Error(t, err)
+	_, err = ImportSymmetricKeyString(csp, "00", Encoding(7), opts)
+	assert.Error(t, err)
+	_, err = ImportSymmetricKeyString(csp, "", EncodingHex, &bccsp.HMACImportKeyOpts{Temporary: true})
+	assert.Error(t, err)
+	_, err = ImportSymmetricKeyString(csp, "00", EncodingHex, &bccsp.ECDSAPrivateKeyImportOpts{Temporary: true})
+	assert.Error(t, err)
+	_, err = ImportSymmetricKeyString(nil, "00", EncodingHex, opts)
+	assert.Error(t, err)
+}
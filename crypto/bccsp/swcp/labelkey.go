@@ -0,0 +1,94 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+)
+
+// DeriveLabeledKey returns symmetric key of bits length derived from master
+// with HKDF using label as info. Derivation is deterministic, the same
+// master key and label always yield the same key and SKI, so that distinct
+// keys per purpose do not need to be stored. Master must be a symmetric key
+// and bits one of 128, 192 or 256. Derived key is not stored.
+func (csp *CSP) DeriveLabeledKey(master bccsp.Key, label string, bits int) (bccsp.Key, error) {
+	if master == nil {
+		return nil, errors.New("Invalid master key. It must not be nil.")
+	}
+	if !master.Symmetric() {
+		return nil, errors.New("Invalid master key. It must be symmetric.")
+	}
+	if label == "" {
+		return nil, errors.New("Invalid label. It must not be empty.")
+	}
+	switch bits {
+	case 128, 192, 256:
+	default:
+		return nil, errors.Errorf("Invalid key size %d bits. It must be 128, 192 or 256.", bits)
+	}
+	return csp.KeyDeriv(master, &bccsp.HKDFDeriveKeyOpts{
+		Temporary: true,
+		Info:      []byte(label),
+		Length:    bits / 8,
+	})
+}
+
+// ExpandKeys derives a named set of independent symmetric keys of bits
+// length from secret, such as encryption, MAC and IV keys from one master
+// secret. Keys are derived with HKDF using configured hash function and
+// label as info, through KeyDeriv as in DeriveLabeledKey, so that they are
+// tracked as ephemeral and checked in FIPS mode. Labels must be distinct
+// and non-empty. Derived keys are not stored.
+func (csp *CSP) ExpandKeys(secret []byte, labels []string, bits int) (map[string]bccsp.Key, error) {
+	if len(secret) == 0 {
+		return nil, errors.New("Invalid secret. It must not be empty.")
+	}
+	if len(labels) == 0 {
+		return nil, errors.New("Invalid labels. At least one label is required.")
+	}
+	switch bits {
+	case 128, 192, 256:
+	default:
+		return nil, errors.Errorf("Invalid key size %d bits. It must be 128, 192 or 256.", bits)
+	}
+	keys := make(map[string]bccsp.Key, len(labels))
+	for _, label := range labels {
+		if label == "" {
+			return nil, errors.New("Invalid label. It must not be empty.")
+		}
+		if _, dup := keys[label]; dup {
+			return nil, errors.Errorf("Invalid labels. Label [%s] is not distinct.", label)
+		}
+		keys[label] = nil
+	}
+
+	master := &aesPrivateKey{append([]byte{}, secret...), false}
+	defer wipe(master.privKey)
+	for _, label := range labels {
+		k, err := csp.KeyDeriv(master, &bccsp.HKDFDeriveKeyOpts{
+			Temporary: true,
+			Info:      []byte(label),
+			Length:    bits / 8,
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed expanding key [%s]", label)
+		}
+		keys[label] = k
+	}
+	return keys, nil
+}
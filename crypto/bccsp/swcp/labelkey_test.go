@@ -0,0 +1,126 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+)
+
+func TestDeriveLabeledKey(t *testing.T) {
+	provider, _, cleanup := currentTestConfig.Provider(t)
+	defer cleanup()
+	csp := provider.(*CSP)
+
+	master, err := csp.KeyGen(&bccsp.AES256KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+
+	k1, err := csp.DeriveLabeledKey(master, "encryption", 256)
+	assert.NoError(t, err)
+	k2, err := csp.DeriveLabeledKey(master, "encryption", 256)
+	assert.NoError(t, err)
+	assert.Equal(t, k1.SKI(), k2.SKI())
+	assert.True(t, k1.Symmetric())
+
+	other, err := csp.DeriveLabeledKey(master, "authentication", 256)
+	assert.NoError(t, err)
+	assert.NotEqual(t, k1.SKI(), other.SKI())
+	assert.NotEqual(t, master.SKI(), k1.SKI())
+
+	short, err := csp.DeriveLabeledKey(master, "encryption", 128)
+	assert.NoError(t, err)
+	assert.Len(t, short.(*aesPrivateKey).privKey, 16)
+	assert.NotEqual(t, k1.SKI(), short.SKI())
+
+	// Derived key is usable for encryption
+	ct, err := csp.Encrypt(k1, []byte("hello"), &bccsp.AESCBCPKCS7ModeOpts{})
+	assert.NoError(t, err)
+	pt, err := csp.Decrypt(k2, ct, &bccsp.AESCBCPKCS7ModeOpts{})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("hello"), pt)
+
+	_, err = csp.DeriveLabeledKey(master, "", 256)
+	assert.Error(t, err)
+	_, err = csp.DeriveLabeledKey(master, "encryption", 100)
+	assert.Error(t, err)
+	_, err = csp.DeriveLabeledKey(nil, "encryption", 256)
+	assert.Error(t, err)
+	ecKey, err := csp.KeyGen(&bccsp.ECDSAKeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	_, err = csp.DeriveLabeledKey(ecKey, "encryption", 256)
+	assert.Error(t, err)
+}
+
+func TestExpandKeys(t *testing.T) {
+	provider, _, cleanup := currentTestConfig.Provider(t)
+	defer cleanup()
+	csp := provider.(*CSP)
+
+	secret := []byte("shared master secret")
+	labels := []string{"enc", "mac", "iv"}
+	keys, err := csp.ExpandKeys(secret, labels, 128)
+	assert.NoError(t, err)
+	assert.Len(t, keys, 3)
+
+	again, err := csp.ExpandKeys(secret, labels, 128)
+	assert.NoError(t, err)
+	seen := make(map[string]bool)
+	for _, label := range labels {
+		k := keys[label]
+		assert.True(t, k.Symmetric())
+		assert.Len(t, k.(*aesPrivateKey).privKey, 16)
+		assert.Equal(t, k.SKI(), again[label].SKI(), label)
+		assert.False(t, seen[string(k.SKI())], label)
+		seen[string(k.SKI())] = true
+	}
+
+	// Key of a label does not depend on other labels
+	single, err := csp.ExpandKeys(secret, []string{"mac"}, 128)
+	assert.NoError(t, err)
+	assert.Equal(t, keys["mac"].SKI(), single["mac"].SKI())
+
+	// Different secret yields different keys
+	other, err := csp.ExpandKeys([]byte("other secret"), labels, 128)
+	assert.NoError(t, err)
+	assert.NotEqual(t, keys["enc"].SKI(), other["enc"].SKI())
+
+	// Keys are derived with KeyDeriv
+	master, err := provider.KeyImport(secret, &bccsp.HMACImportKeyOpts{Temporary: true})
+	assert.NoError(t, err)
+	derived, err := provider.KeyDeriv(master, &bccsp.HKDFDeriveKeyOpts{Temporary: true, Info: []byte("enc"), Length: 16})
+	assert.NoError(t, err)
+	assert.Equal(t, derived.SKI(), keys["enc"].SKI())
+	csp.SetStrictEphemeral(true)
+	defer csp.SetStrictEphemeral(false)
+	keys, err = csp.ExpandKeys(secret, labels, 128)
+	assert.NoError(t, err)
+	assert.Equal(t, ErrEphemeralKey, provider.StoreKey(keys["iv"]))
+
+	_, err = csp.ExpandKeys(secret, []string{"enc", "mac", "enc"}, 128)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "not distinct")
+	_, err = csp.ExpandKeys(secret, []string{"enc", ""}, 128)
+	assert.Error(t, err)
+	_, err = csp.ExpandKeys(secret, nil, 128)
+	assert.Error(t, err)
+	_, err = csp.ExpandKeys(nil, labels, 128)
+	assert.Error(t, err)
+	_, err = csp.ExpandKeys(secret, labels, 64)
+	assert.Error(t, err)
+}
@@ -0,0 +1,46 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+// ErrInputTooLarge - Error returned when input exceeds configured maximum size.
+var ErrInputTooLarge = errors.New("Input is too large.")
+
+// SetMaxInputBytes limits size of inputs accepted by Hash, Encrypt and
+// EncryptMulti, and by Sign and Verify when they hash the message, see
+// bccsp.PrehashSignerOpts. Larger inputs are refused with ErrInputTooLarge
+// before any processing, which protects servers handling untrusted input
+// from memory exhaustion. Zero, the default, means unlimited.
+// The limit is stored atomically and applies to operations started after.
+func (csp *CSP) SetMaxInputBytes(n int64) {
+	if n < 0 {
+		n = 0
+	}
+	atomic.StoreInt64(&csp.maxInputBytes, n)
+}
+
+// checkInputSize - Returns ErrInputTooLarge if input of size n exceeds the limit.
+func (csp *CSP) checkInputSize(n int) error {
+	max := atomic.LoadInt64(&csp.maxInputBytes)
+	if max > 0 && int64(n) > max {
+		return ErrInputTooLarge
+	}
+	return nil
+}
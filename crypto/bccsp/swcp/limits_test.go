@@ -0,0 +1,77 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"crypto"
+	"testing"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+	"github.com/ipfn/ipfn/pkg/digest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaxInputBytes(t *testing.T) {
+	provider, _, cleanup := currentTestConfig.Provider(t)
+	defer cleanup()
+	csp := provider.(*CSP)
+
+	aesKey, err := provider.KeyGen(&bccsp.AESKeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	ecKey, err := provider.KeyGen(&bccsp.ECDSAKeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	pk, err := ecKey.PublicKey()
+	assert.NoError(t, err)
+
+	small := make([]byte, 16)
+	large := make([]byte, 17)
+	prehash := &bccsp.PrehashSignerOpts{Hash: crypto.SHA256, HashMessage: true}
+
+	// Unlimited by default
+	_, err = provider.Hash(large, digest.Sha2_256)
+	assert.NoError(t, err)
+
+	csp.SetMaxInputBytes(16)
+
+	_, err = provider.Hash(small, digest.Sha2_256)
+	assert.NoError(t, err)
+	_, err = provider.Hash(large, digest.Sha2_256)
+	assert.Equal(t, ErrInputTooLarge, err)
+
+	_, err = provider.Encrypt(aesKey, small, &bccsp.AESCBCPKCS7ModeOpts{})
+	assert.NoError(t, err)
+	_, err = provider.Encrypt(aesKey, large, &bccsp.AESCBCPKCS7ModeOpts{})
+	assert.Equal(t, ErrInputTooLarge, err)
+
+	_, err = csp.EncryptMulti([]bccsp.Key{pk}, large, nil)
+	assert.Equal(t, ErrInputTooLarge, err)
+
+	signature, err := provider.Sign(ecKey, small, prehash)
+	assert.NoError(t, err)
+	_, err = provider.Sign(ecKey, large, prehash)
+	assert.Equal(t, ErrInputTooLarge, err)
+	_, err = provider.Verify(pk, signature, large, prehash)
+	assert.Equal(t, ErrInputTooLarge, err)
+
+	// Prehashed digests are not limited
+	_, err = provider.Sign(ecKey, make([]byte, 32), nil)
+	assert.NoError(t, err)
+
+	// Zero restores unlimited input
+	csp.SetMaxInputBytes(0)
+	_, err = provider.Hash(large, digest.Sha2_256)
+	assert.NoError(t, err)
+}
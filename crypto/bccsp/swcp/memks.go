@@ -0,0 +1,78 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"encoding/hex"
+	"errors"
+	"sync"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+)
+
+// NewInMemoryKeyStore instantiates a key store
+// that keeps keys in memory only
+func NewInMemoryKeyStore() bccsp.KeyStore {
+	return &inMemoryKeyStore{keys: make(map[string]bccsp.Key)}
+}
+
+// inMemoryKeyStore is a KeyStore which keeps keys in memory by SKI.
+// Keys are lost when the process exits.
+type inMemoryKeyStore struct {
+	sync.RWMutex
+
+	keys map[string]bccsp.Key
+}
+
+// ReadOnly returns true if this KeyStore is read only, false otherwise.
+// If ReadOnly is true then StoreKey will fail.
+func (ks *inMemoryKeyStore) ReadOnly() bool {
+	return false
+}
+
+// Key returns a key object whose SKI is the one passed.
+func (ks *inMemoryKeyStore) Key(ski []byte) (bccsp.Key, error) {
+	ks.RLock()
+	defer ks.RUnlock()
+	k, found := ks.keys[hex.EncodeToString(ski)]
+	if !found {
+		return nil, errors.New("Key not found.")
+	}
+	return k, nil
+}
+
+// StoreKey stores the key k in this KeyStore.
+// If this KeyStore is read only then the method will fail.
+func (ks *inMemoryKeyStore) StoreKey(k bccsp.Key) error {
+	if k == nil {
+		return errors.New("Invalid key. It must not be nil.")
+	}
+	ks.Lock()
+	defer ks.Unlock()
+	ks.keys[hex.EncodeToString(k.SKI())] = k
+	return nil
+}
+
+// SKIs returns subject key identifiers of all keys in the store.
+func (ks *inMemoryKeyStore) SKIs() ([][]byte, error) {
+	ks.RLock()
+	defer ks.RUnlock()
+	skis := make([][]byte, 0, len(ks.keys))
+	for _, k := range ks.keys {
+		skis = append(skis, k.SKI())
+	}
+	return skis, nil
+}
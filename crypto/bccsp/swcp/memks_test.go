@@ -0,0 +1,44 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInMemoryKeyStore(t *testing.T) {
+	t.Parallel()
+
+	ks := NewInMemoryKeyStore()
+	assert.False(t, ks.ReadOnly())
+
+	k := &aesPrivateKey{make([]byte, 32), false}
+	_, err := ks.Key(k.SKI())
+	assert.Error(t, err)
+
+	assert.NoError(t, ks.StoreKey(k))
+	loaded, err := ks.Key(k.SKI())
+	assert.NoError(t, err)
+	assert.Equal(t, k, loaded)
+
+	skis, err := ks.(KeyLister).SKIs()
+	assert.NoError(t, err)
+	assert.Equal(t, [][]byte{k.SKI()}, skis)
+
+	assert.Error(t, ks.StoreKey(nil))
+}
@@ -0,0 +1,82 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+	"github.com/ipfn/ipfn/pkg/digest"
+)
+
+// SignMessage hashes msg with hashType and signs the digest using key k.
+// Default hash type of the CSP is used when hashType is unknown.
+// Ed25519 keys sign msg directly, as Ed25519 hashes the message itself.
+// Hash function of opts, if set, must match hashType.
+func (csp *CSP) SignMessage(k bccsp.Key, msg []byte, hashType digest.Type, opts bccsp.SignerOpts) ([]byte, error) {
+	digest, err := csp.messageDigest(k, msg, hashType, opts)
+	if err != nil {
+		return nil, err
+	}
+	return csp.Sign(k, digest, opts)
+}
+
+// VerifyMessage hashes msg with hashType and verifies signature against
+// the digest using key k. It is the counterpart of SignMessage.
+func (csp *CSP) VerifyMessage(k bccsp.Key, msg, signature []byte, hashType digest.Type, opts bccsp.SignerOpts) (bool, error) {
+	digest, err := csp.messageDigest(k, msg, hashType, opts)
+	if err != nil {
+		return false, err
+	}
+	return csp.Verify(k, signature, digest, opts)
+}
+
+// messageDigest - Returns digest of msg to be signed or verified with key.
+func (csp *CSP) messageDigest(k bccsp.Key, msg []byte, hashType digest.Type, opts bccsp.SignerOpts) ([]byte, error) {
+	if k == nil {
+		return nil, errors.New("Invalid Key. It must not be nil.")
+	}
+	if len(msg) == 0 {
+		return nil, errors.New("Invalid message. Cannot be empty.")
+	}
+	if o, ok := opts.(*bccsp.PrehashSignerOpts); ok && o.HashMessage {
+		return nil, errors.New("Invalid opts. Message is already hashed by the CSP.")
+	}
+	switch k.(type) {
+	case *ed25519PrivateKey, *ed25519PublicKey:
+		return msg, nil
+	}
+	if hashType == digest.UnknownType {
+		hashType = csp.hashType
+	}
+	if hashType == digest.UnknownType {
+		return nil, errors.New("Invalid hash type. It must be set when no default is configured.")
+	}
+	if err := checkOptsHashType(hashType, opts); err != nil {
+		return nil, err
+	}
+	return csp.Hash(msg, hashType)
+}
+
+// checkOptsHashType - Checks hash function of opts, if set, matches hashType.
+func checkOptsHashType(hashType digest.Type, opts bccsp.SignerOpts) error {
+	if opts != nil && opts.HashFunc() != 0 {
+		if t, found := prehashTypes[opts.HashFunc()]; !found || t != hashType {
+			return errors.Errorf("Hash function of opts [%v] does not match hash type [%s]", opts.HashFunc(), hashType)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+	"github.com/ipfn/ipfn/pkg/digest"
+)
+
+func TestSignMessageVerifyMessage(t *testing.T) {
+	provider, _, cleanup := currentTestConfig.Provider(t)
+	defer cleanup()
+	csp := provider.(*CSP)
+
+	msg := []byte("Hello Message")
+	for _, tc := range []struct {
+		keyGen   bccsp.KeyGenOpts
+		hashType digest.Type
+		opts     bccsp.SignerOpts
+	}{
+		{&bccsp.ECDSAP256KeyGenOpts{Temporary: true}, digest.UnknownType, nil},
+		{&bccsp.ECDSAP384KeyGenOpts{Temporary: true}, digest.Sha3_384, nil},
+		{&bccsp.RSA2048KeyGenOpts{Temporary: true}, digest.Sha2_256, &rsa.PSSOptions{SaltLength: 32, Hash: crypto.SHA256}},
+		{&bccsp.ED25519KeyGenOpts{Temporary: true}, digest.UnknownType, nil},
+	} {
+		k, err := provider.KeyGen(tc.keyGen)
+		assert.NoError(t, err)
+		pk, err := k.PublicKey()
+		assert.NoError(t, err)
+
+		sig, err := csp.SignMessage(k, msg, tc.hashType, tc.opts)
+		assert.NoError(t, err, "%T", tc.keyGen)
+
+		valid, err := csp.VerifyMessage(pk, msg, sig, tc.hashType, tc.opts)
+		assert.NoError(t, err, "%T", tc.keyGen)
+		assert.True(t, valid, "%T", tc.keyGen)
+
+		valid, err = csp.VerifyMessage(pk, []byte("Hello Tampered"), sig, tc.hashType, tc.opts)
+		assert.False(t, valid, "%T", tc.keyGen)
+	}
+
+	// Ed25519 signs the message itself
+	k, err := provider.KeyGen(&bccsp.ED25519KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	sig, err := csp.SignMessage(k, msg, digest.Sha2_256, nil)
+	assert.NoError(t, err)
+	valid, err := provider.Verify(k, sig, msg, nil)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+
+	// ECDSA signs the digest of the message
+	k, err = provider.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	sig, err = csp.SignMessage(k, msg, digest.Sha2_256, nil)
+	assert.NoError(t, err)
+	h, err := provider.Hash(msg, digest.Sha2_256)
+	assert.NoError(t, err)
+	valid, err = provider.Verify(k, sig, h, nil)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+	valid, _ = provider.Verify(k, sig, msg, nil)
+	assert.False(t, valid)
+
+	_, err = csp.SignMessage(k, msg, digest.Sha3_384, &bccsp.ECDSASignerOpts{H: crypto.SHA256})
+	assert.Error(t, err)
+	_, err = csp.SignMessage(k, msg, digest.Sha2_256, &bccsp.PrehashSignerOpts{HashMessage: true})
+	assert.EqualError(t, err, "Invalid opts. Message is already hashed by the CSP.")
+	_, err = csp.VerifyMessage(nil, msg, sig, digest.Sha2_256, nil)
+	assert.EqualError(t, err, "Invalid Key. It must not be nil.")
+	_, err = csp.SignMessage(k, nil, digest.Sha2_256, nil)
+	assert.EqualError(t, err, "Invalid message. Cannot be empty.")
+}
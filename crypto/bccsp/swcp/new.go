@@ -18,6 +18,7 @@ package swcp
 import (
 	"crypto/elliptic"
 	"crypto/sha256"
+	"crypto/sha512"
 	"reflect"
 
 	"github.com/pkg/errors"
@@ -53,11 +54,39 @@ func NewWithParams(securityLevel int, hashFamily digest.Family, keyStore bccsp.K
 	if err != nil {
 		return nil, errors.Wrapf(err, "Failed initializing configuration at [%v,%v]", securityLevel, hashFamily)
 	}
+	return newWithConfig(conf, keyStore)
+}
 
-	swbccsp, err := New(keyStore)
+// NewWithSecurityStrength returns a new instance of the software-based BCCSP
+// with default algorithm parameters of the passed security strength in bits,
+// following equivalence of NIST SP 800-57 Part 1 (Table 2), hash family and
+// KeyStore. Parameters are used when key generation opts do not specify size:
+//
+//	strength  ECDSA  RSA   AES  SHA2      SHA3
+//	112       P-256  2048  128  SHA2-256  SHA3-256
+//	128       P-256  3072  128  SHA2-256  SHA3-256
+//	192       P-384  7680  192  SHA2-384  SHA3-384
+//	256       P-521  -     256  SHA2-512  SHA3-512
+//
+// P-256 is used at strength 112 as P-224 keys are not supported.
+// At strength 256 there is no default RSA key size, as generation of
+// RSA 15360 key takes minutes, RSA opts of explicit size must be used.
+// Notice that unlike NewWithParams, security strength 256 selects P-521.
+func NewWithSecurityStrength(strength int, hashFamily digest.Family, keyStore bccsp.KeyStore) (bccsp.BCCSP, error) {
+	conf := &config{}
+	err := conf.setSecurityStrength(strength, hashFamily)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed initializing configuration at [%v,%v]", strength, hashFamily)
+	}
+	return newWithConfig(conf, keyStore)
+}
+
+func newWithConfig(conf *config, keyStore bccsp.KeyStore) (bccsp.BCCSP, error) {
+	swbccsp, err := newCSP(keyStore)
 	if err != nil {
 		return nil, err
 	}
+	swbccsp.hashType = conf.hashType
 
 	// Notice that errors are ignored here because some test will fail if one
 	// of the following call fails.
@@ -67,6 +96,7 @@ func NewWithParams(securityLevel int, hashFamily digest.Family, keyStore bccsp.K
 
 	// Set the decryptors
 	swbccsp.AddWrapper(reflect.TypeOf(&aesPrivateKey{}), &aescbcpkcs7Decryptor{})
+	swbccsp.AddWrapper(reflect.TypeOf(&rsaPrivateKey{}), &rsaDecryptor{})
 
 	// Set the signers
 	swbccsp.AddWrapper(reflect.TypeOf(&ed25519PrivateKey{}), &ed25519Signer{})
@@ -74,21 +104,30 @@ func NewWithParams(securityLevel int, hashFamily digest.Family, keyStore bccsp.K
 	swbccsp.AddWrapper(reflect.TypeOf(&rsaPrivateKey{}), &rsaSigner{})
 
 	// Set the verifiers
+	swbccsp.AddWrapper(reflect.TypeOf(&ed25519PrivateKey{}), &ed25519PrivateKeyVerifier{})
+	swbccsp.AddWrapper(reflect.TypeOf(&ed25519PublicKey{}), &ed25519PublicKeyKeyVerifier{})
 	swbccsp.AddWrapper(reflect.TypeOf(&ecdsaPrivateKey{}), &ecdsaPrivateKeyVerifier{})
 	swbccsp.AddWrapper(reflect.TypeOf(&ecdsaPublicKey{}), &ecdsaPublicKeyKeyVerifier{})
 	swbccsp.AddWrapper(reflect.TypeOf(&rsaPrivateKey{}), &rsaPrivateKeyVerifier{})
 	swbccsp.AddWrapper(reflect.TypeOf(&rsaPublicKey{}), &rsaPublicKeyKeyVerifier{})
 
 	// Set the hashers
+	swbccsp.AddHasher(digest.Sha2_224, &hasher{algo: digest.Sha2_224, impl: sha256.New224})
 	swbccsp.AddHasher(digest.Sha2_256, &hasher{algo: digest.Sha2_256, impl: sha256.New})
+	swbccsp.AddHasher(digest.Sha2_384, &hasher{algo: digest.Sha2_384, impl: sha512.New384})
+	swbccsp.AddHasher(digest.Sha3_224, &hasher{algo: digest.Sha3_224, impl: sha3.New224})
 	swbccsp.AddHasher(digest.Sha3_256, &hasher{algo: digest.Sha3_256, impl: sha3.New256})
 	swbccsp.AddHasher(digest.Sha3_384, &hasher{algo: digest.Sha3_384, impl: sha3.New384})
+	if _, ok := swbccsp.hashers[conf.hashType]; !ok {
+		swbccsp.AddHasher(conf.hashType, &hasher{algo: conf.hashType, impl: conf.hashFunction})
+	}
 
 	// Set the key generators
 	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.ECDSAKeyGenOpts{}), &ecdsaKeyGenerator{curve: conf.ellipticCurve})
 	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.ECDSAP256KeyGenOpts{}), &ecdsaKeyGenerator{curve: elliptic.P256()})
 	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.ECDSAP384KeyGenOpts{}), &ecdsaKeyGenerator{curve: elliptic.P384()})
 	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.ED25519KeyGenOpts{}), &ed25519KeyGenerator{})
+	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.X25519KeyGenOpts{}), &x25519KeyGenerator{})
 	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.AESKeyGenOpts{}), &aesKeyGenerator{length: conf.aesBitLength})
 	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.AES256KeyGenOpts{}), &aesKeyGenerator{length: 32})
 	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.AES192KeyGenOpts{}), &aesKeyGenerator{length: 24})
@@ -112,8 +151,130 @@ func NewWithParams(securityLevel int, hashFamily digest.Family, keyStore bccsp.K
 	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.ECDSAPKIXPublicKeyImportOpts{}), &ecdsaPKIXPublicKeyImportOptsKeyImporter{})
 	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.ECDSAPrivateKeyImportOpts{}), &ecdsaPrivateKeyImportOptsKeyImporter{})
 	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.ECDSAGoPublicKeyImportOpts{}), &ecdsaGoPublicKeyImportOptsKeyImporter{})
+	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.ECDSAPointImportOpts{}), &ecdsaPointImportOptsKeyImporter{})
 	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.RSAGoPublicKeyImportOpts{}), &rsaGoPublicKeyImportOptsKeyImporter{})
+	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.RSAPrivateKeyImportOpts{}), &rsaPrivateKeyImportOptsKeyImporter{})
+	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.X25519PublicKeyImportOpts{}), &x25519PublicKeyImportOptsKeyImporter{})
 	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.X509PublicKeyImportOpts{}), &x509PublicKeyImportOptsKeyImporter{bccsp: swbccsp})
 
+	// Registered wrappers are bound last, so they take precedence
+	bindRegistered(swbccsp)
+
 	return swbccsp, nil
 }
+
+// Config - Configuration of software-based BCCSP, see NewFromConfig.
+type Config struct {
+	// SecurityLevel is the security level as in NewWithParams.
+	// It must not be set together with SecurityStrength.
+	// Security level 256 is used when both are zero.
+	SecurityLevel int
+	// SecurityStrength is the security strength in bits
+	// as in NewWithSecurityStrength.
+	SecurityStrength int
+	// HashFamily is the hash family, SHA2 or SHA3.
+	// SHA2 is used when it is zero.
+	HashFamily digest.Family
+
+	// KeyStorePath is the path of file-based KeyStore.
+	// Keys are kept in memory only when it is empty.
+	KeyStorePath string
+	// Encrypted enables encryption of keys stored in file-based KeyStore
+	// with Password, which must not be empty.
+	Encrypted bool
+	Password  []byte
+	// ReadOnly opens file-based KeyStore read only.
+	ReadOnly bool
+
+	// FIPS restricts algorithms to FIPS approved ones, see NewFIPS.
+	FIPS bool
+	// DeterministicECDSA enables RFC 6979 nonces, see SetDeterministicECDSA.
+	DeterministicECDSA bool
+}
+
+// Validate returns descriptive error if configuration is invalid
+// or its options are incompatible.
+func (cfg Config) Validate() error {
+	if cfg.SecurityLevel < 0 || cfg.SecurityStrength < 0 {
+		return errors.New("Invalid configuration. Security level and strength must not be negative.")
+	}
+	if cfg.SecurityLevel != 0 && cfg.SecurityStrength != 0 {
+		return errors.New("Invalid configuration. Security level and security strength are mutually exclusive.")
+	}
+	if cfg.FIPS {
+		switch cfg.hashFamily() {
+		case digest.FamilySha2, digest.FamilySha3:
+		default:
+			return errors.Errorf("Invalid configuration. Hash family [%s] is not permitted in FIPS mode.", cfg.hashFamily())
+		}
+	}
+	conf := &config{}
+	if err := cfg.setup(conf); err != nil {
+		return errors.Wrap(err, "Invalid configuration")
+	}
+	if cfg.KeyStorePath == "" {
+		switch {
+		case cfg.Encrypted:
+			return errors.New("Invalid configuration. Encrypted key store requires key store path.")
+		case cfg.ReadOnly:
+			return errors.New("Invalid configuration. Read only key store requires key store path.")
+		}
+	}
+	if cfg.Encrypted && len(cfg.Password) == 0 {
+		return errors.New("Invalid configuration. Encrypted key store requires password.")
+	}
+	if !cfg.Encrypted && len(cfg.Password) != 0 {
+		return errors.New("Invalid configuration. Password is set but key store is not encrypted.")
+	}
+	return nil
+}
+
+// hashFamily - Returns configured hash family or SHA2 by default.
+func (cfg Config) hashFamily() digest.Family {
+	if cfg.HashFamily == 0 {
+		return digest.FamilySha2
+	}
+	return cfg.HashFamily
+}
+
+// setup - Sets algorithm parameters of configuration.
+func (cfg Config) setup(conf *config) error {
+	if cfg.SecurityStrength != 0 {
+		return conf.setSecurityStrength(cfg.SecurityStrength, cfg.hashFamily())
+	}
+	level := cfg.SecurityLevel
+	if level == 0 {
+		level = 256
+	}
+	return conf.setSecurityLevel(level, cfg.hashFamily())
+}
+
+// NewFromConfig returns a new instance of the software-based BCCSP
+// created from validated configuration. KeyStore is not created
+// when configuration is invalid.
+func NewFromConfig(cfg Config) (bccsp.BCCSP, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	conf := &config{}
+	if err := cfg.setup(conf); err != nil {
+		return nil, errors.Wrap(err, "Invalid configuration")
+	}
+
+	keyStore := NewInMemoryKeyStore()
+	if cfg.KeyStorePath != "" {
+		ks := &fileBasedKeyStore{}
+		if err := ks.Init(cfg.Password, cfg.KeyStorePath, cfg.ReadOnly); err != nil {
+			return nil, errors.Wrapf(err, "Failed initializing key store at [%v]", cfg.KeyStorePath)
+		}
+		keyStore = ks
+	}
+
+	csp, err := newWithConfig(conf, keyStore)
+	if err != nil {
+		return nil, err
+	}
+	csp.(*CSP).fips = cfg.FIPS
+	csp.(*CSP).SetDeterministicECDSA(cfg.DeterministicECDSA)
+	return csp, nil
+}
@@ -0,0 +1,133 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"hash"
+	"math/big"
+	"sync/atomic"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+)
+
+// SetDeterministicECDSA sets default ECDSA nonce generation of Sign. When
+// enabled, signatures use RFC 6979 deterministic nonces unless signer opts
+// select otherwise using bccsp.ECDSASignerOpts Deterministic field.
+func (csp *CSP) SetDeterministicECDSA(enabled bool) {
+	var v int32
+	if enabled {
+		v = 1
+	}
+	atomic.StoreInt32(&csp.deterministicECDSA, v)
+}
+
+// resolveNonceOpts - Returns ECDSA signer opts with deterministic nonce
+// selected if it is the default and opts do not select it explicitly.
+func (csp *CSP) resolveNonceOpts(k bccsp.Key, opts bccsp.SignerOpts) bccsp.SignerOpts {
+	if atomic.LoadInt32(&csp.deterministicECDSA) == 0 {
+		return opts
+	}
+	if _, ok := k.(*ecdsaPrivateKey); !ok {
+		return opts
+	}
+	deterministic := true
+	switch o := opts.(type) {
+	case nil:
+		return &bccsp.ECDSASignerOpts{Deterministic: &deterministic}
+	case *bccsp.ECDSASignerOpts:
+		if o.Deterministic != nil {
+			return opts
+		}
+		resolved := *o
+		resolved.Deterministic = &deterministic
+		return &resolved
+	default:
+		return &bccsp.ECDSASignerOpts{Deterministic: &deterministic, H: opts.HashFunc()}
+	}
+}
+
+// rfc6979Nonces returns generator of RFC 6979 deterministic nonces for
+// signing digest with key k. HMAC uses hash h if it is available, otherwise
+// SHA-256, SHA-384 or SHA-512 depending on size of the curve order.
+// Subsequent calls return next candidates, as described in section 3.2 h.
+func rfc6979Nonces(k *ecdsa.PrivateKey, digest []byte, h crypto.Hash) func() (*big.Int, error) {
+	n := k.Params().N
+	size := (n.BitLen() + 7) / 8
+
+	var newHash func() hash.Hash
+	switch {
+	case h != 0 && h.Available():
+		newHash = h.New
+	case n.BitLen() <= 256:
+		newHash = sha256.New
+	case n.BitLen() <= 384:
+		newHash = sha512.New384
+	default:
+		newHash = sha512.New
+	}
+
+	// int2octets(x) || bits2octets(h1)
+	seed := make([]byte, 2*size)
+	k.D.FillBytes(seed[:size])
+	z := hashToInt(digest, n)
+	if z.Cmp(n) >= 0 {
+		z.Sub(z, n)
+	}
+	z.FillBytes(seed[size:])
+
+	hlen := newHash().Size()
+	v := make([]byte, hlen)
+	key := make([]byte, hlen)
+	for i := range v {
+		v[i] = 0x01
+	}
+	mac := func(data ...[]byte) []byte {
+		m := hmac.New(newHash, key)
+		for _, d := range data {
+			m.Write(d)
+		}
+		return m.Sum(nil)
+	}
+	key = mac(v, []byte{0x00}, seed)
+	v = mac(v)
+	key = mac(v, []byte{0x01}, seed)
+	v = mac(v)
+
+	first := true
+	return func() (*big.Int, error) {
+		for {
+			if !first {
+				key = mac(v, []byte{0x00})
+				v = mac(v)
+			}
+			first = false
+			t := make([]byte, 0, size+hlen)
+			for len(t) < size {
+				v = mac(v)
+				t = append(t, v...)
+			}
+			nonce := hashToInt(t, n)
+			if nonce.Sign() > 0 && nonce.Cmp(n) < 0 {
+				return nonce, nil
+			}
+		}
+	}
+}
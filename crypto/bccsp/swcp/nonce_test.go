@@ -0,0 +1,112 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRFC6979Vectors(t *testing.T) {
+	// RFC 6979 A.2.5, ECDSA with P-256 and SHA-256
+	d, _ := new(big.Int).SetString("C9AFA9D845BA75166B5C215767B1D6934E50C3DB36E89B127B8A622B120F6721", 16)
+	key := &ecdsa.PrivateKey{D: d}
+	key.Curve = elliptic.P256()
+	key.X, key.Y = key.Curve.ScalarBaseMult(d.Bytes())
+
+	for _, vector := range []struct {
+		message string
+		k, r, s string
+	}{
+		{
+			message: "sample",
+			k:       "a6e3c57dd01abe90086538398355dd4c3b17aa873382b0f24d6129493d8aad60",
+			r:       "efd48b2aacb6a8fd1140dd9cd45e81d69d2c877b56aaf991c34d0ea84eaf3716",
+			s:       "f7cb1c942d657c41d436c7a1b6e29f65f3e900dbb9aff4064dc4ab2f843acda8",
+		},
+		{
+			message: "test",
+			k:       "d16b6ae827f17175e040871a1c7ec3500192c4c92677336ec2537acaee0008e0",
+			r:       "f1abb023518351cd71d881567b1ea663ed3efcf6c5132b354f28d3b0b7d38367",
+			s:       "019f4113742a2b14bd25926b49c649155f267e60d3814b4c0cc84250e46f0083",
+		},
+	} {
+		digest := sha256.Sum256([]byte(vector.message))
+		nonce, err := rfc6979Nonces(key, digest[:], crypto.SHA256)()
+		assert.NoError(t, err)
+		assert.Equal(t, vector.k, hex.EncodeToString(nonce.Bytes()))
+
+		deterministic := true
+		signature, err := signECDSA(key, digest[:], &bccsp.ECDSASignerOpts{Deterministic: &deterministic, H: crypto.SHA256})
+		assert.NoError(t, err)
+		r, s, err := utils.UnmarshalECDSASignature(signature)
+		assert.NoError(t, err)
+		expected, _ := new(big.Int).SetString(vector.s, 16)
+		expected, _, err = utils.ToLowS(&key.PublicKey, expected)
+		assert.NoError(t, err)
+		assert.Equal(t, vector.r, hex.EncodeToString(r.Bytes()))
+		assert.Equal(t, expected, s)
+	}
+}
+
+func TestDeterministicECDSA(t *testing.T) {
+	provider, _, cleanup := currentTestConfig.Provider(t)
+	defer cleanup()
+	csp := provider.(*CSP)
+
+	k, err := provider.KeyGen(&bccsp.ECDSAKeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	digest := sha256.Sum256([]byte("Hello World"))
+	deterministic, random := true, false
+
+	sign := func(opts bccsp.SignerOpts) []byte {
+		signature, err := provider.Sign(k, digest[:], opts)
+		assert.NoError(t, err)
+		valid, err := provider.Verify(k, signature, digest[:], nil)
+		assert.NoError(t, err)
+		assert.True(t, valid)
+		return signature
+	}
+
+	// Per call selection
+	first := sign(&bccsp.ECDSASignerOpts{Deterministic: &deterministic})
+	assert.Equal(t, first, sign(&bccsp.ECDSASignerOpts{Deterministic: &deterministic}))
+	assert.NotEqual(t, sign(&bccsp.ECDSASignerOpts{Deterministic: &random}), sign(&bccsp.ECDSASignerOpts{Deterministic: &random}))
+	assert.NotEqual(t, sign(nil), sign(nil))
+
+	// Blinding does not change deterministic signature
+	assert.Equal(t, first, sign(&bccsp.ECDSASignerOpts{Deterministic: &deterministic, Blind: true}))
+
+	// Provider default applies when opts do not select nonce
+	csp.SetDeterministicECDSA(true)
+	assert.Equal(t, first, sign(nil))
+	assert.Equal(t, first, sign(&bccsp.ECDSASignerOpts{}))
+	assert.Equal(t, first, sign(&bccsp.ECDSASignerOpts{Blind: true}))
+	assert.NotEqual(t, sign(&bccsp.ECDSASignerOpts{Deterministic: &random}), sign(&bccsp.ECDSASignerOpts{Deterministic: &random}))
+
+	csp.SetDeterministicECDSA(false)
+	assert.NotEqual(t, sign(nil), sign(nil))
+	assert.Equal(t, first, sign(&bccsp.ECDSASignerOpts{Deterministic: &deterministic}))
+}
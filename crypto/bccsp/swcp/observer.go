@@ -0,0 +1,144 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+)
+
+// Operations reported to Observer.
+const (
+	OperationSign    = "sign"
+	OperationVerify  = "verify"
+	OperationEncrypt = "encrypt"
+	OperationDecrypt = "decrypt"
+)
+
+// Outcome - Outcome of operation reported to Observer.
+type Outcome int
+
+const (
+	// OutcomeSuccess - Operation succeeded, signature verified.
+	OutcomeSuccess Outcome = iota
+	// OutcomeInvalid - Signature did not verify, without an error.
+	OutcomeInvalid
+	// OutcomeError - Operation failed with an error.
+	OutcomeError
+)
+
+// String - Returns outcome label, e.g. for metrics.
+func (o Outcome) String() string {
+	switch o {
+	case OutcomeSuccess:
+		return "success"
+	case OutcomeInvalid:
+		return "invalid"
+	case OutcomeError:
+		return "error"
+	}
+	return fmt.Sprintf("Outcome(%d)", int(o))
+}
+
+// OperationEvent - Sign, Verify, Encrypt or Decrypt operation of the CSP.
+type OperationEvent struct {
+	// Operation is one of OperationSign, OperationVerify,
+	// OperationEncrypt and OperationDecrypt.
+	Operation string
+	// Algorithm identifies algorithm of the key, e.g. "ecdsa-p256",
+	// "rsa-2048", "ed25519" or "aes-256", see KeyAlgorithm.
+	Algorithm string
+	// Outcome is the outcome of the operation.
+	Outcome Outcome
+	// Duration is the duration of the operation.
+	Duration time.Duration
+}
+
+// Observer - Receives events of CSP operations, e.g. to export metrics
+// labeled by algorithm and outcome. It is called synchronously from
+// the operation and has to be safe for concurrent use.
+type Observer interface {
+	ObserveOperation(event OperationEvent)
+}
+
+// observerHolder - Holds observer in atomic.Value, which requires
+// values of consistent concrete type.
+type observerHolder struct {
+	observer Observer
+}
+
+// SetObserver sets observer of Sign, Verify, Encrypt and Decrypt operations.
+// Passing nil disables it. Operations are not timed when no observer is set.
+// Operations already in progress may still report to the previous observer.
+func (csp *CSP) SetObserver(observer Observer) {
+	csp.observer.Store(observerHolder{observer})
+}
+
+// loadObserver - Returns observer or nil if not set.
+func (csp *CSP) loadObserver() Observer {
+	holder, _ := csp.observer.Load().(observerHolder)
+	return holder.observer
+}
+
+// observe - Reports operation started at start to the observer.
+// Verification reports valid, other operations pass nil.
+func observe(observer Observer, op string, k bccsp.Key, start time.Time, err *error, valid *bool) {
+	outcome := OutcomeSuccess
+	switch {
+	case *err != nil:
+		outcome = OutcomeError
+	case valid != nil && !*valid:
+		outcome = OutcomeInvalid
+	}
+	observer.ObserveOperation(OperationEvent{
+		Operation: op,
+		Algorithm: KeyAlgorithm(k),
+		Outcome:   outcome,
+		Duration:  time.Since(start),
+	})
+}
+
+// KeyAlgorithm - Returns algorithm identifier of the key used in metrics,
+// e.g. "ecdsa-p256", "rsa-2048", "ed25519" or "aes-256".
+// It returns "unknown" for nil keys and keys of other providers.
+func KeyAlgorithm(k bccsp.Key) string {
+	switch kk := k.(type) {
+	case *ecdsaPrivateKey:
+		return "ecdsa-" + curveLabel(kk.privKey.Params().Name)
+	case *ecdsaPublicKey:
+		return "ecdsa-" + curveLabel(kk.pubKey.Params().Name)
+	case *rsaPrivateKey:
+		return fmt.Sprintf("rsa-%d", kk.privKey.N.BitLen())
+	case *rsaPublicKey:
+		return fmt.Sprintf("rsa-%d", kk.pubKey.N.BitLen())
+	case *ed25519PrivateKey, *ed25519PublicKey:
+		return "ed25519"
+	case *aesPrivateKey:
+		return fmt.Sprintf("aes-%d", len(kk.privKey)*8)
+	}
+	return "unknown"
+}
+
+// curveLabel - Returns curve name lowercased without dashes, e.g. "p256".
+func curveLabel(name string) string {
+	if name == "" {
+		return "unknown"
+	}
+	return strings.ToLower(strings.Replace(name, "-", "", -1))
+}
@@ -0,0 +1,128 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+)
+
+type recordingObserver struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func (o *recordingObserver) ObserveOperation(event OperationEvent) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.counts[event.Operation+"/"+event.Algorithm+"/"+event.Outcome.String()]++
+}
+
+func TestObserver(t *testing.T) {
+	provider, _, cleanup := currentTestConfig.Provider(t)
+	defer cleanup()
+	csp := provider.(*CSP)
+
+	ecKey, err := provider.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	rsaKey, err := provider.KeyGen(&bccsp.RSA2048KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	aesKey, err := provider.KeyGen(&bccsp.AES256KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	digest := make([]byte, 32)
+	rsaOpts := &rsa.PSSOptions{SaltLength: 32, Hash: crypto.SHA256}
+
+	// Not observed before observer is set
+	_, err = provider.Sign(ecKey, digest, nil)
+	assert.NoError(t, err)
+
+	observer := &recordingObserver{counts: make(map[string]int)}
+	csp.SetObserver(observer)
+
+	for i := 0; i < 3; i++ {
+		sig, err := provider.Sign(ecKey, digest, nil)
+		assert.NoError(t, err)
+		valid, err := provider.Verify(ecKey, sig, digest, nil)
+		assert.NoError(t, err)
+		assert.True(t, valid)
+	}
+	sig, err := provider.Sign(rsaKey, digest, rsaOpts)
+	assert.NoError(t, err)
+	valid, err := provider.Verify(rsaKey, sig, digest, rsaOpts)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+	sig[0] ^= 1
+	_, err = provider.Verify(rsaKey, sig, digest, rsaOpts)
+	assert.Error(t, err)
+	_, err = provider.Sign(rsaKey, digest, nil)
+	assert.Error(t, err)
+
+	ecSig, err := provider.Sign(ecKey, digest, nil)
+	assert.NoError(t, err)
+	valid, err = provider.Verify(ecKey, ecSig, []byte("another digest of thirty two by."), nil)
+	assert.NoError(t, err)
+	assert.False(t, valid)
+
+	ct, err := provider.Encrypt(aesKey, []byte("Hello"), &bccsp.AESCBCPKCS7ModeOpts{})
+	assert.NoError(t, err)
+	_, err = provider.Decrypt(aesKey, ct, &bccsp.AESCBCPKCS7ModeOpts{})
+	assert.NoError(t, err)
+
+	assert.Equal(t, map[string]int{
+		"sign/ecdsa-p256/success":   4,
+		"verify/ecdsa-p256/success": 3,
+		"verify/ecdsa-p256/invalid": 1,
+		"sign/rsa-2048/success":     1,
+		"sign/rsa-2048/error":       1,
+		"verify/rsa-2048/success":   1,
+		"verify/rsa-2048/error":     1,
+		"encrypt/aes-256/success":   1,
+		"decrypt/aes-256/success":   1,
+	}, observer.counts)
+
+	// Disabled observer
+	csp.SetObserver(nil)
+	_, err = provider.Sign(ecKey, digest, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, observer.counts["sign/ecdsa-p256/success"])
+}
+
+func TestKeyAlgorithm(t *testing.T) {
+	provider, _, cleanup := currentTestConfig.Provider(t)
+	defer cleanup()
+
+	for opts, expected := range map[bccsp.KeyGenOpts]string{
+		&bccsp.ECDSAP384KeyGenOpts{Temporary: true}: "ecdsa-p384",
+		&bccsp.ED25519KeyGenOpts{Temporary: true}:   "ed25519",
+		&bccsp.AES128KeyGenOpts{Temporary: true}:    "aes-128",
+	} {
+		k, err := provider.KeyGen(opts)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, KeyAlgorithm(k))
+		if !k.Symmetric() {
+			pk, err := k.PublicKey()
+			assert.NoError(t, err)
+			assert.Equal(t, expected, KeyAlgorithm(pk))
+		}
+	}
+	assert.Equal(t, "unknown", KeyAlgorithm(nil))
+}
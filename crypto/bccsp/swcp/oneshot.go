@@ -0,0 +1,72 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"math/big"
+
+	"github.com/pkg/errors"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+)
+
+// WithEphemeralKey derives ephemeral key from master with opts, passes it
+// to fn and zeroes the derived key material when fn returns or panics,
+// codifying one-shot usage of derived keys. Key must not be used or retained
+// after fn returns. Opts must be ephemeral, so that the key is never stored.
+//
+// Zeroing is supported for AES, ECDSA and Ed25519 keys, which covers all
+// keys derived by the software CSP. Returns the error returned by fn.
+func (csp *CSP) WithEphemeralKey(master bccsp.Key, opts bccsp.KeyDerivOpts, fn func(bccsp.Key) error) error {
+	if opts == nil {
+		return errors.New("Invalid opts. It must not be nil.")
+	}
+	if !opts.Ephemeral() {
+		return errors.New("Invalid opts. Key must be ephemeral.")
+	}
+	if fn == nil {
+		return errors.New("Invalid callback. It must not be nil.")
+	}
+	k, err := csp.KeyDeriv(master, opts)
+	if err != nil {
+		return err
+	}
+	defer csp.zeroKey(k)
+	return fn(k)
+}
+
+// zeroKey - Overwrites private key material with zeros and forgets the key
+// in strict ephemeral mode.
+func (csp *CSP) zeroKey(k bccsp.Key) {
+	csp.forgetEphemeral(k)
+	switch k := k.(type) {
+	case *aesPrivateKey:
+		wipe(k.privKey)
+	case *ed25519PrivateKey:
+		wipe(k.privKey)
+	case *ecdsaPrivateKey:
+		wipeInt(k.privKey.D)
+	}
+}
+
+// wipeInt - Overwrites big integer with zeros.
+func wipeInt(n *big.Int) {
+	words := n.Bits()
+	for i := range words {
+		words[i] = 0
+	}
+	n.SetInt64(0)
+}
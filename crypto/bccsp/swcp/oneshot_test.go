@@ -0,0 +1,91 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+)
+
+func TestWithEphemeralKey(t *testing.T) {
+	provider, _, cleanup := currentTestConfig.Provider(t)
+	defer cleanup()
+	csp := provider.(*CSP)
+	csp.SetStrictEphemeral(true)
+	defer csp.SetStrictEphemeral(false)
+
+	master, err := csp.KeyGen(&bccsp.AES256KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	opts := &bccsp.HKDFDeriveKeyOpts{Temporary: true, Info: []byte("one-shot")}
+
+	var derived *aesPrivateKey
+	err = csp.WithEphemeralKey(master, opts, func(k bccsp.Key) error {
+		derived = k.(*aesPrivateKey)
+		assert.NotEqual(t, make([]byte, 32), derived.privKey)
+		assert.True(t, csp.isEphemeral(k))
+		_, err := csp.Encrypt(k, []byte("secret"), &bccsp.AESCBCPKCS7ModeOpts{})
+		return err
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, make([]byte, 32), derived.privKey)
+	// Only the master key remains tracked as ephemeral
+	assert.Len(t, csp.ephemeral.skis, 1)
+	assert.True(t, csp.isEphemeral(master))
+
+	// Callback error is returned and key is zeroed
+	failure := errors.New("failure")
+	err = csp.WithEphemeralKey(master, opts, func(k bccsp.Key) error {
+		derived = k.(*aesPrivateKey)
+		return failure
+	})
+	assert.Equal(t, failure, err)
+	assert.Equal(t, make([]byte, 32), derived.privKey)
+
+	// Key is zeroed on panic
+	assert.Panics(t, func() {
+		csp.WithEphemeralKey(master, opts, func(k bccsp.Key) error {
+			derived = k.(*aesPrivateKey)
+			panic("failure")
+		})
+	})
+	assert.Equal(t, make([]byte, 32), derived.privKey)
+
+	// Master key is left intact
+	assert.False(t, bytes.Equal(make([]byte, 32), master.(*aesPrivateKey).privKey))
+
+	ecMaster, err := csp.KeyGen(&bccsp.ECDSAKeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	var ecDerived *ecdsaPrivateKey
+	err = csp.WithEphemeralKey(ecMaster, &bccsp.ECDSAReRandKeyOpts{Temporary: true, Expansion: []byte{1}}, func(k bccsp.Key) error {
+		ecDerived = k.(*ecdsaPrivateKey)
+		assert.NotZero(t, ecDerived.privKey.D.Sign())
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Zero(t, ecDerived.privKey.D.Sign())
+
+	err = csp.WithEphemeralKey(master, &bccsp.HKDFDeriveKeyOpts{Temporary: false}, func(bccsp.Key) error { return nil })
+	assert.Error(t, err)
+	err = csp.WithEphemeralKey(master, nil, func(bccsp.Key) error { return nil })
+	assert.Error(t, err)
+	err = csp.WithEphemeralKey(master, opts, nil)
+	assert.Error(t, err)
+}
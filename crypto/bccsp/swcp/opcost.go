@@ -0,0 +1,196 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ed25519"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+)
+
+// Operation - Operation of the CSP, one of OperationSign, OperationVerify,
+// OperationEncrypt and OperationDecrypt.
+type Operation string
+
+// opCostSamples - Number of operations timed during calibration.
+const opCostSamples = 16
+
+// opCostCache - Calibrated operation costs by operation and key algorithm.
+type opCostCache struct {
+	sync.Mutex
+
+	costs map[string]*opCost
+}
+
+// opCost - Cost calibrated once, done is closed when calibration completes.
+type opCost struct {
+	done chan struct{}
+	cost time.Duration
+	err  error
+}
+
+// EstimateOpCost returns estimated duration of sign or verify operation
+// with the key, e.g. for capacity planning. It is an estimate only: cost
+// is calibrated by timing operations with a freshly generated key of the
+// same algorithm, e.g. "ecdsa-p256" or "rsa-2048", see KeyAlgorithm, once
+// per algorithm on the first call and cached for the lifetime of the CSP.
+// Call it at startup to calibrate ahead of use, calibration of large RSA
+// keys can take minutes. Concurrent calls for the same algorithm wait for
+// a single calibration, calls for other algorithms are not blocked.
+// Actual cost varies with load, input size and opts, and excludes hashing
+// of the message and overhead of hooks, audit and observers.
+func (csp *CSP) EstimateOpCost(op Operation, key bccsp.Key) (time.Duration, error) {
+	if key == nil {
+		return 0, errors.New("Invalid Key. It must not be nil.")
+	}
+	if op != OperationSign && op != OperationVerify {
+		return 0, errors.Errorf("Unsupported operation [%s]", op)
+	}
+	if op == OperationSign && !key.Private() {
+		return 0, errors.New("Invalid Key. Signing requires a private key.")
+	}
+	algorithm := KeyAlgorithm(key)
+	id := string(op) + "/" + algorithm
+
+	c := &csp.opCosts
+	c.Lock()
+	entry, found := c.costs[id]
+	if !found {
+		if c.costs == nil {
+			c.costs = make(map[string]*opCost)
+		}
+		entry = &opCost{done: make(chan struct{})}
+		c.costs[id] = entry
+	}
+	c.Unlock()
+	if found {
+		<-entry.done
+		return entry.cost, entry.err
+	}
+
+	entry.cost, entry.err = csp.calibrateOpCost(op, key)
+	if entry.err != nil {
+		entry.err = errors.Wrapf(entry.err, "Failed calibrating cost of %s with %s", op, algorithm)
+		// Failed calibration is retried by later calls
+		c.Lock()
+		delete(c.costs, id)
+		c.Unlock()
+	}
+	close(entry.done)
+	return entry.cost, entry.err
+}
+
+// calibrateOpCost - Returns average duration of operation with a fresh key
+// of the same algorithm as key.
+func (csp *CSP) calibrateOpCost(op Operation, key bccsp.Key) (time.Duration, error) {
+	priv, err := calibrationKey(key)
+	if err != nil {
+		return 0, err
+	}
+	signer, found := csp.signers[reflect.TypeOf(priv)]
+	if !found {
+		return 0, errors.Errorf("Unsupported 'SignKey' provided [%s]", reflect.TypeOf(priv))
+	}
+	// Verification is timed with the public key when key is public,
+	// which may take another code path than the private key verifier
+	vk := priv
+	if !key.Private() {
+		if vk, err = priv.PublicKey(); err != nil {
+			return 0, err
+		}
+	}
+	verifier, found := csp.verifiers[reflect.TypeOf(vk)]
+	if !found {
+		return 0, errors.Errorf("Unsupported 'VerifyKey' provided [%s]", reflect.TypeOf(vk))
+	}
+	var opts bccsp.SignerOpts
+	if _, ok := priv.(*rsaPrivateKey); ok {
+		opts = &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: crypto.SHA256}
+	}
+	digest := make([]byte, 32)
+	if _, err = rand.Read(digest); err != nil {
+		return 0, err
+	}
+	signature, err := signer.Sign(priv, digest, opts)
+	if err != nil {
+		return 0, err
+	}
+	start := time.Now()
+	for i := 0; i < opCostSamples; i++ {
+		if op == OperationSign {
+			_, err = signer.Sign(priv, digest, opts)
+		} else {
+			_, err = verifier.Verify(vk, signature, digest, opts)
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	cost := time.Since(start) / opCostSamples
+	if cost <= 0 {
+		// Clock resolution can be coarser than the operation
+		cost = 1
+	}
+	return cost, nil
+}
+
+// calibrationKey - Generates private key of the same algorithm as key.
+func calibrationKey(key bccsp.Key) (bccsp.Key, error) {
+	switch k := key.(type) {
+	case *ecdsaPrivateKey:
+		return newCalibrationECDSAKey(k.privKey.PublicKey)
+	case *ecdsaPublicKey:
+		return newCalibrationECDSAKey(*k.pubKey)
+	case *rsaPrivateKey:
+		return newCalibrationRSAKey(k.privKey.N.BitLen())
+	case *rsaPublicKey:
+		return newCalibrationRSAKey(k.pubKey.N.BitLen())
+	case *ed25519PrivateKey, *ed25519PublicKey:
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		return &ed25519PrivateKey{privKey: priv, pubKey: &ed25519PublicKey{pub}}, nil
+	}
+	return nil, errors.Errorf("Unsupported key type [%s]", reflect.TypeOf(key))
+}
+
+// newCalibrationECDSAKey - Generates ECDSA key on the curve of pub.
+func newCalibrationECDSAKey(pub ecdsa.PublicKey) (bccsp.Key, error) {
+	priv, err := ecdsa.GenerateKey(pub.Curve, rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return &ecdsaPrivateKey{priv}, nil
+}
+
+// newCalibrationRSAKey - Generates RSA key of bits size.
+func newCalibrationRSAKey(bits int) (bccsp.Key, error) {
+	priv, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		return nil, err
+	}
+	return &rsaPrivateKey{priv}, nil
+}
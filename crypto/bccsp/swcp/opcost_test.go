@@ -0,0 +1,119 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+)
+
+func TestEstimateOpCost(t *testing.T) {
+	provider, _, cleanup := currentTestConfig.Provider(t)
+	defer cleanup()
+	csp := provider.(*CSP)
+
+	digest := make([]byte, 32)
+	for _, opts := range []bccsp.KeyGenOpts{
+		&bccsp.ECDSAP256KeyGenOpts{Temporary: true},
+		&bccsp.ED25519KeyGenOpts{Temporary: true},
+		&bccsp.RSA2048KeyGenOpts{Temporary: true},
+	} {
+		k, err := provider.KeyGen(opts)
+		assert.NoError(t, err)
+		pk, err := k.PublicKey()
+		assert.NoError(t, err)
+		var signerOpts bccsp.SignerOpts
+		if _, ok := k.(*rsaPrivateKey); ok {
+			signerOpts = &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: crypto.SHA256}
+		}
+
+		signCost, err := csp.EstimateOpCost(OperationSign, k)
+		assert.NoError(t, err)
+		assert.True(t, signCost > 0)
+		verifyCost, err := csp.EstimateOpCost(OperationVerify, pk)
+		assert.NoError(t, err)
+		assert.True(t, verifyCost > 0)
+
+		// Estimates are cached
+		cached, err := csp.EstimateOpCost(OperationSign, k)
+		assert.NoError(t, err)
+		assert.Equal(t, signCost, cached)
+
+		const n = 16
+		var signature []byte
+		start := time.Now()
+		for i := 0; i < n; i++ {
+			signature, err = provider.Sign(k, digest, signerOpts)
+			assert.NoError(t, err)
+		}
+		assertSameMagnitude(t, time.Since(start)/n, signCost, KeyAlgorithm(k))
+		start = time.Now()
+		for i := 0; i < n; i++ {
+			valid, err := provider.Verify(pk, signature, digest, signerOpts)
+			assert.NoError(t, err)
+			assert.True(t, valid)
+		}
+		assertSameMagnitude(t, time.Since(start)/n, verifyCost, KeyAlgorithm(pk))
+	}
+
+	// Concurrent calls share one calibration
+	ecKey, err := provider.KeyGen(&bccsp.ECDSAP384KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	var wg sync.WaitGroup
+	costs := make([]time.Duration, 8)
+	for i := range costs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			costs[i], _ = csp.EstimateOpCost(OperationSign, ecKey)
+		}(i)
+	}
+	wg.Wait()
+	for _, cost := range costs {
+		assert.True(t, cost > 0)
+		assert.Equal(t, costs[0], cost)
+	}
+
+	aesKey, err := provider.KeyGen(&bccsp.AES256KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	_, err = csp.EstimateOpCost(OperationSign, aesKey)
+	assert.Error(t, err)
+	_, err = csp.EstimateOpCost(OperationSign, nil)
+	assert.Error(t, err)
+
+	ecKey, err = provider.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	_, err = csp.EstimateOpCost(OperationEncrypt, ecKey)
+	assert.Error(t, err)
+	pk, err := ecKey.PublicKey()
+	assert.NoError(t, err)
+	_, err = csp.EstimateOpCost(OperationSign, pk)
+	assert.Error(t, err)
+}
+
+// assertSameMagnitude - Asserts estimate is within two orders of magnitude
+// of actual cost, which tolerates noise of shared test machines.
+func assertSameMagnitude(t *testing.T, actual, estimate time.Duration, algorithm string) {
+	assert.True(t, estimate < actual*100 && actual < estimate*100,
+		"%s: estimate %s, actual %s", algorithm, estimate, actual)
+}
@@ -0,0 +1,85 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+	"github.com/ipfn/ipfn/pkg/digest"
+)
+
+// framedHashTypes - Hash types of families supported by digest.SumFramed.
+var framedHashTypes = map[digest.Family]digest.Type{
+	digest.FamilySha2:   digest.Sha2_256,
+	digest.FamilySha3:   digest.Sha3_256,
+	digest.FamilyKeccak: digest.Keccak256,
+}
+
+// SignParts signs message composed of multiple parts using key k.
+// Parts are length-prefixed and hashed with digest.SumFramed, so that
+// signature of parts ["ab", "c"] is not valid for parts ["a", "bc"].
+//
+// Hash type must be one of SHA2-256, SHA3-256 or Keccak-256. When it is
+// unknown, 256 bit hash of the default hash family of the CSP is used.
+// Ed25519 keys sign the framed digest. Hash function of opts, if set,
+// must match hashType.
+func (csp *CSP) SignParts(k bccsp.Key, parts [][]byte, hashType digest.Type, opts bccsp.SignerOpts) ([]byte, error) {
+	digest, err := csp.partsDigest(k, parts, hashType, opts)
+	if err != nil {
+		return nil, err
+	}
+	return csp.Sign(k, digest, opts)
+}
+
+// VerifyParts verifies signature of message composed of multiple parts
+// using key k. It is the counterpart of SignParts.
+func (csp *CSP) VerifyParts(k bccsp.Key, parts [][]byte, signature []byte, hashType digest.Type, opts bccsp.SignerOpts) (bool, error) {
+	digest, err := csp.partsDigest(k, parts, hashType, opts)
+	if err != nil {
+		return false, err
+	}
+	return csp.Verify(k, signature, digest, opts)
+}
+
+// partsDigest - Returns framed digest of parts to be signed or verified with key.
+func (csp *CSP) partsDigest(k bccsp.Key, parts [][]byte, hashType digest.Type, opts bccsp.SignerOpts) ([]byte, error) {
+	if k == nil {
+		return nil, errors.New("Invalid Key. It must not be nil.")
+	}
+	if len(parts) == 0 {
+		return nil, errors.New("Invalid parts. Cannot be empty.")
+	}
+	if o, ok := opts.(*bccsp.PrehashSignerOpts); ok && o.HashMessage {
+		return nil, errors.New("Invalid opts. Message is already hashed by the CSP.")
+	}
+	family := hashType.Family()
+	if hashType == digest.UnknownType {
+		family = csp.hashType.Family()
+		hashType = framedHashTypes[family]
+	}
+	if t, found := framedHashTypes[family]; !found || t != hashType {
+		return nil, errors.Errorf("Unsupported hash type [%s]. Parts can be hashed with SHA2-256, SHA3-256 or Keccak-256.", hashType)
+	}
+	if err := checkOptsHashType(hashType, opts); err != nil {
+		return nil, err
+	}
+	digest, err := digest.SumFramed(family, parts...)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed hashing parts")
+	}
+	return digest[:], nil
+}
@@ -0,0 +1,85 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+	"github.com/ipfn/ipfn/pkg/digest"
+)
+
+func TestSignPartsVerifyParts(t *testing.T) {
+	provider, _, cleanup := currentTestConfig.Provider(t)
+	defer cleanup()
+	csp := provider.(*CSP)
+
+	ab := [][]byte{[]byte("ab"), []byte("c")}
+	bc := [][]byte{[]byte("a"), []byte("bc")}
+	for _, tc := range []struct {
+		keyGen   bccsp.KeyGenOpts
+		hashType digest.Type
+		opts     bccsp.SignerOpts
+	}{
+		{&bccsp.ECDSAP256KeyGenOpts{Temporary: true}, digest.UnknownType, nil},
+		{&bccsp.ECDSAP384KeyGenOpts{Temporary: true}, digest.Sha3_256, nil},
+		{&bccsp.ECDSAP256KeyGenOpts{Temporary: true}, digest.Keccak256, nil},
+		{&bccsp.RSA2048KeyGenOpts{Temporary: true}, digest.Sha2_256, &rsa.PSSOptions{SaltLength: 32, Hash: crypto.SHA256}},
+		{&bccsp.ED25519KeyGenOpts{Temporary: true}, digest.UnknownType, nil},
+	} {
+		k, err := provider.KeyGen(tc.keyGen)
+		assert.NoError(t, err)
+		pk, err := k.PublicKey()
+		assert.NoError(t, err)
+
+		sigAB, err := csp.SignParts(k, ab, tc.hashType, tc.opts)
+		assert.NoError(t, err, "%T", tc.keyGen)
+		sigBC, err := csp.SignParts(k, bc, tc.hashType, tc.opts)
+		assert.NoError(t, err, "%T", tc.keyGen)
+
+		valid, err := csp.VerifyParts(pk, ab, sigAB, tc.hashType, tc.opts)
+		assert.NoError(t, err, "%T", tc.keyGen)
+		assert.True(t, valid, "%T", tc.keyGen)
+		valid, err = csp.VerifyParts(pk, bc, sigBC, tc.hashType, tc.opts)
+		assert.NoError(t, err, "%T", tc.keyGen)
+		assert.True(t, valid, "%T", tc.keyGen)
+
+		// Signatures of ambiguous concatenations are not interchangeable
+		valid, _ = csp.VerifyParts(pk, bc, sigAB, tc.hashType, tc.opts)
+		assert.False(t, valid, "%T", tc.keyGen)
+		valid, _ = csp.VerifyParts(pk, ab, sigBC, tc.hashType, tc.opts)
+		assert.False(t, valid, "%T", tc.keyGen)
+	}
+
+	k, err := provider.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	_, err = csp.SignParts(k, ab, digest.Sha2_512, nil)
+	assert.Error(t, err)
+	_, err = csp.SignParts(k, ab, digest.Murmur3, nil)
+	assert.Error(t, err)
+	_, err = csp.SignParts(k, nil, digest.Sha2_256, nil)
+	assert.Error(t, err)
+	_, err = csp.SignParts(nil, ab, digest.Sha2_256, nil)
+	assert.Error(t, err)
+	_, err = csp.SignParts(k, ab, digest.Sha3_256, &rsa.PSSOptions{Hash: crypto.SHA256})
+	assert.Error(t, err)
+	_, err = csp.SignParts(k, ab, digest.Sha2_256, &bccsp.PrehashSignerOpts{HashMessage: true})
+	assert.Error(t, err)
+}
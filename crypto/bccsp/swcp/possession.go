@@ -0,0 +1,65 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"reflect"
+
+	"github.com/pkg/errors"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp/utils"
+)
+
+// ProvePossession returns proof of possession of private key over challenge,
+// e.g. to register its public key with a service, which verifies it using
+// utils.VerifyPossession. Proof is a signature of domain separated digest of
+// challenge, see utils.PossessionDigest, so it cannot be used as a signature
+// of other data. Proofs are not passed to signature processors nor recorded
+// in audit chain.
+func (csp *CSP) ProvePossession(key bccsp.Key, challenge []byte) ([]byte, error) {
+	if key == nil {
+		return nil, errors.New("Invalid Key. It must not be nil.")
+	}
+	if key.Symmetric() || !key.Private() {
+		return nil, errors.New("Invalid Key. It must be an asymmetric private key.")
+	}
+	hash, err := utils.PossessionDigest(challenge)
+	if err != nil {
+		return nil, err
+	}
+	signer, found := csp.signers[reflect.TypeOf(key)]
+	if !found {
+		return nil, errors.Errorf("Unsupported 'SignKey' provided [%s]", reflect.TypeOf(key))
+	}
+	if csp.isRevoked(key) {
+		return nil, ErrKeyRevoked
+	}
+	if err = csp.checkFIPSKey(key); err != nil {
+		return nil, err
+	}
+	var opts bccsp.SignerOpts
+	if _, ok := key.(*rsaPrivateKey); ok {
+		opts = &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: crypto.SHA256}
+	}
+	proof, err := signer.Sign(key, hash, opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed signing proof of possession")
+	}
+	return proof, nil
+}
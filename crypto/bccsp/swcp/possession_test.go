@@ -0,0 +1,91 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp/utils"
+)
+
+func TestProvePossession(t *testing.T) {
+	provider, _, cleanup := currentTestConfig.Provider(t)
+	defer cleanup()
+	csp := provider.(*CSP)
+
+	challenge := []byte("registration nonce 1")
+	for _, opts := range []bccsp.KeyGenOpts{
+		&bccsp.ECDSAP256KeyGenOpts{Temporary: true},
+		&bccsp.ECDSAP384KeyGenOpts{Temporary: true},
+		&bccsp.ED25519KeyGenOpts{Temporary: true},
+		&bccsp.RSA2048KeyGenOpts{Temporary: true},
+	} {
+		k, err := csp.KeyGen(opts)
+		assert.NoError(t, err)
+		pk, err := k.PublicKey()
+		assert.NoError(t, err)
+
+		proof, err := csp.ProvePossession(k, challenge)
+		assert.NoError(t, err)
+		valid, err := utils.VerifyPossession(pk, challenge, proof)
+		assert.NoError(t, err)
+		assert.True(t, valid, "%T", opts)
+
+		// Replay against different challenge
+		valid, err = utils.VerifyPossession(pk, []byte("registration nonce 2"), proof)
+		assert.NoError(t, err)
+		assert.False(t, valid, "%T", opts)
+
+		// Proof is not a signature of the challenge itself
+		digest := sha256.Sum256(challenge)
+		var signerOpts bccsp.SignerOpts
+		if _, ok := k.(*rsaPrivateKey); ok {
+			signerOpts = &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: crypto.SHA256}
+		}
+		sig, err := csp.Sign(k, digest[:], signerOpts)
+		assert.NoError(t, err)
+		valid, err = utils.VerifyPossession(pk, challenge, sig)
+		assert.NoError(t, err)
+		assert.False(t, valid, "%T", opts)
+
+		// Key of other holder
+		other, err := csp.KeyGen(opts)
+		assert.NoError(t, err)
+		otherPk, err := other.PublicKey()
+		assert.NoError(t, err)
+		valid, err = utils.VerifyPossession(otherPk, challenge, proof)
+		assert.NoError(t, err)
+		assert.False(t, valid, "%T", opts)
+
+		_, err = csp.ProvePossession(pk, challenge)
+		assert.Error(t, err)
+		_, err = csp.ProvePossession(k, nil)
+		assert.Error(t, err)
+	}
+
+	aes, err := csp.KeyGen(&bccsp.AES256KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	_, err = csp.ProvePossession(aes, challenge)
+	assert.Error(t, err)
+	_, err = utils.VerifyPossession(aes, challenge, []byte{1})
+	assert.Error(t, err)
+}
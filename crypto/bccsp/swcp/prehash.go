@@ -0,0 +1,144 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+
+	"github.com/pkg/errors"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+	"github.com/ipfn/ipfn/pkg/digest"
+)
+
+// ErrDigestHashMismatch - Error returned when digest length does not match
+// size of the hash function declared by signer opts.
+var ErrDigestHashMismatch = errors.New("Digest does not match declared hash function.")
+
+// prehashTypes maps standard hash identifiers to digest types.
+var prehashTypes = map[crypto.Hash]digest.Type{
+	crypto.SHA1:     digest.Sha1,
+	crypto.SHA224:   digest.Sha2_224,
+	crypto.SHA256:   digest.Sha2_256,
+	crypto.SHA384:   digest.Sha2_384,
+	crypto.SHA512:   digest.Sha2_512,
+	crypto.SHA3_224: digest.Sha3_224,
+	crypto.SHA3_256: digest.Sha3_256,
+	crypto.SHA3_384: digest.Sha3_384,
+	crypto.SHA3_512: digest.Sha3_512,
+}
+
+// prehash returns the digest to be signed or verified with key k and opts
+// to be passed to the signer or verifier. Only bccsp.PrehashSignerOpts with
+// HashMessage set cause msg to be hashed, in every other case msg is
+// returned unchanged. For RSA keys bccsp.PrehashSignerOpts are replaced
+// with the hash function of the digest, which selects PKCS #1 v1.5.
+func (csp *CSP) prehash(k bccsp.Key, msg []byte, opts bccsp.SignerOpts) ([]byte, bccsp.SignerOpts, error) {
+	o, ok := opts.(*bccsp.PrehashSignerOpts)
+	if !ok || o == nil {
+		return msg, opts, nil
+	}
+	if !o.HashMessage {
+		rsaOpts, err := rsaPrehashOpts(k, o, o.Hash)
+		return msg, rsaOpts, err
+	}
+	hashType := csp.hashType
+	if curveHash, ok := curveHashType(k); ok && o.Hash == 0 && o.AutoHashForCurve {
+		hashType = curveHash
+	}
+	if o.Hash != 0 {
+		t, found := prehashTypes[o.Hash]
+		if !found {
+			return nil, nil, errors.Errorf("Unsupported prehash function [%v]", o.Hash)
+		}
+		hashType = t
+	}
+	if hashType == digest.UnknownType {
+		return nil, nil, errors.New("Invalid opts. Hash function must be set when no default is configured.")
+	}
+	digest, err := csp.Hash(msg, hashType)
+	if err != nil {
+		return nil, nil, err
+	}
+	opts, err = rsaPrehashOpts(k, o, prehashFunc(hashType))
+	if err != nil {
+		return nil, nil, err
+	}
+	return digest, opts, nil
+}
+
+// rsaPrehashOpts - Returns hash function h as signer opts of RSA key,
+// which RSA signers and verifiers require, opts are kept for other keys.
+func rsaPrehashOpts(k bccsp.Key, opts *bccsp.PrehashSignerOpts, h crypto.Hash) (bccsp.SignerOpts, error) {
+	switch k.(type) {
+	case *rsaPrivateKey, *rsaPublicKey:
+	default:
+		return opts, nil
+	}
+	if h == 0 {
+		return nil, errors.New("Invalid opts. Hash function must be set for RSA keys.")
+	}
+	return h, nil
+}
+
+// prehashFunc - Returns standard hash identifier of digest type,
+// or zero if there is none.
+func prehashFunc(t digest.Type) crypto.Hash {
+	for h, pt := range prehashTypes {
+		if pt == t {
+			return h
+		}
+	}
+	return 0
+}
+
+// curveHashType - Returns SHA-2 hash type matching the curve of ECDSA key,
+// see bccsp.PrehashSignerOpts AutoHashForCurve.
+func curveHashType(k bccsp.Key) (digest.Type, bool) {
+	var pub *ecdsa.PublicKey
+	switch kk := k.(type) {
+	case *ecdsaPrivateKey:
+		pub = &kk.privKey.PublicKey
+	case *ecdsaPublicKey:
+		pub = kk.pubKey
+	default:
+		return digest.UnknownType, false
+	}
+	switch bits := pub.Curve.Params().BitSize; {
+	case bits <= 224:
+		return digest.Sha2_224, true
+	case bits <= 256:
+		return digest.Sha2_256, true
+	case bits <= 384:
+		return digest.Sha2_384, true
+	}
+	return digest.Sha2_512, true
+}
+
+// checkDigestHash - Returns ErrDigestHashMismatch if length of digest does not
+// match size of hash function declared by opts. Opts without hash function,
+// or with one not linked into the binary, are not checked.
+func checkDigestHash(digest []byte, opts bccsp.SignerOpts) error {
+	if opts == nil {
+		return nil
+	}
+	h := opts.HashFunc()
+	if h == 0 || !h.Available() || len(digest) == h.Size() {
+		return nil
+	}
+	return errors.Wrapf(ErrDigestHashMismatch, "Digest length %d does not match declared %s", len(digest), h)
+}
@@ -0,0 +1,253 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp/utils"
+	"github.com/ipfn/ipfn/pkg/digest"
+)
+
+func TestPrehashSignerOpts(t *testing.T) {
+	provider, _, cleanup := currentTestConfig.Provider(t)
+	defer cleanup()
+	csp := provider.(*CSP)
+
+	k, err := provider.KeyGen(&bccsp.ECDSAKeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	pk, err := k.PublicKey()
+	assert.NoError(t, err)
+
+	msg := []byte("Hello World")
+	digest := sha256.Sum256(msg)
+	raw := &bccsp.PrehashSignerOpts{Hash: crypto.SHA256, HashMessage: true}
+	prehashed := &bccsp.PrehashSignerOpts{Hash: crypto.SHA256}
+
+	// Message hashed by the CSP verifies against the caller's digest
+	signature, err := provider.Sign(k, msg, raw)
+	assert.NoError(t, err)
+	valid, err := provider.Verify(pk, signature, digest[:], nil)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+	valid, err = provider.Verify(pk, signature, digest[:], prehashed)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+	r, s, err := utils.UnmarshalECDSASignature(signature)
+	assert.NoError(t, err)
+	assert.True(t, ecdsa.Verify(&k.(*ecdsaPrivateKey).privKey.PublicKey, digest[:], r, s))
+
+	// And the other way around
+	signature, err = provider.Sign(k, digest[:], prehashed)
+	assert.NoError(t, err)
+	valid, err = provider.Verify(pk, signature, msg, raw)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+	valid, err = provider.Verify(pk, signature, msg, prehashed)
+	assert.Equal(t, ErrDigestHashMismatch, errors.Cause(err))
+	assert.False(t, valid)
+
+	// Without explicit hash the configured one is used
+	signature, err = provider.Sign(k, msg, &bccsp.PrehashSignerOpts{HashMessage: true})
+	assert.NoError(t, err)
+	configured, err := provider.Hash(msg, csp.hashType)
+	assert.NoError(t, err)
+	valid, err = provider.Verify(pk, signature, configured, nil)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+
+	_, err = provider.Sign(k, msg, &bccsp.PrehashSignerOpts{Hash: crypto.MD5, HashMessage: true})
+	assert.Error(t, err)
+}
+
+func TestPrehashWithoutConfiguredHash(t *testing.T) {
+	csp := &CSP{}
+	_, _, err := csp.prehash(nil, []byte("msg"), &bccsp.PrehashSignerOpts{HashMessage: true})
+	assert.Error(t, err)
+
+	out, _, err := csp.prehash(nil, []byte("msg"), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("msg"), out)
+
+	// Zero value is prehashed
+	out, _, err = csp.prehash(nil, []byte("msg"), &bccsp.PrehashSignerOpts{})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("msg"), out)
+}
+
+func TestDigestHashMismatch(t *testing.T) {
+	provider, _, cleanup := currentTestConfig.Provider(t)
+	defer cleanup()
+
+	k, err := provider.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	digest := sha256.Sum256([]byte("Hello World"))
+	signature, err := provider.Sign(k, digest[:], crypto.SHA256)
+	assert.NoError(t, err)
+
+	valid, err := provider.Verify(k, signature, digest[:], crypto.SHA256)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+
+	valid, err = provider.Verify(k, signature, digest[:], crypto.SHA384)
+	assert.Equal(t, ErrDigestHashMismatch, errors.Cause(err))
+	assert.Contains(t, err.Error(), "Digest length 32 does not match declared SHA-384")
+	assert.False(t, valid)
+
+	rsaKey, err := provider.KeyGen(&bccsp.RSA2048KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	opts := &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: crypto.SHA256}
+	signature, err = provider.Sign(rsaKey, digest[:], opts)
+	assert.NoError(t, err)
+	_, err = provider.Verify(rsaKey, signature, digest[:], &rsa.PSSOptions{Hash: crypto.SHA512})
+	assert.Equal(t, ErrDigestHashMismatch, errors.Cause(err))
+	assert.Contains(t, err.Error(), "Digest length 32 does not match declared SHA-512")
+}
+
+func TestPrehashSHA224(t *testing.T) {
+	provider, _, cleanup := currentTestConfig.Provider(t)
+	defer cleanup()
+
+	k, err := provider.KeyGen(&bccsp.RSA2048KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	pk, err := k.PublicKey()
+	assert.NoError(t, err)
+
+	msg := []byte("Hello World")
+	digest := sha256.Sum224(msg)
+
+	// PKCS #1 v1.5 signature over SHA-224 digest hashed by the CSP
+	signature, err := provider.Sign(k, msg, &bccsp.PrehashSignerOpts{Hash: crypto.SHA224, HashMessage: true})
+	assert.NoError(t, err)
+	assert.NoError(t, rsa.VerifyPKCS1v15(&k.(*rsaPrivateKey).privKey.PublicKey, crypto.SHA224, digest[:], signature))
+	valid, err := provider.Verify(pk, signature, digest[:], crypto.SHA224)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+}
+
+func TestAutoHashForCurve(t *testing.T) {
+	provider, _, cleanup := currentTestConfig.Provider(t)
+	defer cleanup()
+
+	msg := []byte("Hello World")
+	auto := &bccsp.PrehashSignerOpts{HashMessage: true, AutoHashForCurve: true}
+	for _, test := range []struct {
+		opts bccsp.KeyGenOpts
+		hash crypto.Hash
+	}{
+		{&bccsp.ECDSAP256KeyGenOpts{Temporary: true}, crypto.SHA256},
+		{&bccsp.ECDSAP384KeyGenOpts{Temporary: true}, crypto.SHA384},
+	} {
+		k, err := provider.KeyGen(test.opts)
+		assert.NoError(t, err)
+		pk, err := k.PublicKey()
+		assert.NoError(t, err)
+
+		h := test.hash.New()
+		h.Write(msg)
+		hashed := h.Sum(nil)
+
+		signature, err := provider.Sign(k, msg, auto)
+		assert.NoError(t, err)
+		r, s, err := utils.UnmarshalECDSASignature(signature)
+		assert.NoError(t, err)
+		assert.True(t, ecdsa.Verify(&k.(*ecdsaPrivateKey).privKey.PublicKey, hashed, r, s), "%s", test.hash)
+
+		valid, err := provider.Verify(pk, signature, msg, auto)
+		assert.NoError(t, err)
+		assert.True(t, valid)
+		valid, err = provider.Verify(pk, signature, hashed, test.hash)
+		assert.NoError(t, err)
+		assert.True(t, valid)
+	}
+
+	// Explicit hash takes precedence
+	k, err := provider.KeyGen(&bccsp.ECDSAP384KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	signature, err := provider.Sign(k, msg, &bccsp.PrehashSignerOpts{Hash: crypto.SHA256, HashMessage: true, AutoHashForCurve: true})
+	assert.NoError(t, err)
+	hashed := sha256.Sum256(msg)
+	valid, err := provider.Verify(k, signature, hashed[:], nil)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+
+	// Other keys use configured hash
+	k, err = provider.KeyGen(&bccsp.ED25519KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	signature, err = provider.Sign(k, msg, auto)
+	assert.NoError(t, err)
+	configured, err := provider.Hash(msg, provider.(*CSP).hashType)
+	assert.NoError(t, err)
+	valid, err = provider.Verify(k, signature, configured, nil)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+
+	for _, curve := range []elliptic.Curve{elliptic.P224(), elliptic.P521()} {
+		priv, err := ecdsa.GenerateKey(curve, rand.Reader)
+		assert.NoError(t, err)
+		hashType, ok := curveHashType(&ecdsaPrivateKey{privKey: priv})
+		assert.True(t, ok)
+		expected := map[string]digest.Type{"P-224": digest.Sha2_224, "P-521": digest.Sha2_512}
+		assert.Equal(t, expected[curve.Params().Name], hashType)
+	}
+}
+
+func TestPrehashRSA(t *testing.T) {
+	provider, _, cleanup := currentTestConfig.Provider(t)
+	defer cleanup()
+
+	k, err := provider.KeyGen(&bccsp.RSA2048KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	pk, err := k.PublicKey()
+	assert.NoError(t, err)
+
+	msg := []byte("Hello World")
+	digest := sha256.Sum256(msg)
+	raw := &bccsp.PrehashSignerOpts{Hash: crypto.SHA256, HashMessage: true}
+	prehashed := &bccsp.PrehashSignerOpts{Hash: crypto.SHA256}
+
+	for _, opts := range []*bccsp.PrehashSignerOpts{raw, prehashed} {
+		input := msg
+		if !opts.HashMessage {
+			input = digest[:]
+		}
+		signature, err := provider.Sign(k, input, opts)
+		assert.NoError(t, err)
+		assert.NoError(t, rsa.VerifyPKCS1v15(&k.(*rsaPrivateKey).privKey.PublicKey, crypto.SHA256, digest[:], signature))
+		valid, err := provider.Verify(pk, signature, msg, raw)
+		assert.NoError(t, err)
+		assert.True(t, valid)
+		valid, err = provider.Verify(pk, signature, digest[:], prehashed)
+		assert.NoError(t, err)
+		assert.True(t, valid)
+	}
+
+	// Hash function of prehashed digest is required
+	_, err = provider.Sign(k, digest[:], &bccsp.PrehashSignerOpts{})
+	assert.Error(t, err)
+	_, err = provider.Verify(pk, []byte("signature"), digest[:], &bccsp.PrehashSignerOpts{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,68 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+)
+
+// VerifyQuorum returns true if at least threshold of signatures verify
+// against digest under distinct keys, for multi-signature approval policies.
+//
+// Keys with the same SKI are counted once and every signature is counted
+// for at most one key. Signatures failing to verify, including malformed
+// ones, do not count towards the threshold and are not reported as errors.
+// Threshold must be between 1 and number of keys.
+func (csp *CSP) VerifyQuorum(keys []bccsp.Key, sigs [][]byte, digest []byte, threshold int, opts bccsp.SignerOpts) (bool, error) {
+	if threshold < 1 || threshold > len(keys) {
+		return false, errors.Errorf("Invalid threshold %d. It must be between 1 and %d.", threshold, len(keys))
+	}
+	for i, k := range keys {
+		if k == nil {
+			return false, errors.Errorf("Invalid key %d. It must not be nil.", i)
+		}
+	}
+
+	var (
+		count   int
+		counted = make(map[string]bool, len(keys))
+		used    = make([]bool, len(sigs))
+	)
+	for _, k := range keys {
+		ski := string(k.SKI())
+		if counted[ski] {
+			continue
+		}
+		for i, sig := range sigs {
+			if used[i] {
+				continue
+			}
+			if valid, err := csp.Verify(k, sig, digest, opts); err != nil || !valid {
+				continue
+			}
+			used[i] = true
+			counted[ski] = true
+			count++
+			break
+		}
+		if count >= threshold {
+			return true, nil
+		}
+	}
+	return false, nil
+}
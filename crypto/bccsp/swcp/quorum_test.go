@@ -0,0 +1,84 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+)
+
+func TestVerifyQuorum(t *testing.T) {
+	provider, _, cleanup := currentTestConfig.Provider(t)
+	defer cleanup()
+	csp := provider.(*CSP)
+
+	digest := sha256.Sum256([]byte("approve release"))
+	other := sha256.Sum256([]byte("reject release"))
+
+	var (
+		keys []bccsp.Key
+		sigs [][]byte
+	)
+	for i := 0; i < 5; i++ {
+		k, err := csp.KeyGen(&bccsp.ECDSAKeyGenOpts{Temporary: true})
+		assert.NoError(t, err)
+		pk, err := k.PublicKey()
+		assert.NoError(t, err)
+		keys = append(keys, pk)
+
+		msg := digest[:]
+		if i >= 3 {
+			// signature over a different message
+			msg = other[:]
+		}
+		sig, err := csp.Sign(k, msg, nil)
+		assert.NoError(t, err)
+		sigs = append(sigs, sig)
+	}
+	// malformed signature
+	sigs = append(sigs, []byte("garbage"))
+
+	for threshold, expected := range map[int]bool{1: true, 2: true, 3: true, 4: false, 5: false} {
+		ok, err := csp.VerifyQuorum(keys, sigs, digest[:], threshold, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, ok, "threshold %d", threshold)
+	}
+
+	// Signature order does not matter
+	reversed := make([][]byte, len(sigs))
+	for i, sig := range sigs {
+		reversed[len(sigs)-1-i] = sig
+	}
+	ok, err := csp.VerifyQuorum(keys, reversed, digest[:], 3, nil)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	// Repeated signature and key count once
+	ok, err = csp.VerifyQuorum([]bccsp.Key{keys[0], keys[0], keys[3]}, [][]byte{sigs[0], sigs[0]}, digest[:], 2, nil)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	_, err = csp.VerifyQuorum(keys, sigs, digest[:], 6, nil)
+	assert.Error(t, err)
+	_, err = csp.VerifyQuorum(keys, sigs, digest[:], 0, nil)
+	assert.Error(t, err)
+	_, err = csp.VerifyQuorum([]bccsp.Key{nil}, sigs, digest[:], 1, nil)
+	assert.Error(t, err)
+}
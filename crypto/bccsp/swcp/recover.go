@@ -0,0 +1,87 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"math/big"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/pkg/errors"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp/utils"
+)
+
+// recoverableSignatureSize - Size of r || s || v recoverable signature.
+const recoverableSignatureSize = 65
+
+// VerifyRecoverable verifies secp256k1 recoverable signature of digest
+// by the key with SKI expectedSKI, without the public key at hand.
+//
+// Signature is 65 bytes r || s || v with recovery id v being 0 or 1,
+// 27 or 28 is accepted as well. Public key is recovered from signature,
+// its SKI, computed as SKIs of ECDSA keys of this CSP, must match
+// expectedSKI. Signature must be low-S, unless disabled for the curve
+// with utils.SetLowSPolicy. Signature of different key results in false
+// and no error.
+func (csp *CSP) VerifyRecoverable(digest, recoverableSig []byte, expectedSKI []byte) (bool, error) {
+	if len(digest) == 0 {
+		return false, errors.New("Invalid digest. Cannot be empty.")
+	}
+	if len(expectedSKI) == 0 {
+		return false, errors.New("Invalid SKI. Cannot be of zero length.")
+	}
+	if len(recoverableSig) != recoverableSignatureSize {
+		return false, errors.Errorf("Invalid signature length %d, expected %d", len(recoverableSig), recoverableSignatureSize)
+	}
+	v, err := utils.RecoveryID(recoverableSig[64])
+	if err != nil {
+		return false, err
+	}
+
+	curve := btcec.S256()
+	r := new(big.Int).SetBytes(recoverableSig[:32])
+	s := new(big.Int).SetBytes(recoverableSig[32:64])
+	if utils.LowSEnforced(curve) {
+		halfOrder, err := utils.CurveHalfOrder(curve)
+		if err != nil {
+			return false, err
+		}
+		if s.Cmp(halfOrder) > 0 {
+			return false, errors.New("Invalid signature. S must be in the lower half of the order.")
+		}
+	}
+
+	compact := make([]byte, recoverableSignatureSize)
+	compact[0] = 27 + v
+	copy(compact[1:], recoverableSig[:64])
+	pub, _, err := btcec.RecoverCompact(curve, compact, digest)
+	if err != nil {
+		return false, nil
+	}
+	k := &ecdsaPublicKey{pubKey: pub.ToECDSA()}
+	if err := csp.checkFIPSKey(k); err != nil {
+		return false, err
+	}
+	if !bytes.Equal(k.SKI(), expectedSKI) {
+		return false, nil
+	}
+	if csp.isRevokedOnVerify(k) {
+		return false, ErrKeyRevoked
+	}
+	return ecdsa.Verify(k.pubKey, digest, r, s), nil
+}
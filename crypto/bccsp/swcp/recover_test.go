@@ -0,0 +1,86 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"crypto/sha256"
+	"math/big"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyRecoverable(t *testing.T) {
+	provider, _, cleanup := currentTestConfig.Provider(t)
+	defer cleanup()
+	csp := provider.(*CSP)
+
+	priv, pub := btcec.PrivKeyFromBytes(btcec.S256(), big.NewInt(0x1234567890).Bytes())
+	digest := sha256.Sum256([]byte("Hello Recoverable"))
+	compact, err := btcec.SignCompact(btcec.S256(), priv, digest[:], false)
+	assert.NoError(t, err)
+	// Convert header || r || s to r || s || v
+	sig := append(append([]byte{}, compact[1:]...), compact[0]-27)
+	ski := sha256.Sum256(pub.SerializeUncompressed())
+
+	valid, err := csp.VerifyRecoverable(digest[:], sig, ski[:])
+	assert.NoError(t, err)
+	assert.True(t, valid)
+
+	// Recovery id 27 or 28 is accepted
+	sig27 := append([]byte{}, sig...)
+	sig27[64] += 27
+	valid, err = csp.VerifyRecoverable(digest[:], sig27, ski[:])
+	assert.NoError(t, err)
+	assert.True(t, valid)
+
+	// Mismatched SKI
+	other := sha256.Sum256([]byte("other key"))
+	valid, err = csp.VerifyRecoverable(digest[:], sig, other[:])
+	assert.NoError(t, err)
+	assert.False(t, valid)
+
+	// Different digest recovers different key
+	tampered := sha256.Sum256([]byte("Hello Tampered"))
+	valid, err = csp.VerifyRecoverable(tampered[:], sig, ski[:])
+	assert.NoError(t, err)
+	assert.False(t, valid)
+
+	// High-S signature is rejected
+	highS := append([]byte{}, sig...)
+	s := new(big.Int).Sub(btcec.S256().N, new(big.Int).SetBytes(sig[32:64]))
+	s.FillBytes(highS[32:64])
+	highS[64] ^= 1
+	_, err = csp.VerifyRecoverable(digest[:], highS, ski[:])
+	assert.EqualError(t, err, "Invalid signature. S must be in the lower half of the order.")
+
+	badV := append([]byte{}, sig...)
+	badV[64] = 2
+	_, err = csp.VerifyRecoverable(digest[:], badV, ski[:])
+	assert.EqualError(t, err, "Invalid signature recovery id 2")
+	_, err = csp.VerifyRecoverable(digest[:], sig[:64], ski[:])
+	assert.EqualError(t, err, "Invalid signature length 64, expected 65")
+	_, err = csp.VerifyRecoverable(digest[:], sig, nil)
+	assert.Error(t, err)
+
+	// secp256k1 is not permitted in FIPS mode
+	fips, err := NewFIPS(256, currentTestConfig.hashFamily, NewDummyKeyStore())
+	assert.NoError(t, err)
+	_, err = fips.(*CSP).VerifyRecoverable(digest[:], sig, ski[:])
+	assert.Equal(t, ErrNotPermittedFIPS, errors.Cause(err))
+}
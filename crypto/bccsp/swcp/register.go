@@ -0,0 +1,161 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"reflect"
+	"sync"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+)
+
+// registry contains wrappers registered for custom algorithms.
+// Registered wrappers are bound to every CSP instance created afterwards.
+var registry = newWrapperRegistry()
+
+// wrapperRegistry - Wrappers registered for custom algorithms by type.
+type wrapperRegistry struct {
+	sync.RWMutex
+
+	keyGenerators map[reflect.Type]bccsp.KeyGenerator
+	keyDerivers   map[reflect.Type]bccsp.KeyDeriver
+	keyImporters  map[reflect.Type]bccsp.KeyImporter
+	encryptors    map[reflect.Type]bccsp.Encryptor
+	decryptors    map[reflect.Type]bccsp.Decryptor
+	signers       map[reflect.Type]bccsp.Signer
+	verifiers     map[reflect.Type]bccsp.Verifier
+}
+
+// newWrapperRegistry - Creates empty registry.
+func newWrapperRegistry() *wrapperRegistry {
+	return &wrapperRegistry{
+		keyGenerators: make(map[reflect.Type]bccsp.KeyGenerator),
+		keyDerivers:   make(map[reflect.Type]bccsp.KeyDeriver),
+		keyImporters:  make(map[reflect.Type]bccsp.KeyImporter),
+		encryptors:    make(map[reflect.Type]bccsp.Encryptor),
+		decryptors:    make(map[reflect.Type]bccsp.Decryptor),
+		signers:       make(map[reflect.Type]bccsp.Signer),
+		verifiers:     make(map[reflect.Type]bccsp.Verifier),
+	}
+}
+
+// RegisterKeyGenerator - Registers key generator for custom key generation options type.
+//
+// Registered wrappers are bound to CSP instances when they are created,
+// registration should happen before, preferably in package init function.
+// Registered wrappers take precedence over built-in wrappers of the same type.
+// It panics if type or generator is nil.
+func RegisterKeyGenerator(optsType reflect.Type, gen bccsp.KeyGenerator) {
+	if optsType == nil || gen == nil {
+		panic("swcp: RegisterKeyGenerator type and generator cannot be nil")
+	}
+	registry.Lock()
+	defer registry.Unlock()
+	registry.keyGenerators[optsType] = gen
+}
+
+// RegisterKeyDeriver - Registers key deriver for custom key type.
+// See RegisterKeyGenerator for details.
+func RegisterKeyDeriver(keyType reflect.Type, deriver bccsp.KeyDeriver) {
+	if keyType == nil || deriver == nil {
+		panic("swcp: RegisterKeyDeriver type and deriver cannot be nil")
+	}
+	registry.Lock()
+	defer registry.Unlock()
+	registry.keyDerivers[keyType] = deriver
+}
+
+// RegisterKeyImporter - Registers key importer for custom key import options type.
+// See RegisterKeyGenerator for details.
+func RegisterKeyImporter(optsType reflect.Type, importer bccsp.KeyImporter) {
+	if optsType == nil || importer == nil {
+		panic("swcp: RegisterKeyImporter type and importer cannot be nil")
+	}
+	registry.Lock()
+	defer registry.Unlock()
+	registry.keyImporters[optsType] = importer
+}
+
+// RegisterEncryptor - Registers encryptor for custom key type.
+// See RegisterKeyGenerator for details.
+func RegisterEncryptor(keyType reflect.Type, enc bccsp.Encryptor) {
+	if keyType == nil || enc == nil {
+		panic("swcp: RegisterEncryptor type and encryptor cannot be nil")
+	}
+	registry.Lock()
+	defer registry.Unlock()
+	registry.encryptors[keyType] = enc
+}
+
+// RegisterDecryptor - Registers decryptor for custom key type.
+// See RegisterKeyGenerator for details.
+func RegisterDecryptor(keyType reflect.Type, dec bccsp.Decryptor) {
+	if keyType == nil || dec == nil {
+		panic("swcp: RegisterDecryptor type and decryptor cannot be nil")
+	}
+	registry.Lock()
+	defer registry.Unlock()
+	registry.decryptors[keyType] = dec
+}
+
+// RegisterSigner - Registers signer for custom key type.
+// See RegisterKeyGenerator for details.
+func RegisterSigner(keyType reflect.Type, signer bccsp.Signer) {
+	if keyType == nil || signer == nil {
+		panic("swcp: RegisterSigner type and signer cannot be nil")
+	}
+	registry.Lock()
+	defer registry.Unlock()
+	registry.signers[keyType] = signer
+}
+
+// RegisterVerifier - Registers verifier for custom key type.
+// See RegisterKeyGenerator for details.
+func RegisterVerifier(keyType reflect.Type, verifier bccsp.Verifier) {
+	if keyType == nil || verifier == nil {
+		panic("swcp: RegisterVerifier type and verifier cannot be nil")
+	}
+	registry.Lock()
+	defer registry.Unlock()
+	registry.verifiers[keyType] = verifier
+}
+
+// bindRegistered - Binds registered wrappers to CSP instance.
+func bindRegistered(csp *CSP) {
+	registry.RLock()
+	defer registry.RUnlock()
+	for t, w := range registry.keyGenerators {
+		csp.keyGenerators[t] = w
+	}
+	for t, w := range registry.keyDerivers {
+		csp.keyDerivers[t] = w
+	}
+	for t, w := range registry.keyImporters {
+		csp.keyImporters[t] = w
+	}
+	for t, w := range registry.encryptors {
+		csp.encryptors[t] = w
+	}
+	for t, w := range registry.decryptors {
+		csp.decryptors[t] = w
+	}
+	for t, w := range registry.signers {
+		csp.signers[t] = w
+	}
+	for t, w := range registry.verifiers {
+		csp.verifiers[t] = w
+	}
+}
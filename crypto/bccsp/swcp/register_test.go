@@ -0,0 +1,216 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+	"github.com/stretchr/testify/assert"
+)
+
+// dummyKeyGenOpts contains options for dummy key generation.
+type dummyKeyGenOpts struct{}
+
+func (*dummyKeyGenOpts) Algorithm() string { return "DUMMY" }
+func (*dummyKeyGenOpts) Ephemeral() bool   { return true }
+
+// dummyKey is a symmetric key of a dummy algorithm which
+// signs with SHA-256 of key and digest and encrypts with XOR.
+type dummyKey struct {
+	secret []byte
+}
+
+func (k *dummyKey) Bytes() ([]byte, error)        { return nil, errors.New("Not supported.") }
+func (k *dummyKey) SKI() []byte                   { s := sha256.Sum256(k.secret); return s[:] }
+func (k *dummyKey) Symmetric() bool               { return true }
+func (k *dummyKey) Private() bool                 { return true }
+func (k *dummyKey) PublicKey() (bccsp.Key, error) { return nil, errors.New("Not supported.") }
+
+func (k *dummyKey) sign(digest []byte) []byte {
+	h := sha256.New()
+	h.Write(k.secret)
+	h.Write(digest)
+	return h.Sum(nil)
+}
+
+func (k *dummyKey) xor(src []byte) (dst []byte) {
+	dst = make([]byte, len(src))
+	for i := range src {
+		dst[i] = src[i] ^ k.secret[i%len(k.secret)]
+	}
+	return
+}
+
+// dummyKeyImportOpts contains options for dummy key import.
+type dummyKeyImportOpts struct{}
+
+func (*dummyKeyImportOpts) Algorithm() string { return "DUMMY" }
+func (*dummyKeyImportOpts) Ephemeral() bool   { return true }
+
+type dummyWrapper struct{}
+
+func (*dummyWrapper) KeyGen(opts bccsp.KeyGenOpts) (bccsp.Key, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	return &dummyKey{secret: secret}, nil
+}
+
+func (*dummyWrapper) KeyDeriv(k bccsp.Key, opts bccsp.KeyDerivOpts) (bccsp.Key, error) {
+	return &dummyKey{secret: k.(*dummyKey).sign(nil)}, nil
+}
+
+func (*dummyWrapper) KeyImport(raw interface{}, opts bccsp.KeyImportOpts) (bccsp.Key, error) {
+	secret, ok := raw.([]byte)
+	if !ok || len(secret) == 0 {
+		return nil, errors.New("Invalid raw material. Expected non-empty byte array.")
+	}
+	return &dummyKey{secret: secret}, nil
+}
+
+func (*dummyWrapper) Sign(k bccsp.Key, digest []byte, opts bccsp.SignerOpts) ([]byte, error) {
+	return k.(*dummyKey).sign(digest), nil
+}
+
+func (*dummyWrapper) Verify(k bccsp.Key, signature, digest []byte, opts bccsp.SignerOpts) (bool, error) {
+	return bytes.Equal(k.(*dummyKey).sign(digest), signature), nil
+}
+
+func (*dummyWrapper) Encrypt(k bccsp.Key, plaintext []byte, opts bccsp.EncrypterOpts) ([]byte, error) {
+	return k.(*dummyKey).xor(plaintext), nil
+}
+
+func (*dummyWrapper) Decrypt(k bccsp.Key, ciphertext []byte, opts bccsp.DecrypterOpts) ([]byte, error) {
+	return k.(*dummyKey).xor(ciphertext), nil
+}
+
+// resetRegistry replaces the global registry with an empty one
+// and restores the previous registry when the test completes.
+func resetRegistry(t *testing.T) {
+	saved := registry
+	registry = newWrapperRegistry()
+	t.Cleanup(func() { registry = saved })
+}
+
+func TestRegisterCustomAlgorithm(t *testing.T) {
+	resetRegistry(t)
+
+	keyType := reflect.TypeOf(&dummyKey{})
+	RegisterKeyGenerator(reflect.TypeOf(&dummyKeyGenOpts{}), &dummyWrapper{})
+	RegisterSigner(keyType, &dummyWrapper{})
+	RegisterVerifier(keyType, &dummyWrapper{})
+	RegisterEncryptor(keyType, &dummyWrapper{})
+	RegisterDecryptor(keyType, &dummyWrapper{})
+
+	provider, _, cleanup := currentTestConfig.Provider(t)
+	defer cleanup()
+
+	k, err := provider.KeyGen(&dummyKeyGenOpts{})
+	assert.NoError(t, err)
+	assert.IsType(t, &dummyKey{}, k)
+
+	digest := sha256.Sum256([]byte("Hello World"))
+	signature, err := provider.Sign(k, digest[:], nil)
+	assert.NoError(t, err)
+	valid, err := provider.Verify(k, signature, digest[:], nil)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+	valid, err = provider.Verify(k, signature, []byte("Hello World"), nil)
+	assert.NoError(t, err)
+	assert.False(t, valid)
+
+	msg := []byte("Hello World")
+	ct, err := provider.Encrypt(k, msg, nil)
+	assert.NoError(t, err)
+	assert.NotEqual(t, msg, ct)
+	pt, err := provider.Decrypt(k, ct, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, msg, pt)
+}
+
+func TestRegisterKeyDeriverAndImporter(t *testing.T) {
+	resetRegistry(t)
+
+	RegisterKeyDeriver(reflect.TypeOf(&dummyKey{}), &dummyWrapper{})
+	RegisterKeyImporter(reflect.TypeOf(&dummyKeyImportOpts{}), &dummyWrapper{})
+
+	provider, _, cleanup := currentTestConfig.Provider(t)
+	defer cleanup()
+
+	k, err := provider.KeyImport([]byte("secret"), &dummyKeyImportOpts{})
+	assert.NoError(t, err)
+	assert.Equal(t, &dummyKey{secret: []byte("secret")}, k)
+
+	dk, err := provider.KeyDeriv(k, &bccsp.HKDFDeriveKeyOpts{Temporary: true})
+	assert.NoError(t, err)
+	assert.Equal(t, &dummyKey{secret: k.(*dummyKey).sign(nil)}, dk)
+
+	_, err = provider.KeyImport(nil, &dummyKeyImportOpts{})
+	assert.Error(t, err)
+}
+
+// overrideSigner signs every digest with a fixed signature.
+type overrideSigner struct{}
+
+func (*overrideSigner) Sign(k bccsp.Key, digest []byte, opts bccsp.SignerOpts) ([]byte, error) {
+	return []byte("override"), nil
+}
+
+func TestRegisterOverridesBuiltin(t *testing.T) {
+	resetRegistry(t)
+
+	RegisterSigner(reflect.TypeOf(&ecdsaPrivateKey{}), &overrideSigner{})
+
+	provider, _, cleanup := currentTestConfig.Provider(t)
+	defer cleanup()
+
+	k, err := provider.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+
+	digest := sha256.Sum256([]byte("Hello World"))
+	signature, err := provider.Sign(k, digest[:], nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("override"), signature)
+}
+
+func TestRegisterReset(t *testing.T) {
+	saved := registry
+	t.Run("reset", func(t *testing.T) {
+		resetRegistry(t)
+		RegisterSigner(reflect.TypeOf(&dummyKey{}), &dummyWrapper{})
+		assert.Len(t, registry.signers, 1)
+	})
+	assert.True(t, saved == registry)
+	assert.NotContains(t, registry.signers, reflect.TypeOf(&dummyKey{}))
+}
+
+func TestRegisterNilPanics(t *testing.T) {
+	assert.Panics(t, func() { RegisterKeyGenerator(nil, &dummyWrapper{}) })
+	assert.Panics(t, func() { RegisterKeyGenerator(reflect.TypeOf(&dummyKeyGenOpts{}), nil) })
+	assert.Panics(t, func() { RegisterKeyDeriver(nil, &dummyWrapper{}) })
+	assert.Panics(t, func() { RegisterKeyImporter(reflect.TypeOf(&dummyKeyImportOpts{}), nil) })
+	assert.Panics(t, func() { RegisterSigner(reflect.TypeOf(&dummyKey{}), nil) })
+	assert.Panics(t, func() { RegisterVerifier(nil, &dummyWrapper{}) })
+	assert.Panics(t, func() { RegisterEncryptor(nil, &dummyWrapper{}) })
+	assert.Panics(t, func() { RegisterDecryptor(nil, &dummyWrapper{}) })
+}
@@ -0,0 +1,73 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+)
+
+// ErrKeyRevoked - Error returned when operation is requested with a revoked key.
+var ErrKeyRevoked = errors.New("Key is revoked.")
+
+// revocationList - Set of revoked subject key identifiers.
+type revocationList struct {
+	sync.RWMutex
+
+	skis   map[string]bool
+	verify bool
+}
+
+// SetRevocationList replaces the set of revoked keys by their SKIs.
+// Sign refuses revoked keys with ErrKeyRevoked. Passing nil clears the list.
+// The list is swapped under a lock, operations already in progress
+// may still see the previous list.
+func (csp *CSP) SetRevocationList(skis [][]byte) {
+	revoked := make(map[string]bool, len(skis))
+	for _, ski := range skis {
+		revoked[string(ski)] = true
+	}
+	csp.revoked.Lock()
+	csp.revoked.skis = revoked
+	csp.revoked.Unlock()
+}
+
+// SetRevocationOnVerify configures whether Verify refuses revoked keys.
+// It is disabled by default so signatures created before revocation still
+// verify. When enabled, verification of every signature of a revoked key
+// fails with ErrKeyRevoked, including historical signatures.
+func (csp *CSP) SetRevocationOnVerify(enabled bool) {
+	csp.revoked.Lock()
+	csp.revoked.verify = enabled
+	csp.revoked.Unlock()
+}
+
+// isRevoked - Returns true if key is revoked.
+func (csp *CSP) isRevoked(k bccsp.Key) bool {
+	csp.revoked.RLock()
+	defer csp.revoked.RUnlock()
+	return len(csp.revoked.skis) != 0 && csp.revoked.skis[string(k.SKI())]
+}
+
+// isRevokedOnVerify - Returns true if key is revoked
+// and Verify is configured to refuse revoked keys.
+func (csp *CSP) isRevokedOnVerify(k bccsp.Key) bool {
+	csp.revoked.RLock()
+	defer csp.revoked.RUnlock()
+	return csp.revoked.verify && len(csp.revoked.skis) != 0 && csp.revoked.skis[string(k.SKI())]
+}
@@ -0,0 +1,72 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRevocationList(t *testing.T) {
+	provider, _, cleanup := currentTestConfig.Provider(t)
+	defer cleanup()
+	csp := provider.(*CSP)
+
+	revoked, err := provider.KeyGen(&bccsp.ECDSAKeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	unrevoked, err := provider.KeyGen(&bccsp.ECDSAKeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+
+	digest := sha256.Sum256([]byte("Hello World"))
+	historical, err := provider.Sign(revoked, digest[:], nil)
+	assert.NoError(t, err)
+
+	csp.SetRevocationList([][]byte{revoked.SKI()})
+
+	_, err = provider.Sign(revoked, digest[:], nil)
+	assert.Equal(t, ErrKeyRevoked, err)
+
+	signature, err := provider.Sign(unrevoked, digest[:], nil)
+	assert.NoError(t, err)
+	valid, err := provider.Verify(unrevoked, signature, digest[:], nil)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+
+	// Historical signatures verify unless configured otherwise
+	pk, err := revoked.PublicKey()
+	assert.NoError(t, err)
+	valid, err = provider.Verify(pk, historical, digest[:], nil)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+
+	csp.SetRevocationOnVerify(true)
+	_, err = provider.Verify(pk, historical, digest[:], nil)
+	assert.Equal(t, ErrKeyRevoked, err)
+	valid, err = provider.Verify(unrevoked, signature, digest[:], nil)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+
+	// Clearing the list restores the key
+	csp.SetRevocationList(nil)
+	_, err = provider.Sign(revoked, digest[:], nil)
+	assert.NoError(t, err)
+	valid, err = provider.Verify(pk, historical, digest[:], nil)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+}
@@ -0,0 +1,82 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"bytes"
+
+	"github.com/pkg/errors"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+)
+
+// Rewrap - Decrypts ciphertext with old key and encrypts recovered
+// plaintext with new key in one operation. Plaintext never leaves the
+// provider and is wiped before returning.
+//
+// Mode of the ciphertext is selected as follows:
+//   - ECDSA private old key: ECIES envelope of EncryptMulti, new key is
+//     the only recipient of the result, opts must be nil,
+//   - *bccsp.AESGCMStreamModeOpts: AES-GCM stream of EncryptStreamAEAD,
+//     opts apply to the resulting stream,
+//   - otherwise: Decrypt and Encrypt with the same opts, e.g. AES-CBC modes.
+//
+// Error is returned when old key fails to decrypt the ciphertext.
+// Notice that unauthenticated modes such as plain AES-CBC-PKCS7 detect
+// wrong old key only by chance of invalid padding.
+func (csp *CSP) Rewrap(oldKey, newKey bccsp.Key, ciphertext []byte, opts bccsp.EncrypterOpts) ([]byte, error) {
+	if oldKey == nil || newKey == nil {
+		return nil, errors.New("Invalid Key. It must not be nil.")
+	}
+	if _, ok := oldKey.(*ecdsaPrivateKey); ok {
+		if opts != nil {
+			return nil, errors.Errorf("Unsupported ECIES options [%T]", opts)
+		}
+		plaintext, err := csp.DecryptMulti(oldKey, ciphertext, nil)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed decrypting with old key")
+		}
+		defer wipe(plaintext)
+		return csp.EncryptMulti([]bccsp.Key{newKey}, plaintext, nil)
+	}
+
+	if _, ok := opts.(*bccsp.AESGCMStreamModeOpts); ok {
+		var plain bytes.Buffer
+		if err := csp.DecryptStreamAEAD(oldKey, bytes.NewReader(ciphertext), &plain, nil); err != nil {
+			return nil, errors.Wrap(err, "Failed decrypting with old key")
+		}
+		defer wipe(plain.Bytes())
+		var sealed bytes.Buffer
+		if err := csp.EncryptStreamAEAD(newKey, bytes.NewReader(plain.Bytes()), &sealed, opts); err != nil {
+			return nil, err
+		}
+		return sealed.Bytes(), nil
+	}
+
+	plaintext, err := csp.Decrypt(oldKey, ciphertext, opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed decrypting with old key")
+	}
+	defer wipe(plaintext)
+	return csp.Encrypt(newKey, plaintext, opts)
+}
+
+// wipe - Overwrites buffer with zeros.
+func wipe(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
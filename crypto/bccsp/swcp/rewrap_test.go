@@ -0,0 +1,112 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+)
+
+func TestRewrapAESGCM(t *testing.T) {
+	provider, _, cleanup := currentTestConfig.Provider(t)
+	defer cleanup()
+	csp := provider.(*CSP)
+
+	oldKey, err := provider.KeyGen(&bccsp.AES256KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	newKey, err := provider.KeyGen(&bccsp.AES256KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+
+	msg := []byte("Hello Rewrap")
+	opts := &bccsp.AESGCMStreamModeOpts{ChunkSize: 5}
+	var ct bytes.Buffer
+	assert.NoError(t, csp.EncryptStreamAEAD(oldKey, bytes.NewReader(msg), &ct, opts))
+
+	rewrapped, err := csp.Rewrap(oldKey, newKey, ct.Bytes(), opts)
+	assert.NoError(t, err)
+
+	var pt bytes.Buffer
+	assert.NoError(t, csp.DecryptStreamAEAD(newKey, bytes.NewReader(rewrapped), &pt, nil))
+	assert.Equal(t, msg, pt.Bytes())
+	pt.Reset()
+	assert.Error(t, csp.DecryptStreamAEAD(oldKey, bytes.NewReader(rewrapped), &pt, nil))
+
+	// Old key must decrypt the input
+	_, err = csp.Rewrap(newKey, oldKey, ct.Bytes(), opts)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Failed decrypting with old key")
+}
+
+func TestRewrapAESCBC(t *testing.T) {
+	provider, _, cleanup := currentTestConfig.Provider(t)
+	defer cleanup()
+	csp := provider.(*CSP)
+
+	oldKey, err := provider.KeyGen(&bccsp.AES256KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	newKey, err := provider.KeyGen(&bccsp.AES256KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+
+	msg := []byte("Hello Rewrap")
+	ct, err := provider.Encrypt(oldKey, msg, &bccsp.AESCBCPKCS7ModeOpts{})
+	assert.NoError(t, err)
+
+	rewrapped, err := csp.Rewrap(oldKey, newKey, ct, &bccsp.AESCBCPKCS7ModeOpts{})
+	assert.NoError(t, err)
+	pt, err := provider.Decrypt(newKey, rewrapped, &bccsp.AESCBCPKCS7ModeOpts{})
+	assert.NoError(t, err)
+	assert.Equal(t, msg, pt)
+}
+
+func TestRewrapECIES(t *testing.T) {
+	provider, _, cleanup := currentTestConfig.Provider(t)
+	defer cleanup()
+	csp := provider.(*CSP)
+
+	oldKey, err := provider.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	oldPub, err := oldKey.PublicKey()
+	assert.NoError(t, err)
+	newKey, err := provider.KeyGen(&bccsp.ECDSAP384KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	newPub, err := newKey.PublicKey()
+	assert.NoError(t, err)
+
+	msg := []byte("Hello Rewrap")
+	ct, err := csp.EncryptMulti([]bccsp.Key{oldPub}, msg, nil)
+	assert.NoError(t, err)
+
+	rewrapped, err := csp.Rewrap(oldKey, newPub, ct, nil)
+	assert.NoError(t, err)
+	pt, err := csp.DecryptMulti(newKey, rewrapped, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, msg, pt)
+	_, err = csp.DecryptMulti(oldKey, rewrapped, nil)
+	assert.EqualError(t, err, "Key is not a recipient of the ciphertext.")
+
+	// Old key must decrypt the input
+	_, err = csp.Rewrap(newKey, oldPub, ct, nil)
+	assert.EqualError(t, err, "Failed decrypting with old key: Key is not a recipient of the ciphertext.")
+
+	_, err = csp.Rewrap(oldKey, newPub, ct, &bccsp.AESGCMStreamModeOpts{})
+	assert.Error(t, err)
+	_, err = csp.Rewrap(nil, newPub, ct, nil)
+	assert.EqualError(t, err, "Invalid Key. It must not be nil.")
+}
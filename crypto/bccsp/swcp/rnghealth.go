@@ -0,0 +1,95 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// Parameters of RNG health tests of NIST SP 800-90B section 4.4, for byte
+// samples with assumed min-entropy of 1 bit per sample and false positive
+// probability of 2^-20.
+const (
+	// rngSampleSize - Number of bytes read from RNG.
+	rngSampleSize = 4096
+	// rngRepetitionCutoff - Repetition count test cutoff, 1 + ceil(20 / H).
+	rngRepetitionCutoff = 21
+	// rngProportionWindow - Adaptive proportion test window size.
+	rngProportionWindow = 512
+	// rngProportionCutoff - Adaptive proportion test cutoff.
+	rngProportionCutoff = 410
+)
+
+// HealthCheckRNG runs the repetition count and adaptive proportion health
+// tests of NIST SP 800-90B on output of the random source of the CSP,
+// which is crypto/rand.Reader, and returns an error if any of them fails.
+//
+// Tests only detect catastrophic failures, such as a stuck or heavily
+// biased source. This is a sanity check meant to be run at startup and not
+// a full entropy assessment, passing it does not prove output is random.
+func (csp *CSP) HealthCheckRNG() error {
+	return healthCheckRNG(csp.rng)
+}
+
+// healthCheckRNG - Runs health tests on sample read from r.
+func healthCheckRNG(r io.Reader) error {
+	sample := make([]byte, rngSampleSize)
+	if _, err := io.ReadFull(r, sample); err != nil {
+		return errors.Wrap(err, "Failed reading random sample")
+	}
+	if err := repetitionCountTest(sample); err != nil {
+		return err
+	}
+	return adaptiveProportionTest(sample)
+}
+
+// repetitionCountTest - Fails if any value repeats rngRepetitionCutoff
+// or more times in a row (SP 800-90B section 4.4.1).
+func repetitionCountTest(sample []byte) error {
+	run := 1
+	for i := 1; i < len(sample); i++ {
+		if sample[i] != sample[i-1] {
+			run = 1
+			continue
+		}
+		run++
+		if run >= rngRepetitionCutoff {
+			return errors.Errorf("RNG repetition count test failed. Value %#02x repeated %d times at offset %d.", sample[i], run, i-run+1)
+		}
+	}
+	return nil
+}
+
+// adaptiveProportionTest - Fails if the first value of any window occurs
+// rngProportionCutoff or more times within that window (SP 800-90B
+// section 4.4.2).
+func adaptiveProportionTest(sample []byte) error {
+	for start := 0; start+rngProportionWindow <= len(sample); start += rngProportionWindow {
+		window := sample[start : start+rngProportionWindow]
+		count := 0
+		for _, b := range window {
+			if b == window[0] {
+				count++
+			}
+		}
+		if count >= rngProportionCutoff {
+			return errors.Errorf("RNG adaptive proportion test failed. Value %#02x occurred %d times in window at offset %d.", window[0], count, start)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// constantReader - Broken RNG returning the same byte.
+type constantReader byte
+
+func (r constantReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = byte(r)
+	}
+	return len(p), nil
+}
+
+// biasedReader - Broken RNG returning zero in seven of eight bytes.
+type biasedReader struct{}
+
+func (biasedReader) Read(p []byte) (int, error) {
+	if _, err := rand.Read(p); err != nil {
+		return 0, err
+	}
+	for i := range p {
+		if i%8 != 7 {
+			p[i] = 0
+		}
+	}
+	return len(p), nil
+}
+
+func TestHealthCheckRNG(t *testing.T) {
+	provider, _, cleanup := currentTestConfig.Provider(t)
+	defer cleanup()
+	csp := provider.(*CSP)
+
+	assert.NoError(t, csp.HealthCheckRNG())
+
+	broken := func(r io.Reader) *CSP {
+		csp, err := New(NewDummyKeyStore())
+		assert.NoError(t, err)
+		csp.rng = r
+		return csp
+	}
+
+	err := broken(constantReader(0x42)).HealthCheckRNG()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "repetition count test failed")
+
+	// Runs are short but a single value dominates
+	err = broken(biasedReader{}).HealthCheckRNG()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "adaptive proportion test failed")
+
+	err = broken(bytes.NewReader(make([]byte, 16))).HealthCheckRNG()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Failed reading random sample")
+
+	// Reader of other CSP is not affected
+	assert.NoError(t, csp.HealthCheckRNG())
+}
@@ -0,0 +1,172 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+)
+
+// AutoRotator - Periodically replaces signing key with a freshly generated one.
+//
+// Current key should be used for signing, previous key remains available
+// for verification of signatures created before the last rotation.
+// OnRotate is called after every rotation with the replaced and the new key,
+// so the consumer can publish the new public key. It is called from the
+// rotating goroutine, one call at a time. Errors of periodic key generation
+// are reported to OnError, if set, and the current key is kept.
+//
+// Fields must not be changed after Start. Methods are safe for concurrent use,
+// except that Stop must not be called from OnRotate or OnError, as it waits
+// for the rotation in progress, which is the one calling back.
+type AutoRotator struct {
+	// CSP is used to generate keys.
+	CSP bccsp.BCCSP
+	// Opts are key generation options of every key.
+	Opts bccsp.KeyGenOpts
+	// Interval is the period of rotation.
+	Interval time.Duration
+	// OnRotate is called after every rotation, it may be nil.
+	OnRotate func(old, new bccsp.Key)
+	// OnError is called when periodic rotation fails, it may be nil.
+	OnError func(err error)
+
+	mu       sync.RWMutex
+	current  bccsp.Key
+	previous bccsp.Key
+
+	// rotating serializes rotations and callbacks
+	rotating sync.Mutex
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// Start - Generates the initial key and starts periodic rotation.
+// OnRotate is called with nil old key for the initial key.
+func (r *AutoRotator) Start() error {
+	if r.CSP == nil {
+		return errors.New("Invalid CSP. It must not be nil.")
+	}
+	if r.Opts == nil {
+		return errors.New("Invalid opts. It must not be nil.")
+	}
+	if r.Interval <= 0 {
+		return errors.New("Invalid interval. It must be positive.")
+	}
+	r.mu.Lock()
+	if r.stop != nil {
+		r.mu.Unlock()
+		return errors.New("Rotator is already started.")
+	}
+	stop, done := make(chan struct{}), make(chan struct{})
+	r.stop, r.done = stop, done
+	r.mu.Unlock()
+
+	if err := r.Rotate(); err != nil {
+		r.mu.Lock()
+		if r.stop == stop {
+			r.stop, r.done = nil, nil
+		}
+		r.mu.Unlock()
+		// Concurrent Stop may be waiting for the rotation
+		close(done)
+		return err
+	}
+	go r.run(stop, done)
+	return nil
+}
+
+// Stop - Stops periodic rotation and waits for the pending rotation to finish.
+// Keys remain available. It is safe to call it more than once, and
+// concurrently with Start, but not from OnRotate or OnError.
+func (r *AutoRotator) Stop() {
+	r.mu.Lock()
+	stop, done := r.stop, r.done
+	r.stop, r.done = nil, nil
+	r.mu.Unlock()
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+}
+
+// Rotate - Immediately replaces current key with a freshly generated one.
+func (r *AutoRotator) Rotate() error {
+	r.rotating.Lock()
+	defer r.rotating.Unlock()
+
+	k, err := r.CSP.KeyGen(r.Opts)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	old := r.current
+	r.previous, r.current = old, k
+	r.mu.Unlock()
+
+	if r.OnRotate != nil {
+		r.OnRotate(old, k)
+	}
+	return nil
+}
+
+// Current - Returns current signing key or nil before Start.
+func (r *AutoRotator) Current() bccsp.Key {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.current
+}
+
+// Previous - Returns key replaced by the last rotation or nil.
+func (r *AutoRotator) Previous() bccsp.Key {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.previous
+}
+
+// VerificationKeys - Returns keys usable for verification, current key first.
+func (r *AutoRotator) VerificationKeys() []bccsp.Key {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	keys := make([]bccsp.Key, 0, 2)
+	for _, k := range []bccsp.Key{r.current, r.previous} {
+		if k != nil {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+func (r *AutoRotator) run(stop, done chan struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if err := r.Rotate(); err != nil && r.OnError != nil {
+				r.OnError(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"crypto/sha256"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAutoRotator(t *testing.T) {
+	provider, _, cleanup := currentTestConfig.Provider(t)
+	defer cleanup()
+
+	type rotation struct{ old, new bccsp.Key }
+	var (
+		mu        sync.Mutex
+		rotations []rotation
+	)
+	r := &AutoRotator{
+		CSP:      provider,
+		Opts:     &bccsp.ECDSAKeyGenOpts{Temporary: true},
+		Interval: 10 * time.Millisecond,
+		OnRotate: func(old, new bccsp.Key) {
+			mu.Lock()
+			rotations = append(rotations, rotation{old, new})
+			mu.Unlock()
+		},
+	}
+	assert.Nil(t, r.Current())
+	assert.NoError(t, r.Start())
+	assert.Error(t, r.Start())
+
+	digest := sha256.Sum256([]byte("Hello World"))
+	signature, err := provider.Sign(r.Current(), digest[:], nil)
+	assert.NoError(t, err)
+
+	count := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(rotations)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for count() < 3 && time.Now().Before(deadline) {
+		// Concurrent readers
+		assert.NotNil(t, r.Current())
+		time.Sleep(time.Millisecond)
+	}
+	r.Stop()
+	r.Stop()
+
+	mu.Lock()
+	stopped := append([]rotation{}, rotations...)
+	mu.Unlock()
+	assert.True(t, len(stopped) >= 3)
+
+	// Callbacks chain the keys
+	assert.Nil(t, stopped[0].old)
+	for i := 1; i < len(stopped); i++ {
+		assert.Equal(t, stopped[i-1].new, stopped[i].old)
+		assert.NotEqual(t, stopped[i].old.SKI(), stopped[i].new.SKI())
+	}
+	last := stopped[len(stopped)-1]
+	assert.Equal(t, last.new, r.Current())
+	assert.Equal(t, last.old, r.Previous())
+	assert.Equal(t, []bccsp.Key{last.new, last.old}, r.VerificationKeys())
+
+	// No rotation after stop
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, len(stopped), count())
+
+	// Manual rotation keeps the replaced key for verification
+	assert.NoError(t, r.Rotate())
+	assert.Equal(t, last.new, r.Previous())
+	pk, err := stopped[0].new.PublicKey()
+	assert.NoError(t, err)
+	valid, err := provider.Verify(pk, signature, digest[:], nil)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+}
+
+func TestAutoRotatorInvalid(t *testing.T) {
+	provider, _, cleanup := currentTestConfig.Provider(t)
+	defer cleanup()
+
+	assert.Error(t, (&AutoRotator{Opts: &bccsp.ECDSAKeyGenOpts{}, Interval: time.Second}).Start())
+	assert.Error(t, (&AutoRotator{CSP: provider, Interval: time.Second}).Start())
+	assert.Error(t, (&AutoRotator{CSP: provider, Opts: &bccsp.ECDSAKeyGenOpts{}}).Start())
+
+	// Failed initial key generation leaves rotator stopped
+	r := &AutoRotator{CSP: provider, Opts: &mocks.KeyGenOpts{}, Interval: time.Second}
+	assert.Error(t, r.Start())
+	r.Stop()
+}
+
+func TestAutoRotatorConcurrentStartStop(t *testing.T) {
+	provider, _, cleanup := currentTestConfig.Provider(t)
+	defer cleanup()
+
+	for _, opts := range []bccsp.KeyGenOpts{
+		&bccsp.ECDSAP256KeyGenOpts{Temporary: true},
+		// Failing initial rotation
+		&mocks.KeyGenOpts{},
+	} {
+		r := &AutoRotator{CSP: provider, Opts: opts, Interval: time.Millisecond}
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				r.Start()
+			}()
+			go func() {
+				defer wg.Done()
+				r.Stop()
+			}()
+		}
+		wg.Wait()
+		r.Stop()
+	}
+}
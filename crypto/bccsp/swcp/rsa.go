@@ -16,6 +16,7 @@
 package swcp
 
 import (
+	"crypto"
 	"crypto/rand"
 	"crypto/rsa"
 	"errors"
@@ -30,6 +31,11 @@ func (s *rsaSigner) Sign(k bccsp.Key, digest []byte, opts bccsp.SignerOpts) ([]b
 	if opts == nil {
 		return nil, errors.New("Invalid options. Must be different from nil.")
 	}
+	if h := opts.HashFunc(); h != 0 {
+		if err := checkRSAHash(h); err != nil {
+			return nil, err
+		}
+	}
 
 	return k.(*rsaPrivateKey).privKey.Sign(rand.Reader, digest, opts)
 }
@@ -42,10 +48,20 @@ func (v *rsaPrivateKeyVerifier) Verify(k bccsp.Key, signature, digest []byte, op
 	}
 	switch opts.(type) {
 	case *rsa.PSSOptions:
+		if err := checkRSAHash(opts.(*rsa.PSSOptions).Hash); err != nil {
+			return false, err
+		}
 		err := rsa.VerifyPSS(&(k.(*rsaPrivateKey).privKey.PublicKey),
 			(opts.(*rsa.PSSOptions)).Hash,
 			digest, signature, opts.(*rsa.PSSOptions))
 
+		return err == nil, err
+	case crypto.Hash:
+		if err := checkRSAHash(opts.(crypto.Hash)); err != nil {
+			return false, err
+		}
+		err := rsa.VerifyPKCS1v15(&(k.(*rsaPrivateKey).privKey.PublicKey), opts.(crypto.Hash), digest, signature)
+
 		return err == nil, err
 	default:
 		return false, fmt.Errorf("Opts type not recognized [%s]", opts)
@@ -60,12 +76,52 @@ func (v *rsaPublicKeyKeyVerifier) Verify(k bccsp.Key, signature, digest []byte,
 	}
 	switch opts.(type) {
 	case *rsa.PSSOptions:
+		if err := checkRSAHash(opts.(*rsa.PSSOptions).Hash); err != nil {
+			return false, err
+		}
 		err := rsa.VerifyPSS(k.(*rsaPublicKey).pubKey,
 			(opts.(*rsa.PSSOptions)).Hash,
 			digest, signature, opts.(*rsa.PSSOptions))
 
+		return err == nil, err
+	case crypto.Hash:
+		if err := checkRSAHash(opts.(crypto.Hash)); err != nil {
+			return false, err
+		}
+		err := rsa.VerifyPKCS1v15(k.(*rsaPublicKey).pubKey, opts.(crypto.Hash), digest, signature)
+
 		return err == nil, err
 	default:
 		return false, fmt.Errorf("Opts type not recognized [%s]", opts)
 	}
 }
+
+type rsaDecryptor struct{}
+
+// Decrypt - Decrypts RSA-OAEP or PKCS#1 v1.5 ciphertext. Options are the
+// standard library *rsa.OAEPOptions and *rsa.PKCS1v15DecryptOptions.
+func (d *rsaDecryptor) Decrypt(k bccsp.Key, ciphertext []byte, opts bccsp.DecrypterOpts) ([]byte, error) {
+	if opts == nil {
+		return nil, errors.New("Invalid options. It must not be nil.")
+	}
+	switch o := opts.(type) {
+	case *rsa.OAEPOptions:
+		if err := checkRSAHash(o.Hash); err != nil {
+			return nil, err
+		}
+	case *rsa.PKCS1v15DecryptOptions:
+	default:
+		return nil, fmt.Errorf("Opts type not recognized [%s]", opts)
+	}
+
+	return k.(*rsaPrivateKey).privKey.Decrypt(rand.Reader, ciphertext, opts)
+}
+
+// checkRSAHash - Checks if hash function is linked into the binary.
+// SHA2 and SHA3 hash functions are always available.
+func checkRSAHash(h crypto.Hash) error {
+	if !h.Available() {
+		return fmt.Errorf("Hash function [%s] not available.", h)
+	}
+	return nil
+}
@@ -25,7 +25,9 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
 	"github.com/ipfn/ipfn/pkg/crypto/bccsp/mocks"
+	"github.com/ipfn/ipfn/pkg/digest"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -68,7 +70,7 @@ func TestRSAPublicKey(t *testing.T) {
 
 	lowLevelKey, err := rsa.GenerateKey(rand.Reader, 512)
 	assert.NoError(t, err)
-	k := &rsaPublicKey{&lowLevelKey.PublicKey}
+	k := &rsaPublicKey{pubKey: &lowLevelKey.PublicKey}
 
 	assert.False(t, k.Symmetric())
 	assert.False(t, k.Private())
@@ -173,3 +175,90 @@ func TestRSAVerifiersInvalidInputs(t *testing.T) {
 	assert.Error(t, err)
 	assert.True(t, strings.Contains(err.Error(), "Opts type not recognized ["))
 }
+
+func TestRSAPSSSha3(t *testing.T) {
+	t.Parallel()
+
+	csp, err := NewWithParams(256, digest.FamilySha3, NewDummyKeyStore())
+	assert.NoError(t, err)
+	k, err := csp.KeyGen(&bccsp.RSA2048KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	pk, err := k.PublicKey()
+	assert.NoError(t, err)
+
+	hashed, err := csp.Hash([]byte("Hello World"), digest.Sha3_256)
+	assert.NoError(t, err)
+
+	opts := &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: crypto.SHA3_256}
+	sigma, err := csp.Sign(k, hashed, opts)
+	assert.NoError(t, err)
+
+	valid, err := csp.Verify(k, sigma, hashed, opts)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+	valid, err = csp.Verify(pk, sigma, hashed, opts)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+
+	// Signature does not verify with another hash function
+	valid, _ = csp.Verify(pk, sigma, hashed, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: crypto.SHA256})
+	assert.False(t, valid)
+
+	// SHA3-384
+	hashed, err = csp.Hash([]byte("Hello World"), digest.Sha3_384)
+	assert.NoError(t, err)
+	opts = &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: crypto.SHA3_384}
+	sigma, err = csp.Sign(k, hashed, opts)
+	assert.NoError(t, err)
+	valid, err = csp.Verify(pk, sigma, hashed, opts)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+
+	// Unavailable hash functions
+	for _, h := range []crypto.Hash{0, crypto.MD5SHA1} {
+		opts = &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: h}
+		_, err = csp.Verify(pk, sigma, hashed, opts)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "not available")
+		_, err = csp.Verify(k, sigma, hashed, opts)
+		assert.Error(t, err)
+	}
+	_, err = csp.Sign(k, hashed, &rsa.PSSOptions{Hash: crypto.MD5SHA1})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "not available")
+}
+
+func TestRSADecrypt(t *testing.T) {
+	t.Parallel()
+
+	csp, err := NewDefaultSecurityLevelWithKeystore(NewDummyKeyStore())
+	assert.NoError(t, err)
+	k, err := csp.KeyGen(&bccsp.RSA2048KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	pub := &k.(*rsaPrivateKey).privKey.PublicKey
+
+	msg := []byte("Hello World")
+	label := []byte("label")
+	ct, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, pub, msg, label)
+	assert.NoError(t, err)
+	pt, err := csp.Decrypt(k, ct, &rsa.OAEPOptions{Hash: crypto.SHA256, Label: label})
+	assert.NoError(t, err)
+	assert.Equal(t, msg, pt)
+
+	// Wrong label
+	_, err = csp.Decrypt(k, ct, &rsa.OAEPOptions{Hash: crypto.SHA256})
+	assert.Error(t, err)
+
+	ct, err = rsa.EncryptPKCS1v15(rand.Reader, pub, msg)
+	assert.NoError(t, err)
+	pt, err = csp.Decrypt(k, ct, &rsa.PKCS1v15DecryptOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, msg, pt)
+
+	_, err = csp.Decrypt(k, ct, nil)
+	assert.Error(t, err)
+	_, err = csp.Decrypt(k, ct, &mocks.DecrypterOpts{})
+	assert.Error(t, err)
+	_, err = csp.Decrypt(k, ct, &rsa.OAEPOptions{Hash: crypto.MD5SHA1})
+	assert.Error(t, err)
+}
@@ -24,6 +24,7 @@ import (
 	"math/big"
 
 	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp/utils"
 	"github.com/ipfn/ipfn/pkg/digest"
 )
 
@@ -74,11 +75,22 @@ func (k *rsaPrivateKey) Private() bool {
 // PublicKey returns the corresponding public key part of an asymmetric public/private key pair.
 // This method returns an error in symmetric key schemes.
 func (k *rsaPrivateKey) PublicKey() (bccsp.Key, error) {
-	return &rsaPublicKey{&k.privKey.PublicKey}, nil
+	return &rsaPublicKey{pubKey: &k.privKey.PublicKey}, nil
 }
 
 type rsaPublicKey struct {
 	pubKey *rsa.PublicKey
+	origin utils.Origin
+}
+
+// KeyOrigin returns how this key was imported.
+func (k *rsaPublicKey) KeyOrigin() utils.Origin {
+	return k.origin
+}
+
+// GoPublicKey returns the Go public key of this key.
+func (k *rsaPublicKey) GoPublicKey() interface{} {
+	return k.pubKey
 }
 
 // Bytes converts this key to its byte representation,
@@ -0,0 +1,127 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"crypto/rsa"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+)
+
+// DefaultMinRSABits - Default minimum modulus size of imported RSA keys.
+const DefaultMinRSABits = 2048
+
+// RSAImportPolicy - Policy of RSA keys accepted by KeyImport.
+type RSAImportPolicy struct {
+	// MinRSABits is the minimum modulus size in bits.
+	// DefaultMinRSABits is used when zero.
+	MinRSABits int
+	// AllowSmallExponent permits public exponents smaller than 65537.
+	AllowSmallExponent bool
+	// CheckROCA rejects keys with fingerprint of ROCA vulnerability
+	// (CVE-2017-15361) of keys generated by Infineon RSA library.
+	CheckROCA bool
+}
+
+// rsaImportPolicy - Policy guarded for concurrent use.
+type rsaImportPolicy struct {
+	sync.RWMutex
+	RSAImportPolicy
+}
+
+// SetRSAImportPolicy configures validation of RSA keys imported with KeyImport,
+// including keys of imported certificates. Keys violating the policy are
+// refused with descriptive error. By default keys smaller than 2048 bits and
+// exponents smaller than 65537 are refused and ROCA check is disabled.
+// Policy is replaced under a lock and does not affect already imported keys.
+func (csp *CSP) SetRSAImportPolicy(policy RSAImportPolicy) {
+	csp.rsaImport.Lock()
+	csp.rsaImport.RSAImportPolicy = policy
+	csp.rsaImport.Unlock()
+}
+
+// checkRSAImport - Returns error if imported RSA key violates the policy.
+func (csp *CSP) checkRSAImport(k bccsp.Key) error {
+	var pub *rsa.PublicKey
+	switch kk := k.(type) {
+	case *rsaPublicKey:
+		pub = kk.pubKey
+	case *rsaPrivateKey:
+		pub = &kk.privKey.PublicKey
+	default:
+		return nil
+	}
+	csp.rsaImport.RLock()
+	policy := csp.rsaImport.RSAImportPolicy
+	csp.rsaImport.RUnlock()
+
+	minBits := policy.MinRSABits
+	if minBits == 0 {
+		minBits = DefaultMinRSABits
+	}
+	if bits := pub.N.BitLen(); bits < minBits {
+		return fmt.Errorf("Weak RSA key. Modulus of %d bits is smaller than minimum of %d bits.", bits, minBits)
+	}
+	if pub.E < 65537 && !policy.AllowSmallExponent {
+		return fmt.Errorf("Weak RSA key. Public exponent %d is smaller than 65537.", pub.E)
+	}
+	if policy.CheckROCA && IsROCAVulnerable(pub) {
+		return fmt.Errorf("Weak RSA key. Modulus has fingerprint of ROCA vulnerability (CVE-2017-15361).")
+	}
+	return nil
+}
+
+// rocaPrimes - Small primes used to detect ROCA fingerprint.
+var rocaPrimes = []int64{
+	3, 5, 7, 11, 13, 17, 19, 23, 29, 31, 37, 41, 43, 47, 53, 59, 61, 67,
+	71, 73, 79, 83, 89, 97, 101, 103, 107, 109, 113, 127, 131, 137, 139,
+	149, 151, 157, 163, 167,
+}
+
+var (
+	rocaOnce sync.Once
+	// rocaSubgroups - Residues generated by 65537 modulo ROCA primes.
+	rocaSubgroups []map[int64]bool
+)
+
+// IsROCAVulnerable - Returns true if RSA modulus has fingerprint of keys
+// generated by Infineon library vulnerable to ROCA (CVE-2017-15361).
+//
+// Primes of vulnerable keys are of form k * M + (65537^a mod M), where M is
+// primorial, so modulus modulo every small prime is in the subgroup generated
+// by 65537. False positives of random moduli are negligible.
+func IsROCAVulnerable(pub *rsa.PublicKey) bool {
+	rocaOnce.Do(func() {
+		for _, p := range rocaPrimes {
+			group := make(map[int64]bool)
+			for x := int64(1); !group[x]; x = x * 65537 % p {
+				group[x] = true
+			}
+			rocaSubgroups = append(rocaSubgroups, group)
+		}
+	})
+	mod := new(big.Int)
+	for i, p := range rocaPrimes {
+		mod.Mod(pub.N, big.NewInt(p))
+		if !rocaSubgroups[i][mod.Int64()] {
+			return false
+		}
+	}
+	return true
+}
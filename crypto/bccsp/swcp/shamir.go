@@ -0,0 +1,188 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+)
+
+// shareImporters - Key importers accepting reconstructed key material.
+var shareImporters = map[reflect.Type]bccsp.KeyImporter{
+	reflect.TypeOf(&bccsp.AES256ImportKeyOpts{}): &aes256ImportKeyOptsKeyImporter{},
+	reflect.TypeOf(&bccsp.HMACImportKeyOpts{}):   &hmacImportKeyOptsKeyImporter{},
+}
+
+// SplitKey - Splits key material into Shamir shares over GF(256).
+// Any threshold out of the returned shares reconstruct the key using
+// CombineKeyShares, fewer shares reveal nothing about it.
+// Key has to be symmetric and exportable.
+//
+// Every share is made of one byte holding its index followed
+// by one byte per byte of the key.
+func SplitKey(key bccsp.Key, threshold, shares int) ([][]byte, error) {
+	if key == nil {
+		return nil, errors.New("Invalid key. It must not be nil.")
+	}
+	if !key.Symmetric() {
+		return nil, errors.New("Invalid key. It must be symmetric.")
+	}
+	if threshold < 2 {
+		return nil, fmt.Errorf("Invalid threshold %d. It must be at least 2.", threshold)
+	}
+	if threshold > shares {
+		return nil, fmt.Errorf("Invalid threshold %d. It must not exceed number of shares %d.", threshold, shares)
+	}
+	if shares > 255 {
+		return nil, fmt.Errorf("Invalid number of shares %d. It must not exceed 255.", shares)
+	}
+	secret, err := key.Bytes()
+	if err != nil {
+		return nil, fmt.Errorf("Key is not exportable [%s]", err)
+	}
+	if len(secret) == 0 {
+		return nil, errors.New("Invalid key. It must not be empty.")
+	}
+
+	out := make([][]byte, shares)
+	for i := range out {
+		out[i] = make([]byte, len(secret)+1)
+		out[i][0] = byte(i + 1)
+	}
+	coeffs := make([]byte, threshold)
+	for j, b := range secret {
+		coeffs[0] = b
+		if _, err := rand.Read(coeffs[1:]); err != nil {
+			return nil, fmt.Errorf("Failed generating coefficients [%s]", err)
+		}
+		for i := range out {
+			out[i][j+1] = gfEval(coeffs, out[i][0])
+		}
+	}
+	for i := range coeffs {
+		coeffs[i] = 0
+	}
+	return out, nil
+}
+
+// CombineKeyShares - Reconstructs key from shares created by SplitKey.
+// Supported import options are AES256ImportKeyOpts and HMACImportKeyOpts.
+//
+// Notice that combining less shares than the threshold used to split
+// the key does not fail but results in a different key.
+func CombineKeyShares(shares [][]byte, opts bccsp.KeyImportOpts) (bccsp.Key, error) {
+	if opts == nil {
+		return nil, errors.New("Invalid opts. It must not be nil.")
+	}
+	importer, found := shareImporters[reflect.TypeOf(opts)]
+	if !found {
+		return nil, fmt.Errorf("Unsupported 'KeyImportOpts' provided [%v]", opts)
+	}
+	if len(shares) < 2 {
+		return nil, errors.New("Invalid shares. At least 2 are required.")
+	}
+	size := len(shares[0])
+	if size < 2 {
+		return nil, errors.New("Invalid share. It is too short.")
+	}
+	xs := make([]byte, len(shares))
+	seen := make(map[byte]bool, len(shares))
+	for i, share := range shares {
+		if len(share) != size {
+			return nil, errors.New("Invalid shares. They must be of equal length.")
+		}
+		x := share[0]
+		if x == 0 || seen[x] {
+			return nil, fmt.Errorf("Invalid share index %d.", x)
+		}
+		seen[x] = true
+		xs[i] = x
+	}
+
+	secret := make([]byte, size-1)
+	ys := make([]byte, len(shares))
+	for j := range secret {
+		for i, share := range shares {
+			ys[i] = share[j+1]
+		}
+		secret[j] = gfInterpolateZero(xs, ys)
+	}
+	defer func() {
+		for i := range secret {
+			secret[i] = 0
+		}
+	}()
+	return importer.KeyImport(secret, opts)
+}
+
+// gfMul - Multiplies a and b in GF(256) with reduction polynomial
+// x^8 + x^4 + x^3 + x + 1. It runs in constant time, without branches
+// or table lookups indexed by share bytes, which would leak the secret.
+func gfMul(a, b byte) (p byte) {
+	for i := 0; i < 8; i++ {
+		// p ^= a if lowest bit of b is set
+		p ^= -(b & 1) & a
+		// a *= x, reduced if highest bit of a was set
+		a = a<<1 ^ -(a>>7)&0x1b
+		b >>= 1
+	}
+	return
+}
+
+// gfInv - Returns multiplicative inverse of a in GF(256) computed in
+// constant time as a^254. Inverse of zero is zero.
+func gfInv(a byte) byte {
+	// a^254 = a^2 * a^4 * ... * a^128
+	sq := gfMul(a, a)
+	inv := sq
+	for i := 0; i < 6; i++ {
+		sq = gfMul(sq, sq)
+		inv = gfMul(inv, sq)
+	}
+	return inv
+}
+
+func gfDiv(a, b byte) byte {
+	return gfMul(a, gfInv(b))
+}
+
+// gfEval - Evaluates polynomial with given coefficients at x.
+func gfEval(coeffs []byte, x byte) (y byte) {
+	for i := len(coeffs) - 1; i >= 0; i-- {
+		y = gfMul(y, x) ^ coeffs[i]
+	}
+	return
+}
+
+// gfInterpolateZero - Lagrange interpolation of points at zero.
+func gfInterpolateZero(xs, ys []byte) (y byte) {
+	for i := range xs {
+		num, den := byte(1), byte(1)
+		for j := range xs {
+			if i == j {
+				continue
+			}
+			num = gfMul(num, xs[j])
+			den = gfMul(den, xs[i]^xs[j])
+		}
+		y ^= gfMul(ys[i], gfDiv(num, den))
+	}
+	return
+}
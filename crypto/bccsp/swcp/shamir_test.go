@@ -0,0 +1,115 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+)
+
+func TestSplitKey(t *testing.T) {
+	provider, _, cleanup := currentTestConfig.Provider(t)
+	defer cleanup()
+
+	k, err := provider.KeyGen(&bccsp.AESKeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+
+	// Generated keys are not exportable
+	_, err = SplitKey(k, 3, 5)
+	assert.Error(t, err)
+
+	k, err = provider.KeyDeriv(k, &bccsp.HMACDeriveKeyOpts{Temporary: true, Arg: []byte("backup")})
+	assert.NoError(t, err)
+	raw, err := k.Bytes()
+	assert.NoError(t, err)
+
+	shares, err := SplitKey(k, 3, 5)
+	assert.NoError(t, err)
+	assert.Len(t, shares, 5)
+	for i, share := range shares {
+		assert.Len(t, share, len(raw)+1)
+		assert.Equal(t, byte(i+1), share[0])
+	}
+
+	subsets := [][]int{{0, 1, 2}, {2, 3, 4}, {0, 2, 4}, {4, 1, 3}, {0, 1, 2, 3, 4}}
+	for _, subset := range subsets {
+		var picked [][]byte
+		for _, i := range subset {
+			picked = append(picked, shares[i])
+		}
+		restored, err := CombineKeyShares(picked, &bccsp.HMACImportKeyOpts{Temporary: true})
+		assert.NoError(t, err, "subset %v", subset)
+		assert.Equal(t, k.SKI(), restored.SKI(), "subset %v", subset)
+	}
+
+	// Below threshold the key is not reconstructed
+	restored, err := CombineKeyShares(shares[:2], &bccsp.HMACImportKeyOpts{Temporary: true})
+	assert.NoError(t, err)
+	assert.NotEqual(t, k.SKI(), restored.SKI())
+}
+
+func TestSplitKeyInvalid(t *testing.T) {
+	provider, _, cleanup := currentTestConfig.Provider(t)
+	defer cleanup()
+
+	k := &aesPrivateKey{[]byte("0123456789abcdef0123456789abcdef"), true}
+
+	_, err := SplitKey(nil, 3, 5)
+	assert.Error(t, err)
+	_, err = SplitKey(k, 6, 5)
+	assert.EqualError(t, err, "Invalid threshold 6. It must not exceed number of shares 5.")
+	_, err = SplitKey(k, 1, 5)
+	assert.Error(t, err)
+	_, err = SplitKey(k, 3, 256)
+	assert.Error(t, err)
+
+	ecKey, err := provider.KeyGen(&bccsp.ECDSAKeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	_, err = SplitKey(ecKey, 3, 5)
+	assert.EqualError(t, err, "Invalid key. It must be symmetric.")
+
+	shares, err := SplitKey(k, 2, 3)
+	assert.NoError(t, err)
+	_, err = CombineKeyShares(shares, nil)
+	assert.Error(t, err)
+	_, err = CombineKeyShares(shares, &bccsp.ECDSAPrivateKeyImportOpts{})
+	assert.Error(t, err)
+	_, err = CombineKeyShares(shares[:1], &bccsp.AES256ImportKeyOpts{})
+	assert.Error(t, err)
+	_, err = CombineKeyShares([][]byte{shares[0], shares[0]}, &bccsp.AES256ImportKeyOpts{})
+	assert.EqualError(t, err, "Invalid share index 1.")
+	_, err = CombineKeyShares([][]byte{shares[0], shares[1][:10]}, &bccsp.AES256ImportKeyOpts{})
+	assert.Error(t, err)
+
+	restored, err := CombineKeyShares(shares[1:], &bccsp.AES256ImportKeyOpts{})
+	assert.NoError(t, err)
+	assert.Equal(t, k.SKI(), restored.SKI())
+}
+
+func TestGF256(t *testing.T) {
+	// Known AES field products
+	assert.Equal(t, byte(0xc1), gfMul(0x57, 0x83))
+	assert.Equal(t, byte(0xfe), gfMul(0x57, 0x13))
+	for a := 1; a < 256; a++ {
+		assert.Equal(t, byte(a), gfDiv(gfMul(byte(a), 0x53), 0x53))
+		assert.Equal(t, byte(1), gfMul(byte(a), gfInv(byte(a))))
+	}
+	assert.Equal(t, byte(0), gfInv(0))
+	assert.Equal(t, byte(0), gfMul(0, 0x53))
+}
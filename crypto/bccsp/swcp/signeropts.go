@@ -0,0 +1,61 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"sync"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+)
+
+// defaultSignerOpts - Signer options associated with keys by their SKIs.
+type defaultSignerOpts struct {
+	sync.RWMutex
+
+	opts map[string]bccsp.SignerOpts
+}
+
+// SetDefaultSignerOpts associates signer options with a key, they are used
+// by Sign and Verify when called with nil opts. Explicitly passed opts always
+// take precedence and are not merged with the defaults. Association is kept
+// in memory by key SKI and applies to both private and public key of a pair.
+// Passing nil opts removes the association.
+func (csp *CSP) SetDefaultSignerOpts(k bccsp.Key, opts bccsp.SignerOpts) {
+	ski := string(k.SKI())
+	csp.signerOpts.Lock()
+	defer csp.signerOpts.Unlock()
+	if opts == nil {
+		delete(csp.signerOpts.opts, ski)
+		return
+	}
+	if csp.signerOpts.opts == nil {
+		csp.signerOpts.opts = make(map[string]bccsp.SignerOpts)
+	}
+	csp.signerOpts.opts[ski] = opts
+}
+
+// resolveSignerOpts - Returns opts or default signer opts of the key if opts are nil.
+func (csp *CSP) resolveSignerOpts(k bccsp.Key, opts bccsp.SignerOpts) bccsp.SignerOpts {
+	if opts != nil {
+		return opts
+	}
+	csp.signerOpts.RLock()
+	defer csp.signerOpts.RUnlock()
+	if len(csp.signerOpts.opts) == 0 {
+		return nil
+	}
+	return csp.signerOpts.opts[string(k.SKI())]
+}
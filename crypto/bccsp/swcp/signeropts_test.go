@@ -0,0 +1,68 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+)
+
+func TestDefaultSignerOpts(t *testing.T) {
+	provider, _, cleanup := currentTestConfig.Provider(t)
+	defer cleanup()
+	csp := provider.(*CSP)
+
+	k, err := provider.KeyGen(&bccsp.RSA2048KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	pk, err := k.PublicKey()
+	assert.NoError(t, err)
+	digest := sha256.Sum256([]byte("Hello World"))
+
+	// RSA requires opts
+	_, err = provider.Sign(k, digest[:], nil)
+	assert.Error(t, err)
+
+	pss := &rsa.PSSOptions{SaltLength: 32, Hash: crypto.SHA256}
+	csp.SetDefaultSignerOpts(k, pss)
+
+	sig, err := provider.Sign(k, digest[:], nil)
+	assert.NoError(t, err)
+	valid, err := provider.Verify(pk, sig, digest[:], pss)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+	valid, err = provider.Verify(pk, sig, digest[:], nil)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+	assert.NoError(t, rsa.VerifyPSS(&k.(*rsaPrivateKey).privKey.PublicKey, crypto.SHA256, digest[:], sig, pss))
+
+	// Explicit opts take precedence
+	other := &rsa.PSSOptions{SaltLength: 64, Hash: crypto.SHA256}
+	sig, err = provider.Sign(k, digest[:], other)
+	assert.NoError(t, err)
+	assert.NoError(t, rsa.VerifyPSS(&k.(*rsaPrivateKey).privKey.PublicKey, crypto.SHA256, digest[:], sig, other))
+	assert.Error(t, rsa.VerifyPSS(&k.(*rsaPrivateKey).privKey.PublicKey, crypto.SHA256, digest[:], sig, pss))
+
+	// Removing defaults
+	csp.SetDefaultSignerOpts(k, nil)
+	_, err = provider.Sign(k, digest[:], nil)
+	assert.Error(t, err)
+}
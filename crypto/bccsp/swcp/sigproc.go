@@ -0,0 +1,68 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"github.com/pkg/errors"
+)
+
+// SignatureProcessor - Transforms signature bytes, e.g. to prepend
+// a version byte or to convert DER encoded signature to raw format.
+type SignatureProcessor func(signature []byte) ([]byte, error)
+
+// signatureProcessors - Holds processors in atomic.Value, which requires
+// values of consistent concrete type.
+type signatureProcessors struct {
+	post SignatureProcessor
+	pre  SignatureProcessor
+}
+
+// SetSignatureProcessors sets hooks applied to signatures at the Sign and
+// Verify boundary. Post is applied to output of Sign and pre to signature
+// passed to Verify before it is verified, nil hook is a no-op. Pre must be
+// the inverse of post, otherwise signatures produced by the CSP will not
+// verify. Errors of hooks are returned from Sign and Verify. Helpers which
+// convert signatures themselves, such as VerifyWithAlg, bypass the hooks.
+// Hooks are swapped atomically, as a pair.
+func (csp *CSP) SetSignatureProcessors(post, pre SignatureProcessor) {
+	csp.sigProcessors.Store(signatureProcessors{post: post, pre: pre})
+}
+
+// postProcessSignature - Applies post-processor to signature produced by Sign.
+func (csp *CSP) postProcessSignature(signature []byte) ([]byte, error) {
+	procs, _ := csp.sigProcessors.Load().(signatureProcessors)
+	if procs.post == nil {
+		return signature, nil
+	}
+	signature, err := procs.post(signature)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed post-processing signature")
+	}
+	return signature, nil
+}
+
+// preProcessSignature - Applies pre-processor to signature passed to Verify.
+func (csp *CSP) preProcessSignature(signature []byte) ([]byte, error) {
+	procs, _ := csp.sigProcessors.Load().(signatureProcessors)
+	if procs.pre == nil {
+		return signature, nil
+	}
+	signature, err := procs.pre(signature)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed pre-processing signature")
+	}
+	return signature, nil
+}
@@ -0,0 +1,105 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"crypto/sha256"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp/utils"
+)
+
+const testSignatureVersion = 0x01
+
+func prependVersion(signature []byte) ([]byte, error) {
+	return append([]byte{testSignatureVersion}, signature...), nil
+}
+
+func stripVersion(signature []byte) ([]byte, error) {
+	if signature[0] != testSignatureVersion {
+		return nil, errors.New("unknown signature version")
+	}
+	return signature[1:], nil
+}
+
+func TestSignatureProcessors(t *testing.T) {
+	provider, _, cleanup := currentTestConfig.Provider(t)
+	defer cleanup()
+	csp := provider.(*CSP)
+
+	k, err := csp.KeyGen(&bccsp.ED25519KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	digest := sha256.Sum256([]byte("message"))
+	raw, err := csp.Sign(k, digest[:], nil)
+	assert.NoError(t, err)
+
+	csp.SetSignatureProcessors(prependVersion, stripVersion)
+	signature, err := csp.Sign(k, digest[:], nil)
+	assert.NoError(t, err)
+	assert.Equal(t, append([]byte{testSignatureVersion}, raw...), signature)
+
+	valid, err := csp.Verify(k, signature, digest[:], nil)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+
+	// Signatures of unknown version are rejected by pre-processor
+	_, err = csp.Verify(k, append([]byte{testSignatureVersion + 1}, raw...), digest[:], nil)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Failed pre-processing signature")
+
+	// ECDSA signatures round-trip too
+	ec, err := csp.KeyGen(&bccsp.ECDSAKeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	signature, err = csp.Sign(ec, digest[:], nil)
+	assert.NoError(t, err)
+	assert.Equal(t, byte(testSignatureVersion), signature[0])
+	valid, err = csp.Verify(ec, signature, digest[:], nil)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+
+	// JOSE signatures built from R || S are not pre-processed
+	p256, err := csp.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	signature, err = csp.Sign(p256, digest[:], nil)
+	assert.NoError(t, err)
+	jwsSig := make([]byte, 64)
+	r, s, err := utils.UnmarshalECDSASignature(signature[1:])
+	assert.NoError(t, err)
+	r.FillBytes(jwsSig[:32])
+	s.FillBytes(jwsSig[32:])
+	valid, err = csp.VerifyWithAlg(p256, "ES256", []byte("message"), jwsSig)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+
+	csp.SetSignatureProcessors(func([]byte) ([]byte, error) {
+		return nil, errors.New("hook failed")
+	}, nil)
+	_, err = csp.Sign(k, digest[:], nil)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Failed post-processing signature")
+
+	// Nil hooks are a no-op
+	csp.SetSignatureProcessors(nil, nil)
+	signature, err = csp.Sign(k, digest[:], nil)
+	assert.NoError(t, err)
+	valid, err = csp.Verify(k, signature, digest[:], nil)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+}
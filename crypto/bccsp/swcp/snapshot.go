@@ -0,0 +1,135 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+)
+
+// KeySnapshotter is implemented by keystores able to create copies of
+// their keys. File-based KeyStore implements it.
+type KeySnapshotter interface {
+	// Snapshot returns immutable read-only copy of public keys in the store.
+	Snapshot() (bccsp.KeyStore, error)
+}
+
+// Snapshot returns immutable read-only copy of public keys in this
+// KeyStore, see SnapshotKeyStore.
+func (ks *fileBasedKeyStore) Snapshot() (bccsp.KeyStore, error) {
+	return SnapshotKeyStore(ks)
+}
+
+// Snapshot returns immutable read-only copy of public keys in the
+// underlying KeyStore, which must implement KeySnapshotter.
+func (csp *CSP) Snapshot() (bccsp.KeyStore, error) {
+	snapshotter, ok := csp.ks.(KeySnapshotter)
+	if !ok {
+		return nil, errors.Errorf("KeyStore [%T] cannot be snapshotted", csp.ks)
+	}
+	return snapshotter.Snapshot()
+}
+
+// SnapshotKeyStore - Copies public keys of all keys in the keystore into
+// an in-memory read-only KeyStore, which can be iterated, for example by
+// ExportJWKS, without locking or blocking writers of the live store.
+//
+// Keystore must implement KeyLister. Private keys are copied as their
+// public keys under the same SKI and symmetric keys are skipped, so that
+// the snapshot never holds secret key material.
+//
+// Snapshot is not taken at a single point in time. Keys are listed and then
+// loaded one by one without locking the keystore, so keys stored or removed
+// meanwhile may or may not be included. Keys which fail to load are left out
+// of the snapshot and reported together in returned error, along with the
+// snapshot of all other keys.
+func SnapshotKeyStore(ks bccsp.KeyStore) (bccsp.KeyStore, error) {
+	if ks == nil {
+		return nil, errors.New("Invalid KeyStore. It must not be nil.")
+	}
+	lister, ok := ks.(KeyLister)
+	if !ok {
+		return nil, errors.Errorf("KeyStore [%T] cannot list its keys", ks)
+	}
+	skis, err := lister.SKIs()
+	if err != nil {
+		return nil, err
+	}
+	var failed []string
+	snapshot := &snapshotKeyStore{keys: make(map[string]bccsp.Key, len(skis))}
+	for _, ski := range skis {
+		k, err := ks.Key(ski)
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("SKI [%x]: %s", ski, err))
+			continue
+		}
+		if k.Symmetric() {
+			continue
+		}
+		if k.Private() {
+			if k, err = k.PublicKey(); err != nil {
+				failed = append(failed, fmt.Sprintf("SKI [%x]: failed getting public key [%s]", ski, err))
+				continue
+			}
+		}
+		ski = append([]byte(nil), ski...)
+		snapshot.keys[string(ski)] = k
+		snapshot.skis = append(snapshot.skis, ski)
+	}
+	if len(failed) > 0 {
+		return snapshot, errors.Errorf("Failed snapshotting %d keys [%s]", len(failed), strings.Join(failed, "; "))
+	}
+	return snapshot, nil
+}
+
+// snapshotKeyStore - Immutable read-only in-memory KeyStore of public keys.
+// It is never modified after creation, so it is safe for concurrent use.
+type snapshotKeyStore struct {
+	keys map[string]bccsp.Key
+	skis [][]byte
+}
+
+// ReadOnly returns true, snapshot is read only.
+func (ks *snapshotKeyStore) ReadOnly() bool {
+	return true
+}
+
+// Key returns the public key with SKI ski at the time of snapshot.
+func (ks *snapshotKeyStore) Key(ski []byte) (bccsp.Key, error) {
+	k, ok := ks.keys[string(ski)]
+	if !ok {
+		return nil, errors.Errorf("Key with SKI [%x] not found in snapshot", ski)
+	}
+	return k, nil
+}
+
+// StoreKey fails, snapshot is read only.
+func (ks *snapshotKeyStore) StoreKey(k bccsp.Key) error {
+	return errors.New("Cannot store key. Snapshot KeyStore is read only.")
+}
+
+// SKIs returns subject key identifiers of all keys in the snapshot.
+func (ks *snapshotKeyStore) SKIs() ([][]byte, error) {
+	skis := make([][]byte, len(ks.skis))
+	for i, ski := range ks.skis {
+		skis[i] = append([]byte(nil), ski...)
+	}
+	return skis, nil
+}
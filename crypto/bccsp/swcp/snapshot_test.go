@@ -0,0 +1,100 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+)
+
+func TestSnapshot(t *testing.T) {
+	provider, ks, cleanup := currentTestConfig.Provider(t)
+	defer cleanup()
+	csp := provider.(*CSP)
+
+	ecKey, err := csp.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: false})
+	assert.NoError(t, err)
+	rsaKey, err := csp.KeyGen(&bccsp.RSA2048KeyGenOpts{Temporary: false})
+	assert.NoError(t, err)
+	aesKey, err := csp.KeyGen(&bccsp.AES256KeyGenOpts{Temporary: false})
+	assert.NoError(t, err)
+
+	snapshot, err := csp.Snapshot()
+	assert.NoError(t, err)
+	assert.True(t, snapshot.ReadOnly())
+	assert.Error(t, snapshot.StoreKey(ecKey))
+
+	skis, err := snapshot.(KeyLister).SKIs()
+	assert.NoError(t, err)
+	assert.Len(t, skis, 2)
+	for _, k := range []bccsp.Key{ecKey, rsaKey} {
+		sk, err := snapshot.Key(k.SKI())
+		assert.NoError(t, err)
+		assert.False(t, sk.Private())
+		pk, err := k.PublicKey()
+		assert.NoError(t, err)
+		assert.Equal(t, pk.SKI(), sk.SKI())
+	}
+	// Symmetric keys are not copied
+	_, err = snapshot.Key(aesKey.SKI())
+	assert.Error(t, err)
+
+	// Modifying the live store does not change the snapshot
+	added, err := csp.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: false})
+	assert.NoError(t, err)
+	_, err = csp.Key(added.SKI())
+	assert.NoError(t, err)
+	_, err = snapshot.Key(added.SKI())
+	assert.Error(t, err)
+	after, err := snapshot.(KeyLister).SKIs()
+	assert.NoError(t, err)
+	assert.Equal(t, skis, after)
+
+	// Snapshot can be exported
+	raw, err := ExportJWKS(snapshot)
+	assert.NoError(t, err)
+	assert.Contains(t, string(raw), `"kty":"RSA"`)
+
+	fresh, err := ks.(KeySnapshotter).Snapshot()
+	assert.NoError(t, err)
+	skis, err = fresh.(KeyLister).SKIs()
+	assert.NoError(t, err)
+	assert.Len(t, skis, 3)
+
+	// Corrupted keys are reported and left out of the snapshot
+	corrupted := filepath.Join(ks.(*fileBasedKeyStore).path, "0102_pk")
+	assert.NoError(t, ioutil.WriteFile(corrupted, []byte("not a key"), 0600))
+	partial, err := ks.(KeySnapshotter).Snapshot()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "SKI [0102]")
+	skis, err = partial.(KeyLister).SKIs()
+	assert.NoError(t, err)
+	assert.Len(t, skis, 3)
+	_, err = partial.Key(added.SKI())
+	assert.NoError(t, err)
+
+	dummy, err := NewDefaultSecurityLevelWithKeystore(NewDummyKeyStore())
+	assert.NoError(t, err)
+	_, err = dummy.(*CSP).Snapshot()
+	assert.Error(t, err)
+	_, err = SnapshotKeyStore(nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,219 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+
+	"github.com/pkg/errors"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+)
+
+const (
+	// streamVersion - Version of AEAD stream framing.
+	streamVersion = 1
+
+	// streamPrefixSize - Size of random nonce prefix in stream header.
+	streamPrefixSize = 7
+
+	// streamHeaderSize - Size of AEAD stream header.
+	streamHeaderSize = 1 + 4 + streamPrefixSize
+
+	// maxStreamChunkSize - Maximum accepted chunk size.
+	maxStreamChunkSize = 1 << 24
+)
+
+// DefaultStreamChunkSize - Default plaintext chunk size of AEAD streams.
+var DefaultStreamChunkSize = 64 * 1024
+
+// EncryptStreamAEAD - Encrypts plaintext from r with AES key and writes
+// framed AES-GCM ciphertext to w. Opts may be nil or
+// *bccsp.AESGCMStreamModeOpts.
+//
+// Stream follows the STREAM construction. It starts with a header
+//
+//	version    1 byte, currently 1
+//	chunk      4 bytes big-endian plaintext chunk size
+//	prefix     7 bytes random nonce prefix
+//
+// followed by sealed chunks. Every chunk but the last one holds exactly
+// chunk size of plaintext, the last one holds the remainder which may be
+// empty. Nonce of a chunk is the prefix followed by 4 bytes big-endian
+// chunk counter and one byte set to 1 for the last chunk, 0 otherwise.
+// Header is authenticated as additional data of every chunk.
+// Reordered, dropped or appended chunks fail authentication, so does
+// the stream truncated at chunk boundary.
+func (csp *CSP) EncryptStreamAEAD(k bccsp.Key, r io.Reader, w io.Writer, opts bccsp.EncrypterOpts) error {
+	key, err := csp.streamKey(k)
+	if err != nil {
+		return err
+	}
+	chunkSize := DefaultStreamChunkSize
+	switch o := opts.(type) {
+	case nil:
+	case *bccsp.AESGCMStreamModeOpts:
+		if o.ChunkSize != 0 {
+			chunkSize = o.ChunkSize
+		}
+	default:
+		return errors.Errorf("Unsupported stream options [%T]", opts)
+	}
+	if chunkSize <= 0 || chunkSize > maxStreamChunkSize {
+		return errors.Errorf("Invalid chunk size %d. It must be between 1 and %d.", chunkSize, maxStreamChunkSize)
+	}
+
+	aead, err := newGCM(key)
+	if err != nil {
+		return err
+	}
+	header := make([]byte, streamHeaderSize)
+	header[0] = streamVersion
+	binary.BigEndian.PutUint32(header[1:], uint32(chunkSize))
+	if _, err := rand.Read(header[5:]); err != nil {
+		return errors.Wrap(err, "Failed generating nonce prefix")
+	}
+	if _, err := w.Write(header); err != nil {
+		return errors.Wrap(err, "Failed writing stream header")
+	}
+
+	br := bufio.NewReader(r)
+	plain := make([]byte, chunkSize)
+	sealed := make([]byte, 0, chunkSize+aead.Overhead())
+	for counter := uint64(0); ; counter++ {
+		n, last, err := readChunk(br, plain)
+		if err != nil {
+			return errors.Wrap(err, "Failed reading plaintext")
+		}
+		nonce, err := streamNonce(header, counter, last)
+		if err != nil {
+			return err
+		}
+		sealed = aead.Seal(sealed[:0], nonce, plain[:n], header)
+		if _, err := w.Write(sealed); err != nil {
+			return errors.Wrap(err, "Failed writing chunk")
+		}
+		if last {
+			return nil
+		}
+	}
+}
+
+// DecryptStreamAEAD - Decrypts stream created by EncryptStreamAEAD from r
+// and writes plaintext to w. Opts are reserved for future use and may be nil.
+//
+// Plaintext of a chunk is written only after its tag is verified.
+// Truncated or tampered stream results in error and plaintext of the
+// offending chunk is never written. Notice that plaintext of chunks
+// preceding the offending one may already be written to w, which must be
+// discarded by the caller on error.
+func (csp *CSP) DecryptStreamAEAD(k bccsp.Key, r io.Reader, w io.Writer, opts bccsp.DecrypterOpts) error {
+	key, err := csp.streamKey(k)
+	if err != nil {
+		return err
+	}
+	aead, err := newGCM(key)
+	if err != nil {
+		return err
+	}
+
+	br := bufio.NewReader(r)
+	header := make([]byte, streamHeaderSize)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return errors.Wrap(err, "Failed reading stream header")
+	}
+	if header[0] != streamVersion {
+		return errors.Errorf("Unsupported stream version %d", header[0])
+	}
+	chunkSize := binary.BigEndian.Uint32(header[1:])
+	if chunkSize == 0 || chunkSize > maxStreamChunkSize {
+		return errors.Errorf("Invalid chunk size %d in stream header", chunkSize)
+	}
+
+	sealed := make([]byte, int(chunkSize)+aead.Overhead())
+	plain := make([]byte, 0, chunkSize)
+	for counter := uint64(0); ; counter++ {
+		n, last, err := readChunk(br, sealed)
+		if err != nil {
+			return errors.Wrap(err, "Failed reading ciphertext")
+		}
+		if n < aead.Overhead() {
+			return errors.Errorf("Failed decrypting chunk %d. Stream is truncated.", counter)
+		}
+		nonce, err := streamNonce(header, counter, last)
+		if err != nil {
+			return err
+		}
+		plain, err = aead.Open(plain[:0], nonce, sealed[:n], header)
+		if err != nil {
+			return errors.Wrapf(err, "Failed decrypting chunk %d", counter)
+		}
+		if _, err := w.Write(plain); err != nil {
+			return errors.Wrap(err, "Failed writing plaintext")
+		}
+		if last {
+			return nil
+		}
+	}
+}
+
+// streamKey - Returns raw AES key usable for stream encryption.
+func (csp *CSP) streamKey(k bccsp.Key) ([]byte, error) {
+	if k == nil {
+		return nil, errors.New("Invalid Key. It must not be nil.")
+	}
+	aesKey, ok := k.(*aesPrivateKey)
+	if !ok {
+		return nil, errors.Errorf("Unsupported 'StreamKey' provided [%v]", k)
+	}
+	if err := csp.checkFIPSKey(k); err != nil {
+		return nil, err
+	}
+	return aesKey.privKey, nil
+}
+
+// readChunk - Fills buf from r and reports if it was the last chunk.
+// Chunk is the last one when r ends before or right after it.
+func readChunk(r *bufio.Reader, buf []byte) (n int, last bool, err error) {
+	n, err = io.ReadFull(r, buf)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return n, true, nil
+	}
+	if err != nil {
+		return n, false, err
+	}
+	if _, err = r.Peek(1); err == io.EOF {
+		return n, true, nil
+	}
+	return n, false, err
+}
+
+// streamNonce - Builds nonce of chunk with given counter.
+func streamNonce(header []byte, counter uint64, last bool) ([]byte, error) {
+	if counter > 0xffffffff {
+		return nil, errors.New("Stream is too long. Chunk counter overflow.")
+	}
+	nonce := make([]byte, 12)
+	copy(nonce, header[5:])
+	binary.BigEndian.PutUint32(nonce[streamPrefixSize:], uint32(counter))
+	if last {
+		nonce[11] = 1
+	}
+	return nonce, nil
+}
@@ -0,0 +1,115 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamAEAD(t *testing.T) {
+	provider, _, cleanup := currentTestConfig.Provider(t)
+	defer cleanup()
+	csp := provider.(*CSP)
+
+	k, err := provider.KeyGen(&bccsp.AESKeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	opts := &bccsp.AESGCMStreamModeOpts{ChunkSize: 16}
+
+	encrypt := func(plaintext []byte) []byte {
+		var out bytes.Buffer
+		assert.NoError(t, csp.EncryptStreamAEAD(k, bytes.NewReader(plaintext), &out, opts))
+		return out.Bytes()
+	}
+
+	// Round trip including empty and chunk aligned plaintexts
+	for _, size := range []int{0, 1, 15, 16, 17, 32, 100} {
+		plaintext, err := GetRandomBytes(size)
+		assert.NoError(t, err)
+		var out bytes.Buffer
+		assert.NoError(t, csp.DecryptStreamAEAD(k, bytes.NewReader(encrypt(plaintext)), &out, nil))
+		assert.Equal(t, string(plaintext), out.String(), "size %d", size)
+	}
+
+	// Default chunk size
+	plaintext, err := GetRandomBytes(3*DefaultStreamChunkSize + 5)
+	assert.NoError(t, err)
+	var ciphertext, out bytes.Buffer
+	assert.NoError(t, csp.EncryptStreamAEAD(k, bytes.NewReader(plaintext), &ciphertext, nil))
+	assert.NoError(t, csp.DecryptStreamAEAD(k, &ciphertext, &out, nil))
+	assert.Equal(t, plaintext, out.Bytes())
+
+	plaintext, err = GetRandomBytes(40)
+	assert.NoError(t, err)
+	stream := encrypt(plaintext)
+	sealedChunk := 16 + 16
+
+	// Truncation at chunk boundary drops the final flag
+	out.Reset()
+	truncated := stream[:streamHeaderSize+2*sealedChunk]
+	assert.Error(t, csp.DecryptStreamAEAD(k, bytes.NewReader(truncated), &out, nil))
+	assert.Equal(t, plaintext[:16], out.Bytes())
+
+	// Truncation inside of the final chunk
+	out.Reset()
+	truncated = stream[:len(stream)-1]
+	assert.Error(t, csp.DecryptStreamAEAD(k, bytes.NewReader(truncated), &out, nil))
+	assert.Equal(t, plaintext[:32], out.Bytes())
+
+	// Tampered final chunk is never released
+	out.Reset()
+	tampered := append([]byte{}, stream...)
+	tampered[len(tampered)-20] ^= 1
+	assert.Error(t, csp.DecryptStreamAEAD(k, bytes.NewReader(tampered), &out, nil))
+	assert.Equal(t, plaintext[:32], out.Bytes())
+
+	// Tampered header fails on the first chunk
+	out.Reset()
+	tampered = append([]byte{}, stream...)
+	tampered[streamHeaderSize-1] ^= 1
+	assert.Error(t, csp.DecryptStreamAEAD(k, bytes.NewReader(tampered), &out, nil))
+	assert.Empty(t, out.Bytes())
+
+	// Reordered chunks
+	out.Reset()
+	reordered := append([]byte{}, stream[:streamHeaderSize]...)
+	reordered = append(reordered, stream[streamHeaderSize+sealedChunk:streamHeaderSize+2*sealedChunk]...)
+	reordered = append(reordered, stream[streamHeaderSize:streamHeaderSize+sealedChunk]...)
+	reordered = append(reordered, stream[streamHeaderSize+2*sealedChunk:]...)
+	assert.Error(t, csp.DecryptStreamAEAD(k, bytes.NewReader(reordered), &out, nil))
+	assert.Empty(t, out.Bytes())
+
+	// Appended data
+	out.Reset()
+	appended := append(append([]byte{}, stream...), stream[streamHeaderSize:streamHeaderSize+sealedChunk]...)
+	assert.Error(t, csp.DecryptStreamAEAD(k, bytes.NewReader(appended), &out, nil))
+
+	// Header only
+	out.Reset()
+	assert.Error(t, csp.DecryptStreamAEAD(k, bytes.NewReader(stream[:streamHeaderSize]), &out, nil))
+	assert.Empty(t, out.Bytes())
+
+	// Invalid arguments
+	ecKey, err := provider.KeyGen(&bccsp.ECDSAKeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	assert.Error(t, csp.EncryptStreamAEAD(ecKey, bytes.NewReader(plaintext), &out, nil))
+	assert.Error(t, csp.DecryptStreamAEAD(nil, bytes.NewReader(stream), &out, nil))
+	assert.Error(t, csp.EncryptStreamAEAD(k, bytes.NewReader(plaintext), &out, &bccsp.AESGCMStreamModeOpts{ChunkSize: -1}))
+	assert.Error(t, csp.EncryptStreamAEAD(k, bytes.NewReader(plaintext), &out, &bccsp.AESCBCPKCS7ModeOpts{}))
+}
@@ -0,0 +1,219 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"container/list"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"fmt"
+	"sync"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp/utils"
+	"github.com/ipfn/ipfn/pkg/digest"
+)
+
+// verifyCache - Bounded LRU cache of verification results.
+type verifyCache struct {
+	sync.Mutex
+
+	size    int
+	entries map[digest.Digest]*list.Element
+	order   *list.List
+}
+
+// verifyCacheEntry - Cached verification result.
+type verifyCacheEntry struct {
+	key   digest.Digest
+	valid bool
+}
+
+// SetVerifyCacheSize enables cache of at most n verification results,
+// which saves verification of signatures repeatedly verified, for example
+// on retries. Results are keyed by hash of public key and its type, digest,
+// signature, opts and low-S policy of ECDSA curve, only verifications
+// which completed without error and with opts of known type are cached. Zero, the default, disables
+// and clears the cache. Entries are evicted under the cache lock,
+// so resizing does not race with verifications in progress.
+func (csp *CSP) SetVerifyCacheSize(n int) {
+	c := &csp.verifyCache
+	c.Lock()
+	defer c.Unlock()
+	if n <= 0 {
+		c.size, c.entries, c.order = 0, nil, nil
+		return
+	}
+	if c.entries == nil {
+		c.entries = make(map[digest.Digest]*list.Element, n)
+		c.order = list.New()
+	}
+	c.size = n
+	c.evict()
+}
+
+// cachedVerify - Verifies signature with verifier or returns cached result,
+// when cache is enabled.
+func (csp *CSP) cachedVerify(verifier bccsp.Verifier, k bccsp.Key, signature, hashed []byte, opts bccsp.SignerOpts) (bool, error) {
+	if !csp.verifyCache.enabled() {
+		return verifier.Verify(k, signature, hashed, opts)
+	}
+	key, ok := verifyCacheKey(k, signature, hashed, opts)
+	if !ok {
+		return verifier.Verify(k, signature, hashed, opts)
+	}
+	if valid, found := csp.verifyCache.get(key); found {
+		return valid, nil
+	}
+	valid, err := verifier.Verify(k, signature, hashed, opts)
+	if err != nil {
+		return false, err
+	}
+	csp.verifyCache.put(key, valid)
+	return valid, nil
+}
+
+// get - Returns cached result of verification.
+func (c *verifyCache) get(key digest.Digest) (valid, found bool) {
+	c.Lock()
+	defer c.Unlock()
+	if c.size == 0 {
+		return false, false
+	}
+	elem, found := c.entries[key]
+	if !found {
+		return false, false
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*verifyCacheEntry).valid, true
+}
+
+// put - Caches result of verification.
+func (c *verifyCache) put(key digest.Digest, valid bool) {
+	c.Lock()
+	defer c.Unlock()
+	if c.size == 0 {
+		return
+	}
+	if elem, found := c.entries[key]; found {
+		elem.Value.(*verifyCacheEntry).valid = valid
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&verifyCacheEntry{key: key, valid: valid})
+	c.evict()
+}
+
+// enabled - Returns true if cache is enabled.
+func (c *verifyCache) enabled() bool {
+	c.Lock()
+	defer c.Unlock()
+	return c.size != 0
+}
+
+// evict - Removes least recently used entries exceeding size.
+func (c *verifyCache) evict() {
+	for c.order.Len() > c.size {
+		elem := c.order.Back()
+		c.order.Remove(elem)
+		delete(c.entries, elem.Value.(*verifyCacheEntry).key)
+	}
+}
+
+// verifyCacheKey - Returns cache key of verification, false if result
+// cannot be cached. Fields are length prefixed, so no two distinct inputs
+// share the preimage.
+func verifyCacheKey(k bccsp.Key, signature, hashed []byte, opts bccsp.SignerOpts) (digest.Digest, bool) {
+	encodedOpts, ok := verifyCacheOpts(opts)
+	if !ok {
+		return digest.Digest{}, false
+	}
+	key, err := digest.SumFramed(digest.FamilySha2,
+		[]byte(fmt.Sprintf("%T", k)),
+		verifyCacheKeyBytes(k),
+		hashed,
+		signature,
+		encodedOpts,
+		verifyPolicy(k),
+	)
+	if err != nil {
+		return digest.Digest{}, false
+	}
+	return key, true
+}
+
+// verifyCacheKeyBytes - Returns encoded public key verifying signatures,
+// or SKI if key cannot be encoded.
+func verifyCacheKeyBytes(k bccsp.Key) []byte {
+	if k.Private() {
+		pub, err := k.PublicKey()
+		if err != nil {
+			return k.SKI()
+		}
+		k = pub
+	}
+	raw, err := k.Bytes()
+	if err != nil {
+		return k.SKI()
+	}
+	return raw
+}
+
+// verifyCacheOpts - Returns canonical encoding of signer opts,
+// false if opts are of unknown type.
+func verifyCacheOpts(opts bccsp.SignerOpts) ([]byte, bool) {
+	switch opts := opts.(type) {
+	case nil:
+		return []byte("nil"), true
+	case crypto.Hash:
+		return []byte(fmt.Sprintf("hash:%d", opts)), true
+	case *rsa.PSSOptions:
+		if opts == nil {
+			return nil, false
+		}
+		return []byte(fmt.Sprintf("pss:%d:%d", opts.SaltLength, opts.Hash)), true
+	case *bccsp.PrehashSignerOpts:
+		if opts == nil {
+			return nil, false
+		}
+		return []byte(fmt.Sprintf("prehash:%d:%t:%t", opts.Hash, opts.HashMessage, opts.AutoHashForCurve)), true
+	case *bccsp.ECDSASignerOpts:
+		if opts == nil {
+			return nil, false
+		}
+		return []byte(fmt.Sprintf("ecdsa:%d", opts.H)), true
+	}
+	return nil, false
+}
+
+// verifyPolicy - Returns state of global policy which result of
+// verification with the key depends on, that is low-S policy of curve.
+func verifyPolicy(k bccsp.Key) []byte {
+	var pub *ecdsa.PublicKey
+	switch k := k.(type) {
+	case *ecdsaPublicKey:
+		pub = k.pubKey
+	case *ecdsaPrivateKey:
+		pub = &k.privKey.PublicKey
+	default:
+		return nil
+	}
+	if utils.LowSEnforced(pub.Curve) {
+		return []byte("low-s")
+	}
+	return []byte("any-s")
+}
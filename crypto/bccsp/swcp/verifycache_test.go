@@ -0,0 +1,202 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp/utils"
+)
+
+type countingVerifier struct {
+	bccsp.Verifier
+	calls int
+}
+
+func (v *countingVerifier) Verify(k bccsp.Key, signature, digest []byte, opts bccsp.SignerOpts) (bool, error) {
+	v.calls++
+	return v.Verifier.Verify(k, signature, digest, opts)
+}
+
+func TestVerifyCache(t *testing.T) {
+	provider, _, cleanup := currentTestConfig.Provider(t)
+	defer cleanup()
+	csp := provider.(*CSP)
+
+	verifier := &countingVerifier{Verifier: &ecdsaPublicKeyKeyVerifier{}}
+	assert.NoError(t, csp.AddWrapper(reflect.TypeOf(&ecdsaPublicKey{}), verifier))
+
+	k, err := provider.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	pk, err := k.PublicKey()
+	assert.NoError(t, err)
+	other, err := provider.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	otherPK, err := other.PublicKey()
+	assert.NoError(t, err)
+
+	digest := sha256.Sum256([]byte("Hello World"))
+	otherDigest := sha256.Sum256([]byte("Hello World!"))
+	signature, err := provider.Sign(k, digest[:], nil)
+	assert.NoError(t, err)
+
+	// Disabled by default
+	for i := 0; i < 2; i++ {
+		valid, err := provider.Verify(pk, signature, digest[:], nil)
+		assert.NoError(t, err)
+		assert.True(t, valid)
+	}
+	assert.Equal(t, 2, verifier.calls)
+
+	csp.SetVerifyCacheSize(16)
+	verifier.calls = 0
+	for i := 0; i < 3; i++ {
+		valid, err := provider.Verify(pk, signature, digest[:], nil)
+		assert.NoError(t, err)
+		assert.True(t, valid)
+	}
+	assert.Equal(t, 1, verifier.calls)
+
+	// Different inputs do not collide
+	valid, err := provider.Verify(pk, signature, otherDigest[:], nil)
+	assert.NoError(t, err)
+	assert.False(t, valid)
+	valid, err = provider.Verify(otherPK, signature, digest[:], nil)
+	assert.NoError(t, err)
+	assert.False(t, valid)
+	otherSignature, err := provider.Sign(k, digest[:], nil)
+	assert.NoError(t, err)
+	valid, err = provider.Verify(pk, otherSignature, digest[:], nil)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+	assert.Equal(t, 4, verifier.calls)
+
+	// Cached invalid results stay invalid
+	valid, err = provider.Verify(pk, signature, otherDigest[:], nil)
+	assert.NoError(t, err)
+	assert.False(t, valid)
+	assert.Equal(t, 4, verifier.calls)
+
+	// Failed verifications are not cached
+	for i := 0; i < 2; i++ {
+		_, err = provider.Verify(pk, []byte("garbage"), digest[:], nil)
+		assert.Error(t, err)
+	}
+	assert.Equal(t, 6, verifier.calls)
+
+	// Least recently used results are evicted
+	csp.SetVerifyCacheSize(2)
+	verifier.calls = 0
+	provider.Verify(pk, otherSignature, digest[:], nil)
+	provider.Verify(pk, signature, otherDigest[:], nil)
+	assert.Equal(t, 0, verifier.calls)
+	provider.Verify(pk, signature, digest[:], nil)
+	assert.Equal(t, 1, verifier.calls)
+	provider.Verify(pk, otherSignature, digest[:], nil)
+	assert.Equal(t, 2, verifier.calls)
+
+	// Disabling clears the cache
+	csp.SetVerifyCacheSize(0)
+	verifier.calls = 0
+	provider.Verify(pk, signature, digest[:], nil)
+	provider.Verify(pk, signature, digest[:], nil)
+	assert.Equal(t, 2, verifier.calls)
+
+	// Results do not outlive change of low-S policy
+	csp.SetVerifyCacheSize(16)
+	r, s, err := utils.UnmarshalECDSASignature(signature)
+	assert.NoError(t, err)
+	highS, err := utils.MarshalECDSASignature(r, new(big.Int).Sub(elliptic.P256().Params().N, s))
+	assert.NoError(t, err)
+	utils.SetLowSPolicy(elliptic.P256(), false)
+	defer utils.SetLowSPolicy(elliptic.P256(), true)
+	valid, err = provider.Verify(pk, highS, digest[:], nil)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+	utils.SetLowSPolicy(elliptic.P256(), true)
+	_, err = provider.Verify(pk, highS, digest[:], nil)
+	assert.Error(t, err)
+}
+
+// collidingKey - Public key of which all instances share SKI.
+type collidingKey struct {
+	raw []byte
+}
+
+func (k *collidingKey) Bytes() ([]byte, error)        { return k.raw, nil }
+func (k *collidingKey) SKI() []byte                   { return []byte("ski") }
+func (k *collidingKey) Symmetric() bool               { return false }
+func (k *collidingKey) Private() bool                 { return false }
+func (k *collidingKey) PublicKey() (bccsp.Key, error) { return k, nil }
+
+// unknownSignerOpts - Signer opts of type unknown to verify cache.
+type unknownSignerOpts struct{}
+
+func (*unknownSignerOpts) HashFunc() crypto.Hash { return 0 }
+
+func TestVerifyCacheKey(t *testing.T) {
+	k1, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	k2, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	pk1 := &ecdsaPublicKey{pubKey: &k1.PublicKey}
+	pk2 := &ecdsaPublicKey{pubKey: &k2.PublicKey}
+	digest := []byte("digest")
+	signature := []byte("signature")
+
+	// Keys with colliding SKIs do not share results
+	a, ok := verifyCacheKey(&collidingKey{[]byte("a")}, signature, digest, nil)
+	assert.True(t, ok)
+	b, ok := verifyCacheKey(&collidingKey{[]byte("b")}, signature, digest, nil)
+	assert.True(t, ok)
+	assert.NotEqual(t, a, b)
+
+	// Keys of different public keys do not share results
+	a, _ = verifyCacheKey(pk1, signature, digest, nil)
+	b, _ = verifyCacheKey(pk2, signature, digest, nil)
+	assert.NotEqual(t, a, b)
+	b, _ = verifyCacheKey(&ecdsaPrivateKey{k2}, signature, digest, nil)
+	assert.NotEqual(t, a, b)
+
+	// Opts are encoded by value, not by address
+	a, ok = verifyCacheKey(pk1, signature, digest, &bccsp.PrehashSignerOpts{Hash: crypto.SHA256})
+	assert.True(t, ok)
+	b, _ = verifyCacheKey(pk1, signature, digest, &bccsp.PrehashSignerOpts{Hash: crypto.SHA256})
+	assert.Equal(t, a, b)
+	b, _ = verifyCacheKey(pk1, signature, digest, &bccsp.PrehashSignerOpts{Hash: crypto.SHA256, HashMessage: true})
+	assert.NotEqual(t, a, b)
+	a, ok = verifyCacheKey(pk1, signature, digest, &rsa.PSSOptions{SaltLength: 32, Hash: crypto.SHA256})
+	assert.True(t, ok)
+	b, _ = verifyCacheKey(pk1, signature, digest, &rsa.PSSOptions{SaltLength: 32, Hash: crypto.SHA256})
+	assert.Equal(t, a, b)
+	b, _ = verifyCacheKey(pk1, signature, digest, crypto.SHA256)
+	assert.NotEqual(t, a, b)
+
+	// Opts of unknown type are not cached
+	_, ok = verifyCacheKey(pk1, signature, digest, &unknownSignerOpts{})
+	assert.False(t, ok)
+}
@@ -0,0 +1,135 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+	"github.com/ipfn/ipfn/pkg/crypto/x25519"
+	"github.com/ipfn/ipfn/pkg/digest"
+)
+
+// x25519KeySize - Size of X25519 private and public keys.
+const x25519KeySize = 32
+
+type x25519KeyGenerator struct{}
+
+func (kg *x25519KeyGenerator) KeyGen(opts bccsp.KeyGenOpts) (bccsp.Key, error) {
+	var privKey [x25519KeySize]byte
+	if _, err := io.ReadFull(rand.Reader, privKey[:]); err != nil {
+		return nil, fmt.Errorf("Failed generating X25519 key [%s]", err)
+	}
+	pubKey := x25519.Public(&privKey)
+	return &x25519PrivateKey{privKey: privKey, pubKey: &x25519PublicKey{pubKey}}, nil
+}
+
+type x25519PrivateKey struct {
+	privKey [x25519KeySize]byte
+	pubKey  *x25519PublicKey
+}
+
+// Bytes converts this key to its byte representation,
+// if this operation is allowed.
+func (k *x25519PrivateKey) Bytes() ([]byte, error) {
+	return nil, errors.New("Not supported.")
+}
+
+// SKI returns the subject key identifier of this key.
+func (k *x25519PrivateKey) SKI() []byte {
+	return k.pubKey.SKI()
+}
+
+// Symmetric returns true if this key is a symmetric key,
+// false if this key is asymmetric
+func (k *x25519PrivateKey) Symmetric() bool {
+	return false
+}
+
+// Private returns true if this key is a private key,
+// false otherwise.
+func (k *x25519PrivateKey) Private() bool {
+	return true
+}
+
+// PublicKey returns the corresponding public key part of an asymmetric public/private key pair.
+// This method returns an error in symmetric key schemes.
+func (k *x25519PrivateKey) PublicKey() (bccsp.Key, error) {
+	return k.pubKey, nil
+}
+
+// X25519PublicKey returns raw X25519 public key, see utils.X25519Key.
+func (k *x25519PrivateKey) X25519PublicKey() []byte {
+	return k.pubKey.X25519PublicKey()
+}
+
+type x25519PublicKey struct {
+	pubKey [x25519KeySize]byte
+}
+
+// Bytes converts this key to its byte representation,
+// if this operation is allowed.
+func (k *x25519PublicKey) Bytes() ([]byte, error) {
+	return k.X25519PublicKey(), nil
+}
+
+// SKI returns the subject key identifier of this key.
+func (k *x25519PublicKey) SKI() []byte {
+	return digest.SumSha256Bytes(k.pubKey[:])
+}
+
+// Symmetric returns true if this key is a symmetric key,
+// false if this key is asymmetric
+func (k *x25519PublicKey) Symmetric() bool {
+	return false
+}
+
+// Private returns true if this key is a private key,
+// false otherwise.
+func (k *x25519PublicKey) Private() bool {
+	return false
+}
+
+// PublicKey returns the corresponding public key part of an asymmetric public/private key pair.
+// This method returns an error in symmetric key schemes.
+func (k *x25519PublicKey) PublicKey() (bccsp.Key, error) {
+	return k, nil
+}
+
+// X25519PublicKey returns raw X25519 public key, see utils.X25519Key.
+func (k *x25519PublicKey) X25519PublicKey() []byte {
+	raw := make([]byte, x25519KeySize)
+	copy(raw, k.pubKey[:])
+	return raw
+}
+
+type x25519PublicKeyImportOptsKeyImporter struct{}
+
+func (*x25519PublicKeyImportOptsKeyImporter) KeyImport(raw interface{}, opts bccsp.KeyImportOpts) (bccsp.Key, error) {
+	pub, ok := raw.([]byte)
+	if !ok {
+		return nil, errors.New("Invalid raw material. Expected byte array.")
+	}
+	if len(pub) != x25519KeySize {
+		return nil, fmt.Errorf("Invalid raw material. X25519 public key must be %d bytes long.", x25519KeySize)
+	}
+	k := &x25519PublicKey{}
+	copy(k.pubKey[:], pub)
+	return k, nil
+}
@@ -0,0 +1,71 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package swcp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp/utils"
+	"github.com/ipfn/ipfn/pkg/crypto/x25519"
+	"github.com/ipfn/ipfn/pkg/utils/hexutil"
+)
+
+func TestX25519AgeRecipient(t *testing.T) {
+	provider, _, cleanup := currentTestConfig.Provider(t)
+	defer cleanup()
+
+	// Alice from RFC 7748 section 6.1
+	var priv [32]byte
+	copy(priv[:], hexutil.FromString("77076d0a7318a57d3c16c17251b26645df4c2f87ebc0992ab177fba51db92c2a"))
+	pub := x25519.Public(&priv)
+	alice := &x25519PrivateKey{privKey: priv, pubKey: &x25519PublicKey{pub}}
+
+	recipient, err := utils.X25519ToAgeRecipient(alice)
+	assert.NoError(t, err)
+	assert.Equal(t, "age1s5s0qzvfxzn4gayt0hwtg0hhtgxm7wsdycup4a8t5j5ca25mfe4qt4hs7q", recipient)
+
+	raw, opts, err := utils.AgeRecipientToKeyImportOpts(recipient)
+	assert.NoError(t, err)
+	imported, err := provider.KeyImport(raw, opts)
+	assert.NoError(t, err)
+	assert.False(t, imported.Private())
+	assert.Equal(t, alice.SKI(), imported.SKI())
+	again, err := utils.X25519ToAgeRecipient(imported)
+	assert.NoError(t, err)
+	assert.Equal(t, recipient, again)
+
+	// Generated keys round trip
+	k, err := provider.KeyGen(&bccsp.X25519KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	assert.True(t, k.Private())
+	_, err = k.Bytes()
+	assert.Error(t, err)
+	recipient, err = utils.X25519ToAgeRecipient(k)
+	assert.NoError(t, err)
+	raw, opts, err = utils.AgeRecipientToKeyImportOpts(recipient)
+	assert.NoError(t, err)
+	imported, err = provider.KeyImport(raw, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, k.SKI(), imported.SKI())
+
+	_, err = provider.KeyImport(raw[1:], opts)
+	assert.Error(t, err)
+	_, err = provider.KeyImport("invalid", opts)
+	assert.Error(t, err)
+}
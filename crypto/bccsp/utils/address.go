@@ -0,0 +1,78 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/btcsuite/btcutil/bech32"
+	"golang.org/x/crypto/ed25519"
+	"golang.org/x/crypto/ripemd160"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+)
+
+const (
+	// ed25519PublicKeySize - Size of raw Ed25519 public key.
+	ed25519PublicKeySize = 32
+	// compressedPublicKeySize - Size of compressed 256 bit curve public key.
+	compressedPublicKeySize = 33
+)
+
+// PublicKeyToBech32Address - Derives Cosmos-style bech32 address of public key
+// with given human readable part (e.g. "cosmos").
+//
+// ECDSA keys, including secp256k1, are encoded as compressed points and address
+// is RIPEMD-160 of SHA-256 of encoded key. Ed25519 keys use Tendermint derivation,
+// address is SHA-256 truncated to 20 bytes. Key type is taken from the Go public
+// key of the key, see GoPublicKey, other key types are rejected.
+func PublicKeyToBech32Address(key bccsp.Key, hrp string) (string, error) {
+	pub, err := GoPublicKey(key)
+	if err != nil {
+		return "", err
+	}
+	addr, err := publicKeyAddress(pub)
+	if err != nil {
+		return "", err
+	}
+	data, err := bech32.ConvertBits(addr, 8, 5, true)
+	if err != nil {
+		return "", fmt.Errorf("Failed converting address [%s]", err)
+	}
+	return bech32.Encode(hrp, data)
+}
+
+// publicKeyAddress - Computes 20 bytes address of Go public key.
+func publicKeyAddress(pub interface{}) ([]byte, error) {
+	switch k := pub.(type) {
+	case ed25519.PublicKey:
+		sum := sha256.Sum256(k)
+		return sum[:20], nil
+	case *ecdsa.PublicKey:
+		return hash160(CompressECDSAPublicKey(k)), nil
+	}
+	return nil, fmt.Errorf("Unsupported key type [%T]", pub)
+}
+
+// hash160 - Computes RIPEMD-160 of SHA-256 of data.
+func hash160(data []byte) []byte {
+	sum := sha256.Sum256(data)
+	h := ripemd160.New()
+	h.Write(sum[:])
+	return h.Sum(nil)
+}
@@ -0,0 +1,120 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"math/big"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/btcsuite/btcutil/bech32"
+	"golang.org/x/crypto/ed25519"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp/mocks"
+	"github.com/ipfn/ipfn/pkg/utils/hexutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// goKey - Public key exposing its Go public key.
+type goKey struct {
+	mocks.MockKey
+	pub interface{}
+}
+
+func (k *goKey) GoPublicKey() interface{} {
+	return k.pub
+}
+
+// secp256k1Key - Returns key of compressed secp256k1 point.
+func secp256k1Key(t *testing.T, raw []byte) *goKey {
+	pub, err := btcec.ParsePubKey(raw, btcec.S256())
+	assert.NoError(t, err)
+	return &goKey{pub: pub.ToECDSA()}
+}
+
+func TestPublicKeyToBech32Address(t *testing.T) {
+	// secp256k1 generator point, see BIP-173 P2WPKH example
+	pub := hexutil.FromString("0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798")
+	assert.Equal(t, hexutil.FromString("751e76e8199196d454941c45d1b3a323f1433bd6"), hash160(pub))
+
+	addr, err := PublicKeyToBech32Address(secp256k1Key(t, pub), "cosmos")
+	assert.NoError(t, err)
+	assert.Equal(t, "cosmos1w508d6qejxtdg4y5r3zarvary0c5xw7k6ah60c", addr)
+
+	// Private key is resolved to its public key
+	addr, err = PublicKeyToBech32Address(&mocks.MockKey{Pvt: true, PK: secp256k1Key(t, pub)}, "cosmos")
+	assert.NoError(t, err)
+	assert.Equal(t, "cosmos1w508d6qejxtdg4y5r3zarvary0c5xw7k6ah60c", addr)
+
+	// Ed25519 uses truncated SHA-256
+	edpub := make([]byte, 32)
+	for i := range edpub {
+		edpub[i] = byte(i)
+	}
+	addr, err = PublicKeyToBech32Address(&goKey{pub: ed25519.PublicKey(edpub)}, "cosmos")
+	assert.NoError(t, err)
+	assert.Equal(t, "cosmos1vvxu62txcsekdygj23ythvjmfl6p9fyu43nucc", addr)
+}
+
+func TestPublicKeyToBech32AddressECDSA(t *testing.T) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&priv.PublicKey)
+	assert.NoError(t, err)
+
+	compressed := CompressECDSAPublicKey(&priv.PublicKey)
+	assert.Len(t, compressed, 33)
+	assert.Equal(t, byte(0x02|priv.Y.Bit(0)), compressed[0])
+	assert.Equal(t, 0, priv.X.Cmp(new(big.Int).SetBytes(compressed[1:])))
+
+	addr, err := PublicKeyToBech32Address(&mocks.MockKey{BytesValue: der}, "cosmos")
+	assert.NoError(t, err)
+	assert.Equal(t, hash160(compressed), bech32Data(t, addr))
+	keyAddr, err := PublicKeyToBech32Address(&goKey{pub: &priv.PublicKey}, "cosmos")
+	assert.NoError(t, err)
+	assert.Equal(t, addr, keyAddr)
+}
+
+func TestPublicKeyToBech32AddressErrors(t *testing.T) {
+	_, err := PublicKeyToBech32Address(nil, "cosmos")
+	assert.Error(t, err)
+	_, err = PublicKeyToBech32Address(&mocks.MockKey{Symm: true}, "cosmos")
+	assert.Error(t, err)
+	_, err = PublicKeyToBech32Address(&mocks.MockKey{BytesValue: []byte{1, 2, 3}}, "cosmos")
+	assert.Error(t, err)
+
+	// Raw key bytes are not guessed from their length
+	_, err = PublicKeyToBech32Address(&mocks.MockKey{BytesValue: make([]byte, 32)}, "cosmos")
+	assert.Error(t, err)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	assert.NoError(t, err)
+	_, err = PublicKeyToBech32Address(&goKey{pub: &rsaKey.PublicKey}, "cosmos")
+	assert.Error(t, err)
+}
+
+// bech32Data - Decodes data of bech32 string.
+func bech32Data(t *testing.T, s string) []byte {
+	_, data, err := bech32.Decode(s)
+	assert.NoError(t, err)
+	data, err = bech32.ConvertBits(data, 5, 8, false)
+	assert.NoError(t, err)
+	return data
+}
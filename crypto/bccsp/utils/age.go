@@ -0,0 +1,84 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcutil/bech32"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+)
+
+const (
+	// ageRecipientHRP - Human readable part of age X25519 recipients.
+	ageRecipientHRP = "age"
+	// x25519PublicKeySize - Size of raw X25519 public key.
+	x25519PublicKeySize = 32
+)
+
+// X25519Key - Public or private X25519 key.
+type X25519Key interface {
+	bccsp.Key
+
+	// X25519PublicKey - Returns raw 32 bytes X25519 public key.
+	X25519PublicKey() []byte
+}
+
+// X25519ToAgeRecipient - Encodes X25519 key as age recipient, a bech32
+// string with human readable part "age" (e.g. "age1..."). Private keys
+// are encoded as their public keys.
+func X25519ToAgeRecipient(key bccsp.Key) (string, error) {
+	if key == nil {
+		return "", errors.New("Invalid key. It must not be nil.")
+	}
+	k, ok := key.(X25519Key)
+	if !ok {
+		return "", fmt.Errorf("Invalid key. Expected X25519 key, got [%T].", key)
+	}
+	pub := k.X25519PublicKey()
+	if len(pub) != x25519PublicKeySize {
+		return "", fmt.Errorf("Invalid X25519 public key length %d", len(pub))
+	}
+	data, err := bech32.ConvertBits(pub, 8, 5, true)
+	if err != nil {
+		return "", fmt.Errorf("Failed converting public key [%s]", err)
+	}
+	return bech32.Encode(ageRecipientHRP, data)
+}
+
+// AgeRecipientToKeyImportOpts - Decodes age X25519 recipient and returns
+// raw public key and opts for importing it with BCCSP KeyImport.
+// Checksum and human readable part of the recipient are validated.
+// Returned opts are ephemeral.
+func AgeRecipientToKeyImportOpts(s string) ([]byte, bccsp.KeyImportOpts, error) {
+	hrp, data, err := bech32.Decode(s)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed decoding age recipient [%s]", err)
+	}
+	if hrp != ageRecipientHRP {
+		return nil, nil, fmt.Errorf("Invalid age recipient prefix %q. Expected %q.", hrp, ageRecipientHRP)
+	}
+	pub, err := bech32.ConvertBits(data, 5, 8, false)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed converting age recipient [%s]", err)
+	}
+	if len(pub) != x25519PublicKeySize {
+		return nil, nil, fmt.Errorf("Invalid age recipient length %d. Expected %d bytes.", len(pub), x25519PublicKeySize)
+	}
+	return pub, &bccsp.X25519PublicKeyImportOpts{Temporary: true}, nil
+}
@@ -0,0 +1,80 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp/mocks"
+	"github.com/ipfn/ipfn/pkg/utils/hexutil"
+)
+
+// x25519TestKey - X25519 public key for tests.
+type x25519TestKey struct {
+	mocks.MockKey
+	pub []byte
+}
+
+func (k *x25519TestKey) X25519PublicKey() []byte {
+	return k.pub
+}
+
+func TestAgeRecipient(t *testing.T) {
+	// Public key of Alice from RFC 7748 section 6.1
+	pub := hexutil.FromString("8520f0098930a754748b7ddcb43ef75a0dbf3a0d26381af4eba4a98eaa9b4e6a")
+	const recipient = "age1s5s0qzvfxzn4gayt0hwtg0hhtgxm7wsdycup4a8t5j5ca25mfe4qt4hs7q"
+
+	s, err := X25519ToAgeRecipient(&x25519TestKey{pub: pub})
+	assert.NoError(t, err)
+	assert.Equal(t, recipient, s)
+
+	raw, opts, err := AgeRecipientToKeyImportOpts(recipient)
+	assert.NoError(t, err)
+	assert.Equal(t, pub, raw)
+	assert.IsType(t, &bccsp.X25519PublicKeyImportOpts{}, opts)
+	assert.True(t, opts.Ephemeral())
+
+	// Recipient from age documentation
+	raw, _, err = AgeRecipientToKeyImportOpts("age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p")
+	assert.NoError(t, err)
+	assert.Equal(t, hexutil.FromString("07e22f5e44a542e8dc8e753a42251e1010cc79d192b3f71c5b1c95645209997a"), raw)
+}
+
+func TestAgeRecipientErrors(t *testing.T) {
+	_, err := X25519ToAgeRecipient(nil)
+	assert.Error(t, err)
+	_, err = X25519ToAgeRecipient(&mocks.MockKey{BytesValue: make([]byte, 32)})
+	assert.Error(t, err)
+	_, err = X25519ToAgeRecipient(&x25519TestKey{pub: make([]byte, 31)})
+	assert.Error(t, err)
+
+	for _, s := range []string{
+		// invalid checksum
+		"age1s5s0qzvfxzn4gayt0hwtg0hhtgxm7wsdycup4a8t5j5ca25mfe4qt4hs7p",
+		// invalid prefix
+		"cosmos1w508d6qejxtdg4y5r3zarvary0c5xw7k6ah60c",
+		"age11qqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqq4q7sq9",
+		// 31 bytes key
+		"age1qqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqar9jk6",
+		"",
+	} {
+		_, _, err := AgeRecipientToKeyImportOpts(s)
+		assert.Error(t, err, s)
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// SignaturePEMType - PEM block type of armored signatures.
+const SignaturePEMType = "SIGNATURE"
+
+// DecodeSignature decodes armored signature into raw bytes accepted by Verify.
+// Supported armors are PEM block of type SIGNATURE, hex with optional 0x prefix
+// and standard or URL base64 with or without padding. Surrounding whitespace is
+// ignored. Strings with 0x prefix are always decoded as hex, strings which are
+// valid hex and base64 at the same time are decoded as hex.
+func DecodeSignature(s string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	if len(s) == 0 {
+		return nil, errors.New("Invalid signature. It must not be empty.")
+	}
+	if strings.HasPrefix(s, "-----BEGIN") {
+		block, rest := pem.Decode([]byte(s))
+		if block == nil {
+			return nil, errors.New("Failed decoding PEM signature. Block is malformed.")
+		}
+		if block.Type != SignaturePEMType {
+			return nil, fmt.Errorf("Invalid PEM block type [%s]. Expected [%s].", block.Type, SignaturePEMType)
+		}
+		if len(strings.TrimSpace(string(rest))) != 0 {
+			return nil, errors.New("Invalid PEM signature. Unexpected data after block.")
+		}
+		return nonEmptySignature(block.Bytes)
+	}
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		raw, err := hex.DecodeString(s[2:])
+		if err != nil {
+			return nil, fmt.Errorf("Failed decoding hex signature [%s]", err)
+		}
+		return nonEmptySignature(raw)
+	}
+	if raw, err := hex.DecodeString(s); err == nil {
+		return nonEmptySignature(raw)
+	}
+	for _, enc := range []*base64.Encoding{
+		base64.StdEncoding,
+		base64.RawStdEncoding,
+		base64.URLEncoding,
+		base64.RawURLEncoding,
+	} {
+		if raw, err := enc.DecodeString(s); err == nil {
+			return nonEmptySignature(raw)
+		}
+	}
+	return nil, errors.New("Unrecognized signature format. Expected PEM, hex or base64.")
+}
+
+// nonEmptySignature - Returns error if decoded signature is empty.
+func nonEmptySignature(raw []byte) ([]byte, error) {
+	if len(raw) == 0 {
+		return nil, errors.New("Invalid signature. It must not be empty.")
+	}
+	return raw, nil
+}
@@ -0,0 +1,72 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils_test
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"testing"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp/swcp"
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeSignature(t *testing.T) {
+	csp, err := swcp.NewDefaultSecurityLevelWithKeystore(swcp.NewDummyKeyStore())
+	assert.NoError(t, err)
+	k, err := csp.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	digest := sha256.Sum256([]byte("Hello World"))
+	signature, err := csp.Sign(k, digest[:], nil)
+	assert.NoError(t, err)
+
+	armored := []string{
+		string(pem.EncodeToMemory(&pem.Block{Type: utils.SignaturePEMType, Bytes: signature})),
+		hex.EncodeToString(signature),
+		"0x" + hex.EncodeToString(signature),
+		base64.StdEncoding.EncodeToString(signature),
+		base64.RawStdEncoding.EncodeToString(signature),
+		base64.URLEncoding.EncodeToString(signature),
+		base64.RawURLEncoding.EncodeToString(signature),
+		"\n  " + base64.StdEncoding.EncodeToString(signature) + "\n",
+	}
+	for _, s := range armored {
+		raw, err := utils.DecodeSignature(s)
+		assert.NoError(t, err, s)
+		assert.Equal(t, signature, raw)
+		valid, err := csp.Verify(k, raw, digest[:], nil)
+		assert.NoError(t, err)
+		assert.True(t, valid)
+	}
+
+	for _, s := range []string{
+		"",
+		"  \n",
+		"not a signature!",
+		"0xzz",
+		string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: signature})),
+		string(pem.EncodeToMemory(&pem.Block{Type: utils.SignaturePEMType})),
+		"-----BEGIN SIGNATURE-----\nAAAA",
+		string(pem.EncodeToMemory(&pem.Block{Type: utils.SignaturePEMType, Bytes: signature})) + "trailing",
+	} {
+		_, err := utils.DecodeSignature(s)
+		assert.Error(t, err, s)
+	}
+}
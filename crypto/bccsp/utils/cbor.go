@@ -0,0 +1,252 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// maxCBORDepth - Maximum nesting of decoded CBOR items.
+const maxCBORDepth = 8
+
+// CBOR major types.
+const (
+	cborUint   = 0
+	cborNegInt = 1
+	cborByte   = 2
+	cborText   = 3
+	cborArray  = 4
+	cborMap    = 5
+)
+
+func cborHead(buf []byte, major byte, n uint64) []byte {
+	major <<= 5
+	switch {
+	case n < 24:
+		return append(buf, major|byte(n))
+	case n <= 0xff:
+		return append(buf, major|24, byte(n))
+	case n <= 0xffff:
+		return append(buf, major|25, byte(n>>8), byte(n))
+	case n <= 0xffffffff:
+		return append(buf, major|26, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	}
+	buf = append(buf, major|27)
+	for i := 56; i >= 0; i -= 8 {
+		buf = append(buf, byte(n>>uint(i)))
+	}
+	return buf
+}
+
+func cborInt(buf []byte, v int64) []byte {
+	if v < 0 {
+		return cborHead(buf, cborNegInt, uint64(-1-v))
+	}
+	return cborHead(buf, cborUint, uint64(v))
+}
+
+func cborBytes(buf []byte, b []byte) []byte {
+	return append(cborHead(buf, cborByte, uint64(len(b))), b...)
+}
+
+// EncodeCBORMap encodes map with integer labels in canonical CBOR (RFC 7049
+// section 3.9): labels are sorted, lengths are definite and integers use the
+// shortest form. Supported values are int64, int, []byte and string.
+func EncodeCBORMap(m map[int64]interface{}) ([]byte, error) {
+	labels := make([]int64, 0, len(m))
+	for label := range m {
+		labels = append(labels, label)
+	}
+	// canonical order, shorter encoded labels first, then lexicographic
+	sort.Slice(labels, func(i, j int) bool {
+		a, b := cborInt(nil, labels[i]), cborInt(nil, labels[j])
+		if len(a) != len(b) {
+			return len(a) < len(b)
+		}
+		return bytes.Compare(a, b) < 0
+	})
+	buf := cborHead(nil, cborMap, uint64(len(m)))
+	for _, label := range labels {
+		buf = cborInt(buf, label)
+		switch v := m[label].(type) {
+		case int64:
+			buf = cborInt(buf, v)
+		case int:
+			buf = cborInt(buf, int64(v))
+		case []byte:
+			buf = cborBytes(buf, v)
+		case string:
+			buf = append(cborHead(buf, cborText, uint64(len(v))), v...)
+		default:
+			return nil, fmt.Errorf("unsupported value type %T of label %d", v, label)
+		}
+	}
+	return buf, nil
+}
+
+// DecodeCBORMap decodes CBOR map with integer labels. Entries with text labels
+// are skipped. Integers are decoded as int64, byte and text strings as []byte
+// and string, arrays as []interface{} and maps as map[int64]interface{}.
+// Indefinite length items are not supported.
+func DecodeCBORMap(data []byte) (map[int64]interface{}, error) {
+	return decodeCBORMap(&cborDecoder{data: data})
+}
+
+// DecodeCBORMapStrict decodes CBOR map with integer labels as DecodeCBORMap,
+// except that maps containing entries with text labels are rejected.
+func DecodeCBORMapStrict(data []byte) (map[int64]interface{}, error) {
+	return decodeCBORMap(&cborDecoder{data: data, strict: true})
+}
+
+func decodeCBORMap(d *cborDecoder) (map[int64]interface{}, error) {
+	v, err := d.decode(0)
+	if err != nil {
+		return nil, err
+	}
+	if len(d.data) != 0 {
+		return nil, errors.New("trailing data")
+	}
+	params, ok := v.(map[int64]interface{})
+	if !ok {
+		return nil, errors.New("expected map")
+	}
+	return params, nil
+}
+
+type cborDecoder struct {
+	data []byte
+	// strict rejects text labels instead of skipping them
+	strict bool
+}
+
+// cborIndefinite - Additional information of indefinite length items.
+const cborIndefinite = 31
+
+func (d *cborDecoder) head() (major byte, n uint64, err error) {
+	major, info, n, err := d.readHead()
+	if err != nil {
+		return 0, 0, err
+	}
+	if info == cborIndefinite {
+		return 0, 0, errors.New("indefinite length items are not supported")
+	}
+	return major, n, nil
+}
+
+// readHead - Reads head of data item and its additional information.
+// Argument of indefinite length strings, arrays and maps is zero.
+func (d *cborDecoder) readHead() (major, info byte, n uint64, err error) {
+	if len(d.data) == 0 {
+		return 0, 0, 0, errors.New("unexpected end of data")
+	}
+	major, info = d.data[0]>>5, d.data[0]&0x1f
+	d.data = d.data[1:]
+	switch {
+	case info < 24:
+		return major, info, uint64(info), nil
+	case info == cborIndefinite:
+		switch major {
+		case cborByte, cborText, cborArray, cborMap:
+			return major, info, 0, nil
+		}
+		return 0, 0, 0, fmt.Errorf("invalid indefinite length of major type %d", major)
+	case info > 27:
+		return 0, 0, 0, fmt.Errorf("reserved additional information %d", info)
+	}
+	size := 1 << (info - 24)
+	if len(d.data) < size {
+		return 0, 0, 0, errors.New("unexpected end of data")
+	}
+	for _, b := range d.data[:size] {
+		n = n<<8 | uint64(b)
+	}
+	d.data = d.data[size:]
+	return major, info, n, nil
+}
+
+func (d *cborDecoder) decode(depth int) (interface{}, error) {
+	if depth > maxCBORDepth {
+		return nil, errors.New("nesting is too deep")
+	}
+	major, n, err := d.head()
+	if err != nil {
+		return nil, err
+	}
+	switch major {
+	case cborUint, cborNegInt:
+		if n > 1<<63-1 {
+			return nil, errors.New("integer overflow")
+		}
+		if major == cborNegInt {
+			return -1 - int64(n), nil
+		}
+		return int64(n), nil
+	case cborByte, cborText:
+		if n > uint64(len(d.data)) {
+			return nil, errors.New("unexpected end of data")
+		}
+		b := d.data[:n]
+		d.data = d.data[n:]
+		if major == cborText {
+			return string(b), nil
+		}
+		return append([]byte{}, b...), nil
+	case cborArray:
+		if n > uint64(len(d.data)) {
+			return nil, errors.New("unexpected end of data")
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			if items[i], err = d.decode(depth + 1); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	case cborMap:
+		if n > uint64(len(d.data)) {
+			return nil, errors.New("unexpected end of data")
+		}
+		m := make(map[int64]interface{}, n)
+		for i := uint64(0); i < n; i++ {
+			label, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			value, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			switch l := label.(type) {
+			case int64:
+				if _, dup := m[l]; dup {
+					return nil, fmt.Errorf("duplicate label %d", l)
+				}
+				m[l] = value
+			case string:
+				if d.strict {
+					return nil, fmt.Errorf("unexpected text label %q", l)
+				}
+			default:
+				return nil, errors.New("invalid map label")
+			}
+		}
+		return m, nil
+	}
+	return nil, fmt.Errorf("unsupported major type %d", major)
+}
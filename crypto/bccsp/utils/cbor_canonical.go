@@ -0,0 +1,277 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// maxCanonicalCBORDepth - Maximum nesting of canonicalized CBOR items.
+const maxCanonicalCBORDepth = 32
+
+// CBOR major types of tags and simple values.
+const (
+	cborTag    = 6
+	cborSimple = 7
+)
+
+// cborBreak - Stop code of indefinite length items.
+const cborBreak = 0xff
+
+// CanonicalCBOR re-encodes single CBOR data item in canonical form of CTAP2
+// (FIDO Client to Authenticator Protocol), so that semantically equal items
+// encode to the same bytes:
+//
+//   - integers, lengths, tags and simple values use the shortest form,
+//   - indefinite length strings, arrays and maps are encoded with definite
+//     length, chunks of strings are concatenated,
+//   - map entries are sorted by encoded keys, by major type first, then
+//     shorter keys first, then lexicographically,
+//   - floating point values use the shortest of half, single and double
+//     precision which preserves the value.
+//
+// Maps with duplicate keys, malformed items and trailing data result in error.
+func CanonicalCBOR(data []byte) ([]byte, error) {
+	c := &cborCanonicalizer{cborDecoder{data: data}}
+	out, err := c.item(nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(c.data) != 0 {
+		return nil, errors.New("trailing data")
+	}
+	return out, nil
+}
+
+type cborCanonicalizer struct {
+	cborDecoder
+}
+
+// isBreak - Consumes stop code if it is next.
+func (c *cborCanonicalizer) isBreak() bool {
+	if len(c.data) != 0 && c.data[0] == cborBreak {
+		c.data = c.data[1:]
+		return true
+	}
+	return false
+}
+
+// item - Appends canonical encoding of next data item to buf.
+func (c *cborCanonicalizer) item(buf []byte, depth int) ([]byte, error) {
+	if depth > maxCanonicalCBORDepth {
+		return nil, errors.New("nesting is too deep")
+	}
+	major, info, n, err := c.readHead()
+	if err != nil {
+		return nil, err
+	}
+	indefinite := info == cborIndefinite
+	switch major {
+	case cborUint, cborNegInt, cborTag:
+		buf = cborHead(buf, major, n)
+		if major == cborTag {
+			return c.item(buf, depth+1)
+		}
+		return buf, nil
+	case cborByte, cborText:
+		b, err := c.str(major, n, indefinite)
+		if err != nil {
+			return nil, err
+		}
+		return append(cborHead(buf, major, uint64(len(b))), b...), nil
+	case cborArray:
+		var items []byte
+		count := uint64(0)
+		for ; indefinite || count < n; count++ {
+			if indefinite && c.isBreak() {
+				break
+			}
+			if items, err = c.item(items, depth+1); err != nil {
+				return nil, err
+			}
+		}
+		return append(cborHead(buf, cborArray, count), items...), nil
+	case cborMap:
+		return c.mapItem(buf, n, indefinite, depth)
+	}
+	return c.simple(buf, info, n)
+}
+
+// str - Reads contents of byte or text string,
+// chunks of indefinite length strings are concatenated.
+func (c *cborCanonicalizer) str(major byte, n uint64, indefinite bool) ([]byte, error) {
+	if !indefinite {
+		if n > uint64(len(c.data)) {
+			return nil, errors.New("unexpected end of data")
+		}
+		b := c.data[:n]
+		c.data = c.data[n:]
+		return b, nil
+	}
+	var b []byte
+	for !c.isBreak() {
+		chunkMajor, info, size, err := c.readHead()
+		if err != nil {
+			return nil, err
+		}
+		if chunkMajor != major || info == cborIndefinite {
+			return nil, errors.New("invalid chunk of indefinite length string")
+		}
+		chunk, err := c.str(major, size, false)
+		if err != nil {
+			return nil, err
+		}
+		b = append(b, chunk...)
+	}
+	return b, nil
+}
+
+// mapItem - Appends canonical encoding of map with n entries to buf.
+func (c *cborCanonicalizer) mapItem(buf []byte, n uint64, indefinite bool, depth int) ([]byte, error) {
+	type entry struct {
+		key, value []byte
+	}
+	var entries []entry
+	for i := uint64(0); indefinite || i < n; i++ {
+		if indefinite && c.isBreak() {
+			break
+		}
+		key, err := c.item(nil, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		value, err := c.item(nil, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry{key, value})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i].key, entries[j].key
+		if a[0]>>5 != b[0]>>5 {
+			return a[0]>>5 < b[0]>>5
+		}
+		if len(a) != len(b) {
+			return len(a) < len(b)
+		}
+		return bytes.Compare(a, b) < 0
+	})
+	buf = cborHead(buf, cborMap, uint64(len(entries)))
+	for i, e := range entries {
+		if i > 0 && bytes.Equal(entries[i-1].key, e.key) {
+			return nil, fmt.Errorf("duplicate map key %x", e.key)
+		}
+		buf = append(append(buf, e.key...), e.value...)
+	}
+	return buf, nil
+}
+
+// simple - Appends canonical encoding of simple value or float to buf.
+func (c *cborCanonicalizer) simple(buf []byte, info byte, n uint64) ([]byte, error) {
+	switch info {
+	case 24:
+		if n < 32 {
+			return nil, fmt.Errorf("invalid simple value %d", n)
+		}
+		return append(buf, cborSimple<<5|24, byte(n)), nil
+	case 25:
+		return appendCBORFloat(buf, halfToFloat64(uint16(n))), nil
+	case 26:
+		return appendCBORFloat(buf, float64(math.Float32frombits(uint32(n)))), nil
+	case 27:
+		return appendCBORFloat(buf, math.Float64frombits(n)), nil
+	}
+	return append(buf, cborSimple<<5|info), nil
+}
+
+// appendCBORFloat - Appends float in the shortest form preserving its value.
+// NaN is encoded as half precision quiet NaN.
+func appendCBORFloat(buf []byte, f float64) []byte {
+	if h, ok := float64ToHalf(f); ok {
+		return append(buf, cborSimple<<5|25, byte(h>>8), byte(h))
+	}
+	if f32 := float32(f); float64(f32) == f {
+		buf = append(buf, cborSimple<<5|26, 0, 0, 0, 0)
+		binary.BigEndian.PutUint32(buf[len(buf)-4:], math.Float32bits(f32))
+		return buf
+	}
+	buf = append(buf, cborSimple<<5|27, 0, 0, 0, 0, 0, 0, 0, 0)
+	binary.BigEndian.PutUint64(buf[len(buf)-8:], math.Float64bits(f))
+	return buf
+}
+
+// halfToFloat64 - Converts IEEE 754 half precision float.
+func halfToFloat64(h uint16) float64 {
+	exp, mant := int(h>>10&0x1f), float64(h&0x3ff)
+	var f float64
+	switch exp {
+	case 0:
+		f = math.Ldexp(mant, -24)
+	case 0x1f:
+		if mant != 0 {
+			return math.NaN()
+		}
+		f = math.Inf(1)
+	default:
+		f = math.Ldexp(mant+1024, exp-25)
+	}
+	if h&0x8000 != 0 {
+		return -f
+	}
+	return f
+}
+
+// float64ToHalf - Converts float to IEEE 754 half precision,
+// returns false if the value cannot be represented exactly.
+func float64ToHalf(f float64) (uint16, bool) {
+	if math.IsNaN(f) {
+		return 0x7e00, true
+	}
+	var sign uint16
+	if math.Signbit(f) {
+		sign = 0x8000
+	}
+	abs := math.Abs(f)
+	switch {
+	case abs == 0:
+		return sign, true
+	case math.IsInf(abs, 0):
+		return sign | 0x7c00, true
+	}
+	frac, exp := math.Frexp(abs) // abs = frac * 2^exp, frac in [0.5, 1)
+	if exp > 16 {
+		return 0, false
+	}
+	if exp >= -13 {
+		// normal: 1.m * 2^(exp-1), 10 bits of mantissa
+		m := frac * 2048
+		if m != math.Trunc(m) {
+			return 0, false
+		}
+		return sign | uint16(exp+14)<<10 | uint16(m)&0x3ff, true
+	}
+	// subnormal: m * 2^-24
+	m := math.Ldexp(abs, 24)
+	if m != math.Trunc(m) || m >= 1024 {
+		return 0, false
+	}
+	return sign | uint16(m), true
+}
@@ -0,0 +1,117 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanonicalCBOR(t *testing.T) {
+	for _, test := range []struct {
+		input, expected string
+	}{
+		// Shortest form integers and lengths
+		{"1817", "17"},
+		{"190001", "01"},
+		{"1a000003e8", "1903e8"},
+		{"3b0000000000000000", "20"},
+		{"5800", "40"},
+		{"780161", "6161"},
+		{"98020102", "820102"},
+		// Indefinite lengths (RFC 8949 Appendix A)
+		{"5f42010243030405ff", "450102030405"},
+		{"7f657374726561646d696e67ff", "6973747265616d696e67"},
+		{"9f018202039f0405ffff", "8301820203820405"},
+		{"9fff", "80"},
+		{"bf6346756ef563416d7421ff", "a263416d74216346756ef5"},
+		// Map keys sorted by major type, then length, then bytes
+		{"a3616202616101 0a03", "a30a03616101616202"},
+		{"a2820102f4 18ff f5", "a218fff5820102f4"},
+		{"a2616101 1903e8 02", "a21903e802616101"},
+		{"a220 01 1903e8 02", "a21903e8022001"},
+		// Tags and simple values
+		{"d8011a514b67b0", "c11a514b67b0"},
+		{"d9000100", "c100"},
+		{"f820", "f820"},
+		{"f4", "f4"},
+		// Floats in the shortest form preserving value
+		{"fb3ff0000000000000", "f93c00"},
+		{"fa3f800000", "f93c00"},
+		{"fb8000000000000000", "f98000"},
+		{"fb40effc0000000000", "f97bff"},
+		{"fb3e70000000000000", "f90001"},
+		{"fb3f10000000000000", "f90400"},
+		{"fb40f86a0000000000", "fa47c35000"},
+		{"fb3ff199999999999a", "fb3ff199999999999a"},
+		{"fb7ff0000000000000", "f97c00"},
+		{"fbfff0000000000000", "f9fc00"},
+		{"fb7ff8000000000000", "f97e00"},
+		{"fa7fc00000", "f97e00"},
+	} {
+		input, err := hex.DecodeString(removeSpaces(test.input))
+		assert.NoError(t, err)
+		out, err := CanonicalCBOR(input)
+		if assert.NoError(t, err, test.input) {
+			assert.Equal(t, test.expected, hex.EncodeToString(out), test.input)
+		}
+		// Canonical encoding is a fixed point
+		again, err := CanonicalCBOR(out)
+		assert.NoError(t, err)
+		assert.Equal(t, out, again)
+	}
+
+	for _, input := range []string{
+		"",
+		"0001",     // trailing data
+		"1901",     // truncated argument
+		"44010203", // truncated string
+		"8301",     // truncated array
+		"1c",       // reserved additional information
+		"ff",       // break outside indefinite length item
+		"3f",       // indefinite length integer
+		"5f6161ff", // chunk of different type
+		"5f5f4101ffff",
+		"f813",          // simple value in two bytes
+		"a2010101 02",   // duplicate keys
+		"a20101 180102", // duplicate keys after canonicalization
+	} {
+		data, err := hex.DecodeString(removeSpaces(input))
+		assert.NoError(t, err)
+		_, err = CanonicalCBOR(data)
+		assert.Error(t, err, input)
+	}
+
+	// Nesting is limited
+	deep := make([]byte, 100)
+	for i := range deep {
+		deep[i] = 0x81
+	}
+	_, err := CanonicalCBOR(append(deep, 0x00))
+	assert.Error(t, err)
+}
+
+func removeSpaces(s string) string {
+	out := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] != ' ' {
+			out = append(out, s[i])
+		}
+	}
+	return string(out)
+}
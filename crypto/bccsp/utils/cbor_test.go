@@ -0,0 +1,70 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncodeCBORMap(t *testing.T) {
+	m := map[int64]interface{}{
+		24:  int64(-500),
+		-1:  []byte{0x01, 0x02},
+		0:   "a",
+		-25: 1,
+		1:   int64(0x1234),
+	}
+	raw, err := EncodeCBORMap(m)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{
+		0xa5,
+		0x00, 0x61, 'a',
+		0x01, 0x19, 0x12, 0x34,
+		0x20, 0x42, 0x01, 0x02,
+		0x18, 0x18, 0x39, 0x01, 0xf3,
+		0x38, 0x18, 0x01,
+	}, raw)
+
+	decoded, err := DecodeCBORMap(raw)
+	assert.NoError(t, err)
+	assert.Equal(t, map[int64]interface{}{
+		24:  int64(-500),
+		-1:  []byte{0x01, 0x02},
+		0:   "a",
+		-25: int64(1),
+		1:   int64(0x1234),
+	}, decoded)
+
+	// Text labels are skipped, unless strict
+	withText := []byte{0xa2, 0x01, 0x02, 0x61, 'x', 0x03}
+	decoded, err = DecodeCBORMap(withText)
+	assert.NoError(t, err)
+	assert.Equal(t, map[int64]interface{}{1: int64(2)}, decoded)
+	_, err = DecodeCBORMapStrict(withText)
+	assert.EqualError(t, err, `unexpected text label "x"`)
+	decoded, err = DecodeCBORMapStrict(raw)
+	assert.NoError(t, err)
+	assert.Len(t, decoded, 5)
+
+	raw, err = EncodeCBORMap(map[int64]interface{}{})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0xa0}, raw)
+
+	_, err = EncodeCBORMap(map[int64]interface{}{1: 1.5})
+	assert.Error(t, err)
+}
@@ -0,0 +1,185 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+)
+
+// COSE_Key parameters and values from RFC 8152.
+const (
+	coseKeyType = 1
+	coseKeyAlg  = 3
+
+	coseKeyTypeEC2 = 2
+	coseKeyTypeRSA = 3
+
+	coseEC2Curve = -1
+	coseEC2X     = -2
+	coseEC2Y     = -3
+
+	coseRSAN = -1
+	coseRSAE = -2
+
+	coseCurveP256 = 1
+	coseCurveP384 = 2
+	coseCurveP521 = 3
+
+	coseAlgES256 = -7
+	coseAlgES384 = -35
+	coseAlgES512 = -36
+)
+
+// COSEKeyToKeyImportOpts decodes CBOR encoded COSE_Key (RFC 8152) as used
+// by FIDO2 and WebAuthn. It returns public key with options to import it
+// into BCCSP. EC2 keys on P-256, P-384 and P-521 curves and RSA keys are
+// supported. Parameters other than key type and key material are ignored.
+func COSEKeyToKeyImportOpts(cbor []byte) (interface{}, bccsp.KeyImportOpts, error) {
+	params, err := DecodeCBORMap(cbor)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed decoding COSE key [%s]", err)
+	}
+	kty, ok := params[coseKeyType].(int64)
+	if !ok {
+		return nil, nil, errors.New("Invalid COSE key. Key type is missing.")
+	}
+	switch kty {
+	case coseKeyTypeEC2:
+		pub, err := coseToECDSA(params)
+		if err != nil {
+			return nil, nil, err
+		}
+		return pub, &bccsp.ECDSAGoPublicKeyImportOpts{}, nil
+	case coseKeyTypeRSA:
+		pub, err := coseToRSA(params)
+		if err != nil {
+			return nil, nil, err
+		}
+		return pub, &bccsp.RSAGoPublicKeyImportOpts{}, nil
+	default:
+		return nil, nil, fmt.Errorf("Unsupported COSE key type %d", kty)
+	}
+}
+
+// PublicKeyToCOSEKey encodes public part of ECDSA or RSA key as canonical
+// CBOR COSE_Key. Algorithm of EC2 keys is set to ECDSA with hash matching
+// the curve, it is omitted for RSA keys, which may be used with either
+// PKCS #1 v1.5 or PSS padding.
+func PublicKeyToCOSEKey(key bccsp.Key) ([]byte, error) {
+	if key == nil {
+		return nil, errors.New("Invalid key. It must not be nil.")
+	}
+	pk, err := key.PublicKey()
+	if err != nil {
+		return nil, fmt.Errorf("Failed getting public key [%s]", err)
+	}
+	raw, err := pk.Bytes()
+	if err != nil {
+		return nil, fmt.Errorf("Failed marshalling public key [%s]", err)
+	}
+	pub, err := DERToPublicKey(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf []byte
+	switch k := pub.(type) {
+	case *ecdsa.PublicKey:
+		var crv, alg int64
+		switch k.Curve {
+		case elliptic.P256():
+			crv, alg = coseCurveP256, coseAlgES256
+		case elliptic.P384():
+			crv, alg = coseCurveP384, coseAlgES384
+		case elliptic.P521():
+			crv, alg = coseCurveP521, coseAlgES512
+		default:
+			return nil, fmt.Errorf("Unsupported curve [%s]", k.Curve.Params().Name)
+		}
+		size := (k.Curve.Params().BitSize + 7) / 8
+		buf = cborHead(buf, cborMap, 5)
+		buf = cborInt(cborInt(buf, coseKeyType), coseKeyTypeEC2)
+		buf = cborInt(cborInt(buf, coseKeyAlg), alg)
+		buf = cborInt(cborInt(buf, coseEC2Curve), crv)
+		buf = cborBytes(cborInt(buf, coseEC2X), padBytes(k.X.Bytes(), size))
+		buf = cborBytes(cborInt(buf, coseEC2Y), padBytes(k.Y.Bytes(), size))
+	case *rsa.PublicKey:
+		buf = cborHead(buf, cborMap, 3)
+		buf = cborInt(cborInt(buf, coseKeyType), coseKeyTypeRSA)
+		buf = cborBytes(cborInt(buf, coseRSAN), k.N.Bytes())
+		buf = cborBytes(cborInt(buf, coseRSAE), big.NewInt(int64(k.E)).Bytes())
+	default:
+		return nil, fmt.Errorf("Unsupported public key type [%T]", pub)
+	}
+	return buf, nil
+}
+
+func coseToECDSA(params map[int64]interface{}) (*ecdsa.PublicKey, error) {
+	crv, _ := params[coseEC2Curve].(int64)
+	var curve elliptic.Curve
+	switch crv {
+	case coseCurveP256:
+		curve = elliptic.P256()
+	case coseCurveP384:
+		curve = elliptic.P384()
+	case coseCurveP521:
+		curve = elliptic.P521()
+	default:
+		return nil, fmt.Errorf("Unsupported COSE curve %d", crv)
+	}
+	size := (curve.Params().BitSize + 7) / 8
+	x, okX := params[coseEC2X].([]byte)
+	y, okY := params[coseEC2Y].([]byte)
+	if !okX || !okY || len(x) != size || len(y) != size {
+		return nil, errors.New("Invalid COSE key. Coordinates are missing or malformed.")
+	}
+	pub := &ecdsa.PublicKey{
+		Curve: curve,
+		X:     new(big.Int).SetBytes(x),
+		Y:     new(big.Int).SetBytes(y),
+	}
+	if !curve.IsOnCurve(pub.X, pub.Y) {
+		return nil, errors.New("Invalid COSE key. Point is not on curve.")
+	}
+	return pub, nil
+}
+
+func coseToRSA(params map[int64]interface{}) (*rsa.PublicKey, error) {
+	n, okN := params[coseRSAN].([]byte)
+	e, okE := params[coseRSAE].([]byte)
+	if !okN || !okE || len(n) == 0 || len(e) == 0 || len(e) > 4 {
+		return nil, errors.New("Invalid COSE key. Modulus or exponent is missing or malformed.")
+	}
+	exp := new(big.Int).SetBytes(e)
+	if exp.Int64() < 3 || exp.Int64() > 1<<31-1 {
+		return nil, errors.New("Invalid COSE key. Exponent is out of range.")
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exp.Int64())}, nil
+}
+
+func padBytes(b []byte, size int) []byte {
+	if len(b) >= size {
+		return b
+	}
+	return append(make([]byte, size-len(b)), b...)
+}
@@ -0,0 +1,124 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp/swcp"
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCOSEKey(t *testing.T) {
+	csp, err := swcp.NewDefaultSecurityLevelWithKeystore(swcp.NewDummyKeyStore())
+	assert.NoError(t, err)
+
+	k, err := csp.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	pk, err := k.PublicKey()
+	assert.NoError(t, err)
+	raw, err := pk.Bytes()
+	assert.NoError(t, err)
+	goPub, err := utils.DERToPublicKey(raw)
+	assert.NoError(t, err)
+	ecPub := goPub.(*ecdsa.PublicKey)
+
+	// WebAuthn-style credential public key: {1: 2, 3: -7, -1: 1, -2: x, -3: y}
+	webauthn := []byte{0xa5, 0x01, 0x02, 0x03, 0x26, 0x20, 0x01, 0x21, 0x58, 0x20}
+	webauthn = append(webauthn, pad32(ecPub.X.Bytes())...)
+	webauthn = append(webauthn, 0x22, 0x58, 0x20)
+	webauthn = append(webauthn, pad32(ecPub.Y.Bytes())...)
+
+	encoded, err := utils.PublicKeyToCOSEKey(k)
+	assert.NoError(t, err)
+	assert.Equal(t, webauthn, encoded)
+
+	key, opts, err := utils.COSEKeyToKeyImportOpts(webauthn)
+	assert.NoError(t, err)
+	assert.IsType(t, &bccsp.ECDSAGoPublicKeyImportOpts{}, opts)
+	imported, err := csp.KeyImport(key, &bccsp.ECDSAGoPublicKeyImportOpts{Temporary: true})
+	assert.NoError(t, err)
+	assert.Equal(t, pk.SKI(), imported.SKI())
+
+	digest := sha256.Sum256([]byte("Hello World"))
+	signature, err := csp.Sign(k, digest[:], nil)
+	assert.NoError(t, err)
+	valid, err := csp.Verify(imported, signature, digest[:], nil)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+
+	// Text labels are ignored
+	withText := append([]byte{0xa6, 0x63, 'f', 'o', 'o', 0x01}, webauthn[1:]...)
+	key, _, err = utils.COSEKeyToKeyImportOpts(withText)
+	assert.NoError(t, err)
+	assert.Equal(t, ecPub.X, key.(*ecdsa.PublicKey).X)
+
+	// RSA round trip
+	rsaKey, err := csp.KeyGen(&bccsp.RSA2048KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	encoded, err = utils.PublicKeyToCOSEKey(rsaKey)
+	assert.NoError(t, err)
+	key, opts, err = utils.COSEKeyToKeyImportOpts(encoded)
+	assert.NoError(t, err)
+	assert.IsType(t, &bccsp.RSAGoPublicKeyImportOpts{}, opts)
+	assert.Equal(t, 65537, key.(*rsa.PublicKey).E)
+	imported, err = csp.KeyImport(key, &bccsp.RSAGoPublicKeyImportOpts{Temporary: true})
+	assert.NoError(t, err)
+	rsaPub, err := rsaKey.PublicKey()
+	assert.NoError(t, err)
+	assert.Equal(t, rsaPub.SKI(), imported.SKI())
+
+	// P-384 round trip
+	k, err = csp.KeyGen(&bccsp.ECDSAP384KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	encoded, err = utils.PublicKeyToCOSEKey(k)
+	assert.NoError(t, err)
+	key, _, err = utils.COSEKeyToKeyImportOpts(encoded)
+	assert.NoError(t, err)
+	assert.Equal(t, elliptic.P384(), key.(*ecdsa.PublicKey).Curve)
+
+	// Malformed keys
+	offCurve := append([]byte{}, webauthn...)
+	offCurve[len(offCurve)-1] ^= 1
+	for _, data := range [][]byte{
+		nil,
+		{0xa0},
+		{0xa1, 0x01, 0x04},
+		webauthn[:len(webauthn)-1],
+		append(append([]byte{}, webauthn...), 0x00),
+		offCurve,
+		{0xbf, 0xff},
+		{0x82, 0x01, 0x02},
+		{0xa2, 0x01, 0x02, 0x01, 0x02},
+		{0xa1, 0x01, 0x9a, 0xff, 0xff, 0xff, 0xff},
+	} {
+		_, _, err := utils.COSEKeyToKeyImportOpts(data)
+		assert.Error(t, err, "%x", data)
+	}
+
+	_, err = utils.PublicKeyToCOSEKey(nil)
+	assert.Error(t, err)
+}
+
+func pad32(b []byte) []byte {
+	return append(make([]byte, 32-len(b)), b...)
+}
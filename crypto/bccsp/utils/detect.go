@@ -0,0 +1,95 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+)
+
+// KeyType - Format of encoded key material, see DetectKeyType.
+type KeyType int
+
+const (
+	// KeyTypeUnknown - Unrecognized key material.
+	KeyTypeUnknown KeyType = iota
+	// KeyTypeCertificate - X.509 certificate.
+	KeyTypeCertificate
+	// KeyTypePublicKey - PKIX public key.
+	KeyTypePublicKey
+	// KeyTypePrivateKey - PKCS#1, PKCS#8 or SEC 1 private key.
+	KeyTypePrivateKey
+	// KeyTypeJWK - JSON Web Key.
+	KeyTypeJWK
+)
+
+// String - Returns key type label.
+func (t KeyType) String() string {
+	switch t {
+	case KeyTypeCertificate:
+		return "certificate"
+	case KeyTypePublicKey:
+		return "public key"
+	case KeyTypePrivateKey:
+		return "private key"
+	case KeyTypeJWK:
+		return "jwk"
+	}
+	return "unknown"
+}
+
+// DetectKeyType - Detects format of key material encoded as DER, PEM or JWK.
+//
+// DER of the key is returned along with its type, PEM is decoded from the
+// first block, JWK is returned unchanged. Format is detected by parsing the
+// material, not by PEM block type, which is frequently mislabeled.
+// Encrypted PEM and unrecognized material are rejected with an error.
+func DetectKeyType(data []byte) (KeyType, []byte, error) {
+	// Whitespace is trimmed only from text, it is valid ending of DER
+	text := bytes.TrimSpace(data)
+	if len(text) == 0 {
+		return KeyTypeUnknown, nil, errors.New("Invalid key material. It must not be empty.")
+	}
+	if text[0] == '{' {
+		var jwk struct {
+			Kty string `json:"kty"`
+		}
+		if err := json.Unmarshal(text, &jwk); err != nil || jwk.Kty == "" {
+			return KeyTypeUnknown, nil, errors.New("Unrecognized key material. JSON is not a JWK.")
+		}
+		return KeyTypeJWK, text, nil
+	}
+	der := data
+	if block, _ := pem.Decode(text); block != nil {
+		if x509.IsEncryptedPEMBlock(block) {
+			return KeyTypeUnknown, nil, errors.New("Encrypted PEM is not supported.")
+		}
+		der = block.Bytes
+	}
+	if _, err := x509.ParseCertificate(der); err == nil {
+		return KeyTypeCertificate, der, nil
+	}
+	if _, err := x509.ParsePKIXPublicKey(der); err == nil {
+		return KeyTypePublicKey, der, nil
+	}
+	if _, err := DERToPrivateKey(der); err == nil {
+		return KeyTypePrivateKey, der, nil
+	}
+	return KeyTypeUnknown, nil, errors.New("Unrecognized key material. Expected certificate, public or private key as DER or PEM, or JWK.")
+}
@@ -0,0 +1,94 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectKeyType(t *testing.T) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	pub, err := x509.MarshalPKIXPublicKey(&priv.PublicKey)
+	assert.NoError(t, err)
+	sec1, err := x509.MarshalECPrivateKey(priv)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "detect"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, &priv.PublicKey, priv)
+	assert.NoError(t, err)
+
+	for _, tc := range []struct {
+		data     []byte
+		expected KeyType
+		der      []byte
+	}{
+		{cert, KeyTypeCertificate, cert},
+		{pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert}), KeyTypeCertificate, cert},
+		{pub, KeyTypePublicKey, pub},
+		{pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pub}), KeyTypePublicKey, pub},
+		{sec1, KeyTypePrivateKey, sec1},
+		// Mislabeled PEM block is detected by its contents
+		{pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: sec1}), KeyTypePrivateKey, sec1},
+		{[]byte(` {"kty":"EC"}` + "\n"), KeyTypeJWK, []byte(`{"kty":"EC"}`)},
+	} {
+		kind, der, err := DetectKeyType(tc.data)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.expected, kind, tc.expected.String())
+		assert.Equal(t, tc.der, der)
+	}
+
+	// DER ending with whitespace byte is not trimmed
+	for i := 0; i < 4096; i++ {
+		priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		assert.NoError(t, err)
+		pub, err := x509.MarshalPKIXPublicKey(&priv.PublicKey)
+		assert.NoError(t, err)
+		if pub[len(pub)-1] != '\n' && pub[len(pub)-1] != ' ' {
+			continue
+		}
+		kind, der, err := DetectKeyType(pub)
+		assert.NoError(t, err)
+		assert.Equal(t, KeyTypePublicKey, kind)
+		assert.Equal(t, pub, der)
+		break
+	}
+
+	_, _, err = DetectKeyType(nil)
+	assert.EqualError(t, err, "Invalid key material. It must not be empty.")
+	_, _, err = DetectKeyType([]byte(`{"key":"value"}`))
+	assert.EqualError(t, err, "Unrecognized key material. JSON is not a JWK.")
+	_, _, err = DetectKeyType([]byte("garbage"))
+	assert.EqualError(t, err, "Unrecognized key material. Expected certificate, public or private key as DER or PEM, or JWK.")
+	block, err := x509.EncryptPEMBlock(rand.Reader, "EC PRIVATE KEY", sec1, []byte("pwd"), x509.PEMCipherAES256)
+	assert.NoError(t, err)
+	_, _, err = DetectKeyType(pem.EncodeToMemory(block))
+	assert.EqualError(t, err, "Encrypted PEM is not supported.")
+}
@@ -16,37 +16,156 @@
 package utils
 
 import (
+	"bytes"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"encoding/asn1"
 	"errors"
 	"fmt"
 	"math/big"
+	"sync"
 )
 
 type ECDSASignature struct {
 	R, S *big.Int
 }
 
-var (
-	// curveHalfOrders contains the precomputed curve group orders halved.
-	// It is used to ensure that signature' S value is lower or equal to the
-	// curve group order halved. We accept only low-S signatures.
-	// They are precomputed for efficiency reasons.
-	curveHalfOrders = map[elliptic.Curve]*big.Int{
-		elliptic.P224(): new(big.Int).Rsh(elliptic.P224().Params().N, 1),
-		elliptic.P256(): new(big.Int).Rsh(elliptic.P256().Params().N, 1),
-		elliptic.P384(): new(big.Int).Rsh(elliptic.P384().Params().N, 1),
-		elliptic.P521(): new(big.Int).Rsh(elliptic.P521().Params().N, 1),
+// supportedCurves are curves fully supported by the package. Their half
+// orders are precomputed and low-S is enforced on them by default.
+var supportedCurves = []elliptic.Curve{
+	elliptic.P224(),
+	elliptic.P256(),
+	elliptic.P384(),
+	elliptic.P521(),
+}
+
+// SupportedCurves returns curves fully supported by the package, which have
+// precomputed half orders and low-S enforced by default. Half orders of other
+// curves are computed on first use, see CurveHalfOrder.
+func SupportedCurves() []elliptic.Curve {
+	return append([]elliptic.Curve{}, supportedCurves...)
+}
+
+// curveHalfOrders caches the curve group orders halved.
+// It is used to ensure that signature' S value is lower or equal to the
+// curve group order halved. We accept only low-S signatures.
+// They are cached for efficiency reasons.
+var curveHalfOrders = struct {
+	sync.RWMutex
+	orders map[elliptic.Curve]*big.Int
+}{
+	orders: func() map[elliptic.Curve]*big.Int {
+		orders := make(map[elliptic.Curve]*big.Int, len(supportedCurves))
+		for _, curve := range supportedCurves {
+			orders[curve] = new(big.Int).Rsh(curve.Params().N, 1)
+		}
+		return orders
+	}(),
+}
+
+// lowSPolicy contains per-curve low-S enforcement policy applied on
+// signature verification. Curves absent from the table are enforced.
+var lowSPolicy = struct {
+	sync.RWMutex
+	curves map[elliptic.Curve]bool
+}{
+	curves: func() map[elliptic.Curve]bool {
+		curves := make(map[elliptic.Curve]bool, len(supportedCurves))
+		for _, curve := range supportedCurves {
+			curves[curve] = true
+		}
+		return curves
+	}(),
+}
+
+// SetLowSPolicy sets whether signatures over the curve must be low-S to verify.
+// It should be configured before signatures are verified.
+func SetLowSPolicy(curve elliptic.Curve, enforce bool) {
+	lowSPolicy.Lock()
+	defer lowSPolicy.Unlock()
+	lowSPolicy.curves[curve] = enforce
+}
+
+// LowSEnforced returns true if signatures over the curve must be low-S to verify.
+func LowSEnforced(curve elliptic.Curve) bool {
+	lowSPolicy.RLock()
+	defer lowSPolicy.RUnlock()
+	enforce, ok := lowSPolicy.curves[curve]
+	return !ok || enforce
+}
+
+// CurveHalfOrder returns the group order of the curve halved.
+// It is computed on first use and cached.
+func CurveHalfOrder(curve elliptic.Curve) (*big.Int, error) {
+	if curve == nil {
+		return nil, errors.New("curve must be different from nil")
 	}
-)
+	curveHalfOrders.RLock()
+	halfOrder, ok := curveHalfOrders.orders[curve]
+	curveHalfOrders.RUnlock()
+	if ok {
+		return new(big.Int).Set(halfOrder), nil
+	}
+
+	params := curve.Params()
+	if params == nil || params.N == nil || params.N.Cmp(big.NewInt(2)) < 0 {
+		return nil, fmt.Errorf("curve has invalid order [%s]", curve)
+	}
+	halfOrder = new(big.Int).Rsh(params.N, 1)
+
+	curveHalfOrders.Lock()
+	curveHalfOrders.orders[curve] = halfOrder
+	curveHalfOrders.Unlock()
+	return new(big.Int).Set(halfOrder), nil
+}
 
+// GetCurveHalfOrdersAt returns the group order of the curve halved,
+// or nil if the curve is invalid. See CurveHalfOrder.
 func GetCurveHalfOrdersAt(c elliptic.Curve) *big.Int {
-	return big.NewInt(0).Set(curveHalfOrders[c])
+	halfOrder, _ := CurveHalfOrder(c)
+	return halfOrder
 }
 
 func MarshalECDSASignature(r, s *big.Int) ([]byte, error) {
-	return asn1.Marshal(ECDSASignature{r, s})
+	if r == nil || s == nil || r.Sign() < 0 || s.Sign() < 0 {
+		return asn1.Marshal(ECDSASignature{r, s})
+	}
+	rLen, sLen := derIntegerLength(r), derIntegerLength(s)
+	body := 1 + derLengthSize(rLen) + rLen + 1 + derLengthSize(sLen) + sLen
+	raw := make([]byte, 0, 1+derLengthSize(body)+body)
+	raw = appendDERLength(append(raw, 0x30), body)
+	raw = appendDERInteger(raw, r)
+	return appendDERInteger(raw, s), nil
+}
+
+// appendDERInteger - Appends DER encoding of non-negative integer.
+func appendDERInteger(b []byte, n *big.Int) []byte {
+	length := derIntegerLength(n)
+	b = appendDERLength(append(b, 0x02), length)
+	start := len(b)
+	for i := 0; i < length; i++ {
+		b = append(b, 0)
+	}
+	if size := (n.BitLen() + 7) / 8; size > 0 {
+		n.FillBytes(b[start+length-size:])
+	}
+	return b
+}
+
+// appendDERLength - Appends DER encoding of length.
+func appendDERLength(b []byte, length int) []byte {
+	if length < 0x80 {
+		return append(b, byte(length))
+	}
+	n := 0
+	for l := length; l > 0; l >>= 8 {
+		n++
+	}
+	b = append(b, 0x80|byte(n))
+	for i := n - 1; i >= 0; i-- {
+		b = append(b, byte(length>>uint(8*i)))
+	}
+	return b
 }
 
 func UnmarshalECDSASignature(raw []byte) (*big.Int, *big.Int, error) {
@@ -75,6 +194,92 @@ func UnmarshalECDSASignature(raw []byte) (*big.Int, *big.Int, error) {
 	return sig.R, sig.S, nil
 }
 
+// UnmarshalECDSASignatureLax unmarshals a DER encoded ECDSA signature
+// produced by legacy encoders which do not follow the canonical encoding.
+// UnmarshalECDSASignature remains the strict default.
+//
+// The following deviations from DER are tolerated:
+//   - lengths encoded in long form when short form would suffice
+//     (up to 4 length octets),
+//   - INTEGERs with redundant leading zero octets,
+//   - INTEGERs with the most significant bit set and no leading zero
+//     octet, which are interpreted as unsigned big-endian values.
+//
+// Everything else is rejected: tags other than SEQUENCE and INTEGER,
+// indefinite lengths, lengths exceeding the input, empty INTEGERs and
+// any data trailing the INTEGERs or the SEQUENCE.
+// As in the strict parser, R and S must be larger than zero.
+func UnmarshalECDSASignatureLax(raw []byte) (*big.Int, *big.Int, error) {
+	body, rest, err := laxDERElement(raw, 0x30)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed unmashalling signature [%s]", err)
+	}
+	if len(rest) != 0 {
+		return nil, nil, errors.New("failed unmashalling signature [trailing data after sequence]")
+	}
+
+	rb, body, err := laxDERElement(body, 0x02)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed unmashalling signature R [%s]", err)
+	}
+	sb, body, err := laxDERElement(body, 0x02)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed unmashalling signature S [%s]", err)
+	}
+	if len(body) != 0 {
+		return nil, nil, errors.New("failed unmashalling signature [trailing data in sequence]")
+	}
+	if len(rb) == 0 || len(sb) == 0 {
+		return nil, nil, errors.New("invalid signature, empty integer")
+	}
+
+	r := new(big.Int).SetBytes(rb)
+	s := new(big.Int).SetBytes(sb)
+	if r.Sign() != 1 {
+		return nil, nil, errors.New("invalid signature, R must be larger than zero")
+	}
+	if s.Sign() != 1 {
+		return nil, nil, errors.New("invalid signature, S must be larger than zero")
+	}
+
+	return r, s, nil
+}
+
+// laxDERElement reads a single TLV element with expected tag from raw.
+// It returns element contents and the remaining bytes.
+func laxDERElement(raw []byte, tag byte) (body, rest []byte, err error) {
+	if len(raw) < 2 {
+		return nil, nil, errors.New("truncated element")
+	}
+	if raw[0] != tag {
+		return nil, nil, fmt.Errorf("unexpected tag 0x%02x, expected 0x%02x", raw[0], tag)
+	}
+
+	length, offset := int(raw[1]), 2
+	if length&0x80 != 0 {
+		n := length & 0x7f
+		if n == 0 {
+			return nil, nil, errors.New("indefinite length not supported")
+		}
+		if n > 4 {
+			return nil, nil, errors.New("length too large")
+		}
+		if len(raw) < offset+n {
+			return nil, nil, errors.New("truncated length")
+		}
+		length = 0
+		for _, b := range raw[offset : offset+n] {
+			length = length<<8 | int(b)
+		}
+		offset += n
+	}
+	if length < 0 || len(raw)-offset < length {
+		return nil, nil, errors.New("length exceeds input")
+	}
+
+	return raw[offset : offset+length], raw[offset+length:], nil
+}
+
 func SignatureToLowS(k *ecdsa.PublicKey, signature []byte) ([]byte, error) {
 	r, s, err := UnmarshalECDSASignature(signature)
 	if err != nil {
@@ -95,15 +300,29 @@ func SignatureToLowS(k *ecdsa.PublicKey, signature []byte) ([]byte, error) {
 
 // IsLow checks that s is a low-S
 func IsLowS(k *ecdsa.PublicKey, s *big.Int) (bool, error) {
-	halfOrder, ok := curveHalfOrders[k.Curve]
-	if !ok {
-		return false, fmt.Errorf("curve not recognized [%s]", k.Curve)
+	halfOrder, err := CurveHalfOrder(k.Curve)
+	if err != nil {
+		return false, err
 	}
 
 	return s.Cmp(halfOrder) != 1, nil
 
 }
 
+// IsLowSSignature checks that DER encoded signature over the curve is low-S
+// without verifying it. It allows rejecting malleable signatures early.
+func IsLowSSignature(sig []byte, curve elliptic.Curve) (bool, error) {
+	halfOrder, err := CurveHalfOrder(curve)
+	if err != nil {
+		return false, err
+	}
+	_, s, err := UnmarshalECDSASignature(sig)
+	if err != nil {
+		return false, err
+	}
+	return s.Cmp(halfOrder) != 1, nil
+}
+
 func ToLowS(k *ecdsa.PublicKey, s *big.Int) (*big.Int, bool, error) {
 	lowS, err := IsLowS(k, s)
 	if err != nil {
@@ -120,3 +339,99 @@ func ToLowS(k *ecdsa.PublicKey, s *big.Int) (*big.Int, bool, error) {
 
 	return s, false, nil
 }
+
+// CompressECDSAPublicKey encodes ECDSA public key as SEC1 compressed point.
+func CompressECDSAPublicKey(pub *ecdsa.PublicKey) []byte {
+	size := (pub.Params().BitSize + 7) / 8
+	raw := make([]byte, 1+size)
+	raw[0] = 0x02 | byte(pub.Y.Bit(0))
+	x := pub.X.Bytes()
+	copy(raw[1+size-len(x):], x)
+	return raw
+}
+
+// UnmarshalECDSAPoint decodes ECDSA public key on the curve from SEC1
+// compressed or uncompressed point. Compressed points are supported on
+// curves of the form y^2 = x^3 - 3x + b, such as NIST curves, and
+// y^2 = x^3 + b, such as secp256k1.
+func UnmarshalECDSAPoint(curve elliptic.Curve, point []byte) (*ecdsa.PublicKey, error) {
+	if curve == nil {
+		return nil, errors.New("curve must be different from nil")
+	}
+	params := curve.Params()
+	size := (params.BitSize + 7) / 8
+	switch {
+	case len(point) == 1+2*size && point[0] == 0x04:
+		x := new(big.Int).SetBytes(point[1 : 1+size])
+		y := new(big.Int).SetBytes(point[1+size:])
+		if x.Cmp(params.P) >= 0 || y.Cmp(params.P) >= 0 || !curve.IsOnCurve(x, y) {
+			return nil, fmt.Errorf("point is not on curve [%s]", params.Name)
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case len(point) == 1+size && (point[0] == 0x02 || point[0] == 0x03):
+		x := new(big.Int).SetBytes(point[1:])
+		if x.Cmp(params.P) >= 0 {
+			return nil, fmt.Errorf("point is not on curve [%s]", params.Name)
+		}
+		// y^2 = x^3 + b with a of 0 or -3
+		x3b := new(big.Int).Mul(x, x)
+		x3b.Mul(x3b, x)
+		x3b.Add(x3b, params.B)
+		for _, a := range []int64{-3, 0} {
+			y2 := new(big.Int).Mul(x, big.NewInt(a))
+			y2.Add(y2, x3b)
+			y2.Mod(y2, params.P)
+			y := new(big.Int).ModSqrt(y2, params.P)
+			if y == nil {
+				continue
+			}
+			if y.Bit(0) != uint(point[0]&1) {
+				y.Sub(params.P, y)
+			}
+			if curve.IsOnCurve(x, y) {
+				return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+			}
+		}
+		return nil, fmt.Errorf("point is not on curve [%s]", params.Name)
+	default:
+		return nil, fmt.Errorf("invalid point encoding of length %d for curve [%s]", len(point), params.Name)
+	}
+}
+
+// ECDSAKeyFromScalar builds ECDSA private key on the curve from scalar d.
+// Scalar must satisfy 1 <= d < n, where n is the order of the curve.
+// It allows reproducing keys of published test vectors.
+func ECDSAKeyFromScalar(curve elliptic.Curve, d *big.Int) (*ecdsa.PrivateKey, error) {
+	if curve == nil {
+		return nil, errors.New("curve must be different from nil")
+	}
+	if d == nil {
+		return nil, errors.New("scalar must be different from nil")
+	}
+	params := curve.Params()
+	if d.Sign() <= 0 || d.Cmp(params.N) >= 0 {
+		return nil, fmt.Errorf("scalar out of range [1, n) of curve [%s]", params.Name)
+	}
+	scalar := make([]byte, (params.N.BitLen()+7)/8)
+	d.FillBytes(scalar)
+	x, y := curve.ScalarBaseMult(scalar)
+	return &ecdsa.PrivateKey{
+		PublicKey: ecdsa.PublicKey{Curve: curve, X: x, Y: y},
+		D:         new(big.Int).Set(d),
+	}, nil
+}
+
+// ECDHKeyInfo returns context information binding key derived from ECDH
+// shared secret to public keys of both parties. Points are encoded
+// uncompressed and sorted, so that both parties compute the same info.
+func ECDHKeyInfo(a, b *ecdsa.PublicKey) []byte {
+	pa := elliptic.Marshal(a.Curve, a.X, a.Y)
+	pb := elliptic.Marshal(b.Curve, b.X, b.Y)
+	if bytes.Compare(pa, pb) > 0 {
+		pa, pb = pb, pa
+	}
+	info := make([]byte, 0, len("ECDH")+len(pa)+len(pb))
+	info = append(info, "ECDH"...)
+	info = append(info, pa...)
+	return append(info, pb...)
+}
@@ -19,6 +19,7 @@ import (
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
+	"encoding/asn1"
 	"math/big"
 	"testing"
 
@@ -97,6 +98,17 @@ func TestIsLowS(t *testing.T) {
 	assert.True(t, lowS)
 }
 
+func TestLowSPolicy(t *testing.T) {
+	for _, curve := range []elliptic.Curve{elliptic.P224(), elliptic.P256(), elliptic.P384(), elliptic.P521()} {
+		assert.True(t, LowSEnforced(curve))
+	}
+
+	SetLowSPolicy(elliptic.P224(), false)
+	defer SetLowSPolicy(elliptic.P224(), true)
+	assert.False(t, LowSEnforced(elliptic.P224()))
+	assert.True(t, LowSEnforced(elliptic.P256()))
+}
+
 func TestSignatureToLowS(t *testing.T) {
 	lowLevelKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
 	assert.NoError(t, err)
@@ -118,3 +130,280 @@ func TestSignatureToLowS(t *testing.T) {
 	assert.NoError(t, err)
 	assert.True(t, lowS)
 }
+
+func TestCurveHalfOrder(t *testing.T) {
+	for _, curve := range []elliptic.Curve{elliptic.P256(), elliptic.P384(), elliptic.P521()} {
+		expected := new(big.Int).Rsh(curve.Params().N, 1)
+		halfOrder, err := CurveHalfOrder(curve)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, halfOrder, curve.Params().Name)
+		assert.Equal(t, expected, GetCurveHalfOrdersAt(curve))
+
+		// cached value cannot be modified by callers
+		halfOrder.SetInt64(0)
+		halfOrder, err = CurveHalfOrder(curve)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, halfOrder)
+	}
+
+	// Curve unknown to the package
+	custom := &elliptic.CurveParams{Name: "custom", N: big.NewInt(101), BitSize: 7}
+	halfOrder, err := CurveHalfOrder(custom)
+	assert.NoError(t, err)
+	assert.Equal(t, big.NewInt(50), halfOrder)
+	lowS, err := IsLowS(&ecdsa.PublicKey{Curve: custom}, big.NewInt(50))
+	assert.NoError(t, err)
+	assert.True(t, lowS)
+	lowS, err = IsLowS(&ecdsa.PublicKey{Curve: custom}, big.NewInt(51))
+	assert.NoError(t, err)
+	assert.False(t, lowS)
+
+	// Degenerate curves
+	_, err = CurveHalfOrder(nil)
+	assert.Error(t, err)
+	_, err = CurveHalfOrder(&elliptic.CurveParams{Name: "no order"})
+	assert.Error(t, err)
+	_, err = CurveHalfOrder(&elliptic.CurveParams{Name: "zero order", N: big.NewInt(0)})
+	assert.Error(t, err)
+	assert.Nil(t, GetCurveHalfOrdersAt(nil))
+}
+
+func TestSupportedCurves(t *testing.T) {
+	curves := SupportedCurves()
+	assert.Equal(t, []elliptic.Curve{elliptic.P224(), elliptic.P256(), elliptic.P384(), elliptic.P521()}, curves)
+	for _, curve := range curves {
+		halfOrder := GetCurveHalfOrdersAt(curve)
+		assert.NotNil(t, halfOrder, curve.Params().Name)
+		assert.Equal(t, new(big.Int).Rsh(curve.Params().N, 1), halfOrder)
+		assert.True(t, LowSEnforced(curve))
+	}
+
+	// Returned slice is a copy
+	curves[0] = nil
+	assert.Equal(t, elliptic.P224(), SupportedCurves()[0])
+}
+
+func TestIsLowSSignature(t *testing.T) {
+	curve := elliptic.P256()
+	halfOrder := GetCurveHalfOrdersAt(curve)
+
+	for _, tc := range []struct {
+		s    *big.Int
+		lowS bool
+	}{
+		{big.NewInt(1), true},
+		{halfOrder, true},
+		{new(big.Int).Add(halfOrder, big.NewInt(1)), false},
+		{new(big.Int).Sub(curve.Params().N, big.NewInt(1)), false},
+	} {
+		sig, err := MarshalECDSASignature(big.NewInt(1), tc.s)
+		assert.NoError(t, err)
+		lowS, err := IsLowSSignature(sig, curve)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.lowS, lowS, "s = %x", tc.s)
+	}
+
+	// Both forms of a real signature
+	lowLevelKey, err := ecdsa.GenerateKey(curve, rand.Reader)
+	assert.NoError(t, err)
+	digest := make([]byte, 32)
+	r, s, err := ecdsa.Sign(rand.Reader, lowLevelKey, digest)
+	assert.NoError(t, err)
+	s, _, err = ToLowS(&lowLevelKey.PublicKey, s)
+	assert.NoError(t, err)
+	low, err := MarshalECDSASignature(r, s)
+	assert.NoError(t, err)
+	high, err := MarshalECDSASignature(r, new(big.Int).Sub(curve.Params().N, s))
+	assert.NoError(t, err)
+	lowS, err := IsLowSSignature(low, curve)
+	assert.NoError(t, err)
+	assert.True(t, lowS)
+	lowS, err = IsLowSSignature(high, curve)
+	assert.NoError(t, err)
+	assert.False(t, lowS)
+
+	_, err = IsLowSSignature([]byte{0x30, 0x00}, curve)
+	assert.Error(t, err)
+	_, err = IsLowSSignature(low, &elliptic.CurveParams{Name: "unknown"})
+	assert.Error(t, err)
+}
+
+func TestUnmarshalECDSASignatureLax(t *testing.T) {
+	for _, tc := range []struct {
+		raw    []byte
+		r, s   int64
+		strict bool
+	}{
+		// canonical encoding
+		{[]byte{0x30, 0x06, 0x02, 0x01, 0x01, 0x02, 0x01, 0x02}, 0x01, 0x02, true},
+		// missing leading zero octets
+		{[]byte{0x30, 0x07, 0x02, 0x01, 0x8F, 0x02, 0x02, 0xff, 0xf1}, 0x8F, 0xfff1, false},
+		// redundant leading zero octet
+		{[]byte{0x30, 0x07, 0x02, 0x01, 0x8F, 0x02, 0x02, 0x00, 0x01}, 0x8F, 0x01, false},
+		// long form length
+		{[]byte{0x30, 0x07, 0x02, 0x01, 0x8F, 0x02, 0x81, 0x01, 0x01}, 0x8F, 0x01, false},
+		{[]byte{0x30, 0x07, 0x02, 0x01, 0x8F, 0x02, 0x81, 0x01, 0x8F}, 0x8F, 0x8F, false},
+		{[]byte{0x30, 0x81, 0x07, 0x02, 0x01, 0x8F, 0x02, 0x81, 0x01, 0x8F}, 0x8F, 0x8F, false},
+		// many redundant leading zero octets
+		{[]byte{0x30, 0x0A, 0x02, 0x01, 0x8F, 0x02, 0x05, 0x00, 0x00, 0x00, 0x00, 0x8F}, 0x8F, 0x8F, false},
+	} {
+		_, _, err := UnmarshalECDSASignature(tc.raw)
+		if tc.strict {
+			assert.NoError(t, err)
+		} else {
+			assert.Error(t, err, "strict parser should fail for [% x]", tc.raw)
+		}
+
+		r, s, err := UnmarshalECDSASignatureLax(tc.raw)
+		assert.NoError(t, err, "lax parser should succeed for [% x]", tc.raw)
+		assert.Equal(t, big.NewInt(tc.r), r)
+		assert.Equal(t, big.NewInt(tc.s), s)
+	}
+
+	for _, raw := range [][]byte{
+		nil,
+		{0x30},
+		{0x31, 0x06, 0x02, 0x01, 0x01, 0x02, 0x01, 0x02},
+		{0x30, 0x06, 0x03, 0x01, 0x01, 0x02, 0x01, 0x02},
+		{0x30, 0x80, 0x02, 0x01, 0x01, 0x02, 0x01, 0x02, 0x00, 0x00},
+		{0x30, 0x07, 0x02, 0x01, 0x01, 0x02, 0x01, 0x02},
+		{0x30, 0x06, 0x02, 0x01, 0x01, 0x02, 0x01, 0x02, 0x00},
+		{0x30, 0x07, 0x02, 0x01, 0x01, 0x02, 0x01, 0x02, 0x00},
+		{0x30, 0x05, 0x02, 0x00, 0x02, 0x01, 0x02},
+		{0x30, 0x06, 0x02, 0x01, 0x00, 0x02, 0x01, 0x02},
+		{0x30, 0x06, 0x02, 0x01, 0x01, 0x02, 0x01, 0x00},
+	} {
+		_, _, err := UnmarshalECDSASignatureLax(raw)
+		assert.Error(t, err, "lax parser should fail for [% x]", raw)
+	}
+
+	lowLevelKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	r, s, err := ecdsa.Sign(rand.Reader, lowLevelKey, []byte("hello"))
+	assert.NoError(t, err)
+	sig, err := MarshalECDSASignature(r, s)
+	assert.NoError(t, err)
+	r2, s2, err := UnmarshalECDSASignatureLax(sig)
+	assert.NoError(t, err)
+	assert.Equal(t, r, r2)
+	assert.Equal(t, s, s2)
+}
+
+func TestMarshalECDSASignature(t *testing.T) {
+	values := []*big.Int{
+		big.NewInt(0),
+		big.NewInt(1),
+		big.NewInt(0x7f),
+		big.NewInt(0x80),
+		new(big.Int).Lsh(big.NewInt(1), 520),
+		elliptic.P521().Params().N,
+	}
+	for i := 0; i < 16; i++ {
+		n, err := rand.Int(rand.Reader, elliptic.P256().Params().N)
+		assert.NoError(t, err)
+		values = append(values, n)
+	}
+	for _, r := range values {
+		for _, s := range values {
+			raw, err := MarshalECDSASignature(r, s)
+			assert.NoError(t, err)
+			expected, err := asn1.Marshal(ECDSASignature{r, s})
+			assert.NoError(t, err)
+			assert.Equal(t, expected, raw)
+		}
+	}
+
+	// Negative values fall back to generic encoding
+	raw, err := MarshalECDSASignature(big.NewInt(-1), big.NewInt(1))
+	assert.NoError(t, err)
+	expected, _ := asn1.Marshal(ECDSASignature{big.NewInt(-1), big.NewInt(1)})
+	assert.Equal(t, expected, raw)
+
+	// Encoding is not shared between calls
+	a, _ := MarshalECDSASignature(big.NewInt(1), big.NewInt(2))
+	b, _ := MarshalECDSASignature(big.NewInt(3), big.NewInt(4))
+	assert.Equal(t, []byte{0x30, 0x06, 0x02, 0x01, 0x01, 0x02, 0x01, 0x02}, a)
+	assert.Equal(t, []byte{0x30, 0x06, 0x02, 0x01, 0x03, 0x02, 0x01, 0x04}, b)
+
+	r, s := values[len(values)-2], values[len(values)-1]
+	allocs := testing.AllocsPerRun(100, func() {
+		MarshalECDSASignature(r, s)
+	})
+	assert.Equal(t, float64(1), allocs)
+}
+
+func BenchmarkMarshalECDSASignature(b *testing.B) {
+	r, _ := rand.Int(rand.Reader, elliptic.P256().Params().N)
+	s, _ := rand.Int(rand.Reader, elliptic.P256().Params().N)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		MarshalECDSASignature(r, s)
+	}
+}
+
+func BenchmarkMarshalECDSASignatureASN1(b *testing.B) {
+	r, _ := rand.Int(rand.Reader, elliptic.P256().Params().N)
+	s, _ := rand.Int(rand.Reader, elliptic.P256().Params().N)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		asn1.Marshal(ECDSASignature{r, s})
+	}
+}
+
+func TestECDSAKeyFromScalar(t *testing.T) {
+	// RFC 6979 Appendix A.2.5
+	d, _ := new(big.Int).SetString("C9AFA9D845BA75166B5C215767B1D6934E50C3DB36E89B127B8A622B120F6721", 16)
+	x, _ := new(big.Int).SetString("60FED4BA255A9D31C961EB74C6356D68C049B8923B61FA6CE669622E60F29FB6", 16)
+	y, _ := new(big.Int).SetString("7903FE1008B8BC99A41AE9E95628BC64F2F1B20C2D7E9F5177A3C294D4462299", 16)
+	k, err := ECDSAKeyFromScalar(elliptic.P256(), d)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, x.Cmp(k.X))
+	assert.Equal(t, 0, y.Cmp(k.Y))
+	assert.Equal(t, 0, d.Cmp(k.D))
+	assert.Equal(t, elliptic.P256(), k.Curve)
+
+	// Key is usable and scalar is copied
+	r, s, err := ecdsa.Sign(rand.Reader, k, []byte("digest"))
+	assert.NoError(t, err)
+	assert.True(t, ecdsa.Verify(&k.PublicKey, []byte("digest"), r, s))
+	d.SetInt64(1)
+	assert.NotEqual(t, 0, d.Cmp(k.D))
+
+	// Smallest scalar yields generator
+	k, err = ECDSAKeyFromScalar(elliptic.P384(), big.NewInt(1))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, elliptic.P384().Params().Gx.Cmp(k.X))
+	assert.Equal(t, 0, elliptic.P384().Params().Gy.Cmp(k.Y))
+
+	n := elliptic.P256().Params().N
+	for _, d := range []*big.Int{nil, big.NewInt(0), big.NewInt(-1), n, new(big.Int).Add(n, big.NewInt(1))} {
+		_, err = ECDSAKeyFromScalar(elliptic.P256(), d)
+		assert.Error(t, err)
+	}
+	_, err = ECDSAKeyFromScalar(nil, big.NewInt(1))
+	assert.EqualError(t, err, "curve must be different from nil")
+}
+
+func TestUnmarshalECDSAPoint(t *testing.T) {
+	priv, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	assert.NoError(t, err)
+
+	for _, point := range [][]byte{
+		elliptic.Marshal(priv.Curve, priv.X, priv.Y),
+		CompressECDSAPublicKey(&priv.PublicKey),
+	} {
+		pub, err := UnmarshalECDSAPoint(elliptic.P384(), point)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, priv.X.Cmp(pub.X))
+		assert.Equal(t, 0, priv.Y.Cmp(pub.Y))
+	}
+
+	_, err = UnmarshalECDSAPoint(nil, []byte{0x02})
+	assert.Error(t, err)
+	_, err = UnmarshalECDSAPoint(elliptic.P384(), nil)
+	assert.Error(t, err)
+	bad := elliptic.Marshal(priv.Curve, priv.X, priv.Y)
+	bad[len(bad)-1] ^= 1
+	_, err = UnmarshalECDSAPoint(elliptic.P384(), bad)
+	assert.Error(t, err)
+}
@@ -0,0 +1,145 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcec"
+
+	"github.com/ipfn/ipfn/pkg/digest"
+)
+
+const (
+	// eip191Prefix - Prefix of EIP-191 personal messages.
+	eip191Prefix = "\x19Ethereum Signed Message:\n"
+	// ethSignatureSize - Size of Ethereum r || s || v signature.
+	ethSignatureSize = 65
+	// ethAddressSize - Size of Ethereum address.
+	ethAddressSize = 20
+)
+
+// EIP191Hash - Computes Keccak-256 hash of message prefixed as in EIP-191
+// version 0x45, as signed by personal_sign and eth_sign.
+func EIP191Hash(message []byte) []byte {
+	prefix := eip191Prefix + strconv.Itoa(len(message))
+	return digest.SumKeccak256Bytes([]byte(prefix), message)
+}
+
+// VerifyEIP191 - Verifies Ethereum personal_sign (EIP-191) signature of message.
+//
+// Signature is 65 bytes r || s || v with v being 27 or 28, 0 or 1 is accepted
+// as well. Expected address is hex with optional 0x prefix. Mixed case address
+// must have valid EIP-55 checksum. Signer address is recovered from signature
+// and compared to the expected address. Signature of different signer results
+// in false and no error.
+func VerifyEIP191(message []byte, sig []byte, expectedAddr string) (bool, error) {
+	expected, err := parseEthAddress(expectedAddr)
+	if err != nil {
+		return false, err
+	}
+	if len(sig) != ethSignatureSize {
+		return false, fmt.Errorf("Invalid signature length %d, expected %d", len(sig), ethSignatureSize)
+	}
+	v, err := RecoveryID(sig[64])
+	if err != nil {
+		return false, err
+	}
+
+	// Reject malleable signatures as Ethereum does since Homestead
+	halfOrder, err := CurveHalfOrder(btcec.S256())
+	if err != nil {
+		return false, err
+	}
+	if new(big.Int).SetBytes(sig[32:64]).Cmp(halfOrder) > 0 {
+		return false, errors.New("Invalid signature. S must be in the lower half of the order.")
+	}
+
+	compact := make([]byte, ethSignatureSize)
+	compact[0] = 27 + v
+	copy(compact[1:], sig[:64])
+	pub, _, err := btcec.RecoverCompact(btcec.S256(), compact, EIP191Hash(message))
+	if err != nil {
+		return false, nil
+	}
+	addr := ethAddress(pub.SerializeUncompressed())
+	return bytes.Equal(addr, expected), nil
+}
+
+// RecoveryID - Normalizes recovery id v of r || s || v recoverable secp256k1
+// signature to 0 or 1. Both 0 or 1 and Ethereum style 27 or 28 are accepted.
+func RecoveryID(v byte) (byte, error) {
+	id := v
+	if id >= 27 {
+		id -= 27
+	}
+	if id > 1 {
+		return 0, fmt.Errorf("Invalid signature recovery id %d", v)
+	}
+	return id, nil
+}
+
+// EthAddress - Returns EIP-55 mixed case checksum encoded Ethereum address
+// of secp256k1 public key.
+func EthAddress(pub *ecdsa.PublicKey) (string, error) {
+	if pub == nil || pub.Curve == nil {
+		return "", errors.New("Invalid public key. It must not be nil.")
+	}
+	if name := pub.Curve.Params().Name; name != btcec.S256().Name {
+		return "", fmt.Errorf("Invalid public key curve [%s]. Expected secp256k1.", name)
+	}
+	return ethChecksumAddress(ethAddress((*btcec.PublicKey)(pub).SerializeUncompressed())), nil
+}
+
+// ethAddress - Computes Ethereum address of uncompressed public key,
+// which is the last 20 bytes of Keccak-256 of the point without prefix.
+func ethAddress(uncompressed []byte) []byte {
+	return digest.SumKeccak256Bytes(uncompressed[1:])[32-ethAddressSize:]
+}
+
+// parseEthAddress - Parses hex encoded Ethereum address.
+// Mixed case addresses are validated against EIP-55 checksum.
+func parseEthAddress(addr string) ([]byte, error) {
+	addr = strings.TrimPrefix(strings.TrimPrefix(addr, "0x"), "0X")
+	raw, err := hex.DecodeString(addr)
+	if err != nil || len(raw) != ethAddressSize {
+		return nil, fmt.Errorf("Invalid Ethereum address [%s]", addr)
+	}
+	if addr != strings.ToLower(addr) && addr != strings.ToUpper(addr) && addr != ethChecksumAddress(raw)[2:] {
+		return nil, fmt.Errorf("Invalid Ethereum address checksum [%s]", addr)
+	}
+	return raw, nil
+}
+
+// ethChecksumAddress - Encodes address with EIP-55 mixed case checksum.
+func ethChecksumAddress(addr []byte) string {
+	lower := hex.EncodeToString(addr)
+	hash := digest.SumKeccak256Bytes([]byte(lower))
+	out := []byte(lower)
+	for i, c := range out {
+		if c >= 'a' && hash[i/2]>>(4*uint(1-i%2))&0xf >= 8 {
+			out[i] = c - 'a' + 'A'
+		}
+	}
+	return "0x" + string(out)
+}
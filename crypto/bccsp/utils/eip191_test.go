@@ -0,0 +1,100 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyEIP191(t *testing.T) {
+	// Signature of "Some data" produced by web3.eth.accounts.sign with key
+	// 4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318,
+	// the same signature is produced by MetaMask personal_sign.
+	message := []byte("Some data")
+	addr := "0x2c7536E3605D9C16a7a3D7b1898e529396a65c23"
+	sig, _ := hex.DecodeString("b91467e570a6466aa9e9876cbcd013baba02900b8979d43fe208a4a4f339f5fd" +
+		"6007e74cd82e037b800186422fc2da167c747ef045e5d18a5f5d4300f8e1a0291c")
+
+	assert.Equal(t, "1da44b586eb0729ff70a73c326926f6ed5a25f5b056e7f47fbc6e58d86871655", hex.EncodeToString(EIP191Hash(message)))
+
+	priv, _ := hex.DecodeString("4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318")
+	_, pub := btcec.PrivKeyFromBytes(btcec.S256(), priv)
+	assert.Equal(t, addr, ethChecksumAddress(ethAddress(pub.SerializeUncompressed())))
+	ethAddr, err := EthAddress(pub.ToECDSA())
+	assert.NoError(t, err)
+	assert.Equal(t, addr, ethAddr)
+
+	for _, a := range []string{addr, strings.ToLower(addr), "0x" + strings.ToUpper(addr[2:]), addr[2:]} {
+		valid, err := VerifyEIP191(message, sig, a)
+		assert.NoError(t, err, a)
+		assert.True(t, valid, a)
+	}
+
+	// Recovery id 0 or 1
+	legacy := append([]byte{}, sig...)
+	legacy[64] -= 27
+	valid, err := VerifyEIP191(message, legacy, addr)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+
+	// Different message or signer
+	valid, err = VerifyEIP191([]byte("Other data"), sig, addr)
+	assert.NoError(t, err)
+	assert.False(t, valid)
+	valid, err = VerifyEIP191(message, sig, "0x0000000000000000000000000000000000000001")
+	assert.NoError(t, err)
+	assert.False(t, valid)
+
+	// Malformed input
+	_, err = VerifyEIP191(message, sig[:64], addr)
+	assert.Error(t, err)
+	bad := append([]byte{}, sig...)
+	bad[64] = 29
+	_, err = VerifyEIP191(message, bad, addr)
+	assert.Error(t, err)
+	_, err = VerifyEIP191(message, sig, "0x2c7536e3605D9C16a7a3D7b1898e529396a65c23")
+	assert.Error(t, err)
+	_, err = VerifyEIP191(message, sig, "0x2c75")
+	assert.Error(t, err)
+
+	// High S is rejected
+	highS := append([]byte{}, sig...)
+	s := new(big.Int).SetBytes(sig[32:64])
+	s.Sub(btcec.S256().N, s)
+	copy(highS[32:64], s.Bytes())
+	highS[64] ^= 1
+	_, err = VerifyEIP191(message, highS, addr)
+	assert.Error(t, err)
+}
+
+func TestRecoveryID(t *testing.T) {
+	for v, expected := range map[byte]byte{0: 0, 1: 1, 27: 0, 28: 1} {
+		id, err := RecoveryID(v)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, id)
+	}
+	for _, v := range []byte{2, 26, 29, 255} {
+		_, err := RecoveryID(v)
+		assert.EqualError(t, err, fmt.Sprintf("Invalid signature recovery id %d", v))
+	}
+}
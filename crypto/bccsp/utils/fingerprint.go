@@ -0,0 +1,51 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+)
+
+// KeyFingerprint - Returns hex encoded SHA-256 fingerprint of public key bytes.
+//
+// Private keys are fingerprinted by their public key, so both halves of
+// a key pair share fingerprint. Symmetric keys are rejected.
+func KeyFingerprint(key bccsp.Key) (string, error) {
+	if key == nil {
+		return "", errors.New("Invalid key. It must not be nil.")
+	}
+	if key.Symmetric() {
+		return "", errors.New("Invalid key. It must not be symmetric.")
+	}
+	if key.Private() {
+		var err error
+		key, err = key.PublicKey()
+		if err != nil {
+			return "", fmt.Errorf("Failed getting public key [%s]", err)
+		}
+	}
+	raw, err := key.Bytes()
+	if err != nil {
+		return "", fmt.Errorf("Failed marshalling public key [%s]", err)
+	}
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:]), nil
+}
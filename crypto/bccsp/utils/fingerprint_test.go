@@ -0,0 +1,47 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp/mocks"
+)
+
+func TestKeyFingerprint(t *testing.T) {
+	pub := []byte("public key bytes")
+	sum := sha256.Sum256(pub)
+
+	fp, err := KeyFingerprint(&mocks.MockKey{BytesValue: pub})
+	assert.NoError(t, err)
+	assert.Equal(t, hex.EncodeToString(sum[:]), fp)
+
+	fp, err = KeyFingerprint(&mocks.MockKey{Pvt: true, PK: &mocks.MockKey{BytesValue: pub}})
+	assert.NoError(t, err)
+	assert.Equal(t, hex.EncodeToString(sum[:]), fp)
+
+	_, err = KeyFingerprint(nil)
+	assert.EqualError(t, err, "Invalid key. It must not be nil.")
+	_, err = KeyFingerprint(&mocks.MockKey{Symm: true})
+	assert.EqualError(t, err, "Invalid key. It must not be symmetric.")
+	_, err = KeyFingerprint(&mocks.MockKey{BytesErr: errors.New("no bytes")})
+	assert.EqualError(t, err, "Failed marshalling public key [no bytes]")
+}
@@ -0,0 +1,72 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/ed25519"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+)
+
+// GoKey - Asymmetric key which exposes its Go public key.
+type GoKey interface {
+	bccsp.Key
+
+	// GoPublicKey - Returns *ecdsa.PublicKey, *rsa.PublicKey or ed25519.PublicKey.
+	GoPublicKey() interface{}
+}
+
+// GoPublicKey - Returns Go public key of asymmetric key, which is one of
+// *ecdsa.PublicKey, *rsa.PublicKey or ed25519.PublicKey. Private keys are
+// resolved to their public keys. Keys which do not implement GoKey, such as
+// keys of other providers, are parsed from their PKIX encoded bytes.
+func GoPublicKey(key bccsp.Key) (interface{}, error) {
+	if key == nil {
+		return nil, errors.New("Invalid key. It must not be nil.")
+	}
+	if key.Symmetric() {
+		return nil, errors.New("Invalid key. It must not be symmetric.")
+	}
+	if key.Private() {
+		var err error
+		key, err = key.PublicKey()
+		if err != nil {
+			return nil, fmt.Errorf("Failed getting public key [%s]", err)
+		}
+	}
+	var pub interface{}
+	if k, ok := key.(GoKey); ok {
+		pub = k.GoPublicKey()
+	} else {
+		raw, err := key.Bytes()
+		if err != nil {
+			return nil, fmt.Errorf("Failed marshalling public key [%s]", err)
+		}
+		if pub, err = DERToPublicKey(raw); err != nil {
+			return nil, fmt.Errorf("Failed parsing public key [%s]", err)
+		}
+	}
+	switch pub.(type) {
+	case *ecdsa.PublicKey, *rsa.PublicKey, ed25519.PublicKey:
+		return pub, nil
+	}
+	return nil, fmt.Errorf("Unsupported public key type [%T]", pub)
+}
@@ -0,0 +1,101 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec"
+	"golang.org/x/crypto/ed25519"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp/swcp"
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGoPublicKey(t *testing.T) {
+	csp, err := swcp.NewDefaultSecurityLevelWithKeystore(swcp.NewDummyKeyStore())
+	assert.NoError(t, err)
+
+	secp256k1, err := btcec.NewPrivateKey(btcec.S256())
+	assert.NoError(t, err)
+	secp256k1Key, err := csp.KeyImport(secp256k1.PubKey().ToECDSA(), &bccsp.ECDSAGoPublicKeyImportOpts{Temporary: true})
+	assert.NoError(t, err)
+
+	type keyTest struct {
+		key     bccsp.Key
+		keyType utils.Libp2pKeyType
+	}
+	tests := []keyTest{{secp256k1Key, utils.Libp2pKeyTypeSecp256k1}}
+	for opts, keyType := range map[bccsp.KeyGenOpts]utils.Libp2pKeyType{
+		&bccsp.ECDSAP256KeyGenOpts{Temporary: true}: utils.Libp2pKeyTypeECDSA,
+		&bccsp.ECDSAP384KeyGenOpts{Temporary: true}: utils.Libp2pKeyTypeECDSA,
+		&bccsp.ED25519KeyGenOpts{Temporary: true}:   utils.Libp2pKeyTypeEd25519,
+		&bccsp.RSA2048KeyGenOpts{Temporary: true}:   utils.Libp2pKeyTypeRSA,
+	} {
+		k, err := csp.KeyGen(opts)
+		assert.NoError(t, err)
+		tests = append(tests, keyTest{k, keyType})
+	}
+
+	for _, test := range tests {
+		pub, err := utils.GoPublicKey(test.key)
+		assert.NoError(t, err)
+		switch test.keyType {
+		case utils.Libp2pKeyTypeEd25519:
+			assert.IsType(t, ed25519.PublicKey{}, pub)
+		case utils.Libp2pKeyTypeRSA:
+			assert.IsType(t, &rsa.PublicKey{}, pub)
+		default:
+			assert.IsType(t, &ecdsa.PublicKey{}, pub)
+		}
+
+		proto, err := utils.PublicKeyToLibp2pProto(test.key)
+		assert.NoError(t, err)
+		keyType, _, err := utils.Libp2pProtoToPublicKey(proto)
+		assert.NoError(t, err)
+		assert.Equal(t, test.keyType, keyType)
+		_, err = utils.PublicKeyToPeerID(test.key)
+		assert.NoError(t, err)
+
+		_, err = utils.PublicKeyToBech32Address(test.key, "cosmos")
+		if test.keyType == utils.Libp2pKeyTypeRSA {
+			assert.Error(t, err)
+		} else {
+			assert.NoError(t, err)
+		}
+	}
+
+	// X25519 keys have 32 bytes like Ed25519 keys
+	x25519, err := csp.KeyGen(&bccsp.X25519KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	_, err = utils.GoPublicKey(x25519)
+	assert.Error(t, err)
+	_, err = utils.PublicKeyToBech32Address(x25519, "cosmos")
+	assert.Error(t, err)
+	_, err = utils.PublicKeyToPeerID(x25519)
+	assert.Error(t, err)
+	_, err = utils.VerifyPossession(x25519, []byte("challenge"), []byte{1})
+	assert.Error(t, err)
+
+	aes, err := csp.KeyGen(&bccsp.AES256KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	_, err = utils.GoPublicKey(aes)
+	assert.Error(t, err)
+}
@@ -0,0 +1,255 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/btcsuite/btcd/btcec"
+	"golang.org/x/crypto/ed25519"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+)
+
+// jwtKeyIDSize - Size of JWK thumbprint prefix used as JWT key ID.
+const jwtKeyIDSize = 16
+
+// JWTKeyID - Returns key ID suitable for JWT 'kid' header.
+//
+// Key ID is base64url encoded first 16 bytes of RFC 7638 JWK thumbprint of
+// the public key, so it is stable across key encodings and matches the
+// thumbprint computed by other JWK implementations. Empty string is returned
+// for keys without JWK representation, see JWKThumbprint.
+func JWTKeyID(key bccsp.Key) string {
+	thumbprint, err := JWKThumbprint(key)
+	if err != nil {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(thumbprint[:jwtKeyIDSize])
+}
+
+// JWKThumbprint - Computes RFC 7638 SHA-256 JWK thumbprint of public key.
+//
+// Supported are RSA keys, ECDSA keys on P-256, P-384 and P-521 curves,
+// secp256k1 keys (RFC 8812) and Ed25519 keys (RFC 8037). Private keys are
+// thumbprinted by their public key. Symmetric keys are rejected.
+func JWKThumbprint(key bccsp.Key) ([]byte, error) {
+	params, err := jwkPublicParams(key)
+	if err != nil {
+		return nil, err
+	}
+	return jwkThumbprint(params)
+}
+
+// PublicKeyToJWK - Returns members of JWK representing public key,
+// including 'kid' set to JWTKeyID of the key. Supported keys are the same
+// as in JWKThumbprint. Members are encoded as JSON object by encoding/json.
+func PublicKeyToJWK(key bccsp.Key) (map[string]string, error) {
+	params, err := jwkPublicParams(key)
+	if err != nil {
+		return nil, err
+	}
+	thumbprint, err := jwkThumbprint(params)
+	if err != nil {
+		return nil, err
+	}
+	params["kid"] = base64.RawURLEncoding.EncodeToString(thumbprint[:jwtKeyIDSize])
+	return params, nil
+}
+
+// jwkThumbprint - Hashes required JWK members. Encoding of map by
+// encoding/json is lexicographically ordered and has no whitespace,
+// as required by RFC 7638.
+func jwkThumbprint(params map[string]string) ([]byte, error) {
+	members, err := json.Marshal(params)
+	if err != nil {
+		return nil, fmt.Errorf("Failed marshalling JWK [%s]", err)
+	}
+	sum := sha256.Sum256(members)
+	return sum[:], nil
+}
+
+// jwkPublicParams - Returns required JWK members of public key.
+func jwkPublicParams(key bccsp.Key) (map[string]string, error) {
+	if key == nil {
+		return nil, errors.New("Invalid key. It must not be nil.")
+	}
+	if key.Symmetric() {
+		return nil, errors.New("Invalid key. It must not be symmetric.")
+	}
+	if key.Private() {
+		var err error
+		key, err = key.PublicKey()
+		if err != nil {
+			return nil, fmt.Errorf("Failed getting public key [%s]", err)
+		}
+	}
+	raw, err := key.Bytes()
+	if err != nil {
+		return nil, fmt.Errorf("Failed marshalling public key [%s]", err)
+	}
+	return jwkParams(raw)
+}
+
+// jwkParams - Returns required JWK members of public key bytes.
+func jwkParams(raw []byte) (map[string]string, error) {
+	if len(raw) == ed25519PublicKeySize {
+		return map[string]string{"crv": "Ed25519", "kty": "OKP", "x": b64url(raw)}, nil
+	}
+	if len(raw) > 0 && (raw[0] == 0x02 || raw[0] == 0x03 || raw[0] == 0x04) {
+		pub, err := btcec.ParsePubKey(raw, btcec.S256())
+		if err != nil {
+			return nil, fmt.Errorf("Failed parsing secp256k1 public key [%s]", err)
+		}
+		return jwkECParams("secp256k1", pub.ToECDSA()), nil
+	}
+	pub, err := DERToPublicKey(raw)
+	if err != nil {
+		return nil, fmt.Errorf("Failed parsing public key [%s]", err)
+	}
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		return map[string]string{
+			"e":   b64url(big.NewInt(int64(k.E)).Bytes()),
+			"kty": "RSA",
+			"n":   b64url(k.N.Bytes()),
+		}, nil
+	case *ecdsa.PublicKey:
+		switch name := k.Curve.Params().Name; name {
+		case "P-256", "P-384", "P-521":
+			return jwkECParams(name, k), nil
+		default:
+			return nil, fmt.Errorf("Unsupported JWK curve [%s]", name)
+		}
+	}
+	return nil, fmt.Errorf("Unsupported key type [%T]", pub)
+}
+
+// jwkECParams - Returns required JWK members of elliptic curve key.
+// Coordinates are padded to the size of the field.
+func jwkECParams(crv string, pub *ecdsa.PublicKey) map[string]string {
+	size := (pub.Curve.Params().BitSize + 7) / 8
+	x := make([]byte, size)
+	y := make([]byte, size)
+	pub.X.FillBytes(x)
+	pub.Y.FillBytes(y)
+	return map[string]string{"crv": crv, "kty": "EC", "x": b64url(x), "y": b64url(y)}
+}
+
+// JWKToPublicKey - Parses public key from JWK, the inverse of PublicKeyToJWK.
+//
+// Returned key is *rsa.PublicKey, *ecdsa.PublicKey or ed25519.PublicKey.
+// Supported keys are the same as in JWKThumbprint. JWKs of private keys,
+// containing the 'd' member, are rejected, so that private key material
+// is not imported by mistake as a public key.
+func JWKToPublicKey(data []byte) (interface{}, error) {
+	var members map[string]interface{}
+	if err := json.Unmarshal(data, &members); err != nil {
+		return nil, fmt.Errorf("Failed unmarshalling JWK [%s]", err)
+	}
+	// Other members, such as 'key_ops' array, are ignored
+	jwk := make(map[string]string, len(members))
+	for name, value := range members {
+		if str, ok := value.(string); ok {
+			jwk[name] = str
+		}
+	}
+	if _, ok := members["d"]; ok {
+		return nil, errors.New("Invalid JWK. Private keys are not supported.")
+	}
+	member := func(name string) ([]byte, error) {
+		b, err := base64.RawURLEncoding.DecodeString(jwk[name])
+		if err != nil || len(b) == 0 {
+			return nil, fmt.Errorf("Invalid JWK member '%s'", name)
+		}
+		return b, nil
+	}
+	switch kty := jwk["kty"]; kty {
+	case "RSA":
+		n, err := member("n")
+		if err != nil {
+			return nil, err
+		}
+		e, err := member("e")
+		if err != nil {
+			return nil, err
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() > 1<<31-1 {
+			return nil, errors.New("Invalid JWK member 'e'")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+	case "EC":
+		curve, err := jwkCurve(jwk["crv"])
+		if err != nil {
+			return nil, err
+		}
+		x, err := member("x")
+		if err != nil {
+			return nil, err
+		}
+		y, err := member("y")
+		if err != nil {
+			return nil, err
+		}
+		pub := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(pub.X, pub.Y) {
+			return nil, errors.New("Invalid JWK. Point is not on curve.")
+		}
+		return pub, nil
+	case "OKP":
+		if crv := jwk["crv"]; crv != "Ed25519" {
+			return nil, fmt.Errorf("Unsupported JWK curve [%s]", crv)
+		}
+		x, err := member("x")
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519PublicKeySize {
+			return nil, errors.New("Invalid JWK member 'x'")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("Unsupported JWK key type [%s]", kty)
+	}
+}
+
+// jwkCurve - Returns elliptic curve of JWK 'crv' member.
+func jwkCurve(crv string) (elliptic.Curve, error) {
+	switch crv {
+	case "P-256":
+		return elliptic.P256(), nil
+	case "P-384":
+		return elliptic.P384(), nil
+	case "P-521":
+		return elliptic.P521(), nil
+	case "secp256k1":
+		return btcec.S256(), nil
+	}
+	return nil, fmt.Errorf("Unsupported JWK curve [%s]", crv)
+}
+
+func b64url(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,157 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp/mocks"
+)
+
+func TestJWKThumbprint(t *testing.T) {
+	// RFC 7638 Section 3.1
+	n, err := base64.RawURLEncoding.DecodeString("0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw")
+	assert.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&rsa.PublicKey{N: new(big.Int).SetBytes(n), E: 65537})
+	assert.NoError(t, err)
+	thumbprint, err := JWKThumbprint(&mocks.MockKey{BytesValue: der})
+	assert.NoError(t, err)
+	assert.Equal(t, "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs", base64.RawURLEncoding.EncodeToString(thumbprint))
+
+	// RFC 8037 Appendix A.3
+	x, err := base64.RawURLEncoding.DecodeString("11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo")
+	assert.NoError(t, err)
+	thumbprint, err = JWKThumbprint(&mocks.MockKey{Pvt: true, PK: &mocks.MockKey{BytesValue: x}})
+	assert.NoError(t, err)
+	assert.Equal(t, "kPrK_qmxVWaYVA9wwBF6Iuo3vVzz7TxHCTwXBygrS4k", base64.RawURLEncoding.EncodeToString(thumbprint))
+
+	_, err = JWKThumbprint(nil)
+	assert.EqualError(t, err, "Invalid key. It must not be nil.")
+	_, err = JWKThumbprint(&mocks.MockKey{Symm: true})
+	assert.EqualError(t, err, "Invalid key. It must not be symmetric.")
+
+	p224, err := ecdsa.GenerateKey(elliptic.P224(), rand.Reader)
+	assert.NoError(t, err)
+	der, err = x509.MarshalPKIXPublicKey(&p224.PublicKey)
+	assert.NoError(t, err)
+	_, err = JWKThumbprint(&mocks.MockKey{BytesValue: der})
+	assert.EqualError(t, err, "Unsupported JWK curve [P-224]")
+}
+
+func TestJWTKeyID(t *testing.T) {
+	p256, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&p256.PublicKey)
+	assert.NoError(t, err)
+
+	kid := JWTKeyID(&mocks.MockKey{BytesValue: der})
+	assert.Len(t, kid, 22)
+	assert.Equal(t, kid, JWTKeyID(&mocks.MockKey{BytesValue: der}))
+	assert.Equal(t, kid, JWTKeyID(&mocks.MockKey{Pvt: true, PK: &mocks.MockKey{BytesValue: der}}))
+
+	// secp256k1 compressed and uncompressed points share key ID
+	k1, err := btcec.NewPrivateKey(btcec.S256())
+	assert.NoError(t, err)
+	compressed := JWTKeyID(&mocks.MockKey{BytesValue: k1.PubKey().SerializeCompressed()})
+	assert.NotEmpty(t, compressed)
+	assert.Equal(t, compressed, JWTKeyID(&mocks.MockKey{BytesValue: k1.PubKey().SerializeUncompressed()}))
+
+	kids := map[string]bool{kid: true, compressed: true}
+	for i := 0; i < 32; i++ {
+		k, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		assert.NoError(t, err)
+		der, err := x509.MarshalPKIXPublicKey(&k.PublicKey)
+		assert.NoError(t, err)
+		kid := JWTKeyID(&mocks.MockKey{BytesValue: der})
+		assert.False(t, kids[kid])
+		kids[kid] = true
+	}
+
+	assert.Empty(t, JWTKeyID(nil))
+	assert.Empty(t, JWTKeyID(&mocks.MockKey{BytesValue: []byte{1, 2, 3}}))
+}
+
+func TestPublicKeyToJWK(t *testing.T) {
+	p256, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&p256.PublicKey)
+	assert.NoError(t, err)
+	key := &mocks.MockKey{BytesValue: der}
+
+	jwk, err := PublicKeyToJWK(key)
+	assert.NoError(t, err)
+	assert.Equal(t, "EC", jwk["kty"])
+	assert.Equal(t, "P-256", jwk["crv"])
+	assert.Equal(t, JWTKeyID(key), jwk["kid"])
+	x, err := base64.RawURLEncoding.DecodeString(jwk["x"])
+	assert.NoError(t, err)
+	assert.Len(t, x, 32)
+	assert.Equal(t, 0, p256.X.Cmp(new(big.Int).SetBytes(x)))
+
+	_, err = PublicKeyToJWK(&mocks.MockKey{Symm: true})
+	assert.EqualError(t, err, "Invalid key. It must not be symmetric.")
+}
+
+func TestJWKToPublicKey(t *testing.T) {
+	p384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	assert.NoError(t, err)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	assert.NoError(t, err)
+	k1, err := btcec.NewPrivateKey(btcec.S256())
+	assert.NoError(t, err)
+
+	for _, pub := range []interface{}{&p384.PublicKey, &rsaKey.PublicKey, k1.PubKey().ToECDSA()} {
+		der, err := x509.MarshalPKIXPublicKey(pub)
+		if err != nil {
+			// secp256k1 has no PKIX encoding
+			der = k1.PubKey().SerializeCompressed()
+		}
+		jwk, err := PublicKeyToJWK(&mocks.MockKey{BytesValue: der})
+		assert.NoError(t, err)
+		data, err := json.Marshal(jwk)
+		assert.NoError(t, err)
+		parsed, err := JWKToPublicKey(data)
+		assert.NoError(t, err)
+		assert.Equal(t, pub, parsed)
+	}
+
+	// RFC 8037 Appendix A.2
+	pub, err := JWKToPublicKey([]byte(`{"kty":"OKP","crv":"Ed25519","x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo","key_ops":["verify"]}`))
+	assert.NoError(t, err)
+	assert.Len(t, pub, 32)
+
+	_, err = JWKToPublicKey([]byte(`{"kty":"OKP","crv":"Ed25519","d":"nWGxne_9WmC6hEr0kuwsxERJxWl7MmkZcDusAxyuf2A","x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}`))
+	assert.EqualError(t, err, "Invalid JWK. Private keys are not supported.")
+	_, err = JWKToPublicKey([]byte(`{"kty":"EC","crv":"P-256","x":"AQ","y":"AQ"}`))
+	assert.EqualError(t, err, "Invalid JWK. Point is not on curve.")
+	_, err = JWKToPublicKey([]byte(`{"kty":"EC","crv":"P-224","x":"AQ","y":"AQ"}`))
+	assert.EqualError(t, err, "Unsupported JWK curve [P-224]")
+	_, err = JWKToPublicKey([]byte(`{"kty":"oct","k":"AQ"}`))
+	assert.EqualError(t, err, "Unsupported JWK key type [oct]")
+	_, err = JWKToPublicKey([]byte(`{"kty":"RSA","e":"AQAB"}`))
+	assert.EqualError(t, err, "Invalid JWK member 'n'")
+}
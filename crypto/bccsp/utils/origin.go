@@ -0,0 +1,86 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"errors"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+)
+
+// OriginKind - Describes how a key was imported.
+type OriginKind int
+
+const (
+	// OriginUnknown - Key was generated, derived, loaded from a key store
+	// or its origin is not tracked.
+	OriginUnknown OriginKind = iota
+	// OriginDER - Key was imported from PKIX DER encoding.
+	OriginDER
+	// OriginGoKey - Key was imported from Go public key structure.
+	OriginGoKey
+	// OriginCertificate - Key was imported from X509 certificate.
+	OriginCertificate
+)
+
+// String - Returns name of the origin kind.
+func (kind OriginKind) String() string {
+	switch kind {
+	case OriginDER:
+		return "der"
+	case OriginGoKey:
+		return "go"
+	case OriginCertificate:
+		return "certificate"
+	default:
+		return "unknown"
+	}
+}
+
+// Origin - Origin of imported key.
+type Origin struct {
+	// Kind - How the key was imported.
+	Kind OriginKind
+	// Raw - Original encoding of the imported material, DER encoded
+	// public key or certificate. It is nil for OriginGoKey and OriginUnknown.
+	Raw []byte
+}
+
+// OriginKey - Key which keeps track of its origin.
+type OriginKey interface {
+	bccsp.Key
+
+	// KeyOrigin - Returns origin of the key.
+	KeyOrigin() Origin
+}
+
+// KeyOrigin - Reports how key was imported and preserves original bytes
+// for re-export, see Origin. Origin is kept only in memory, keys loaded
+// from a key store are of unknown origin.
+func KeyOrigin(key bccsp.Key) (Origin, error) {
+	if key == nil {
+		return Origin{}, errors.New("Invalid key. It must not be nil.")
+	}
+	k, ok := key.(OriginKey)
+	if !ok {
+		return Origin{}, errors.New("Key does not keep track of its origin.")
+	}
+	origin := k.KeyOrigin()
+	if origin.Raw != nil {
+		origin.Raw = Clone(origin.Raw)
+	}
+	return origin, nil
+}
@@ -0,0 +1,205 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcec"
+	"golang.org/x/crypto/ed25519"
+
+	multihash "gx/ipfs/QmerPMzPk1mJVowm8KgmoknWa4yCYvvugMPsgWmDNUvDLW/go-multihash"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+	"github.com/ipfn/ipfn/pkg/digest"
+)
+
+// Libp2pKeyType - Key type enum of libp2p crypto.pb protobuf.
+type Libp2pKeyType int
+
+// libp2p public key types.
+const (
+	Libp2pKeyTypeRSA       Libp2pKeyType = 0
+	Libp2pKeyTypeEd25519   Libp2pKeyType = 1
+	Libp2pKeyTypeSecp256k1 Libp2pKeyType = 2
+	Libp2pKeyTypeECDSA     Libp2pKeyType = 3
+)
+
+// maxInlineKeySize - Maximum size of serialized public key
+// inlined in peer ID using identity multihash.
+const maxInlineKeySize = 42
+
+// PublicKeyToPeerID - Encodes public key as base58btc libp2p peer ID.
+//
+// Ed25519 keys are raw 32 bytes, secp256k1 keys are raw 33 bytes compressed points,
+// ECDSA and RSA keys are PKIX encoded. Public key serialized in libp2p protobuf
+// format is inlined using identity multihash if it is short enough, otherwise
+// SHA-256 multihash is used.
+func PublicKeyToPeerID(key bccsp.Key) (string, error) {
+	body, err := PublicKeyToLibp2pProto(key)
+	if err != nil {
+		return "", err
+	}
+
+	var mh []byte
+	if len(body) <= maxInlineKeySize {
+		mh, err = multihash.Encode(body, multihash.ID)
+	} else {
+		mh, err = multihash.Encode(digest.SumSha256Bytes(body), multihash.SHA2_256)
+	}
+	if err != nil {
+		return "", fmt.Errorf("Failed encoding multihash [%s]", err)
+	}
+	return multihash.Multihash(mh).B58String(), nil
+}
+
+// PublicKeyToLibp2pProto - Serializes public key as libp2p crypto.pb PublicKey
+// protobuf with key type enum in field 1 and key data in field 2.
+//
+// Ed25519 keys are raw 32 bytes, secp256k1 keys are raw 33 bytes compressed points,
+// ECDSA and RSA keys are PKIX encoded, which matches key data used by libp2p.
+// Key type is taken from the Go public key of the key, see GoPublicKey.
+func PublicKeyToLibp2pProto(key bccsp.Key) ([]byte, error) {
+	pub, err := GoPublicKey(key)
+	if err != nil {
+		return nil, err
+	}
+	keyType, raw, err := peerKeyData(pub)
+	if err != nil {
+		return nil, err
+	}
+
+	// protobuf: field 1 varint key type, field 2 length-delimited key data
+	body := make([]byte, 0, len(raw)+8)
+	body = append(body, 0x08, byte(keyType), 0x12)
+	body = appendUvarint(body, uint64(len(raw)))
+	return append(body, raw...), nil
+}
+
+// Libp2pProtoToPublicKey - Parses libp2p crypto.pb PublicKey protobuf.
+// It returns key type and key data in the format of PublicKeyToLibp2pProto.
+// Key data is validated against key type. Unknown fields are skipped.
+func Libp2pProtoToPublicKey(data []byte) (Libp2pKeyType, []byte, error) {
+	var (
+		keyType         uint64
+		raw             []byte
+		hasType, hasKey bool
+	)
+	for len(data) > 0 {
+		tag, n := binary.Uvarint(data)
+		if n <= 0 {
+			return 0, nil, errors.New("Invalid libp2p public key. Malformed field tag.")
+		}
+		data = data[n:]
+		field, wire := tag>>3, tag&7
+		switch wire {
+		case 0:
+			v, n := binary.Uvarint(data)
+			if n <= 0 {
+				return 0, nil, errors.New("Invalid libp2p public key. Malformed varint.")
+			}
+			data = data[n:]
+			if field == 1 {
+				keyType, hasType = v, true
+			}
+		case 2:
+			l, n := binary.Uvarint(data)
+			if n <= 0 || l > uint64(len(data)-n) {
+				return 0, nil, errors.New("Invalid libp2p public key. Malformed length.")
+			}
+			if field == 2 {
+				raw, hasKey = append([]byte{}, data[n:n+int(l)]...), true
+			}
+			data = data[n+int(l):]
+		case 1, 5:
+			// fixed64 and fixed32
+			size := 8
+			if wire == 5 {
+				size = 4
+			}
+			if len(data) < size {
+				return 0, nil, errors.New("Invalid libp2p public key. Truncated fixed field.")
+			}
+			data = data[size:]
+		default:
+			return 0, nil, fmt.Errorf("Invalid libp2p public key. Unsupported wire type %d.", wire)
+		}
+	}
+	if !hasType || !hasKey {
+		return 0, nil, errors.New("Invalid libp2p public key. Key type or data is missing.")
+	}
+
+	t := Libp2pKeyType(keyType)
+	switch t {
+	case Libp2pKeyTypeEd25519:
+		if len(raw) != ed25519PublicKeySize {
+			return 0, nil, fmt.Errorf("Invalid Ed25519 public key size %d", len(raw))
+		}
+	case Libp2pKeyTypeSecp256k1:
+		if _, err := btcec.ParsePubKey(raw, btcec.S256()); err != nil || len(raw) != compressedPublicKeySize {
+			return 0, nil, errors.New("Invalid secp256k1 public key. Expected compressed point.")
+		}
+	case Libp2pKeyTypeRSA, Libp2pKeyTypeECDSA:
+		pub, err := DERToPublicKey(raw)
+		if err != nil {
+			return 0, nil, fmt.Errorf("Failed parsing public key [%s]", err)
+		}
+		if actual, _, _ := peerKeyData(pub); actual != t {
+			return 0, nil, fmt.Errorf("Key type %d does not match key data [%T]", t, pub)
+		}
+	default:
+		return 0, nil, fmt.Errorf("Unsupported libp2p key type %d", keyType)
+	}
+	return t, raw, nil
+}
+
+// peerKeyData - Returns libp2p key type and key data of Go public key.
+func peerKeyData(pub interface{}) (Libp2pKeyType, []byte, error) {
+	switch k := pub.(type) {
+	case ed25519.PublicKey:
+		return Libp2pKeyTypeEd25519, k, nil
+	case *ecdsa.PublicKey:
+		if k.Curve == btcec.S256() {
+			return Libp2pKeyTypeSecp256k1, CompressECDSAPublicKey(k), nil
+		}
+		raw, err := x509.MarshalPKIXPublicKey(k)
+		if err != nil {
+			return 0, nil, fmt.Errorf("Failed marshalling public key [%s]", err)
+		}
+		return Libp2pKeyTypeECDSA, raw, nil
+	case *rsa.PublicKey:
+		raw, err := x509.MarshalPKIXPublicKey(k)
+		if err != nil {
+			return 0, nil, fmt.Errorf("Failed marshalling public key [%s]", err)
+		}
+		return Libp2pKeyTypeRSA, raw, nil
+	}
+	return 0, nil, fmt.Errorf("Unsupported key type [%T]", pub)
+}
+
+// appendUvarint - Appends unsigned varint to buffer.
+func appendUvarint(buf []byte, x uint64) []byte {
+	for x >= 0x80 {
+		buf = append(buf, byte(x)|0x80)
+		x >>= 7
+	}
+	return append(buf, byte(x))
+}
@@ -0,0 +1,153 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ed25519"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp/mocks"
+	"github.com/ipfn/ipfn/pkg/utils/hexutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPublicKeyToPeerID(t *testing.T) {
+	tests := []struct {
+		key    string
+		peerID string
+	}{
+		// Ed25519 key from libp2p peer ID specification
+		{"1ed1e8fae2c4a144b8be8fd4b47bf3d3b34b871c3cacf6010f0e42d474fce27e", "12D3KooWBtg3aaRMjxwedh83aGiUkwSxDwUZkzuJcfaqUmo7R3pq"},
+		// secp256k1 generator point
+		{"0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798", "16Uiu2HAm3cuhhRL2msUuLF62KRSfneFDx94RsuouyW25Ho42cFMq"},
+	}
+	keys := []bccsp.Key{
+		&goKey{pub: ed25519.PublicKey(hexutil.FromString(tests[0].key))},
+		secp256k1Key(t, hexutil.FromString(tests[1].key)),
+	}
+	for i, test := range tests {
+		peerID, err := PublicKeyToPeerID(keys[i])
+		assert.NoError(t, err)
+		assert.Equal(t, test.peerID, peerID)
+	}
+}
+
+func TestPublicKeyToPeerIDECDSA(t *testing.T) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&priv.PublicKey)
+	assert.NoError(t, err)
+
+	// Keys longer than inline limit are hashed with SHA-256
+	peerID, err := PublicKeyToPeerID(&mocks.MockKey{Pvt: true, PK: &mocks.MockKey{BytesValue: der}})
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(peerID, "Qm"))
+}
+
+func TestPublicKeyToPeerIDErrors(t *testing.T) {
+	_, err := PublicKeyToPeerID(nil)
+	assert.Error(t, err)
+	_, err = PublicKeyToPeerID(&mocks.MockKey{Symm: true})
+	assert.Error(t, err)
+	_, err = PublicKeyToPeerID(&mocks.MockKey{BytesValue: []byte{1, 2, 3}})
+	assert.Error(t, err)
+}
+
+func TestLibp2pProto(t *testing.T) {
+	// Serialized keys from libp2p peer ID specification
+	tests := []struct {
+		key     string
+		proto   string
+		keyType Libp2pKeyType
+	}{
+		{"1ed1e8fae2c4a144b8be8fd4b47bf3d3b34b871c3cacf6010f0e42d474fce27e", "080112201ed1e8fae2c4a144b8be8fd4b47bf3d3b34b871c3cacf6010f0e42d474fce27e", Libp2pKeyTypeEd25519},
+		{"037777e994e452c21604f91de093ce415f5432f701dd8cd1a7a6fea0e630bfca99", "08021221037777e994e452c21604f91de093ce415f5432f701dd8cd1a7a6fea0e630bfca99", Libp2pKeyTypeSecp256k1},
+	}
+	keys := []bccsp.Key{
+		&goKey{pub: ed25519.PublicKey(hexutil.FromString(tests[0].key))},
+		secp256k1Key(t, hexutil.FromString(tests[1].key)),
+	}
+	for i, test := range tests {
+		proto, err := PublicKeyToLibp2pProto(keys[i])
+		assert.NoError(t, err)
+		assert.Equal(t, test.proto, hexutil.ToString(proto))
+
+		keyType, raw, err := Libp2pProtoToPublicKey(proto)
+		assert.NoError(t, err)
+		assert.Equal(t, test.keyType, keyType)
+		assert.Equal(t, test.key, hexutil.ToString(raw))
+	}
+
+	// RSA and ECDSA keys are PKIX encoded
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	assert.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	for keyType, pub := range map[Libp2pKeyType]interface{}{Libp2pKeyTypeRSA: &rsaKey.PublicKey, Libp2pKeyTypeECDSA: &ecKey.PublicKey} {
+		der, err := x509.MarshalPKIXPublicKey(pub)
+		assert.NoError(t, err)
+		proto, err := PublicKeyToLibp2pProto(&mocks.MockKey{Pvt: true, PK: &mocks.MockKey{BytesValue: der}})
+		assert.NoError(t, err)
+		parsedType, raw, err := Libp2pProtoToPublicKey(proto)
+		assert.NoError(t, err)
+		assert.Equal(t, keyType, parsedType)
+		assert.Equal(t, der, raw)
+	}
+
+	// Field order does not matter and unknown fields are skipped
+	keyType, raw, err := Libp2pProtoToPublicKey(hexutil.FromString("122037777e994e452c21604f91de093ce415f5432f701dd8cd1a7a6fea0e630bfca9" + "18050801"))
+	assert.NoError(t, err)
+	assert.Equal(t, Libp2pKeyTypeEd25519, keyType)
+	assert.Len(t, raw, 32)
+
+	// Unknown fixed64 and fixed32 fields are skipped
+	keyType, raw, err = Libp2pProtoToPublicKey(hexutil.FromString("190102030405060708" + "0801" + "25010203041220" + tests[0].key))
+	assert.NoError(t, err)
+	assert.Equal(t, Libp2pKeyTypeEd25519, keyType)
+	assert.Equal(t, tests[0].key, hexutil.ToString(raw))
+
+	for _, proto := range []string{
+		"",
+		"0801",
+		"1220" + tests[0].key,
+		"0805" + "1220" + tests[0].key,
+		"0802" + "1220" + tests[0].key,
+		"0801" + "1221" + tests[1].key,
+		"0800" + "1220" + tests[0].key,
+		"08011240" + tests[0].key,
+		"0d010203",
+		"0801" + "1220" + tests[0].key + "19010203",
+		"0b" + "0801" + "1220" + tests[0].key,
+	} {
+		_, _, err := Libp2pProtoToPublicKey(hexutil.FromString(proto))
+		assert.Error(t, err, proto)
+	}
+
+	_, err = PublicKeyToLibp2pProto(nil)
+	assert.Error(t, err)
+	_, err = PublicKeyToLibp2pProto(&mocks.MockKey{BytesValue: []byte{1, 2, 3}})
+	assert.Error(t, err)
+	_, err = PublicKeyToLibp2pProto(&mocks.MockKey{BytesValue: hexutil.FromString(tests[0].key)})
+	assert.Error(t, err)
+}
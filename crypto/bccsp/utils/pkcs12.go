@@ -0,0 +1,72 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/pkcs12"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+)
+
+// ErrIncorrectPassword - Error returned when PKCS#12 bundle password is incorrect.
+var ErrIncorrectPassword = errors.New("Incorrect PKCS#12 password.")
+
+// PKCS12ToKeyAndCert decodes PKCS#12 (.pfx) bundle containing private key and
+// leaf certificate. It returns DER encoded private key with options to import it
+// into BCCSP and DER encoded certificate. ECDSA and RSA keys are supported.
+func PKCS12ToKeyAndCert(data []byte, password []byte) (keyDER []byte, opts bccsp.KeyImportOpts, certDER []byte, err error) {
+	if len(data) == 0 {
+		return nil, nil, nil, errors.New("Invalid PKCS#12 data. It must not be empty.")
+	}
+
+	key, cert, err := pkcs12.Decode(data, string(password))
+	if err == pkcs12.ErrIncorrectPassword {
+		return nil, nil, nil, ErrIncorrectPassword
+	}
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("Failed decoding PKCS#12 [%s]", err)
+	}
+
+	switch k := key.(type) {
+	case *ecdsa.PrivateKey:
+		pub, ok := cert.PublicKey.(*ecdsa.PublicKey)
+		if !ok || pub.Curve != k.Curve || pub.X.Cmp(k.X) != 0 || pub.Y.Cmp(k.Y) != 0 {
+			return nil, nil, nil, errors.New("Private key does not match certificate.")
+		}
+		keyDER, err = PrivateKeyToDER(k)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("Failed marshalling ECDSA private key [%s]", err)
+		}
+		opts = &bccsp.ECDSAPrivateKeyImportOpts{}
+	case *rsa.PrivateKey:
+		pub, ok := cert.PublicKey.(*rsa.PublicKey)
+		if !ok || pub.E != k.E || pub.N.Cmp(k.N) != 0 {
+			return nil, nil, nil, errors.New("Private key does not match certificate.")
+		}
+		keyDER = x509.MarshalPKCS1PrivateKey(k)
+		opts = &bccsp.RSAPrivateKeyImportOpts{}
+	default:
+		return nil, nil, nil, fmt.Errorf("Unsupported private key type [%T]", key)
+	}
+
+	return keyDER, opts, cert.Raw, nil
+}
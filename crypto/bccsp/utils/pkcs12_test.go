@@ -0,0 +1,90 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils_test
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp/swcp"
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+// Bundles in testdata were generated using openssl with password "secret":
+//
+//     openssl pkcs12 -export -inkey key.pem -in cert.pem -out bundle.p12 \
+//         -keypbe PBE-SHA1-3DES -certpbe PBE-SHA1-3DES -macalg sha1
+
+func TestPKCS12ToKeyAndCert(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pkcs12")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	ks, err := swcp.NewFileBasedKeyStore(nil, dir, false)
+	assert.NoError(t, err)
+	csp, err := swcp.NewDefaultSecurityLevelWithKeystore(ks)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		file string
+		opts bccsp.KeyImportOpts
+		sign bccsp.SignerOpts
+	}{
+		{"testdata/ecdsa.p12", &bccsp.ECDSAPrivateKeyImportOpts{}, nil},
+		{"testdata/rsa.p12", &bccsp.RSAPrivateKeyImportOpts{}, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: crypto.SHA256}},
+	}
+	for _, test := range tests {
+		data, err := ioutil.ReadFile(test.file)
+		assert.NoError(t, err)
+
+		keyDER, opts, certDER, err := utils.PKCS12ToKeyAndCert(data, []byte("secret"))
+		assert.NoError(t, err, test.file)
+		assert.IsType(t, test.opts, opts)
+
+		// Import the key and verify it matches the certificate
+		k, err := csp.KeyImport(keyDER, opts)
+		assert.NoError(t, err)
+		assert.True(t, k.Private())
+		cert, err := x509.ParseCertificate(certDER)
+		assert.NoError(t, err)
+		pk, err := csp.KeyImport(cert, &bccsp.X509PublicKeyImportOpts{Temporary: true})
+		assert.NoError(t, err)
+		pub, err := k.PublicKey()
+		assert.NoError(t, err)
+		assert.Equal(t, pk.SKI(), pub.SKI())
+
+		digest := sha256.Sum256([]byte("Hello World"))
+		signature, err := csp.Sign(k, digest[:], test.sign)
+		assert.NoError(t, err)
+		valid, err := csp.Verify(pk, signature, digest[:], test.sign)
+		assert.NoError(t, err)
+		assert.True(t, valid)
+
+		_, _, _, err = utils.PKCS12ToKeyAndCert(data, []byte("wrong"))
+		assert.Equal(t, utils.ErrIncorrectPassword, err)
+	}
+
+	_, _, _, err = utils.PKCS12ToKeyAndCert(nil, []byte("secret"))
+	assert.Error(t, err)
+	_, _, _, err = utils.PKCS12ToKeyAndCert([]byte{0x30, 0x03, 0x02, 0x01, 0x03}, []byte("secret"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,89 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/ed25519"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+	"github.com/ipfn/ipfn/pkg/digest"
+)
+
+// PossessionDomain - Domain separation prefix of proof-of-possession digests.
+const PossessionDomain = "ipfn/bccsp proof-of-possession v1"
+
+// PossessionDigest - Returns digest signed as a proof of possession of a key.
+//
+// Challenge is hashed with SHA-256 framed together with PossessionDomain, see
+// digest.SumFramed, so that a proof is never a signature of arbitrary data
+// chosen by the verifier. RSA proofs are PSS signatures with SHA-256 and salt
+// of the hash length.
+func PossessionDigest(challenge []byte) ([]byte, error) {
+	if len(challenge) == 0 {
+		return nil, errors.New("Invalid challenge. It must not be empty.")
+	}
+	sum, err := digest.SumFramed(digest.FamilySha2, []byte(PossessionDomain), challenge)
+	if err != nil {
+		return nil, err
+	}
+	return sum[:], nil
+}
+
+// VerifyPossession - Verifies proof of possession of private key of pub
+// over challenge, created with ProvePossession of the software CSP.
+//
+// Proofs which are malformed or do not verify, including proofs of other
+// challenges, result in false and no error. Key type is taken from the Go
+// public key of pub, see GoPublicKey.
+func VerifyPossession(pub bccsp.Key, challenge, proof []byte) (bool, error) {
+	if pub == nil {
+		return false, errors.New("Invalid key. It must not be nil.")
+	}
+	if pub.Symmetric() {
+		return false, errors.New("Invalid key. It must not be symmetric.")
+	}
+	if len(proof) == 0 {
+		return false, errors.New("Invalid proof. It must not be empty.")
+	}
+	hash, err := PossessionDigest(challenge)
+	if err != nil {
+		return false, err
+	}
+	key, err := GoPublicKey(pub)
+	if err != nil {
+		return false, err
+	}
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		r, s, err := UnmarshalECDSASignature(proof)
+		if err != nil {
+			return false, nil
+		}
+		return ecdsa.Verify(k, hash, r, s), nil
+	case *rsa.PublicKey:
+		opts := &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash}
+		return rsa.VerifyPSS(k, crypto.SHA256, hash, proof, opts) == nil, nil
+	case ed25519.PublicKey:
+		return ed25519.Verify(k, hash, proof), nil
+	}
+	return false, fmt.Errorf("Unsupported public key type [%T]", key)
+}
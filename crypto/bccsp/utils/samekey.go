@@ -0,0 +1,143 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+	"golang.org/x/crypto/ed25519"
+)
+
+// SamePublicKey - Returns true if keys have the same public key parameters.
+//
+// Keys are compared by curve and point or modulus and exponent instead of
+// their byte representation, so the same key imported from PKIX DER, from
+// certificate or marshalled as SEC1 point compares equal. Private keys are
+// compared by their public key. Symmetric and unparseable keys never compare
+// equal.
+func SamePublicKey(a, b bccsp.Key) bool {
+	x, err := canonicalPublicKey(a)
+	if err != nil {
+		return false
+	}
+	y, err := canonicalPublicKey(b)
+	if err != nil {
+		return false
+	}
+	return x.equal(y)
+}
+
+// canonicalKey - Encoding independent public key parameters.
+type canonicalKey struct {
+	kind     string
+	curve    elliptic.Curve
+	data     []byte
+	exponent int
+}
+
+// equal - Returns true if keys have the same parameters.
+func (x canonicalKey) equal(y canonicalKey) bool {
+	if x.kind != y.kind || !bytes.Equal(x.data, y.data) {
+		return false
+	}
+	// Curve is unknown for keys encoded as bare points
+	if x.curve != nil && y.curve != nil && x.curve.Params().Name != y.curve.Params().Name {
+		return false
+	}
+	return x.exponent == y.exponent
+}
+
+// canonicalPublicKey - Parses public key parameters from key bytes.
+// ECDSA points are canonicalized to their SEC1 compressed form.
+func canonicalPublicKey(key bccsp.Key) (canonicalKey, error) {
+	if key == nil {
+		return canonicalKey{}, errors.New("Invalid key. It must not be nil.")
+	}
+	if key.Symmetric() {
+		return canonicalKey{}, errors.New("Invalid key. It must not be symmetric.")
+	}
+	if key.Private() {
+		var err error
+		key, err = key.PublicKey()
+		if err != nil {
+			return canonicalKey{}, fmt.Errorf("Failed getting public key [%s]", err)
+		}
+	}
+	raw, err := key.Bytes()
+	if err != nil {
+		return canonicalKey{}, fmt.Errorf("Failed marshalling public key [%s]", err)
+	}
+	switch {
+	case len(raw) == ed25519PublicKeySize:
+		return canonicalKey{kind: "ed25519", data: raw}, nil
+	case len(raw)%2 == 1 && (raw[0] == 0x02 || raw[0] == 0x03):
+		return canonicalKey{kind: "ecdsa", data: raw}, nil
+	case len(raw)%2 == 1 && raw[0] == 0x04:
+		size := (len(raw) - 1) / 2
+		point := make([]byte, 1+size)
+		point[0] = 0x02 | raw[len(raw)-1]&1
+		copy(point[1:], raw[1:1+size])
+		return canonicalKey{kind: "ecdsa", data: point}, nil
+	}
+	pub, err := DERToPublicKey(raw)
+	if err != nil {
+		return canonicalKey{}, fmt.Errorf("Failed parsing public key [%s]", err)
+	}
+	return canonicalGoPublicKey(pub)
+}
+
+// canonicalGoPublicKey - Returns parameters of Go public key.
+func canonicalGoPublicKey(pub interface{}) (canonicalKey, error) {
+	switch k := pub.(type) {
+	case *ecdsa.PublicKey:
+		return canonicalKey{kind: "ecdsa", curve: k.Curve, data: CompressECDSAPublicKey(k)}, nil
+	case *rsa.PublicKey:
+		return canonicalKey{kind: "rsa", data: k.N.Bytes(), exponent: k.E}, nil
+	case ed25519.PublicKey:
+		return canonicalKey{kind: "ed25519", data: k}, nil
+	}
+	return canonicalKey{}, fmt.Errorf("Unsupported public key type [%T]", pub)
+}
+
+// IsKeyPair - Returns true if pub is the public half of priv.
+//
+// Public key derived from priv is compared with pub by its parameters, as in
+// SamePublicKey, which catches mix-ups of provisioned key pairs without
+// access to a signer. Error is returned when priv is not a private key,
+// pub is not a public key or their parameters cannot be parsed.
+func IsKeyPair(priv, pub bccsp.Key) (bool, error) {
+	if priv == nil || !priv.Private() {
+		return false, errors.New("Invalid private key. It must be an asymmetric private key.")
+	}
+	if pub == nil || pub.Private() {
+		return false, errors.New("Invalid public key. It must not be a private key.")
+	}
+	x, err := canonicalPublicKey(priv)
+	if err != nil {
+		return false, err
+	}
+	y, err := canonicalPublicKey(pub)
+	if err != nil {
+		return false, err
+	}
+	return x.equal(y), nil
+}
@@ -0,0 +1,203 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp/mocks"
+	bsigner "github.com/ipfn/ipfn/pkg/crypto/bccsp/signer"
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp/swcp"
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSamePublicKey(t *testing.T) {
+	csp, err := swcp.NewDefaultSecurityLevelWithKeystore(swcp.NewDummyKeyStore())
+	assert.NoError(t, err)
+
+	for _, opts := range []bccsp.KeyGenOpts{
+		&bccsp.ECDSAP256KeyGenOpts{Temporary: true},
+		&bccsp.RSA2048KeyGenOpts{Temporary: true},
+	} {
+		k, err := csp.KeyGen(opts)
+		assert.NoError(t, err)
+		pk, err := k.PublicKey()
+		assert.NoError(t, err)
+		signer, err := bsigner.New(csp, k)
+		assert.NoError(t, err)
+
+		// From PKIX DER
+		der, err := pk.Bytes()
+		assert.NoError(t, err)
+		pub, err := utils.DERToPublicKey(der)
+		assert.NoError(t, err)
+
+		// From certificate
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(1),
+			Subject:      pkix.Name{CommonName: "test"},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+		}
+		certDER, err := x509.CreateCertificate(rand.Reader, template, template, pub, signer)
+		assert.NoError(t, err)
+		cert, err := x509.ParseCertificate(certDER)
+		assert.NoError(t, err)
+		fromCert, err := csp.KeyImport(cert, &bccsp.X509PublicKeyImportOpts{Temporary: true})
+		assert.NoError(t, err)
+
+		// From Go public key decoded from COSE key
+		cose, err := utils.PublicKeyToCOSEKey(pk)
+		assert.NoError(t, err)
+		fromCOSE, _, err := utils.COSEKeyToKeyImportOpts(cose)
+		assert.NoError(t, err)
+		var fromGo, fromPKIX bccsp.Key
+		switch key := fromCOSE.(type) {
+		case *ecdsa.PublicKey:
+			fromGo, err = csp.KeyImport(key, &bccsp.ECDSAGoPublicKeyImportOpts{Temporary: true})
+			assert.NoError(t, err)
+			fromPKIX, err = csp.KeyImport(der, &bccsp.ECDSAPKIXPublicKeyImportOpts{Temporary: true})
+			assert.NoError(t, err)
+		default:
+			fromGo, err = csp.KeyImport(key, &bccsp.RSAGoPublicKeyImportOpts{Temporary: true})
+			assert.NoError(t, err)
+			fromPKIX = fromGo
+		}
+
+		for _, key := range []bccsp.Key{k, pk, fromCert, fromGo, fromPKIX} {
+			assert.True(t, utils.SamePublicKey(pk, key))
+			assert.True(t, utils.SamePublicKey(key, fromCert))
+		}
+	}
+
+	// Point formats are ignored
+	k, err := csp.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	for _, format := range []swcp.PointFormat{swcp.PointCompressed, swcp.PointUncompressed} {
+		point, err := swcp.MarshalECDSAPublicKey(k, format)
+		assert.NoError(t, err)
+		assert.True(t, utils.SamePublicKey(k, &mocks.MockKey{BytesValue: point}))
+	}
+
+	// Different keys
+	other, err := csp.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	assert.False(t, utils.SamePublicKey(k, other))
+	p384, err := csp.KeyGen(&bccsp.ECDSAP384KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	assert.False(t, utils.SamePublicKey(k, p384))
+	rsaKey, err := csp.KeyGen(&bccsp.RSA2048KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	assert.False(t, utils.SamePublicKey(k, rsaKey))
+	aesKey, err := csp.KeyGen(&bccsp.AESKeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	assert.False(t, utils.SamePublicKey(aesKey, aesKey))
+	assert.False(t, utils.SamePublicKey(nil, k))
+	assert.False(t, utils.SamePublicKey(k, nil))
+}
+
+func TestKeyMatchesCertificate(t *testing.T) {
+	csp, err := swcp.NewDefaultSecurityLevelWithKeystore(swcp.NewDummyKeyStore())
+	assert.NoError(t, err)
+
+	for _, opts := range []bccsp.KeyGenOpts{
+		&bccsp.ECDSAP256KeyGenOpts{Temporary: true},
+		&bccsp.RSA2048KeyGenOpts{Temporary: true},
+	} {
+		k, err := csp.KeyGen(opts)
+		assert.NoError(t, err)
+		certDER, err := bsigner.SelfSignedCert(csp, k, pkix.Name{CommonName: "test"}, time.Hour, nil)
+		assert.NoError(t, err)
+
+		pk, err := k.PublicKey()
+		assert.NoError(t, err)
+		for _, key := range []bccsp.Key{k, pk} {
+			match, err := utils.KeyMatchesCertificate(key, certDER)
+			assert.NoError(t, err)
+			assert.True(t, match)
+		}
+
+		// Mismatched pairs
+		for _, opts := range []bccsp.KeyGenOpts{
+			&bccsp.ECDSAP256KeyGenOpts{Temporary: true},
+			&bccsp.RSA2048KeyGenOpts{Temporary: true},
+			&bccsp.AESKeyGenOpts{Temporary: true},
+		} {
+			other, err := csp.KeyGen(opts)
+			assert.NoError(t, err)
+			match, err := utils.KeyMatchesCertificate(other, certDER)
+			assert.NoError(t, err)
+			assert.False(t, match)
+		}
+
+		_, err = utils.KeyMatchesCertificate(k, certDER[:len(certDER)-1])
+		assert.Error(t, err)
+		_, err = utils.KeyMatchesCertificate(nil, certDER)
+		assert.Error(t, err)
+	}
+}
+
+func TestIsKeyPair(t *testing.T) {
+	csp, err := swcp.NewDefaultSecurityLevelWithKeystore(swcp.NewDummyKeyStore())
+	assert.NoError(t, err)
+
+	for _, opts := range []bccsp.KeyGenOpts{
+		&bccsp.ECDSAP256KeyGenOpts{Temporary: true},
+		&bccsp.ED25519KeyGenOpts{Temporary: true},
+		&bccsp.RSA2048KeyGenOpts{Temporary: true},
+	} {
+		k, err := csp.KeyGen(opts)
+		assert.NoError(t, err)
+		pk, err := k.PublicKey()
+		assert.NoError(t, err)
+		other, err := csp.KeyGen(opts)
+		assert.NoError(t, err)
+		otherPk, err := other.PublicKey()
+		assert.NoError(t, err)
+
+		ok, err := utils.IsKeyPair(k, pk)
+		assert.NoError(t, err)
+		assert.True(t, ok, "%T", opts)
+		ok, err = utils.IsKeyPair(k, otherPk)
+		assert.NoError(t, err)
+		assert.False(t, ok, "%T", opts)
+
+		// Arguments in wrong order
+		_, err = utils.IsKeyPair(pk, k)
+		assert.Error(t, err)
+	}
+
+	aes, err := csp.KeyGen(&bccsp.AES256KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	ec, err := csp.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	ecPk, err := ec.PublicKey()
+	assert.NoError(t, err)
+	_, err = utils.IsKeyPair(aes, ecPk)
+	assert.Error(t, err)
+	_, err = utils.IsKeyPair(nil, ecPk)
+	assert.Error(t, err)
+	_, err = utils.IsKeyPair(ec, nil)
+	assert.Error(t, err)
+}
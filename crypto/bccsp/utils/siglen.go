@@ -0,0 +1,140 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+)
+
+// SignatureEncoding - Signature encoding type.
+type SignatureEncoding int
+
+const (
+	// SignatureDER - ASN.1 DER encoded signature.
+	// ECDSA signatures are encoded as SEQUENCE of R and S integers.
+	SignatureDER SignatureEncoding = iota
+
+	// SignatureRaw - Raw signature encoding.
+	// ECDSA signatures are encoded as fixed size R || S concatenation.
+	SignatureRaw
+)
+
+// String - Returns signature encoding name.
+func (enc SignatureEncoding) String() string {
+	switch enc {
+	case SignatureDER:
+		return "DER"
+	case SignatureRaw:
+		return "raw"
+	default:
+		return fmt.Sprintf("SignatureEncoding(%d)", int(enc))
+	}
+}
+
+// ExpectedSignatureLength returns the length of a signature produced with given key
+// in given encoding. For raw ECDSA and RSA signatures the length is fixed, for DER
+// encoded ECDSA signatures returned length is the maximum length of a signature.
+func ExpectedSignatureLength(key bccsp.Key, encoding SignatureEncoding) (int, error) {
+	if key == nil {
+		return 0, errors.New("Invalid key. It must not be nil.")
+	}
+	if key.Symmetric() {
+		return 0, errors.New("Invalid key. It must not be symmetric.")
+	}
+	if key.Private() {
+		var err error
+		key, err = key.PublicKey()
+		if err != nil {
+			return 0, fmt.Errorf("Failed getting public key [%s]", err)
+		}
+	}
+	raw, err := key.Bytes()
+	if err != nil {
+		return 0, fmt.Errorf("Failed marshalling public key [%s]", err)
+	}
+	pub, err := DERToPublicKey(raw)
+	if err != nil {
+		return 0, fmt.Errorf("Failed parsing public key [%s]", err)
+	}
+	switch pub := pub.(type) {
+	case *ecdsa.PublicKey:
+		bitSize := pub.Curve.Params().N.BitLen()
+		switch encoding {
+		case SignatureRaw:
+			return 2 * ((bitSize + 7) / 8), nil
+		case SignatureDER:
+			// Integers are positive so one more byte is needed
+			// when the most significant bit of the value is set.
+			intLen := bitSize/8 + 1
+			intTLV := 1 + derLengthSize(intLen) + intLen
+			return 1 + derLengthSize(2*intTLV) + 2*intTLV, nil
+		default:
+			return 0, fmt.Errorf("Unsupported signature encoding [%s]", encoding)
+		}
+	case *rsa.PublicKey:
+		switch encoding {
+		case SignatureRaw, SignatureDER:
+			return pub.Size(), nil
+		default:
+			return 0, fmt.Errorf("Unsupported signature encoding [%s]", encoding)
+		}
+	default:
+		return 0, fmt.Errorf("Unsupported key type [%T]", pub)
+	}
+}
+
+// ECDSASignatureDERLength returns the exact length of ASN.1 DER encoding of
+// signature with given R and S values, as produced by MarshalECDSASignature,
+// which allows preallocating buffers without encoding the signature. Integers
+// take an additional byte when the most significant bit of their value is set
+// and zero is encoded as a single byte. It returns 0 if r or s is nil.
+func ECDSASignatureDERLength(r, s *big.Int) int {
+	if r == nil || s == nil {
+		return 0
+	}
+	rLen, sLen := derIntegerLength(r), derIntegerLength(s)
+	body := 1 + derLengthSize(rLen) + rLen + 1 + derLengthSize(sLen) + sLen
+	return 1 + derLengthSize(body) + body
+}
+
+// derIntegerLength returns the length of ASN.1 DER encoded integer contents,
+// the minimal two's complement representation of n.
+func derIntegerLength(n *big.Int) int {
+	if n.Sign() < 0 {
+		// Minimal encoding of -m-1 has the same length as of m
+		// with its sign bit set.
+		m := new(big.Int).Not(n)
+		return m.BitLen()/8 + 1
+	}
+	return n.BitLen()/8 + 1
+}
+
+// derLengthSize returns the size of ASN.1 DER encoded length.
+func derLengthSize(length int) int {
+	size := 1
+	if length >= 0x80 {
+		for ; length > 0; length >>= 8 {
+			size++
+		}
+	}
+	return size
+}
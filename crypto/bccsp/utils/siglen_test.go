@@ -0,0 +1,157 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"math/big"
+	"testing"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpectedSignatureLengthECDSA(t *testing.T) {
+	tests := []struct {
+		curve elliptic.Curve
+		raw   int
+		der   int
+	}{
+		{elliptic.P256(), 64, 72},
+		{elliptic.P384(), 96, 104},
+		{elliptic.P521(), 132, 139},
+	}
+	for _, test := range tests {
+		priv, err := ecdsa.GenerateKey(test.curve, rand.Reader)
+		assert.NoError(t, err)
+		pkRaw, err := x509.MarshalPKIXPublicKey(&priv.PublicKey)
+		assert.NoError(t, err)
+		key := &mocks.MockKey{BytesValue: pkRaw}
+
+		n, err := ExpectedSignatureLength(key, SignatureRaw)
+		assert.NoError(t, err)
+		assert.Equal(t, test.raw, n)
+
+		n, err = ExpectedSignatureLength(key, SignatureDER)
+		assert.NoError(t, err)
+		assert.Equal(t, test.der, n)
+
+		digest := sha256.Sum256([]byte("Hello World"))
+		for i := 0; i < 16; i++ {
+			r, s, err := ecdsa.Sign(rand.Reader, priv, digest[:])
+			assert.NoError(t, err)
+			sig, err := MarshalECDSASignature(r, s)
+			assert.NoError(t, err)
+			assert.True(t, len(sig) <= test.der)
+		}
+
+		// Private key is resolved to its public key
+		n, err = ExpectedSignatureLength(&mocks.MockKey{Pvt: true, PK: key}, SignatureRaw)
+		assert.NoError(t, err)
+		assert.Equal(t, test.raw, n)
+	}
+}
+
+func TestExpectedSignatureLengthRSA(t *testing.T) {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	pkRaw, err := x509.MarshalPKIXPublicKey(&priv.PublicKey)
+	assert.NoError(t, err)
+	key := &mocks.MockKey{BytesValue: pkRaw}
+
+	n, err := ExpectedSignatureLength(key, SignatureRaw)
+	assert.NoError(t, err)
+	assert.Equal(t, 256, n)
+
+	n, err = ExpectedSignatureLength(key, SignatureDER)
+	assert.NoError(t, err)
+	assert.Equal(t, 256, n)
+}
+
+func TestExpectedSignatureLengthErrors(t *testing.T) {
+	_, err := ExpectedSignatureLength(nil, SignatureRaw)
+	assert.Error(t, err)
+
+	_, err = ExpectedSignatureLength(&mocks.MockKey{Symm: true}, SignatureRaw)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "It must not be symmetric")
+
+	_, err = ExpectedSignatureLength(&mocks.MockKey{BytesValue: []byte{0x30}}, SignatureRaw)
+	assert.Error(t, err)
+
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	pkRaw, err := x509.MarshalPKIXPublicKey(&priv.PublicKey)
+	assert.NoError(t, err)
+	_, err = ExpectedSignatureLength(&mocks.MockKey{BytesValue: pkRaw}, SignatureEncoding(10))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Unsupported signature encoding")
+}
+
+func TestECDSASignatureDERLength(t *testing.T) {
+	values := []*big.Int{
+		big.NewInt(0),
+		big.NewInt(1),
+		big.NewInt(0x7f),
+		big.NewInt(0x80),
+		big.NewInt(0xff),
+		big.NewInt(0x100),
+		big.NewInt(0x7fff),
+		big.NewInt(0x8000),
+		// 32 and 66 byte values with and without the high bit set
+		new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 255), big.NewInt(1)),
+		new(big.Int).Lsh(big.NewInt(1), 255),
+		new(big.Int).Lsh(big.NewInt(1), 520),
+		// Leading zero bytes of a 32 byte value
+		new(big.Int).SetBytes([]byte{0x00, 0x00, 0x01, 0x02}),
+		// Long form lengths
+		new(big.Int).Lsh(big.NewInt(1), 1100),
+	}
+	for _, r := range values {
+		for _, s := range values {
+			raw, err := MarshalECDSASignature(r, s)
+			assert.NoError(t, err)
+			assert.Equal(t, len(raw), ECDSASignatureDERLength(r, s), "%x %x", r, s)
+		}
+	}
+
+	// Negative values follow two's complement rules
+	for _, v := range []int64{-1, -0x80, -0x81, -0x8000, -0x8001} {
+		r := big.NewInt(v)
+		raw, err := asn1.Marshal(ECDSASignature{r, big.NewInt(1)})
+		assert.NoError(t, err)
+		assert.Equal(t, len(raw), ECDSASignatureDERLength(r, big.NewInt(1)), "%d", v)
+	}
+
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	digest := sha256.Sum256([]byte("message"))
+	for i := 0; i < 100; i++ {
+		r, s, err := ecdsa.Sign(rand.Reader, priv, digest[:])
+		assert.NoError(t, err)
+		raw, err := MarshalECDSASignature(r, s)
+		assert.NoError(t, err)
+		assert.Equal(t, len(raw), ECDSASignatureDERLength(r, s))
+	}
+
+	assert.Equal(t, 0, ECDSASignatureDERLength(nil, big.NewInt(1)))
+}
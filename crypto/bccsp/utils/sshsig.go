@@ -0,0 +1,155 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// SSH signature algorithm names (RFC 5656, RFC 8332, RFC 8709).
+const (
+	SSHECDSAP256 = "ecdsa-sha2-nistp256"
+	SSHECDSAP384 = "ecdsa-sha2-nistp384"
+	SSHECDSAP521 = "ecdsa-sha2-nistp521"
+	SSHEd25519   = "ssh-ed25519"
+	SSHRSA       = "ssh-rsa"
+	SSHRSASHA256 = "rsa-sha2-256"
+	SSHRSASHA512 = "rsa-sha2-512"
+)
+
+// SSHSigToRaw - Converts SSH signature blob, string algorithm name followed
+// by string signature, into signature accepted by BCCSP Verify and returns
+// it with the algorithm name. ECDSA signatures, mpint R and S, are converted
+// to ASN.1 DER. Ed25519 and RSA signatures are returned as is.
+//
+// Digest to verify depends on the algorithm, e.g. SHA-256 for
+// ecdsa-sha2-nistp256. Notice that signatures created by `ssh-keygen -Y sign`
+// are made over SSHSIG envelope of the message, not the message itself.
+func SSHSigToRaw(blob []byte) (raw []byte, alg string, err error) {
+	name, rest, err := sshString(blob)
+	if err != nil {
+		return nil, "", fmt.Errorf("Failed parsing SSH signature algorithm [%s]", err)
+	}
+	sig, rest, err := sshString(rest)
+	if err != nil {
+		return nil, "", fmt.Errorf("Failed parsing SSH signature [%s]", err)
+	}
+	if len(rest) != 0 {
+		return nil, "", errors.New("Invalid SSH signature. Unexpected trailing data.")
+	}
+	alg = string(name)
+	switch alg {
+	case SSHECDSAP256, SSHECDSAP384, SSHECDSAP521:
+		r, rest, err := sshMpint(sig)
+		if err != nil {
+			return nil, "", fmt.Errorf("Failed parsing ECDSA signature R [%s]", err)
+		}
+		s, rest, err := sshMpint(rest)
+		if err != nil {
+			return nil, "", fmt.Errorf("Failed parsing ECDSA signature S [%s]", err)
+		}
+		if len(rest) != 0 {
+			return nil, "", errors.New("Invalid ECDSA signature. Unexpected trailing data.")
+		}
+		raw, err = MarshalECDSASignature(r, s)
+		if err != nil {
+			return nil, "", err
+		}
+		return raw, alg, nil
+	case SSHEd25519:
+		if len(sig) != 64 {
+			return nil, "", fmt.Errorf("Invalid Ed25519 signature length %d", len(sig))
+		}
+		return Clone(sig), alg, nil
+	case SSHRSA, SSHRSASHA256, SSHRSASHA512:
+		return Clone(sig), alg, nil
+	default:
+		return nil, "", fmt.Errorf("Unsupported SSH signature algorithm %q", alg)
+	}
+}
+
+// RawToSSHSig - Converts signature created by BCCSP Sign into SSH signature
+// blob of algorithm alg. It is the reverse of SSHSigToRaw.
+func RawToSSHSig(raw []byte, alg string) ([]byte, error) {
+	var sig []byte
+	switch alg {
+	case SSHECDSAP256, SSHECDSAP384, SSHECDSAP521:
+		r, s, err := UnmarshalECDSASignature(raw)
+		if err != nil {
+			return nil, fmt.Errorf("Failed unmarshalling ECDSA signature [%s]", err)
+		}
+		sig = appendSSHMpint(appendSSHMpint(nil, r), s)
+	case SSHEd25519:
+		if len(raw) != 64 {
+			return nil, fmt.Errorf("Invalid Ed25519 signature length %d", len(raw))
+		}
+		sig = raw
+	case SSHRSA, SSHRSASHA256, SSHRSASHA512:
+		if len(raw) == 0 {
+			return nil, errors.New("Invalid RSA signature. It must not be empty.")
+		}
+		sig = raw
+	default:
+		return nil, fmt.Errorf("Unsupported SSH signature algorithm %q", alg)
+	}
+	return appendSSHString(appendSSHString(nil, []byte(alg)), sig), nil
+}
+
+// sshString - Parses SSH string, uint32 length followed by bytes.
+func sshString(b []byte) (value, rest []byte, err error) {
+	if len(b) < 4 {
+		return nil, nil, errors.New("string length is truncated")
+	}
+	n := binary.BigEndian.Uint32(b)
+	if uint64(n) > uint64(len(b)-4) {
+		return nil, nil, errors.New("string is truncated")
+	}
+	return b[4 : 4+n], b[4+n:], nil
+}
+
+// sshMpint - Parses SSH non-negative mpint in minimal encoding.
+func sshMpint(b []byte) (n *big.Int, rest []byte, err error) {
+	value, rest, err := sshString(b)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(value) > 0 && value[0]&0x80 != 0 {
+		return nil, nil, errors.New("mpint is negative")
+	}
+	if len(value) > 1 && value[0] == 0 && value[1]&0x80 == 0 {
+		return nil, nil, errors.New("mpint is not minimally encoded")
+	}
+	return new(big.Int).SetBytes(value), rest, nil
+}
+
+// appendSSHString - Appends SSH string.
+func appendSSHString(b, value []byte) []byte {
+	var n [4]byte
+	binary.BigEndian.PutUint32(n[:], uint32(len(value)))
+	return append(append(b, n[:]...), value...)
+}
+
+// appendSSHMpint - Appends non-negative integer as SSH mpint.
+func appendSSHMpint(b []byte, n *big.Int) []byte {
+	value := n.Bytes()
+	if len(value) > 0 && value[0]&0x80 != 0 {
+		value = append([]byte{0}, value...)
+	}
+	return appendSSHString(b, value)
+}
@@ -0,0 +1,106 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ed25519"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestSSHSigToRaw(t *testing.T) {
+	data := []byte("signed artifact")
+
+	for _, tc := range []struct {
+		curve elliptic.Curve
+		alg   string
+		sum   func([]byte) []byte
+	}{
+		{elliptic.P256(), SSHECDSAP256, func(b []byte) []byte { h := sha256.Sum256(b); return h[:] }},
+		{elliptic.P384(), SSHECDSAP384, func(b []byte) []byte { h := sha512.Sum384(b); return h[:] }},
+		{elliptic.P521(), SSHECDSAP521, func(b []byte) []byte { h := sha512.Sum512(b); return h[:] }},
+	} {
+		priv, err := ecdsa.GenerateKey(tc.curve, rand.Reader)
+		assert.NoError(t, err)
+		signer, err := ssh.NewSignerFromKey(priv)
+		assert.NoError(t, err)
+		sshSig, err := signer.Sign(rand.Reader, data)
+		assert.NoError(t, err)
+		blob := ssh.Marshal(sshSig)
+
+		raw, alg, err := SSHSigToRaw(blob)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.alg, alg)
+		assert.True(t, ecdsa.VerifyASN1(&priv.PublicKey, tc.sum(data), raw), alg)
+
+		back, err := RawToSSHSig(raw, alg)
+		assert.NoError(t, err)
+		assert.Equal(t, blob, back, alg)
+		parsed := new(ssh.Signature)
+		assert.NoError(t, ssh.Unmarshal(back, parsed))
+		assert.NoError(t, signer.PublicKey().Verify(data, parsed), alg)
+	}
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	signer, err := ssh.NewSignerFromKey(priv)
+	assert.NoError(t, err)
+	sshSig, err := signer.Sign(rand.Reader, data)
+	assert.NoError(t, err)
+	raw, alg, err := SSHSigToRaw(ssh.Marshal(sshSig))
+	assert.NoError(t, err)
+	assert.Equal(t, SSHEd25519, alg)
+	assert.True(t, ed25519.Verify(pub, data, raw))
+	back, err := RawToSSHSig(raw, alg)
+	assert.NoError(t, err)
+	assert.Equal(t, ssh.Marshal(sshSig), back)
+}
+
+func TestSSHSigToRawErrors(t *testing.T) {
+	r := appendSSHMpint(nil, big.NewInt(1))
+	for _, blob := range [][]byte{
+		nil,
+		{0, 0, 0, 9, 's'},
+		appendSSHString(appendSSHString(nil, []byte("ssh-dss")), []byte{1}),
+		appendSSHString(appendSSHString(nil, []byte(SSHEd25519)), []byte{1}),
+		// missing S
+		appendSSHString(appendSSHString(nil, []byte(SSHECDSAP256)), r),
+		// negative R
+		appendSSHString(appendSSHString(nil, []byte(SSHECDSAP256)), appendSSHString(appendSSHString(nil, []byte{0x80}), []byte{1})),
+		// non-minimal R
+		appendSSHString(appendSSHString(nil, []byte(SSHECDSAP256)), appendSSHString(appendSSHString(nil, []byte{0, 1}), []byte{1})),
+		// trailing data
+		append(appendSSHString(appendSSHString(nil, []byte(SSHECDSAP256)), append(r, r...)), 0),
+	} {
+		_, _, err := SSHSigToRaw(blob)
+		assert.Error(t, err, "%x", blob)
+	}
+
+	_, err := RawToSSHSig([]byte{1, 2, 3}, SSHECDSAP256)
+	assert.Error(t, err)
+	_, err = RawToSSHSig(make([]byte, 64), "ssh-dss")
+	assert.Error(t, err)
+	_, err = RawToSSHSig(make([]byte, 63), SSHEd25519)
+	assert.Error(t, err)
+}
@@ -0,0 +1,281 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
+)
+
+// Threshold ECDSA scheme with a trusted dealer.
+//
+// The dealer splits private key d using Shamir secret sharing with polynomial
+// of degree t-1, participant i receives key share d_i = f(i) (SplitECDSAKey).
+//
+// For every signature the dealer issues one-time presignatures
+// (NewECDSAPresignatures): it picks random nonce k, computes r = (kG).x mod n
+// and shares k^-1 using polynomial a and k^-1 * d using polynomial c, both of
+// degree t-1. Participant i receives r, a_i = a(i) and correction term
+// m_i = c(i) - a_i * d_i binding the presignature to its key share.
+//
+// Participant i computes partial signature s_i = a_i * z + r * (a_i * d_i + m_i),
+// which equals a_i * z + r * c_i, where z is the digest (PartialSign).
+// Any t partial signatures are combined using Lagrange interpolation at zero
+// into s = k^-1 * (z + r * d) (CombinePartialSignatures).
+//
+// Presignatures MUST NOT be reused, reusing nonce leaks the private key.
+// The dealer learns the private key, fewer than t participants learn nothing.
+
+// ECDSAPartialSignature - Partial ECDSA signature produced by a participant.
+type ECDSAPartialSignature struct {
+	Index int
+	R, S  *big.Int
+}
+
+// MarshalECDSAPartialSignature - Marshals partial signature in ASN.1 DER.
+func MarshalECDSAPartialSignature(index int, r, s *big.Int) ([]byte, error) {
+	return asn1.Marshal(ECDSAPartialSignature{index, r, s})
+}
+
+// UnmarshalECDSAPartialSignature - Unmarshals partial signature from ASN.1 DER.
+func UnmarshalECDSAPartialSignature(raw []byte) (*ECDSAPartialSignature, error) {
+	sig := new(ECDSAPartialSignature)
+	rest, err := asn1.Unmarshal(raw, sig)
+	if err != nil {
+		return nil, fmt.Errorf("failed unmashalling partial signature [%s]", err)
+	}
+	if len(rest) != 0 {
+		return nil, errors.New("invalid partial signature, trailing data")
+	}
+	if sig.Index <= 0 {
+		return nil, errors.New("invalid partial signature, participant index must be positive")
+	}
+	if sig.R == nil || sig.S == nil || sig.R.Sign() <= 0 || sig.S.Sign() < 0 {
+		return nil, errors.New("invalid partial signature, R must be positive and S must not be negative")
+	}
+	return sig, nil
+}
+
+// SplitECDSAKey - Splits private key into shares, any threshold of them
+// are required to sign. Share of participant with index i is at i-1.
+func SplitECDSAKey(priv *ecdsa.PrivateKey, threshold, participants int) ([]*ecdsa.PrivateKey, error) {
+	if priv == nil {
+		return nil, errors.New("Invalid key. It must not be nil.")
+	}
+	if err := validateThreshold(priv.Curve, threshold, participants); err != nil {
+		return nil, err
+	}
+	f, err := randomPolynomial(priv.Curve, priv.D, threshold)
+	if err != nil {
+		return nil, err
+	}
+	shares := make([]*ecdsa.PrivateKey, participants)
+	for i := range shares {
+		d := evalPolynomial(priv.Curve, f, i+1)
+		if d.Sign() == 0 {
+			return nil, errors.New("failed splitting key, zero share")
+		}
+		share := &ecdsa.PrivateKey{D: d}
+		share.Curve = priv.Curve
+		share.X, share.Y = priv.Curve.ScalarBaseMult(d.Bytes())
+		shares[i] = share
+	}
+	return shares, nil
+}
+
+// NewECDSAPresignatures - Creates one-time presignatures for participants
+// holding given key shares. Presignature of participant with index i is at i-1.
+func NewECDSAPresignatures(priv *ecdsa.PrivateKey, shares []*ecdsa.PrivateKey, threshold int) ([]*bccsp.ECDSAPartialSignOpts, error) {
+	if priv == nil {
+		return nil, errors.New("Invalid key. It must not be nil.")
+	}
+	if err := validateThreshold(priv.Curve, threshold, len(shares)); err != nil {
+		return nil, err
+	}
+	n := priv.Params().N
+	var k, r *big.Int
+	for r == nil || r.Sign() == 0 {
+		var err error
+		k, err = randomScalar(priv.Curve)
+		if err != nil {
+			return nil, err
+		}
+		r, _ = priv.Curve.ScalarBaseMult(k.Bytes())
+		r.Mod(r, n)
+	}
+	kInv := new(big.Int).ModInverse(k, n)
+	a, err := randomPolynomial(priv.Curve, kInv, threshold)
+	if err != nil {
+		return nil, err
+	}
+	c, err := randomPolynomial(priv.Curve, new(big.Int).Mod(new(big.Int).Mul(kInv, priv.D), n), threshold)
+	if err != nil {
+		return nil, err
+	}
+	presigs := make([]*bccsp.ECDSAPartialSignOpts, len(shares))
+	for i, share := range shares {
+		if share == nil || share.D == nil {
+			return nil, fmt.Errorf("Invalid key share at index %d. It must not be nil.", i+1)
+		}
+		ai := evalPolynomial(priv.Curve, a, i+1)
+		mi := new(big.Int).Mul(ai, share.D)
+		mi.Sub(evalPolynomial(priv.Curve, c, i+1), mi)
+		mi.Mod(mi, n)
+		presigs[i] = &bccsp.ECDSAPartialSignOpts{
+			Index:      i + 1,
+			R:          new(big.Int).Set(r),
+			NonceShare: ai,
+			Correction: mi,
+		}
+	}
+	return presigs, nil
+}
+
+// CombinePartialSignatures - Combines partial signatures into DER encoded
+// low-S ECDSA signature. At least threshold partial signatures with distinct
+// indices and equal R are required. Combined signature is verified against
+// public key and digest so invalid partial signatures are detected.
+func CombinePartialSignatures(pub *ecdsa.PublicKey, digest []byte, threshold int, partials [][]byte) ([]byte, error) {
+	if pub == nil {
+		return nil, errors.New("Invalid public key. It must not be nil.")
+	}
+	if threshold < 1 {
+		return nil, fmt.Errorf("invalid threshold %d, it must be positive", threshold)
+	}
+	if len(partials) < threshold {
+		return nil, fmt.Errorf("not enough partial signatures, got %d, threshold is %d", len(partials), threshold)
+	}
+	n := pub.Params().N
+	sigs := make([]*ECDSAPartialSignature, threshold)
+	indices := make([]int, threshold)
+	seen := make(map[int]bool, threshold)
+	for i := range sigs {
+		sig, err := UnmarshalECDSAPartialSignature(partials[i])
+		if err != nil {
+			return nil, err
+		}
+		if big.NewInt(int64(sig.Index)).Cmp(n) >= 0 {
+			return nil, fmt.Errorf("invalid participant index %d", sig.Index)
+		}
+		if seen[sig.Index] {
+			return nil, fmt.Errorf("duplicate partial signature of participant %d", sig.Index)
+		}
+		if i > 0 && sig.R.Cmp(sigs[0].R) != 0 {
+			return nil, fmt.Errorf("partial signature of participant %d has different R", sig.Index)
+		}
+		seen[sig.Index] = true
+		sigs[i] = sig
+		indices[i] = sig.Index
+	}
+	s := new(big.Int)
+	for i, sig := range sigs {
+		s.Add(s, new(big.Int).Mul(lagrangeCoefficient(pub.Curve, indices, i), sig.S))
+		s.Mod(s, n)
+	}
+	if s.Sign() == 0 {
+		return nil, errors.New("invalid combined signature, S is zero")
+	}
+	s, _, err := ToLowS(pub, s)
+	if err != nil {
+		return nil, err
+	}
+	if !ecdsa.Verify(pub, digest, sigs[0].R, s) {
+		return nil, errors.New("invalid combined signature, verification failed")
+	}
+	return MarshalECDSASignature(sigs[0].R, s)
+}
+
+// validateThreshold - Validates threshold scheme parameters.
+func validateThreshold(curve elliptic.Curve, threshold, participants int) error {
+	if threshold < 1 {
+		return fmt.Errorf("invalid threshold %d, it must be positive", threshold)
+	}
+	if participants < threshold {
+		return fmt.Errorf("invalid number of participants %d, it must not be lower than threshold %d", participants, threshold)
+	}
+	if big.NewInt(int64(participants)).Cmp(curve.Params().N) >= 0 {
+		return fmt.Errorf("invalid number of participants %d, too large", participants)
+	}
+	return nil
+}
+
+// randomScalar - Returns random non-zero scalar modulo curve order.
+func randomScalar(curve elliptic.Curve) (*big.Int, error) {
+	n := curve.Params().N
+	for {
+		k, err := rand.Int(rand.Reader, n)
+		if err != nil {
+			return nil, err
+		}
+		if k.Sign() != 0 {
+			return k, nil
+		}
+	}
+}
+
+// randomPolynomial - Returns random polynomial of degree threshold-1
+// with constant term set to secret.
+func randomPolynomial(curve elliptic.Curve, secret *big.Int, threshold int) ([]*big.Int, error) {
+	f := make([]*big.Int, threshold)
+	f[0] = new(big.Int).Set(secret)
+	for i := 1; i < threshold; i++ {
+		coeff, err := randomScalar(curve)
+		if err != nil {
+			return nil, err
+		}
+		f[i] = coeff
+	}
+	return f, nil
+}
+
+// evalPolynomial - Evaluates polynomial at x modulo curve order.
+func evalPolynomial(curve elliptic.Curve, f []*big.Int, x int) *big.Int {
+	n := curve.Params().N
+	bx := big.NewInt(int64(x))
+	y := new(big.Int)
+	for i := len(f) - 1; i >= 0; i-- {
+		y.Mul(y, bx)
+		y.Add(y, f[i])
+		y.Mod(y, n)
+	}
+	return y
+}
+
+// lagrangeCoefficient - Computes Lagrange coefficient at zero
+// of participant indices[i] modulo curve order.
+func lagrangeCoefficient(curve elliptic.Curve, indices []int, i int) *big.Int {
+	n := curve.Params().N
+	num, den := big.NewInt(1), big.NewInt(1)
+	xi := big.NewInt(int64(indices[i]))
+	for j, index := range indices {
+		if j == i {
+			continue
+		}
+		xj := big.NewInt(int64(index))
+		num.Mul(num, xj)
+		num.Mod(num, n)
+		den.Mul(den, new(big.Int).Sub(xj, xi))
+		den.Mod(den, n)
+	}
+	return num.Mul(num, den.ModInverse(den, n)).Mod(num, n)
+}
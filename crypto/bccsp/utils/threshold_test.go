@@ -0,0 +1,86 @@
+// Copyright © 2018 The IPFN Developers. All Rights Reserved.
+// Copyright © 2016-2018 IBM Corp. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitECDSAKey(t *testing.T) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	_, err = SplitECDSAKey(nil, 2, 3)
+	assert.Error(t, err)
+	_, err = SplitECDSAKey(priv, 0, 3)
+	assert.Error(t, err)
+	_, err = SplitECDSAKey(priv, 4, 3)
+	assert.Error(t, err)
+
+	shares, err := SplitECDSAKey(priv, 3, 5)
+	assert.NoError(t, err)
+	assert.Len(t, shares, 5)
+
+	// Any three shares interpolate to private key
+	indices := []int{5, 2, 4}
+	d := new(big.Int)
+	for i, index := range indices {
+		d.Add(d, new(big.Int).Mul(lagrangeCoefficient(priv.Curve, indices, i), shares[index-1].D))
+	}
+	d.Mod(d, priv.Params().N)
+	assert.Equal(t, priv.D, d)
+}
+
+func TestCombinePartialSignaturesInvalid(t *testing.T) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	digest := []byte("01234567890123456789012345678901")
+
+	first, err := MarshalECDSAPartialSignature(1, big.NewInt(7), big.NewInt(11))
+	assert.NoError(t, err)
+	duplicate, err := MarshalECDSAPartialSignature(1, big.NewInt(7), big.NewInt(13))
+	assert.NoError(t, err)
+	otherR, err := MarshalECDSAPartialSignature(2, big.NewInt(8), big.NewInt(13))
+	assert.NoError(t, err)
+	zeroIndex, err := MarshalECDSAPartialSignature(0, big.NewInt(7), big.NewInt(13))
+	assert.NoError(t, err)
+
+	_, err = CombinePartialSignatures(nil, digest, 2, [][]byte{first, otherR})
+	assert.Error(t, err)
+	_, err = CombinePartialSignatures(&priv.PublicKey, digest, 0, [][]byte{first})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid threshold")
+	_, err = CombinePartialSignatures(&priv.PublicKey, digest, 2, [][]byte{first})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "not enough partial signatures")
+	_, err = CombinePartialSignatures(&priv.PublicKey, digest, 2, [][]byte{first, duplicate})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "duplicate partial signature")
+	_, err = CombinePartialSignatures(&priv.PublicKey, digest, 2, [][]byte{first, otherR})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "different R")
+	_, err = CombinePartialSignatures(&priv.PublicKey, digest, 2, [][]byte{first, zeroIndex})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "participant index must be positive")
+	_, err = CombinePartialSignatures(&priv.PublicKey, digest, 2, [][]byte{first, {0x30, 0x00}})
+	assert.Error(t, err)
+}
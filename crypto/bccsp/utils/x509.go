@@ -17,9 +17,65 @@ package utils
 
 import (
 	"crypto/x509"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ipfn/ipfn/pkg/crypto/bccsp"
 )
 
 // DERToX509Certificate converts der to x509
 func DERToX509Certificate(asn1Data []byte) (*x509.Certificate, error) {
 	return x509.ParseCertificate(asn1Data)
 }
+
+// CheckCertificateValidity returns error if certificate is expired or not yet
+// valid at given time. Returned error is x509.CertificateInvalidError with
+// x509.Expired reason.
+func CheckCertificateValidity(cert *x509.Certificate, now time.Time) error {
+	if cert == nil {
+		return errors.New("Invalid certificate. It must not be nil.")
+	}
+	switch {
+	case now.Before(cert.NotBefore):
+		return x509.CertificateInvalidError{
+			Cert:   cert,
+			Reason: x509.Expired,
+			Detail: fmt.Sprintf("certificate is not valid before %s", cert.NotBefore.Format(time.RFC3339)),
+		}
+	case now.After(cert.NotAfter):
+		return x509.CertificateInvalidError{
+			Cert:   cert,
+			Reason: x509.Expired,
+			Detail: fmt.Sprintf("certificate expired at %s", cert.NotAfter.Format(time.RFC3339)),
+		}
+	}
+	return nil
+}
+
+// KeyMatchesCertificate returns true if public part of the key is the public
+// key of DER encoded certificate. Only public key material is compared, so it
+// can be used with keys which cannot be exported, e.g. stored in HSM.
+// Mismatch, including a symmetric key or different key type, results in false
+// and no error, errors are returned only for malformed inputs.
+func KeyMatchesCertificate(key bccsp.Key, certDER []byte) (bool, error) {
+	if key == nil {
+		return false, errors.New("Invalid key. It must not be nil.")
+	}
+	cert, err := DERToX509Certificate(certDER)
+	if err != nil {
+		return false, fmt.Errorf("Failed parsing certificate [%s]", err)
+	}
+	certKey, err := canonicalGoPublicKey(cert.PublicKey)
+	if err != nil {
+		return false, err
+	}
+	if key.Symmetric() {
+		return false, nil
+	}
+	k, err := canonicalPublicKey(key)
+	if err != nil {
+		return false, err
+	}
+	return k.equal(certKey), nil
+}
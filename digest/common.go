@@ -15,8 +15,13 @@
 package digest
 
 import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"hash"
+
 	keccak "github.com/gxed/hashland/keccakpg"
-	"github.com/minio/sha256-simd"
+	"golang.org/x/crypto/sha3"
 )
 
 // SumKeccak256Bytes - Sums Keccak256 secure hash.
@@ -26,7 +31,7 @@ func SumKeccak256Bytes(data ...[]byte) []byte {
 
 // SumSha256Bytes - Sums Sha256 secure hash.
 func SumSha256Bytes(data ...[]byte) []byte {
-	return SumBytes(sha256.New(), data...)
+	return SumBytes(NewSha256(), data...)
 }
 
 // SumKeccak256 - Sums Keccak256 secure hash.
@@ -36,5 +41,51 @@ func SumKeccak256(data ...[]byte) Digest {
 
 // SumSha256 - Sums Sha256 secure hash.
 func SumSha256(data ...[]byte) Digest {
-	return Sum(sha256.New(), data...)
+	return Sum(NewSha256(), data...)
+}
+
+// SumSha224 - Sums Sha224 secure hash.
+// Digest is returned as bytes as it is shorter than Digest.
+func SumSha224(data ...[]byte) []byte {
+	return SumBytes(sha256.New224(), data...)
+}
+
+// SumSha3_224 - Sums Sha3-224 secure hash.
+// Digest is returned as bytes as it is shorter than Digest.
+func SumSha3_224(data ...[]byte) []byte {
+	return SumBytes(sha3.New224(), data...)
+}
+
+// SumFramed - Sums 256 bit hash of length-prefixed fields using hash family.
+//
+// Every field is prefixed with its length encoded as unsigned varint, hash of
+// fields a and b is H(varint(len(a)) || a || varint(len(b)) || b). Therefore
+// fields ["ab", "c"] and ["a", "bc"] produce different digests.
+func SumFramed(family Family, fields ...[]byte) (digest Digest, err error) {
+	h, err := newFamilyHash(family)
+	if err != nil {
+		return
+	}
+	var prefix [binary.MaxVarintLen64]byte
+	for _, field := range fields {
+		n := binary.PutUvarint(prefix[:], uint64(len(field)))
+		h.Write(prefix[:n])
+		h.Write(field)
+	}
+	h.Sum(digest[:0])
+	return
+}
+
+// newFamilyHash - Creates 256 bit hash of family.
+func newFamilyHash(family Family) (hash.Hash, error) {
+	switch family {
+	case FamilySha2:
+		return NewSha256(), nil
+	case FamilySha3:
+		return sha3.New256(), nil
+	case FamilyKeccak:
+		return keccak.New256(), nil
+	default:
+		return nil, fmt.Errorf("unsupported hash family %s", family)
+	}
 }
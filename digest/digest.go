@@ -16,9 +16,13 @@ package digest
 
 import (
 	"bytes"
+	"encoding/binary"
 	"encoding/hex"
+	"errors"
 	"hash"
 	"io"
+	"math/bits"
+	"sync"
 )
 
 // Size - Default digest size.
@@ -33,18 +37,37 @@ var emptyDigest = Digest{0}
 
 // Sum - Sums hash digest using provided hasher.
 func Sum(h hash.Hash, data ...[]byte) (digest Digest) {
+	h.Reset()
+	for _, body := range data {
+		h.Write(body)
+	}
+	buf := sumPool.Get().(*[]byte)
+	var sum []byte
 	if r, ok := h.(io.Reader); ok {
-		h.Reset()
-		for _, body := range data {
-			h.Write(body)
-		}
-		r.Read(digest[:])
-		return
+		sum = (*buf)[:Size]
+		r.Read(sum)
+	} else {
+		sum = h.Sum((*buf)[:0])
 	}
-	return FromBytes(SumBytes(h, data...))
+	copy(digest[:], sum)
+	*buf = sum[:0]
+	sumPool.Put(buf)
+	return
+}
+
+// sumPool - Pool of scratch buffers for hash output.
+//
+// Buffers never leave Sum, output is copied into returned digest.
+var sumPool = sync.Pool{
+	New: func() interface{} {
+		buf := make([]byte, 0, 64)
+		return &buf
+	},
 }
 
 // SumBytes - Sums hash digest using provided hasher.
+//
+// Returned slice is owned by the caller and is never pooled.
 func SumBytes(h hash.Hash, data ...[]byte) (digest []byte) {
 	h.Reset()
 	for _, body := range data {
@@ -84,3 +107,34 @@ func Compare(a, b Digest) int {
 func (digest Digest) Bytes() []byte {
 	return digest[:]
 }
+
+// LeadingZeroBits - Returns number of leading zero bits of digest.
+func (digest Digest) LeadingZeroBits() int {
+	for i, b := range digest {
+		if b != 0 {
+			return i*8 + bits.LeadingZeros8(b)
+		}
+	}
+	return Size * 8
+}
+
+// MeetsDifficulty - Returns true if digest begins with at least given number of zero bits.
+// Difficulty lower or equal to zero is always met.
+func (digest Digest) MeetsDifficulty(bits int) bool {
+	return digest.LeadingZeroBits() >= bits
+}
+
+// Shard - Returns shard index in range [0, n) of digest.
+// Index is the digest interpreted as big-endian integer modulo n, which is
+// uniformly distributed for digests of cryptographic hash functions, bias of
+// reducing 256 bit integer is negligible. Number of shards must be positive.
+func (digest Digest) Shard(n int) (int, error) {
+	if n <= 0 {
+		return 0, errors.New("number of shards must be positive")
+	}
+	var rem uint64
+	for i := 0; i < Size; i += 8 {
+		rem = bits.Rem64(rem, binary.BigEndian.Uint64(digest[i:]), uint64(n))
+	}
+	return int(rem), nil
+}
@@ -18,6 +18,7 @@ import (
 	"bytes"
 	"fmt"
 	"math"
+	"sort"
 )
 
 // Family - Hash digest family multihash ID.
@@ -121,3 +122,65 @@ func (family *Family) UnmarshalText(body []byte) (_ error) {
 		return fmt.Errorf("Unknown hash family %q", body)
 	}
 }
+
+// familyPreference - Hash families in order of preference, see StrongestCommon.
+var familyPreference = []Family{
+	FamilySha3,
+	FamilySha2,
+	FamilyBlake2b,
+	FamilyKeccak,
+	FamilyShake,
+	FamilyBlake2s,
+	FamilyDoubleSha2,
+}
+
+// StrongestCommon - Returns the strongest hash family supported by both
+// peers, for negotiation of hash function. Families are preferred in order:
+//
+//	sha3 > sha2 > blake2b > keccak > shake > blake2s > doublesha2
+//
+// SHA3 is preferred over SHA2 as its 512 and 256 bit variants are each
+// preferred over the SHA2 variant of the same size. SHA1 and non-cryptographic
+// MURMUR3 are never negotiated. Returns false if there is no common family.
+func StrongestCommon(a, b []Family) (Family, bool) {
+	for _, family := range familyPreference {
+		if containsFamily(a, family) && containsFamily(b, family) {
+			return family, true
+		}
+	}
+	return FamilyUnknown, false
+}
+
+// containsFamily - Returns true if families contain family.
+func containsFamily(families []Family, family Family) bool {
+	for _, f := range families {
+		if f == family {
+			return true
+		}
+	}
+	return false
+}
+
+// FamilyOf - Returns hash family of algorithm type.
+// Returns false for types without family, such as XXH3.
+func FamilyOf(t Type) (Family, bool) {
+	family := t.Family()
+	return family, family != FamilyUnknown
+}
+
+// TypesInFamily - Returns named algorithm types of family ordered by
+// multihash code. Types are enumerated from Names, so that new types are
+// included once they are named and mapped in Type.Family.
+// Returns nil for FamilyUnknown.
+func TypesInFamily(family Family) (types []Type) {
+	if family == FamilyUnknown {
+		return nil
+	}
+	for t := range Names {
+		if t.Family() == family {
+			types = append(types, t)
+		}
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	return
+}
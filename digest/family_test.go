@@ -0,0 +1,88 @@
+// Copyright © 2017-2018 The IPFN Developers. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package digest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStrongestCommon(t *testing.T) {
+	for _, tc := range []struct {
+		a, b     []Family
+		expected Family
+		found    bool
+	}{
+		{[]Family{FamilySha2, FamilySha3}, []Family{FamilySha3, FamilySha2}, FamilySha3, true},
+		{[]Family{FamilySha2, FamilySha3}, []Family{FamilySha2}, FamilySha2, true},
+		{[]Family{FamilyKeccak, FamilyBlake2b, FamilySha2}, []Family{FamilyBlake2b, FamilyKeccak}, FamilyBlake2b, true},
+		{[]Family{FamilyDoubleSha2, FamilyBlake2s}, []Family{FamilyBlake2s, FamilyDoubleSha2}, FamilyBlake2s, true},
+		{[]Family{FamilySha3}, []Family{FamilySha2}, FamilyUnknown, false},
+		{[]Family{FamilySha1, FamilyMurmur3}, []Family{FamilyMurmur3, FamilySha1}, FamilyUnknown, false},
+		{nil, []Family{FamilySha2}, FamilyUnknown, false},
+		{nil, nil, FamilyUnknown, false},
+	} {
+		family, found := StrongestCommon(tc.a, tc.b)
+		assert.Equal(t, tc.found, found, "%v %v", tc.a, tc.b)
+		assert.Equal(t, tc.expected, family, "%v %v", tc.a, tc.b)
+
+		// Negotiation is symmetric
+		family, found = StrongestCommon(tc.b, tc.a)
+		assert.Equal(t, tc.found, found)
+		assert.Equal(t, tc.expected, family)
+	}
+}
+
+func TestFamilyOf(t *testing.T) {
+	family, ok := FamilyOf(Sha2_256)
+	assert.True(t, ok)
+	assert.Equal(t, FamilySha2, family)
+	family, ok = FamilyOf(Keccak256)
+	assert.True(t, ok)
+	assert.Equal(t, FamilyKeccak, family)
+	_, ok = FamilyOf(XXH3)
+	assert.False(t, ok)
+	_, ok = FamilyOf(UnknownType)
+	assert.False(t, ok)
+
+	assert.Equal(t, []Type{Sha2_256, Sha2_512, Sha2_384, Sha2_224}, TypesInFamily(FamilySha2))
+	assert.Equal(t, []Type{Sha3_512, Sha3_384, Sha3_256, Sha3_224}, TypesInFamily(FamilySha3))
+	assert.Empty(t, TypesInFamily(FamilyUnknown))
+}
+
+func TestTypesInFamilyComplete(t *testing.T) {
+	families := []Family{
+		FamilySha1, FamilySha2, FamilySha3, FamilyKeccak, FamilyShake,
+		FamilyBlake2b, FamilyBlake2s, FamilyDoubleSha2, FamilyMurmur3,
+	}
+	grouped := make(map[Type]Family)
+	for _, family := range families {
+		for _, typ := range TypesInFamily(family) {
+			_, dup := grouped[typ]
+			assert.False(t, dup, "%s in more than one family", typ)
+			grouped[typ] = family
+		}
+	}
+	for typ, name := range Names {
+		assert.Equal(t, typ, Types[name], "%s", name)
+		if typ.Cryptographic() {
+			family, ok := FamilyOf(typ)
+			assert.True(t, ok, "%s has no family", typ)
+			assert.Equal(t, family, grouped[typ], "%s", typ)
+		}
+	}
+	assert.Len(t, Types, len(Names))
+}
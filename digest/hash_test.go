@@ -15,6 +15,8 @@
 package digest
 
 import (
+	"fmt"
+	"math/big"
 	"testing"
 
 	multihash "gx/ipfs/QmerPMzPk1mJVowm8KgmoknWa4yCYvvugMPsgWmDNUvDLW/go-multihash"
@@ -39,6 +41,53 @@ func TestSum(t *testing.T) {
 	assert.Equal(t, digest, hashed)
 }
 
+func TestSumPooled(t *testing.T) {
+	expect := FromHex("9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08")
+	done := make(chan Digest)
+	for i := 0; i < 8; i++ {
+		go func() {
+			h := sha256.New()
+			var hashed Digest
+			for j := 0; j < 100; j++ {
+				hashed = Sum(h, []byte("test"))
+			}
+			done <- hashed
+		}()
+	}
+	for i := 0; i < 8; i++ {
+		assert.Equal(t, expect, <-done)
+	}
+
+	// Hashes with output larger than digest are truncated
+	hashed := Sum(sha3.New512(), []byte("test"))
+	assert.Equal(t, FromBytes(SumBytes(sha3.New512(), []byte("test"))), hashed)
+
+	h := sha256.New()
+	data := []byte("test")
+	allocs := testing.AllocsPerRun(100, func() {
+		Sum(h, data)
+	})
+	assert.Equal(t, float64(0), allocs)
+}
+
+func BenchmarkSum(b *testing.B) {
+	h := sha256.New()
+	data := []byte("test")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Sum(h, data)
+	}
+}
+
+func BenchmarkSumBytes(b *testing.B) {
+	h := sha256.New()
+	data := []byte("test")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		FromBytes(SumBytes(h, data))
+	}
+}
+
 func TestIsEmpty(t *testing.T) {
 	assert.Equal(t, false, IsEmpty(FromHex("9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08")))
 	assert.Equal(t, true, IsEmpty(FromHex("0000000000000000000000000000000000000000000000000000000000000000")))
@@ -50,6 +99,60 @@ func TestEqual(t *testing.T) {
 	assert.Equal(t, true, FromHex("0000000000000000000000000000000000000000000000000000000000000000") == Empty())
 }
 
+func TestLeadingZeroBits(t *testing.T) {
+	tests := []struct {
+		hex  string
+		bits int
+	}{
+		{"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08", 0},
+		{"7f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08", 1},
+		{"0186d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08", 7},
+		{"0080d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08", 8},
+		{"0000000fff7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08ff", 28},
+		{"0000000000000000000000000000000000000000000000000000000000000001", 255},
+		{"0000000000000000000000000000000000000000000000000000000000000000", 256},
+	}
+	for _, test := range tests {
+		digest := FromHex(test.hex)
+		assert.Equal(t, test.bits, digest.LeadingZeroBits(), test.hex)
+		assert.Equal(t, true, digest.MeetsDifficulty(test.bits), test.hex)
+		assert.Equal(t, false, digest.MeetsDifficulty(test.bits+1), test.hex)
+		assert.Equal(t, true, digest.MeetsDifficulty(0), test.hex)
+		assert.Equal(t, true, digest.MeetsDifficulty(-1), test.hex)
+	}
+}
+
+func TestShard(t *testing.T) {
+	// Uniform distribution
+	const shards, samples = 10, 20000
+	counts := make([]int, shards)
+	for i := 0; i < samples; i++ {
+		shard, err := SumSha256([]byte(fmt.Sprintf("content %d", i))).Shard(shards)
+		assert.NoError(t, err)
+		assert.True(t, shard >= 0 && shard < shards)
+		counts[shard]++
+	}
+	for shard, count := range counts {
+		assert.InDelta(t, samples/shards, count, samples/shards/10, "shard %d", shard)
+	}
+
+	// Deterministic big-endian modulo
+	digest := SumSha256([]byte("test"))
+	for _, n := range []int{1, 2, 3, 7, 256, 1000003, 1<<62 + 1} {
+		shard, err := digest.Shard(n)
+		assert.NoError(t, err)
+		expected := new(big.Int).Mod(new(big.Int).SetBytes(digest[:]), big.NewInt(int64(n)))
+		assert.Equal(t, int(expected.Int64()), shard, "%d", n)
+		again, _ := digest.Shard(n)
+		assert.Equal(t, shard, again)
+	}
+
+	_, err := digest.Shard(0)
+	assert.Error(t, err)
+	_, err = digest.Shard(-1)
+	assert.Error(t, err)
+}
+
 func TestHashEncoding(t *testing.T) {
 	hashed := Sum(sha256.New(), []byte("test"))
 	hash := HashFromDigest(Sha2_256, hashed)
@@ -104,3 +207,235 @@ func TestSumKeccak256(t *testing.T) {
 	digest := FromHex("9c22ff5f21f0b81b113e63f7db6da94fedef11b2119b4088b89664fb9a3cb658")
 	assert.Equal(t, digest, hashed)
 }
+
+func TestSumFramed(t *testing.T) {
+	for _, family := range []Family{FamilySha2, FamilySha3, FamilyKeccak} {
+		a, err := SumFramed(family, []byte("ab"), []byte("c"))
+		assert.Equal(t, err, nil)
+		b, err := SumFramed(family, []byte("a"), []byte("bc"))
+		assert.Equal(t, err, nil)
+		assert.NotEqual(t, a, b)
+
+		h, err := newFamilyHash(family)
+		assert.Equal(t, err, nil)
+		// naive concatenation collides
+		assert.Equal(t, SumBytes(h, []byte("ab"), []byte("c")), SumBytes(h, []byte("a"), []byte("bc")))
+		// framing is varint length followed by field
+		assert.Equal(t, a[:], SumBytes(h, []byte{2}, []byte("ab"), []byte{1}, []byte("c")))
+	}
+
+	empty, err := SumFramed(FamilySha2)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, SumSha256(), empty)
+	field, err := SumFramed(FamilySha2, nil)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, SumSha256([]byte{0}), field)
+
+	_, err = SumFramed(FamilyMurmur3, []byte("test"))
+	assert.Error(t, err)
+}
+
+// Sanity vectors of the reference XXH3 implementation (xxhsum),
+// computed over the xxhsum test buffer for every given length.
+func TestSumXXH3(t *testing.T) {
+	buf := make([]byte, 2367)
+	gen := uint64(2654435761)
+	for i := range buf {
+		buf[i] = byte(gen >> 56)
+		gen *= 11400714785074694797
+	}
+	vectors := []struct {
+		len int
+		sum uint64
+	}{
+		{0, 0x2D06800538D394C2},
+		{1, 0xC44BDFF4074EECDB},
+		{6, 0x27B56A84CD2D7325},
+		{12, 0xA713DAF0DFBB77E7},
+		{24, 0xA3FE70BF9D3510EB},
+		{48, 0x397DA259ECBA1F11},
+		{80, 0xBCDEFBBB2C47C90A},
+		{195, 0xCD94217EE362EC3A},
+		{403, 0xCDEB804D65C6DEA4},
+		{512, 0x617E49599013CB6B},
+		{2048, 0xDD59E2C3A5F038E0},
+		{2240, 0x6E73A90539CF2948},
+		{2367, 0xCB37AEB9E5D361ED},
+	}
+	for _, v := range vectors {
+		assert.Equal(t, v.sum, SumXXH3(buf[:v.len]), "len %d", v.len)
+		// split input hashes the same
+		half := v.len / 2
+		assert.Equal(t, v.sum, SumXXH3(buf[:half], buf[half:v.len]), "len %d", v.len)
+	}
+	assert.Equal(t, []byte{0x2D, 0x06, 0x80, 0x05, 0x38, 0xD3, 0x94, 0xC2}, SumXXH3Bytes())
+	assert.Len(t, SumXXH3Bytes([]byte("test")), XXH3Size)
+
+	// XXH3 is not cryptographic
+	assert.False(t, XXH3.Cryptographic())
+	assert.False(t, Murmur3.Cryptographic())
+	assert.True(t, Sha2_256.Cryptographic())
+	assert.Equal(t, "xxh3-64", XXH3.String())
+}
+
+func TestMultibase(t *testing.T) {
+	h := HashFromHex(Sha2_256, "2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae")
+
+	// Known encodings of sha2-256 multihash of "foo"
+	tests := []struct {
+		base    Multibase
+		encoded string
+	}{
+		{Base16, "f12202c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae"},
+		{Base58BTC, "zQmRJzsvyCQyizr73Gmms8ZRtvNxmgqumxc2KUp71dfEmoj"},
+		{Base32, "bciqcyjvunnup7rup7gnukpa5gbatie2cfvygja57ud4yuxuimjtoplq"},
+		{Base64, "mEiAsJrRraP/Gj/mbRTwdMEE0E0ItcGSDv6D5il6IYmbnrg"},
+		{Base64URL, "uEiAsJrRraP_Gj_mbRTwdMEE0E0ItcGSDv6D5il6IYmbnrg"},
+	}
+	for _, test := range tests {
+		encoded, err := EncodeMultibase(h, test.base)
+		assert.NoError(t, err)
+		assert.Equal(t, test.encoded, encoded)
+
+		decoded, err := ParseMultibaseHash(encoded)
+		assert.NoError(t, err)
+		assert.Equal(t, Sha2_256, decoded.Algorithm())
+		assert.True(t, HashEqual(h, decoded))
+	}
+
+	// Digest is encoded as sha2-256 multihash
+	digest := FromHex("2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae")
+	for _, test := range tests {
+		encoded, err := digest.Multibase(test.base)
+		assert.NoError(t, err)
+		assert.Equal(t, test.encoded, encoded)
+
+		decoded, err := ParseMultibase(encoded)
+		assert.NoError(t, err)
+		assert.Equal(t, digest, decoded)
+	}
+
+	// Hashes of other types keep their type
+	keccak := HashFromDigest(Keccak256, digest)
+	encoded, err := EncodeMultibase(keccak, Base58BTC)
+	assert.NoError(t, err)
+	decoded, err := ParseMultibaseHash(encoded)
+	assert.NoError(t, err)
+	assert.Equal(t, Keccak256, decoded.Algorithm())
+	assert.True(t, HashEqual(keccak, decoded))
+	_, err = ParseMultibase(encoded)
+	assert.Error(t, err)
+
+	_, err = EncodeMultibase(EmptyHash(), Base58BTC)
+	assert.Error(t, err)
+	_, err = EncodeMultibase(HashFromSum(Sha2_256, []byte{1}), Multibase('x'))
+	assert.Error(t, err)
+	_, err = digest.Multibase(Multibase('x'))
+	assert.Error(t, err)
+	for _, src := range []string{"", "z", "xQmRJzsvyCQyizr73Gmms8ZRtvNxmgqumxc2KUp71dfEmoj", "z0OIl", "fzz", "f1220"} {
+		_, err := ParseMultibaseHash(src)
+		assert.Error(t, err, src)
+		_, err = ParseMultibase(src)
+		assert.Error(t, err, src)
+	}
+}
+
+func TestMerkleProof(t *testing.T) {
+	var leaves []Hash
+	for _, data := range []string{"a", "b", "c", "d", "e"} {
+		leaves = append(leaves, HashFromDigest(Sha2_256, SumSha256([]byte(data))))
+	}
+	root, err := MerkleRoot(leaves)
+	assert.NoError(t, err)
+
+	// Root of two leaves is parent of their leaf nodes
+	left, err := MerkleLeaf(leaves[0])
+	assert.NoError(t, err)
+	assert.Equal(t, SumSha256([]byte{0}, leaves[0].Digest()).Bytes(), left.Digest())
+	right, err := MerkleLeaf(leaves[1])
+	assert.NoError(t, err)
+	parent, err := MerkleParent(left, right)
+	assert.NoError(t, err)
+	assert.Equal(t, SumSha256([]byte{1}, left.Digest(), right.Digest()).Bytes(), parent.Digest())
+	pair, err := MerkleRoot(leaves[:2])
+	assert.NoError(t, err)
+	assert.True(t, HashEqual(parent, pair))
+
+	// Internal node does not verify as a leaf
+	valid, err := VerifyMerkleProof(parent, nil, pair)
+	assert.NoError(t, err)
+	assert.False(t, valid)
+	single, err := MerkleRoot(leaves[:1])
+	assert.NoError(t, err)
+	assert.True(t, HashEqual(left, single))
+
+	for i, leaf := range leaves {
+		proof, err := MerkleProof(leaves, i)
+		assert.NoError(t, err)
+		valid, err := VerifyMerkleProof(leaf, proof, root)
+		assert.NoError(t, err)
+		assert.True(t, valid, "leaf %d", i)
+
+		// Proof of one leaf does not prove another
+		valid, err = VerifyMerkleProof(leaves[(i+1)%len(leaves)], proof, root)
+		assert.NoError(t, err)
+		assert.False(t, valid, "leaf %d", i)
+	}
+
+	proof, err := MerkleProof(leaves, 2)
+	assert.NoError(t, err)
+	assert.Len(t, proof, 3)
+
+	// Internal node with shortened proof does not verify as a leaf
+	node, err := MerkleLeaf(leaves[2])
+	assert.NoError(t, err)
+	node, err = MerkleParent(node, proof[0].Sibling)
+	assert.NoError(t, err)
+	valid, err = VerifyMerkleProof(node, proof[1:], root)
+	assert.NoError(t, err)
+	assert.False(t, valid)
+
+	// Flipped sibling
+	flipped := append([]ProofNode{}, proof...)
+	sibling := append([]byte{}, flipped[0].Sibling.Digest()...)
+	sibling[0] ^= 1
+	flipped[0].Sibling = HashFromSum(Sha2_256, sibling)
+	valid, err = VerifyMerkleProof(leaves[2], flipped, root)
+	assert.NoError(t, err)
+	assert.False(t, valid)
+
+	// Wrong order of nodes
+	reordered := []ProofNode{proof[1], proof[0], proof[2]}
+	valid, err = VerifyMerkleProof(leaves[2], reordered, root)
+	assert.NoError(t, err)
+	assert.False(t, valid)
+
+	// Wrong side of sibling
+	sided := append([]ProofNode{}, proof...)
+	sided[0].Left = !sided[0].Left
+	valid, err = VerifyMerkleProof(leaves[2], sided, root)
+	assert.NoError(t, err)
+	assert.False(t, valid)
+
+	// Inconsistent algorithms
+	keccakLeaf := HashFromDigest(Keccak256, SumKeccak256([]byte("c")))
+	_, err = VerifyMerkleProof(keccakLeaf, proof, root)
+	assert.Error(t, err)
+	mixed := append([]ProofNode{}, proof...)
+	mixed[1].Sibling = HashFromSum(Sha3_256, proof[1].Sibling.Digest())
+	_, err = VerifyMerkleProof(leaves[2], mixed, HashFromSum(Sha3_256, root.Digest()))
+	assert.Error(t, err)
+	_, err = VerifyMerkleProof(leaves[2], mixed, root)
+	assert.Error(t, err)
+	_, err = MerkleParent(leaves[0], keccakLeaf)
+	assert.Error(t, err)
+	_, err = MerkleParent(HashFromSum(Sha1, make([]byte, 20)), HashFromSum(Sha1, make([]byte, 20)))
+	assert.Error(t, err)
+
+	_, err = MerkleRoot(nil)
+	assert.Error(t, err)
+	_, err = MerkleProof(leaves, len(leaves))
+	assert.Error(t, err)
+	_, err = VerifyMerkleProof(EmptyHash(), proof, root)
+	assert.Error(t, err)
+}
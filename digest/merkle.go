@@ -0,0 +1,199 @@
+// Copyright © 2017-2018 The IPFN Developers. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package digest
+
+import (
+	"errors"
+	"fmt"
+	"hash"
+
+	keccak "github.com/gxed/hashland/keccakpg"
+	"golang.org/x/crypto/sha3"
+)
+
+// ProofNode - Node of Merkle inclusion proof.
+type ProofNode struct {
+	// Sibling - Digest of sibling node.
+	Sibling Hash
+	// Left - True if sibling is the left child of the parent.
+	Left bool
+}
+
+// Domain separation prefixes of Merkle tree hashes as in RFC 6962, so that
+// an internal node cannot be presented as a leaf.
+var (
+	merkleLeafPrefix = []byte{0x00}
+	merkleNodePrefix = []byte{0x01}
+)
+
+// MerkleLeaf - Computes leaf node of Merkle tree from leaf digest.
+// Node is hash of 0x00 byte and leaf digest using algorithm of the leaf.
+func MerkleLeaf(leaf Hash) (Hash, error) {
+	if IsHashEmpty(leaf) {
+		return nil, errors.New("merkle node is empty")
+	}
+	h, err := newMerkleHash(leaf.Algorithm())
+	if err != nil {
+		return nil, err
+	}
+	return HashFromSum(leaf.Algorithm(), SumBytes(h, merkleLeafPrefix, leaf.Digest())), nil
+}
+
+// MerkleParent - Computes parent node of two nodes of Merkle tree.
+// Parent is hash of 0x01 byte, left digest and right digest using
+// algorithm of both nodes. Supported algorithms are sha2-256, sha3-256
+// and keccak-256.
+func MerkleParent(left, right Hash) (Hash, error) {
+	if IsHashEmpty(left) || IsHashEmpty(right) {
+		return nil, errors.New("merkle node is empty")
+	}
+	if left.Algorithm() != right.Algorithm() {
+		return nil, fmt.Errorf("inconsistent merkle node algorithms %s and %s", left.Algorithm(), right.Algorithm())
+	}
+	h, err := newMerkleHash(left.Algorithm())
+	if err != nil {
+		return nil, err
+	}
+	return HashFromSum(left.Algorithm(), SumBytes(h, merkleNodePrefix, left.Digest(), right.Digest())), nil
+}
+
+// MerkleRoot - Computes root of Merkle tree of leaves. Leaves are hashed
+// with MerkleLeaf and their parents with MerkleParent. Unpaired node on
+// a level is promoted to the next level unchanged.
+func MerkleRoot(leaves []Hash) (Hash, error) {
+	if len(leaves) == 0 {
+		return nil, errors.New("merkle tree has no leaves")
+	}
+	level, err := merkleLeaves(leaves)
+	if err != nil {
+		return nil, err
+	}
+	for len(level) > 1 {
+		next, err := merkleLevel(level)
+		if err != nil {
+			return nil, err
+		}
+		level = next
+	}
+	return level[0], nil
+}
+
+// MerkleProof - Creates inclusion proof of leaf at index for MerkleRoot of leaves.
+func MerkleProof(leaves []Hash, index int) ([]ProofNode, error) {
+	if index < 0 || index >= len(leaves) {
+		return nil, fmt.Errorf("leaf index %d out of range", index)
+	}
+	var proof []ProofNode
+	level, err := merkleLeaves(leaves)
+	if err != nil {
+		return nil, err
+	}
+	for len(level) > 1 {
+		next, err := merkleLevel(level)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case index%2 == 1:
+			proof = append(proof, ProofNode{Sibling: level[index-1], Left: true})
+		case index+1 < len(level):
+			proof = append(proof, ProofNode{Sibling: level[index+1]})
+		}
+		index /= 2
+		level = next
+	}
+	return proof, nil
+}
+
+// VerifyMerkleProof - Verifies inclusion proof of leaf in Merkle tree with root.
+// Proof is applied from the leaf node computed with MerkleLeaf up, parents
+// are computed with MerkleParent.
+// Leaf, siblings and root must use the same algorithm, otherwise error is
+// returned. Proof which does not lead to the root results in false.
+func VerifyMerkleProof(leaf Hash, proof []ProofNode, root Hash) (bool, error) {
+	if IsHashEmpty(leaf) || IsHashEmpty(root) {
+		return false, errors.New("merkle node is empty")
+	}
+	algo := leaf.Algorithm()
+	if root.Algorithm() != algo {
+		return false, fmt.Errorf("inconsistent merkle root algorithm %s, expected %s", root.Algorithm(), algo)
+	}
+	node, err := MerkleLeaf(leaf)
+	if err != nil {
+		return false, err
+	}
+	for i, p := range proof {
+		if IsHashEmpty(p.Sibling) {
+			return false, fmt.Errorf("merkle proof node %d is empty", i)
+		}
+		if p.Sibling.Algorithm() != algo {
+			return false, fmt.Errorf("inconsistent merkle proof node %d algorithm %s, expected %s", i, p.Sibling.Algorithm(), algo)
+		}
+		if p.Left {
+			node, err = MerkleParent(p.Sibling, node)
+		} else {
+			node, err = MerkleParent(node, p.Sibling)
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+	return HashEqual(node, root), nil
+}
+
+// merkleLeaves - Computes leaf nodes of Merkle tree.
+func merkleLeaves(leaves []Hash) ([]Hash, error) {
+	nodes := make([]Hash, len(leaves))
+	for i, leaf := range leaves {
+		node, err := MerkleLeaf(leaf)
+		if err != nil {
+			return nil, err
+		}
+		nodes[i] = node
+	}
+	return nodes, nil
+}
+
+// merkleLevel - Computes parents of nodes on a level of Merkle tree.
+// Unpaired node is promoted unchanged.
+func merkleLevel(level []Hash) ([]Hash, error) {
+	next := make([]Hash, 0, (len(level)+1)/2)
+	for i := 0; i < len(level); i += 2 {
+		if i+1 == len(level) {
+			next = append(next, level[i])
+			continue
+		}
+		parent, err := MerkleParent(level[i], level[i+1])
+		if err != nil {
+			return nil, err
+		}
+		next = append(next, parent)
+	}
+	return next, nil
+}
+
+// newMerkleHash - Creates hash function of Merkle tree nodes.
+func newMerkleHash(t Type) (hash.Hash, error) {
+	switch t {
+	case Sha2_256:
+		return NewSha256(), nil
+	case Sha3_256:
+		return sha3.New256(), nil
+	case Keccak256:
+		return keccak.New256(), nil
+	default:
+		return nil, fmt.Errorf("unsupported merkle hash algorithm %s", t)
+	}
+}
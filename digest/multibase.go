@@ -0,0 +1,120 @@
+// Copyright © 2017-2018 The IPFN Developers. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package digest
+
+import (
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	mh "gx/ipfs/QmerPMzPk1mJVowm8KgmoknWa4yCYvvugMPsgWmDNUvDLW/go-multihash"
+)
+
+// Multibase - Multibase encoding prefix.
+type Multibase byte
+
+// Source of constants: https://github.com/multiformats/multibase
+const (
+	// Base16 - Lowercase hexadecimal.
+	Base16 Multibase = 'f'
+	// Base32 - Lowercase RFC 4648 base32 without padding.
+	Base32 Multibase = 'b'
+	// Base58BTC - Base58 with bitcoin alphabet.
+	Base58BTC Multibase = 'z'
+	// Base64 - RFC 4648 base64 without padding.
+	Base64 Multibase = 'm'
+	// Base64URL - RFC 4648 URL safe base64 without padding.
+	Base64URL Multibase = 'u'
+)
+
+var base32Encoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+// Multibase - Encodes digest as SHA2-256 multihash with multibase prefix,
+// e.g. 'z' for base58btc or 'b' for base32. See EncodeMultibase for hashes
+// of other types.
+func (digest Digest) Multibase(base Multibase) (string, error) {
+	return EncodeMultibase(HashFromDigest(Sha2_256, digest), base)
+}
+
+// ParseMultibase - Decodes digest of multibase encoded SHA2-256 multihash.
+// Unknown multibase prefixes and other hash types are rejected.
+func ParseMultibase(src string) (digest Digest, err error) {
+	h, err := ParseMultibaseHash(src)
+	if err != nil {
+		return
+	}
+	if h.Algorithm() != Sha2_256 || h.Size() != Size {
+		return digest, fmt.Errorf("expected sha2-256 multihash, got %s", h.Algorithm())
+	}
+	return FromBytes(h.Digest()), nil
+}
+
+// EncodeMultibase - Encodes hash multihash with multibase prefix,
+// e.g. 'z' for base58btc or 'b' for base32.
+func EncodeMultibase(h Hash, base Multibase) (string, error) {
+	if IsHashEmpty(h) {
+		return "", errors.New("hash is empty")
+	}
+	body := h.Bytes()
+	var enc string
+	switch base {
+	case Base16:
+		enc = hex.EncodeToString(body)
+	case Base32:
+		enc = base32Encoding.EncodeToString(body)
+	case Base58BTC:
+		enc = mh.Multihash(body).B58String()
+	case Base64:
+		enc = base64.RawStdEncoding.EncodeToString(body)
+	case Base64URL:
+		enc = base64.RawURLEncoding.EncodeToString(body)
+	default:
+		return "", fmt.Errorf("unsupported multibase prefix %q", byte(base))
+	}
+	return string(base) + enc, nil
+}
+
+// ParseMultibaseHash - Decodes multibase encoded multihash.
+// Unknown multibase prefixes are rejected, base58btc encoded multihash
+// must be of type known to go-multihash.
+func ParseMultibaseHash(src string) (Hash, error) {
+	if len(src) < 2 {
+		return nil, errors.New("multibase string is too short")
+	}
+	var (
+		body []byte
+		err  error
+	)
+	switch Multibase(src[0]) {
+	case Base16:
+		body, err = hex.DecodeString(src[1:])
+	case Base32:
+		body, err = base32Encoding.DecodeString(src[1:])
+	case Base58BTC:
+		body, err = mh.FromB58String(src[1:])
+	case Base64:
+		body, err = base64.RawStdEncoding.DecodeString(src[1:])
+	case Base64URL:
+		body, err = base64.RawURLEncoding.DecodeString(src[1:])
+	default:
+		return nil, fmt.Errorf("unsupported multibase prefix %q", src[0])
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed decoding multibase: %v", err)
+	}
+	return DecodeHash(body)
+}
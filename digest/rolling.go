@@ -0,0 +1,75 @@
+// Copyright © 2017-2018 The IPFN Developers. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package digest
+
+// rollingBase - Multiplier of rolling hash polynomial, a 64bit prime.
+const rollingBase uint64 = 0x100000001b3
+
+// RollingHash - Rolling hash over a sliding window of bytes, building block
+// of content-defined chunking.
+//
+// WARNING: RollingHash is NOT a cryptographic hash function. Collisions are
+// trivial to craft, it is only meant for finding chunk boundaries.
+type RollingHash interface {
+	// Roll - Appends byte to the window and returns the byte which left it.
+	// Window is initially filled with zeros.
+	Roll(in byte) (out byte)
+
+	// Sum - Returns hash of current window.
+	Sum() uint64
+}
+
+// NewRollingHash - Creates Rabin-Karp style rolling hash over window of
+// windowSize bytes. Hash of window b[0..n-1] is the polynomial
+//
+//	b[0]*B^(n-1) + b[1]*B^(n-2) + ... + b[n-1]  (mod 2^64)
+//
+// updated in constant time per byte. Panics if windowSize is not positive.
+//
+// WARNING: RollingHash is NOT a cryptographic hash function, see RollingHash.
+func NewRollingHash(windowSize int) RollingHash {
+	if windowSize <= 0 {
+		panic("digest: rolling hash window size must be positive")
+	}
+	outPow := uint64(1)
+	for i := 1; i < windowSize; i++ {
+		outPow *= rollingBase
+	}
+	return &rabinHash{window: make([]byte, windowSize), outPow: outPow}
+}
+
+// rabinHash - Polynomial rolling hash.
+type rabinHash struct {
+	window []byte
+	pos    int
+	sum    uint64
+	// outPow - B^(n-1), weight of the oldest byte in window.
+	outPow uint64
+}
+
+func (h *rabinHash) Roll(in byte) (out byte) {
+	out = h.window[h.pos]
+	h.window[h.pos] = in
+	h.pos++
+	if h.pos == len(h.window) {
+		h.pos = 0
+	}
+	h.sum = (h.sum-uint64(out)*h.outPow)*rollingBase + uint64(in)
+	return
+}
+
+func (h *rabinHash) Sum() uint64 {
+	return h.sum
+}
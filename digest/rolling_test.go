@@ -0,0 +1,59 @@
+// Copyright © 2017-2018 The IPFN Developers. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package digest
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// rollingSum - Computes rolling hash of window from scratch.
+func rollingSum(window []byte) (sum uint64) {
+	for _, b := range window {
+		sum = sum*rollingBase + uint64(b)
+	}
+	return
+}
+
+func TestRollingHash(t *testing.T) {
+	data := make([]byte, 1000)
+	rand.New(rand.NewSource(1)).Read(data)
+
+	for _, size := range []int{1, 2, 16, 48, 64} {
+		h := NewRollingHash(size)
+		padded := append(make([]byte, size), data...)
+		for i, b := range data {
+			out := h.Roll(b)
+			assert.Equal(t, padded[i], out, "window %d byte %d", size, i)
+			assert.Equal(t, rollingSum(padded[i+1:i+1+size]), h.Sum(), "window %d byte %d", size, i)
+		}
+	}
+
+	// Same window content gives same sum regardless of preceding bytes
+	a, b := NewRollingHash(4), NewRollingHash(4)
+	for _, c := range []byte("xyzabcd") {
+		a.Roll(c)
+	}
+	for _, c := range []byte("abcd") {
+		b.Roll(c)
+	}
+	assert.Equal(t, a.Sum(), b.Sum())
+	b.Roll('e')
+	assert.NotEqual(t, a.Sum(), b.Sum())
+
+	assert.Panics(t, func() { NewRollingHash(0) })
+}
@@ -0,0 +1,94 @@
+// Copyright © 2017-2018 The IPFN Developers. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package digest
+
+import (
+	stdsha256 "crypto/sha256"
+	"hash"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/minio/sha256-simd"
+)
+
+// sha256Impl - SHA-256 implementation.
+type sha256Impl struct {
+	name string
+	new  func() hash.Hash
+}
+
+// sha256Impls - Available SHA-256 implementations. All of them produce the
+// same output, they differ only in speed on the running CPU.
+var sha256Impls = []sha256Impl{
+	{name: "simd", new: sha256.New},
+	{name: "stdlib", new: stdsha256.New},
+}
+
+var (
+	selectedSha256 atomic.Value
+	selectSha256   sync.Once
+)
+
+func init() {
+	selectedSha256.Store(sha256Impls[0])
+}
+
+// NewSha256 - Creates SHA-256 hash using selected implementation.
+// SIMD implementation is used unless BenchmarkAndSelectSHA256 selects other.
+func NewSha256() hash.Hash {
+	return selectedSha256.Load().(sha256Impl).new()
+}
+
+// SelectedSHA256 - Returns name of selected SHA-256 implementation,
+// either "simd" or "stdlib".
+func SelectedSHA256() string {
+	return selectedSha256.Load().(sha256Impl).name
+}
+
+// BenchmarkAndSelectSHA256 - Benchmarks SHA-256 implementations on the
+// running CPU and selects the fastest one for use in this package.
+// Benchmark takes a few milliseconds and runs only once, subsequent calls
+// return the cached choice. Returns name of selected implementation.
+func BenchmarkAndSelectSHA256() string {
+	selectSha256.Do(func() {
+		best, bestTime := sha256Impls[0], time.Duration(-1)
+		for _, impl := range sha256Impls {
+			if elapsed := benchmarkSha256(impl.new); bestTime < 0 || elapsed < bestTime {
+				best, bestTime = impl, elapsed
+			}
+		}
+		selectedSha256.Store(best)
+	})
+	return SelectedSHA256()
+}
+
+// benchmarkSha256 - Returns the shortest of a few runs hashing 1 MiB of data.
+func benchmarkSha256(newHash func() hash.Hash) time.Duration {
+	buf := make([]byte, 8<<10)
+	best := time.Duration(-1)
+	for run := 0; run < 3; run++ {
+		start := time.Now()
+		h := newHash()
+		for i := 0; i < 128; i++ {
+			h.Write(buf)
+		}
+		h.Sum(nil)
+		if elapsed := time.Since(start); best < 0 || elapsed < best {
+			best = elapsed
+		}
+	}
+	return best
+}
@@ -0,0 +1,71 @@
+// Copyright © 2017-2018 The IPFN Developers. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package digest
+
+import (
+	stdsha256 "crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/sha3"
+)
+
+func TestBenchmarkAndSelectSHA256(t *testing.T) {
+	inputs := [][]byte{nil, []byte("test"), make([]byte, 1000)}
+
+	for _, impl := range sha256Impls {
+		for _, input := range inputs {
+			h := impl.new()
+			h.Write(input)
+			expected := stdsha256.Sum256(input)
+			assert.Equal(t, expected[:], h.Sum(nil), impl.name)
+		}
+	}
+
+	name := BenchmarkAndSelectSHA256()
+	assert.Contains(t, []string{"simd", "stdlib"}, name)
+	assert.Equal(t, name, SelectedSHA256())
+	assert.Equal(t, name, BenchmarkAndSelectSHA256())
+
+	for _, input := range inputs {
+		expected := stdsha256.Sum256(input)
+		assert.Equal(t, Digest(expected), SumSha256(input))
+		assert.Equal(t, expected[:], SumSha256Bytes(input))
+	}
+}
+
+func TestSum224(t *testing.T) {
+	// FIPS 180-4 and FIPS 202 examples
+	assert.Equal(t, "23097d223405d8228642a477bda255b32aadbce4bda0b3f7e36c9da7", hex.EncodeToString(SumSha224([]byte("abc"))))
+	assert.Equal(t, "e642824c3f8cf24ad09234ee7d3c766fc9a3a5168d0c94ad73b46fdf", hex.EncodeToString(SumSha3_224([]byte("abc"))))
+	assert.Equal(t, "d14a028c2a3a2bc9476102bb288234c415a2b01f828ea62ac5b3e42f", hex.EncodeToString(SumSha224()))
+	assert.Equal(t, "6b4e03423667dbb73b6e15454f0eb1abd4597f9a1b078e3f5b5a6bc7", hex.EncodeToString(SumSha3_224()))
+
+	for _, input := range [][]byte{nil, []byte("test"), make([]byte, 1000)} {
+		expected := stdsha256.Sum224(input)
+		assert.Equal(t, expected[:], SumSha224(input))
+		expected3 := sha3.Sum224(input)
+		assert.Equal(t, expected3[:], SumSha3_224(input))
+	}
+	// Data is hashed as concatenated
+	assert.Equal(t, SumSha224([]byte("abc")), SumSha224([]byte("a"), []byte("bc")))
+
+	assert.Equal(t, FamilySha2, Sha2_224.Family())
+	assert.Equal(t, "sha2-224", Sha2_224.String())
+	typ, err := NewType("sha2-224")
+	assert.NoError(t, err)
+	assert.Equal(t, Sha2_224, typ)
+}
@@ -25,8 +25,12 @@ type Type uint64
 const (
 	// Sha1 - SHA1 hashing algorithm.
 	Sha1 Type = 0x11
+	// Sha2_224 - SHA2 224bit hashing algorithm.
+	Sha2_224 Type = 0x1013
 	// Sha2_256 - SHA2 256bit hashing algorithm.
 	Sha2_256 Type = 0x12
+	// Sha2_384 - SHA2 384bit hashing algorithm.
+	Sha2_384 Type = 0x20
 	// Sha2_512 - SHA2 512bit hashing algorithm.
 	Sha2_512 Type = 0x13
 	// Sha3_224 - SHA3 224bit hashing algorithm.
@@ -61,6 +65,9 @@ const (
 	DoubleSha2_256 Type = 0x56
 	// Murmur3 - MURMUR3 hashing algorithm.
 	Murmur3 Type = 0x22
+	// XXH3 - XXH3 64bit hashing algorithm.
+	// It is NOT cryptographic, see SumXXH3.
+	XXH3 Type = 0xb3e3
 	// UnknownType - Unknown hashing algorithm.
 	UnknownType Type = 0
 )
@@ -68,7 +75,9 @@ const (
 // Names - Multihash identifier names.
 var Names = map[Type]string{
 	Sha1:           "sha1",
+	Sha2_224:       "sha2-224",
 	Sha2_256:       "sha2-256",
+	Sha2_384:       "sha2-384",
 	Sha2_512:       "sha2-512",
 	Sha3_224:       "sha3-224",
 	Sha3_256:       "sha3-256",
@@ -76,6 +85,7 @@ var Names = map[Type]string{
 	Sha3_512:       "sha3-512",
 	DoubleSha2_256: "dbl-sha2-256",
 	Murmur3:        "murmur3",
+	XXH3:           "xxh3-64",
 	Keccak224:      "keccak-224",
 	Keccak256:      "keccak-256",
 	Keccak384:      "keccak-384",
@@ -87,7 +97,9 @@ var Names = map[Type]string{
 // Types - Multihash identifier names.
 var Types = map[string]Type{
 	"sha1":         Sha1,
+	"sha2-224":     Sha2_224,
 	"sha2-256":     Sha2_256,
+	"sha2-384":     Sha2_384,
 	"sha2-512":     Sha2_512,
 	"sha3-224":     Sha3_224,
 	"sha3-256":     Sha3_256,
@@ -95,6 +107,7 @@ var Types = map[string]Type{
 	"sha3-512":     Sha3_512,
 	"dbl-sha2-256": DoubleSha2_256,
 	"murmur3":      Murmur3,
+	"xxh3-64":      XXH3,
 	"keccak-224":   Keccak224,
 	"keccak-256":   Keccak256,
 	"keccak-384":   Keccak384,
@@ -116,8 +129,12 @@ func (t Type) Family() Family {
 	switch t {
 	case Sha1:
 		return FamilySha1
+	case Sha2_224:
+		return FamilySha2
 	case Sha2_256:
 		return FamilySha2
+	case Sha2_384:
+		return FamilySha2
 	case Sha2_512:
 		return FamilySha2
 	case Sha3_224:
@@ -149,6 +166,17 @@ func (t Type) Family() Family {
 	}
 }
 
+// Cryptographic - Returns false for fast non-cryptographic algorithms
+// which must not be used for signing or any security purpose.
+func (t Type) Cryptographic() bool {
+	switch t {
+	case Murmur3, XXH3:
+		return false
+	default:
+		return true
+	}
+}
+
 // Code - Returns algorithm multihash code.
 func (t Type) Code() uint64 {
 	return uint64(t)
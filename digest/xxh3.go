@@ -0,0 +1,50 @@
+// Copyright © 2017-2018 The IPFN Developers. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package digest
+
+import (
+	"encoding/binary"
+
+	"github.com/zeebo/xxh3"
+)
+
+// XXH3Size - Size of XXH3 sum in bytes.
+const XXH3Size = 8
+
+// SumXXH3 - Sums XXH3 64bit hash.
+//
+// WARNING: XXH3 is NOT a cryptographic hash function. Collisions can be
+// crafted at will, it is only meant for cache keys and sharding of trusted
+// data. Never use it for signing, content addressing or integrity checks.
+// Therefore it is not available to CSP hashers.
+func SumXXH3(data ...[]byte) uint64 {
+	if len(data) == 1 {
+		return xxh3.Hash(data[0])
+	}
+	h := xxh3.New()
+	for _, b := range data {
+		h.Write(b)
+	}
+	return h.Sum64()
+}
+
+// SumXXH3Bytes - Sums XXH3 64bit hash in big endian byte order.
+//
+// WARNING: XXH3 is NOT a cryptographic hash function, see SumXXH3.
+func SumXXH3Bytes(data ...[]byte) []byte {
+	sum := make([]byte, XXH3Size)
+	binary.BigEndian.PutUint64(sum, SumXXH3(data...))
+	return sum
+}